@@ -1,6 +1,8 @@
 package main
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
 	"flag"
@@ -25,7 +27,11 @@ type config struct {
 	domain          string
 	version         string
 	maxUploadSize   uint64
+	maxFileVersions int32
+	trashRetention  int32
 	jwtSecret       string
+	imageURLKey     []byte
+	shareResumeKey  []byte
 	apiKeyPrefix    string
 	jwtTTL          time.Duration
 	refreshTokenTTL time.Duration
@@ -89,6 +95,22 @@ func main() {
 	}
 }
 
+// signingKey decodes the hex encoded key held by the environment variable name. Unless the variable is set,
+// the key is derived from the JWT secret for `purpose`, so every purpose is signed with a key of its own.
+func signingKey(name, purpose string, jwtSecret []byte) ([]byte, error) {
+	key, err := hex.DecodeString(utils.GetEnvOrFile(name))
+	if err != nil {
+		return nil, err
+	}
+	if len(key) > 0 {
+		return key, nil
+	}
+
+	mac := hmac.New(sha256.New, jwtSecret)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil), nil
+}
+
 func parseConfig() (config, error) {
 	var cfg config
 
@@ -98,6 +120,16 @@ func parseConfig() (config, error) {
 		return cfg, fmt.Errorf("invalid JWT secret: %w", err)
 	}
 
+	imageURLKey, err := signingKey("IMAGE_URL_SECRET", "image-urls", jwtSecret)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid image URL secret: %w", err)
+	}
+
+	shareResumeKey, err := signingKey("SHARE_RESUME_SECRET", "share-download-resume", jwtSecret)
+	if err != nil {
+		return cfg, fmt.Errorf("invalid share resume secret: %w", err)
+	}
+
 	mailPort, _ := strconv.Atoi(utils.GetEnvOrFile("MAILTRAP_SMTP_PORT"))
 
 	var parsedValue uint64
@@ -107,12 +139,28 @@ func parseConfig() (config, error) {
 		parsedValue = 100 << 20
 	}
 
+	maxFileVersions, err := strconv.ParseInt(utils.GetEnvOrFile("MAX_FILE_VERSIONS"), 10, 32)
+	if err != nil || maxFileVersions <= 0 {
+		// Keep the 10 most recent versions of a file by default
+		maxFileVersions = 10
+	}
+
+	trashRetention, err := strconv.ParseInt(utils.GetEnvOrFile("TRASH_RETENTION_DAYS"), 10, 32)
+	if err != nil || trashRetention <= 0 || trashRetention > 365 {
+		// Keep deleted files in the trash for 7 days by default
+		trashRetention = 7
+	}
+
 	cfg.maxUploadSize = parsedValue
+	cfg.maxFileVersions = int32(maxFileVersions)
+	cfg.trashRetention = int32(trashRetention)
 	cfg.port = port
 	cfg.env = utils.GetEnvOrFile("ENV")
 	cfg.domain = utils.GetEnvOrFile("DOMAIN")
 	cfg.version = vcs.Version()
 	cfg.jwtSecret = string(jwtSecret)
+	cfg.imageURLKey = imageURLKey
+	cfg.shareResumeKey = shareResumeKey
 	cfg.apiKeyPrefix = security.ShortProjectPrefix(utils.GetEnvOrFile("PROJECT_NAME"))
 	cfg.jwtTTL = 15 * time.Minute
 	cfg.refreshTokenTTL = 7 * 24 * time.Hour
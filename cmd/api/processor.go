@@ -8,6 +8,7 @@ import (
 	"log/slog"
 
 	"github.com/hibiken/asynq"
+	"github.com/i-christian/fileShare/internal/encryption"
 	"github.com/i-christian/fileShare/internal/files"
 	"github.com/i-christian/fileShare/internal/jobs"
 	"github.com/i-christian/fileShare/internal/mailer"
@@ -23,12 +24,13 @@ type Processor interface {
 type RedisTaskProcessor struct {
 	server      *asynq.Server
 	fileService *files.FileService
+	keyService  *encryption.KeyService
 	mailer      *mailer.Mailer
 	conn        *sql.DB
 	logger      *slog.Logger
 }
 
-func NewRedisTaskProcessor(redisOpt asynq.RedisClientOpt, fileService *files.FileService, conn *sql.DB, logger *slog.Logger, mailer *mailer.Mailer) *RedisTaskProcessor {
+func NewRedisTaskProcessor(redisOpt asynq.RedisClientOpt, fileService *files.FileService, keyService *encryption.KeyService, conn *sql.DB, logger *slog.Logger, mailer *mailer.Mailer) *RedisTaskProcessor {
 	server := asynq.NewServer(
 		redisOpt,
 		asynq.Config{
@@ -51,6 +53,7 @@ func NewRedisTaskProcessor(redisOpt asynq.RedisClientOpt, fileService *files.Fil
 	return &RedisTaskProcessor{
 		server:      server,
 		fileService: fileService,
+		keyService:  keyService,
 		logger:      logger,
 		mailer:      mailer,
 		conn:        conn,
@@ -61,8 +64,12 @@ func (p *RedisTaskProcessor) Start() error {
 	mux := asynq.NewServeMux()
 
 	mux.HandleFunc(worker.TaskGenerateThumbnail, p.ProcessTaskGenerateThumbnail)
+	mux.HandleFunc(worker.TaskExtractImageMetadata, p.ProcessTaskExtractImageMetadata)
+	mux.HandleFunc(worker.TaskExtractText, p.ProcessTaskExtractText)
+	mux.HandleFunc(worker.TaskScanFile, p.ProcessTaskScanFile)
 	mux.HandleFunc(worker.TaskSendEmail, p.ProcessTaskSendEmail)
 	mux.HandleFunc(worker.TaskCleanupSystem, p.ProcessTaskCleanupSystem)
+	mux.HandleFunc(worker.TaskRewrapDataKeys, p.ProcessTaskRewrapDataKeys)
 
 	return p.server.Run(mux)
 }
@@ -88,6 +95,57 @@ func (p *RedisTaskProcessor) ProcessTaskGenerateThumbnail(ctx context.Context, t
 	return nil
 }
 
+func (p *RedisTaskProcessor) ProcessTaskExtractImageMetadata(ctx context.Context, task *asynq.Task) error {
+	var payload worker.ImageMetadataPayload
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", asynq.SkipRetry)
+	}
+
+	p.logger.Info("processing image metadata task", "file_id", payload.FileID)
+
+	err := p.fileService.ExtractImageMetadata(ctx, payload.FileID, payload.StorageKey)
+	if err != nil {
+		return fmt.Errorf("failed to extract image metadata: %w", err)
+	}
+
+	p.logger.Info("processed image metadata task successfully", "file_id", payload.FileID)
+	return nil
+}
+
+func (p *RedisTaskProcessor) ProcessTaskExtractText(ctx context.Context, task *asynq.Task) error {
+	var payload worker.ExtractTextPayload
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", asynq.SkipRetry)
+	}
+
+	p.logger.Info("processing text extraction task", "file_id", payload.FileID)
+
+	err := p.fileService.ExtractText(ctx, payload.FileID, payload.StorageKey)
+	if err != nil {
+		return fmt.Errorf("failed to extract text: %w", err)
+	}
+
+	p.logger.Info("processed text extraction task successfully", "file_id", payload.FileID)
+	return nil
+}
+
+func (p *RedisTaskProcessor) ProcessTaskScanFile(ctx context.Context, task *asynq.Task) error {
+	var payload worker.ScanFilePayload
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal payload: %w", asynq.SkipRetry)
+	}
+
+	p.logger.Info("processing scan task", "file_id", payload.FileID)
+
+	err := p.fileService.ScanFile(ctx, payload.FileID, payload.StorageKey)
+	if err != nil {
+		return fmt.Errorf("failed to scan file: %w", err)
+	}
+
+	p.logger.Info("processed scan task successfully", "file_id", payload.FileID)
+	return nil
+}
+
 func (p *RedisTaskProcessor) ProcessTaskSendEmail(ctx context.Context, task *asynq.Task) error {
 	var payload worker.EmailPayload
 	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
@@ -113,11 +171,49 @@ func (p *RedisTaskProcessor) ProcessTaskCleanupSystem(ctx context.Context, task
 		p.logger.Error("failed to cleanup files", "error", err)
 	}
 
+	deletedUploadCount, err := p.fileService.CleanupExpiredUploads(ctx, limit)
+	if err != nil {
+		p.logger.Error("failed to cleanup uploads", "error", err)
+	}
+
+	deletedVersionCount, err := p.fileService.CleanupExcessFileVersions(ctx, limit)
+	if err != nil {
+		p.logger.Error("failed to cleanup file versions", "error", err)
+	}
+
+	deletedFolderCount, err := p.fileService.CleanupExpiredFolders(ctx)
+	if err != nil {
+		p.logger.Error("failed to cleanup folders", "error", err)
+	}
+
+	deletedBlobCount, err := p.fileService.CleanupUnreferencedBlobs(ctx, limit)
+	if err != nil {
+		p.logger.Error("failed to cleanup blobs", "error", err)
+	}
+
 	expiredCounts, err := jobs.CleanUpExpired(ctx, p.conn)
 	if err != nil {
 		p.logger.Error("failed to cleanup tokens", "error", err)
 	}
 
-	p.logger.Info("system cleanup task finished", "apiKeys", expiredCounts.APIKeysDeleted, "actionTokens", expiredCounts.ActionTokensDeleted, "refreshTokens", expiredCounts.RefreshTokensDeleted, "deleted files", deletedFileCount)
+	p.logger.Info("system cleanup task finished", "apiKeys", expiredCounts.APIKeysDeleted, "actionTokens", expiredCounts.ActionTokensDeleted, "refreshTokens", expiredCounts.RefreshTokensDeleted, "deleted files", deletedFileCount, "expired uploads", deletedUploadCount, "pruned file versions", deletedVersionCount, "deleted folders", deletedFolderCount, "deleted blobs", deletedBlobCount)
+	return nil
+}
+
+// ProcessTaskRewrapDataKeys re-wraps data keys still wrapped by a retired master key with the current one.
+// It does nothing when encryption at rest is disabled.
+func (p *RedisTaskProcessor) ProcessTaskRewrapDataKeys(ctx context.Context, task *asynq.Task) error {
+	if p.keyService == nil {
+		return nil
+	}
+
+	rewrapped, failed, err := p.keyService.RewrapDataKeys(ctx, 100)
+	if err != nil {
+		return fmt.Errorf("failed to rewrap data keys: %w", err)
+	}
+
+	if rewrapped > 0 || failed > 0 {
+		p.logger.Info("data key rotation finished", "rewrapped keys", rewrapped, "failed keys", failed)
+	}
 	return nil
 }
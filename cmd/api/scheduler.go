@@ -40,6 +40,11 @@ func (s *RedisTaskScheduler) Start() error {
 		return fmt.Errorf("failed to register cleanup task: %w", err)
 	}
 
+	// Picks up master key rotations, which only take effect for existing data keys once they are re-wrapped
+	if _, err := s.scheduler.Register("30 * * * *", asynq.NewTask(worker.TaskRewrapDataKeys, nil)); err != nil {
+		return fmt.Errorf("failed to register data key rotation task: %w", err)
+	}
+
 	s.logger.Info("scheduler started and cron jobs registered")
 	return s.scheduler.Run()
 }
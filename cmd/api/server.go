@@ -18,11 +18,14 @@ import (
 	"github.com/i-christian/fileShare/internal/auth"
 	"github.com/i-christian/fileShare/internal/database"
 	"github.com/i-christian/fileShare/internal/db"
+	"github.com/i-christian/fileShare/internal/encryption"
 	"github.com/i-christian/fileShare/internal/files"
 	"github.com/i-christian/fileShare/internal/filestore"
 	"github.com/i-christian/fileShare/internal/mailer"
+	"github.com/i-christian/fileShare/internal/preview"
 	"github.com/i-christian/fileShare/internal/public"
 	"github.com/i-christian/fileShare/internal/router"
+	"github.com/i-christian/fileShare/internal/scanner"
 	"github.com/i-christian/fileShare/internal/user"
 	"github.com/i-christian/fileShare/internal/utils"
 	"github.com/i-christian/fileShare/internal/worker"
@@ -42,6 +45,16 @@ func (app *application) serve(dbConn *sql.DB) error {
 
 	fileStorage := filestore.SetUpFileStorage(app.logger)
 	psqlService := database.New(dbConn)
+
+	var keyService *encryption.KeyService
+	if keyProvider := encryption.SetUpKeyProvider(app.logger); keyProvider != nil {
+		keyService = encryption.NewKeyService(psqlService, keyProvider, app.logger)
+		fileStorage = filestore.NewEncryptedStorage(fileStorage, keyService)
+	}
+
+	fileScanner := scanner.SetUpScanner(app.logger)
+	previewers := preview.SetUpRegistry(app.logger)
+
 	redisOpt := asynq.RedisClientOpt{
 		Addr: utils.GetEnvOrFile("REDIS_ADDR"),
 	}
@@ -49,14 +62,15 @@ func (app *application) serve(dbConn *sql.DB) error {
 
 	publicHandler := public.NewPublicHandler(app.config.env, app.config.version, app.logger)
 
-	authService := auth.NewAuthService(psqlService, app.config.jwtSecret, app.config.jwtTTL, app.logger)
+	signingKeys := auth.SetUpSigningKeys(app.config.jwtTTL, app.logger)
+	authService := auth.NewAuthService(dbConn, psqlService, app.config.jwtSecret, signingKeys, app.config.jwtTTL, app.logger)
 	apiKeyService := auth.NewAPIKeyService(8, app.config.apiKeyPrefix, psqlService, app.logger, &app.wg)
 	authHandler := auth.NewAuthHandler(authService, apiKeyService, app.config.refreshTokenTTL, app.logger, taskDistributor)
 
 	userService := user.NewUserService(psqlService, app.logger)
 	userHandler := user.NewUserHandler(userService)
 
-	fileService := files.NewFileService(psqlService, fileStorage, app.logger, taskDistributor)
+	fileService := files.NewFileService(dbConn, psqlService, fileStorage, app.logger, taskDistributor, fileScanner, previewers, app.config.imageURLKey, app.config.shareResumeKey, app.config.maxFileVersions, app.config.trashRetention)
 	fileHandler := files.NewFileHandler(app.config.maxUploadSize, fileService, app.logger)
 
 	routeConfig := &router.RoutesConfig{
@@ -85,7 +99,7 @@ func (app *application) serve(dbConn *sql.DB) error {
 		}
 	}()
 
-	taskProcessor := NewRedisTaskProcessor(redisOpt, fileService, dbConn, app.logger, mailService)
+	taskProcessor := NewRedisTaskProcessor(redisOpt, fileService, keyService, dbConn, app.logger, mailService)
 	go func() {
 		if err := taskProcessor.Start(); err != nil {
 			app.logger.Error("failed to start task processor", "error", err)
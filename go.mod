@@ -20,6 +20,7 @@ require (
 	github.com/sqlc-dev/pqtype v0.3.0
 	github.com/wneessen/go-mail v0.7.2
 	golang.org/x/crypto v0.38.0
+	golang.org/x/image v0.33.0
 	golang.org/x/time v0.14.0
 )
 
@@ -47,7 +48,6 @@ require (
 	github.com/sethvargo/go-retry v0.3.0 // indirect
 	github.com/spf13/cast v1.10.0 // indirect
 	go.uber.org/multierr v1.11.0 // indirect
-	golang.org/x/image v0.33.0 // indirect
 	golang.org/x/sync v0.18.0 // indirect
 	golang.org/x/sys v0.38.0 // indirect
 	golang.org/x/text v0.31.0 // indirect
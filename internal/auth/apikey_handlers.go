@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/i-christian/fileShare/internal/utils"
+	"github.com/i-christian/fileShare/internal/utils/security"
+	"github.com/i-christian/fileShare/internal/validator"
+)
+
+// defaultKeyRotationOverlap is how long the secret a rotated API key replaces is still accepted when no overlap is given.
+const defaultKeyRotationOverlap = 24 * time.Hour
+
+// ListAPIKeys lists the API keys of the user, with when and from which IP address each was last used
+func (h *AuthHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	user, ok := security.GetUserFromContext(r)
+	if !ok || user.IsAnonymous() {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	keys, err := h.apiKeyService.ListAPIKeys(r.Context(), user.UserID)
+	if err != nil {
+		utils.WriteServerError(h.logger, "failed to list api keys", err)
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"api_keys": keys}, nil)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+	}
+}
+
+// RevokeAPIKey revokes an API key of the user, requests made with it fail from then on
+func (h *AuthHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	apiKeyID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		utils.BadRequestResponse(w, errors.New("invalid api key ID parameter"))
+		return
+	}
+
+	user, ok := security.GetUserFromContext(r)
+	if !ok || user.IsAnonymous() {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	err = h.apiKeyService.RevokeAPIKey(r.Context(), apiKeyID, user.UserID)
+	if err != nil {
+		h.writeAPIKeyError(w, "revoke api key", err)
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "api key revoked successfully"}, nil)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+	}
+}
+
+// RenameAPIKey changes the name of an API key of the user
+func (h *AuthHandler) RenameAPIKey(w http.ResponseWriter, r *http.Request) {
+	apiKeyID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		utils.BadRequestResponse(w, errors.New("invalid api key ID parameter"))
+		return
+	}
+
+	user, ok := security.GetUserFromContext(r)
+	if !ok || user.IsAnonymous() {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	var input struct {
+		KeyName string `json:"key_name"`
+	}
+
+	err = utils.ReadJSON(w, r, &input)
+	if err != nil {
+		utils.BadRequestResponse(w, err)
+		return
+	}
+
+	v := validator.New()
+	if validator.ValidateAPIKeyName(v, input.KeyName); !v.Valid() {
+		utils.FailedValidationResponse(w, v.Errors)
+		return
+	}
+
+	err = h.apiKeyService.RenameAPIKey(r.Context(), apiKeyID, user.UserID, input.KeyName)
+	if err != nil {
+		h.writeAPIKeyError(w, "rename api key", err)
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "api key renamed successfully"}, nil)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+	}
+}
+
+// RotateAPIKey issues a new secret for an API key of the user. The old secret keeps working
+// for overlap_minutes, 24 hours when it is left out, so clients can switch over without downtime.
+func (h *AuthHandler) RotateAPIKey(w http.ResponseWriter, r *http.Request) {
+	apiKeyID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		utils.BadRequestResponse(w, errors.New("invalid api key ID parameter"))
+		return
+	}
+
+	user, ok := security.GetUserFromContext(r)
+	if !ok || user.IsAnonymous() {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	var input struct {
+		OverlapMinutes *int `json:"overlap_minutes"`
+	}
+
+	// The body is optional, the default overlap applies without one
+	if r.ContentLength != 0 {
+		err = utils.ReadJSON(w, r, &input)
+		if err != nil {
+			utils.BadRequestResponse(w, err)
+			return
+		}
+	}
+
+	overlap := defaultKeyRotationOverlap
+	if input.OverlapMinutes != nil {
+		v := validator.New()
+		if validator.ValidateKeyRotation(v, *input.OverlapMinutes); !v.Valid() {
+			utils.FailedValidationResponse(w, v.Errors)
+			return
+		}
+		overlap = time.Duration(*input.OverlapMinutes) * time.Minute
+	}
+
+	fullKey, previousExpiresAt, err := h.apiKeyService.RotateAPIKey(r.Context(), apiKeyID, user.UserID, overlap)
+	if err != nil {
+		h.writeAPIKeyError(w, "rotate api key", err)
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"apiKey":                  fullKey,
+		"previous_key_expires_at": previousExpiresAt,
+	}, nil)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+	}
+}
+
+// DeleteAPIKey permanently deletes an API key of the user
+func (h *AuthHandler) DeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	apiKeyID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		utils.BadRequestResponse(w, errors.New("invalid api key ID parameter"))
+		return
+	}
+
+	user, ok := security.GetUserFromContext(r)
+	if !ok || user.IsAnonymous() {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	err = h.apiKeyService.DeleteAPIKey(r.Context(), apiKeyID, user.UserID)
+	if err != nil {
+		h.writeAPIKeyError(w, "delete api key", err)
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "api key deleted successfully"}, nil)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+	}
+}
+
+func (h *AuthHandler) writeAPIKeyError(w http.ResponseWriter, msg string, err error) {
+	switch {
+	case errors.Is(err, utils.ErrRecordNotFound):
+		utils.NotFoundResponse(w)
+	case errors.Is(err, utils.ErrKeyInactive):
+		utils.WriteErrorJSON(w, http.StatusConflict, err.Error())
+	default:
+		utils.WriteServerError(h.logger, "failed to "+msg, err)
+		utils.ServerErrorResponse(w, "failed to "+msg)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"log/slog"
+	"net"
 	"strings"
 	"sync"
 	"time"
@@ -14,6 +15,7 @@ import (
 	"github.com/i-christian/fileShare/internal/utils"
 	"github.com/i-christian/fileShare/internal/utils/security"
 	"github.com/i-christian/fileShare/internal/worker"
+	"github.com/sqlc-dev/pqtype"
 )
 
 type APIKeyService struct {
@@ -57,8 +59,7 @@ func (s *APIKeyService) GenerateAPIKey(ctx context.Context, userID uuid.UUID, na
 		}
 	}
 
-	secret, _ := security.GenerateStringAndHash()
-	keyHash, err := security.HashPassword(secret)
+	secret, keyHash, err := newAPIKeySecret()
 	if err != nil {
 		return "", err
 	}
@@ -80,8 +81,20 @@ func (s *APIKeyService) GenerateAPIKey(ctx context.Context, userID uuid.UUID, na
 	return fullKey, nil
 }
 
-// ValidateAPIKey checks a full API key string
-func (s *APIKeyService) ValidateAPIKey(ctx context.Context, keyString string) (*security.ContextUser, error) {
+// newAPIKeySecret generates the secret part of an API key and its hash.
+func newAPIKeySecret() (secret, keyHash string, err error) {
+	secret, _ = security.GenerateStringAndHash()
+	keyHash, err = security.HashPassword(secret)
+	if err != nil {
+		return "", "", err
+	}
+
+	return secret, keyHash, nil
+}
+
+// ValidateAPIKey checks a full API key string, accepting the secret a rotated key had before
+// until the overlap window of the rotation ends, and records when and from which IP address it was used.
+func (s *APIKeyService) ValidateAPIKey(ctx context.Context, keyString, ipAddress string) (*security.ContextUser, error) {
 	parts := strings.SplitN(keyString, "_", 2)
 	if len(parts) != 2 {
 		return nil, errors.New("invalid api key format")
@@ -98,19 +111,27 @@ func (s *APIKeyService) ValidateAPIKey(ctx context.Context, keyString string) (*
 		return nil, errors.Join(err, utils.ErrUnexpectedError)
 	}
 
+	if dBKey.IsRevoked {
+		return nil, errors.New("api key has been revoked")
+	}
+
 	if !dBKey.ExpiresAt.IsZero() && time.Now().After(dBKey.ExpiresAt) {
 		return nil, errors.New("api key has expired")
 	}
 
 	err = security.VerifyPassword(dBKey.KeyHash, secret)
 	if err != nil {
-		return nil, errors.New("invalid api key")
+		inOverlap := dBKey.PreviousKeyHash.Valid && dBKey.PreviousKeyExpiresAt.Valid && time.Now().Before(dBKey.PreviousKeyExpiresAt.Time)
+		if !inOverlap || security.VerifyPassword(dBKey.PreviousKeyHash.String, secret) != nil {
+			return nil, errors.New("invalid api key")
+		}
 	}
 
 	worker.BackgroundTask(s.wg, s.logger, func(l *slog.Logger) {
 		params := database.UpdateApiKeyLastUsedParams{
 			ApiKeyID:   dBKey.ApiKeyID,
 			LastUsedAt: sql.NullTime{Time: time.Now(), Valid: true},
+			LastUsedIp: inet(ipAddress),
 		}
 
 		err := s.queries.UpdateApiKeyLastUsed(context.Background(), params)
@@ -123,12 +144,160 @@ func (s *APIKeyService) ValidateAPIKey(ctx context.Context, keyString string) (*
 		}
 	})
 
+	scopes := make([]string, 0, len(dBKey.Scope))
+	for _, scope := range dBKey.Scope {
+		scopes = append(scopes, string(scope))
+	}
+
 	return &security.ContextUser{
 		FirstName:   dBKey.FirstName,
 		LastName:    dBKey.LastName,
 		Email:       dBKey.Email,
 		Role:        string(dBKey.Role),
+		AuthMethod:  security.AuthAPIKey,
+		Scopes:      scopes,
 		UserID:      dBKey.UserID,
 		IsActivated: dBKey.IsVerified,
 	}, nil
 }
+
+// inet converts an IP address to an inet value, invalid addresses are stored as NULL.
+func inet(address string) pqtype.Inet {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return pqtype.Inet{}
+	}
+
+	bits := net.IPv6len * 8
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits = ip4, net.IPv4len*8
+	}
+
+	return pqtype.Inet{IPNet: net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, Valid: true}
+}
+
+// ListAPIKeys returns every API key of the user, newest first.
+func (s *APIKeyService) ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]APIKey, error) {
+	rows, err := s.queries.ListApiKeysByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]APIKey, 0, len(rows))
+	for _, row := range rows {
+		key := APIKey{
+			APIKeyID:   row.ApiKeyID,
+			Name:       row.Name,
+			Prefix:     row.Prefix,
+			Scope:      make([]string, 0, len(row.Scope)),
+			IsRevoked:  row.IsRevoked,
+			RevokedAt:  row.RevokedAt.Time,
+			CreatedAt:  row.CreatedAt,
+			ExpiresAt:  row.ExpiresAt,
+			LastUsedAt: row.LastUsedAt.Time,
+		}
+		for _, scope := range row.Scope {
+			key.Scope = append(key.Scope, string(scope))
+		}
+		if row.LastUsedIp.Valid {
+			key.LastUsedIP = row.LastUsedIp.IPNet.IP.String()
+		}
+		if row.PreviousKeyExpiresAt.Valid && time.Now().Before(row.PreviousKeyExpiresAt.Time) {
+			key.PreviousKeyExpiresAt = row.PreviousKeyExpiresAt.Time
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey revokes an API key of the user, it fails authentication from then on but is still listed.
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, apiKeyID, userID uuid.UUID) error {
+	revoked, err := s.queries.RevokeApiKey(ctx, database.RevokeApiKeyParams{
+		ApiKeyID: apiKeyID,
+		UserID:   userID,
+	})
+	if err != nil {
+		return err
+	}
+	if revoked == 0 {
+		return utils.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// RenameAPIKey changes the name of an API key of the user.
+func (s *APIKeyService) RenameAPIKey(ctx context.Context, apiKeyID, userID uuid.UUID, name string) error {
+	renamed, err := s.queries.RenameApiKey(ctx, database.RenameApiKeyParams{
+		ApiKeyID: apiKeyID,
+		UserID:   userID,
+		Name:     name,
+	})
+	if err != nil {
+		return err
+	}
+	if renamed == 0 {
+		return utils.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// RotateAPIKey issues a new secret for an API key of the user, returning the full new key one time.
+// The secret it replaces is still accepted for the overlap window, so clients can switch to the new key without downtime.
+// Rotating again within the window stops the oldest secret from being accepted straight away.
+func (s *APIKeyService) RotateAPIKey(ctx context.Context, apiKeyID, userID uuid.UUID, overlap time.Duration) (string, time.Time, error) {
+	status, err := s.queries.GetApiKeyStatus(ctx, database.GetApiKeyStatusParams{
+		ApiKeyID: apiKeyID,
+		UserID:   userID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", time.Time{}, utils.ErrRecordNotFound
+		}
+		return "", time.Time{}, err
+	}
+
+	if status.IsRevoked || time.Now().After(status.ExpiresAt) {
+		return "", time.Time{}, utils.ErrKeyInactive
+	}
+
+	secret, keyHash, err := newAPIKeySecret()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	previousExpiresAt := time.Now().Add(overlap)
+	prefix, err := s.queries.RotateApiKey(ctx, database.RotateApiKeyParams{
+		ApiKeyID:             apiKeyID,
+		UserID:               userID,
+		PreviousKeyExpiresAt: sql.NullTime{Time: previousExpiresAt, Valid: true},
+		KeyHash:              keyHash,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", time.Time{}, utils.ErrRecordNotFound
+		}
+		return "", time.Time{}, err
+	}
+
+	return prefix + "_" + secret, previousExpiresAt, nil
+}
+
+// DeleteAPIKey permanently deletes an API key of the user.
+func (s *APIKeyService) DeleteAPIKey(ctx context.Context, apiKeyID, userID uuid.UUID) error {
+	deleted, err := s.queries.DeleteApiKey(ctx, database.DeleteApiKeyParams{
+		ApiKeyID: apiKeyID,
+		UserID:   userID,
+	})
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return utils.ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,6 +17,9 @@ import (
 	"github.com/i-christian/fileShare/internal/worker"
 )
 
+// maxUserAgentLength is the longest user agent recorded for a session, longer ones are truncated.
+const maxUserAgentLength = 512
+
 type AuthHandler struct {
 	authService     *AuthService
 	apiKeyService   *APIKeyService
@@ -120,16 +124,32 @@ func (h *AuthHandler) LoginWithRefresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	accessToken, refreshToken, err := h.authService.LoginWithRefresh(r.Context(), req.Email, req.Password, h.refreshTokenTTL)
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = strings.ToValidUTF8(userAgent[:maxUserAgentLength], "")
+	}
+	ipAddress, _ := security.GetIPAddress(r)
+
+	login, err := h.authService.LoginWithRefresh(r.Context(), req.Email, req.Password, userAgent, ipAddress, h.refreshTokenTTL)
 	if err != nil {
+		if errors.Is(err, utils.ErrUnexpectedError) {
+			utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+			utils.WriteServerError(h.logger, "login failure", err)
+			return
+		}
+
 		utils.UnauthorisedResponse(w, ErrInvalidCredentials.Error())
 		utils.WriteServerError(h.logger, "login failure", err)
 		return
 	}
 
+	if login.NewDevice {
+		h.notifyNewDevice(login, userAgent, ipAddress)
+	}
+
 	data := map[string]string{
-		"access_token":  accessToken,
-		"refresh_token": refreshToken,
+		"access_token":  login.AccessToken,
+		"refresh_token": login.RefreshToken,
 	}
 
 	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"tokens": data}, nil)
@@ -139,6 +159,40 @@ func (h *AuthHandler) LoginWithRefresh(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// notifyNewDevice emails the user about a login from a device none of their earlier sessions were started from.
+func (h *AuthHandler) notifyNewDevice(login LoginResult, userAgent, ipAddress string) {
+	if userAgent == "" {
+		userAgent = "unknown"
+	}
+	if ipAddress == "" {
+		ipAddress = "unknown"
+	}
+
+	data := map[string]any{
+		"AppName":   utils.GetEnvOrFile("PROJECT_NAME"),
+		"FirstName": login.FirstName,
+		"UserAgent": userAgent,
+		"IPAddress": ipAddress,
+		"LoginTime": time.Now().UTC().Format(time.RFC1123),
+		"Year":      time.Now().Year(),
+	}
+	payload := &worker.EmailPayload{
+		Recipient:    login.Email,
+		UserID:       login.UserID,
+		TemplateFile: "new_device_login.tmpl",
+		Data:         data,
+	}
+	opts := []asynq.Option{
+		asynq.Queue("critical"),
+		asynq.MaxRetry(5),
+	}
+
+	err := h.distributor.DistributeSendEmail(context.Background(), payload, opts...)
+	if err != nil {
+		utils.WriteServerError(h.logger, "failed to queue new device email", err)
+	}
+}
+
 func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		RefreshToken string `json:"refresh_token"`
@@ -149,7 +203,9 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	accessToken, err := h.authService.RefreshAccessToken(r.Context(), req.RefreshToken)
+	ipAddress, _ := security.GetIPAddress(r)
+
+	accessToken, refreshToken, err := h.authService.RefreshAccessToken(r.Context(), req.RefreshToken, ipAddress, h.refreshTokenTTL)
 	if err != nil {
 		if errors.Is(err, utils.ErrUnexpectedError) {
 			utils.UnauthorisedResponse(w, utils.ErrUnexpectedError.Error())
@@ -162,7 +218,70 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"access_token": accessToken}, nil)
+	data := map[string]string{
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"tokens": data}, nil)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+		utils.WriteServerError(h.logger, "failed to encode json response", err)
+	}
+}
+
+// Logout ends the session the refresh token was issued to, access tokens already issued stay valid until they expire
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	if err := utils.ReadJSON(w, r, &req); err != nil {
+		utils.BadRequestResponse(w, err)
+		return
+	}
+
+	v := validator.New()
+	if v.Check(req.RefreshToken != "", "refresh_token", "must be provided"); !v.Valid() {
+		utils.FailedValidationResponse(w, v.Errors)
+		return
+	}
+
+	err := h.authService.Logout(r.Context(), req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, ErrInvalidToken) {
+			utils.UnauthorisedResponse(w, err.Error())
+			return
+		}
+
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+		utils.WriteServerError(h.logger, "failed to log out", err)
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "logged out successfully"}, nil)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+		utils.WriteServerError(h.logger, "failed to encode json response", err)
+	}
+}
+
+// LogoutEverywhere ends every session of the user
+func (h *AuthHandler) LogoutEverywhere(w http.ResponseWriter, r *http.Request) {
+	user, ok := security.GetUserFromContext(r)
+	if !ok || user.IsAnonymous() {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	err := h.authService.LogoutEverywhere(r.Context(), user.UserID)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+		utils.WriteServerError(h.logger, "failed to log out everywhere", err)
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "logged out of every session successfully"}, nil)
 	if err != nil {
 		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
 		utils.WriteServerError(h.logger, "failed to encode json response", err)
@@ -343,3 +462,15 @@ func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
 		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
 	}
 }
+
+// JWKS publishes the public keys access tokens are signed with as a JSON Web Key Set, so other services can verify them
+func (h *AuthHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	headers := http.Header{}
+	headers.Set("Cache-Control", "public, max-age=300")
+
+	err := utils.WriteJSON(w, http.StatusOK, utils.Envelope{"keys": h.authService.PublicKeys()}, headers)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+		utils.WriteServerError(h.logger, "failed to encode json response", err)
+	}
+}
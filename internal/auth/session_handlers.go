@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/i-christian/fileShare/internal/utils"
+	"github.com/i-christian/fileShare/internal/utils/security"
+)
+
+// ListSessions lists the sessions the user is logged in with, with the device and IP address each was last used from
+func (h *AuthHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	user, ok := security.GetUserFromContext(r)
+	if !ok || user.IsAnonymous() {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	sessions, err := h.authService.ListSessions(r.Context(), user.UserID, user.SessionID)
+	if err != nil {
+		utils.WriteServerError(h.logger, "failed to list sessions", err)
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"sessions": sessions}, nil)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+	}
+}
+
+// RevokeSession logs one of the user's sessions out, access tokens already issued to it stay valid until they expire
+func (h *AuthHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	sessionID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		utils.BadRequestResponse(w, errors.New("invalid session ID parameter"))
+		return
+	}
+
+	user, ok := security.GetUserFromContext(r)
+	if !ok || user.IsAnonymous() {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	err = h.authService.RevokeSession(r.Context(), sessionID, user.UserID)
+	if err != nil {
+		if errors.Is(err, utils.ErrRecordNotFound) {
+			utils.NotFoundResponse(w)
+			return
+		}
+
+		utils.WriteServerError(h.logger, "failed to revoke session", err)
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "session revoked successfully"}, nil)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+	}
+}
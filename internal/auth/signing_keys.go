@@ -0,0 +1,317 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/i-christian/fileShare/internal/utils"
+)
+
+// minRSAKeyBits is the smallest RSA key access tokens can be signed with.
+const minRSAKeyBits = 2048
+
+// signingKeyClockSkew is how early a token signed with a key which was just activated is accepted,
+// so instances whose clocks are slightly behind accept tokens issued by those ahead of them.
+const signingKeyClockSkew = time.Minute
+
+// JWK is the public part of a signing key, as published in the JSON Web Key Set.
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// signingKey signs access tokens from activatesAt until the key activated after it takes over at retiresAt,
+// its tokens are still accepted for the grace period of the key ring after that.
+type signingKey struct {
+	activatesAt time.Time
+	retiresAt   time.Time
+	method      jwt.SigningMethod
+	private     crypto.Signer
+	jwk         JWK
+}
+
+// SigningKeyRing holds the Ed25519 and RSA keys access tokens are signed with, each identified by the kid header
+// of the tokens it signs. Keys take over signing at the time they are scheduled to, so keys are rotated without
+// a restart, and tokens signed by the keys they replace are accepted until they have expired.
+type SigningKeyRing struct {
+	keys        []*signingKey
+	gracePeriod time.Duration
+	// secretAcceptedUntil is when tokens signed with JWT_SECRET stop being accepted, they are refused when it is zero
+	secretAcceptedUntil time.Time
+}
+
+// SetUpSigningKeys loads the key ring from the JWT_SIGNING_KEYS_FILE env variable. It returns nil when the variable is
+// not set, in which case access tokens are signed with JWT_SECRET. Keys are accepted for at least accessTokenTTL
+// after they are replaced, longer when JWT_KEY_GRACE_PERIOD says so. Tokens signed with JWT_SECRET are refused once
+// the key ring is loaded, unless JWT_SECRET_ACCEPTED_UNTIL sets the time until which they are still accepted.
+func SetUpSigningKeys(accessTokenTTL time.Duration, logger *slog.Logger) *SigningKeyRing {
+	keyRingFile := utils.GetEnvOrFile("JWT_SIGNING_KEYS_FILE")
+	if keyRingFile == "" {
+		logger.Warn("JWT_SIGNING_KEYS_FILE is not set, access tokens are signed with JWT_SECRET and no keys are published")
+		return nil
+	}
+
+	gracePeriod := accessTokenTTL
+	if value := utils.GetEnvOrFile("JWT_KEY_GRACE_PERIOD"); value != "" {
+		configured, err := time.ParseDuration(value)
+		if err != nil {
+			logger.Error("invalid JWT_KEY_GRACE_PERIOD", "error", err)
+			os.Exit(1)
+		}
+		// Tokens signed just before a key is replaced must stay valid until they expire
+		gracePeriod = max(configured, accessTokenTTL)
+	}
+
+	keyRing, err := LoadSigningKeyRing(keyRingFile, gracePeriod)
+	if err != nil {
+		utils.WriteServerError(logger, "failed to load JWT signing keys", err)
+		os.Exit(1)
+	}
+
+	if value := utils.GetEnvOrFile("JWT_SECRET_ACCEPTED_UNTIL"); value != "" {
+		// A fixed time rather than one relative to startup, so restarts and new instances do not extend it
+		keyRing.secretAcceptedUntil, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			logger.Error("invalid JWT_SECRET_ACCEPTED_UNTIL", "error", err)
+			os.Exit(1)
+		}
+		if time.Now().Before(keyRing.secretAcceptedUntil) {
+			logger.Warn("tokens signed with JWT_SECRET are still accepted", "until", keyRing.secretAcceptedUntil.Format(time.RFC3339))
+		}
+	}
+
+	current, err := keyRing.current(time.Now())
+	if err != nil {
+		utils.WriteServerError(logger, "failed to load JWT signing keys", err)
+		os.Exit(1)
+	}
+
+	logger.Info("Initialised JWT signing keys", "keys", len(keyRing.keys), "current key", current.jwk.Kid, "algorithm", current.jwk.Alg)
+	return keyRing
+}
+
+// LoadSigningKeyRing reads a key ring file listing one signing key per line, as the time the key takes over signing
+// in RFC 3339 format followed by the path of its PEM encoded private key, relative to the key ring file.
+// Blank lines and lines starting with # are ignored.
+func LoadSigningKeyRing(path string, gracePeriod time.Duration) (*SigningKeyRing, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing keys file: %w", err)
+	}
+
+	k := &SigningKeyRing{gracePeriod: gracePeriod}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		fields := strings.Fields(entry)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d of signing keys file must hold an activation time and a key file", line)
+		}
+
+		activatesAt, err := time.Parse(time.RFC3339, fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid activation time on line %d of signing keys file: %w", line, err)
+		}
+
+		keyFile := fields[1]
+		if !filepath.IsAbs(keyFile) {
+			keyFile = filepath.Join(filepath.Dir(path), keyFile)
+		}
+
+		key, err := loadSigningKey(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid signing key on line %d of signing keys file: %w", line, err)
+		}
+		key.activatesAt = activatesAt
+
+		for _, other := range k.keys {
+			if other.jwk.Kid == key.jwk.Kid {
+				return nil, fmt.Errorf("signing key on line %d of signing keys file is listed twice", line)
+			}
+			if other.activatesAt.Equal(activatesAt) {
+				return nil, fmt.Errorf("signing key on line %d of signing keys file activates at the same time as another key", line)
+			}
+		}
+
+		k.keys = append(k.keys, key)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(k.keys) == 0 {
+		return nil, errors.New("signing keys file lists no keys")
+	}
+
+	slices.SortFunc(k.keys, func(a, b *signingKey) int {
+		return a.activatesAt.Compare(b.activatesAt)
+	})
+	for i := 1; i < len(k.keys); i++ {
+		k.keys[i-1].retiresAt = k.keys[i].activatesAt
+	}
+
+	return k, nil
+}
+
+// loadSigningKey reads a PEM encoded Ed25519 or RSA private key, in PKCS #8 or, for RSA keys, PKCS #1 form.
+func loadSigningKey(path string) (*signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s holds no PEM encoded key", path)
+	}
+
+	var parsed any
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s holds a %s, not a private key", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	switch private := parsed.(type) {
+	case ed25519.PrivateKey:
+		public := private.Public().(ed25519.PublicKey)
+		return &signingKey{
+			method:  jwt.SigningMethodEdDSA,
+			private: private,
+			jwk: withThumbprint(JWK{
+				Kty: "OKP",
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(public),
+				Use: "sig",
+				Alg: jwt.SigningMethodEdDSA.Alg(),
+			}),
+		}, nil
+
+	case *rsa.PrivateKey:
+		if private.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("%s holds a %d bit RSA key, at least %d bits are required", path, private.N.BitLen(), minRSAKeyBits)
+		}
+		return &signingKey{
+			method:  jwt.SigningMethodRS256,
+			private: private,
+			jwk: withThumbprint(JWK{
+				Kty: "RSA",
+				N:   base64.RawURLEncoding.EncodeToString(private.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(private.E)).Bytes()),
+				Use: "sig",
+				Alg: jwt.SigningMethodRS256.Alg(),
+			}),
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("%s holds a %T, only Ed25519 and RSA keys are supported", path, parsed)
+	}
+}
+
+// withThumbprint sets the ID of a key to its RFC 7638 thumbprint, so IDs never need to be configured alongside the keys.
+func withThumbprint(jwk JWK) JWK {
+	// The required members of the key in lexicographic order, as the thumbprint is defined over
+	var members string
+	switch jwk.Kty {
+	case "OKP":
+		members = fmt.Sprintf(`{"crv":%q,"kty":%q,"x":%q}`, jwk.Crv, jwk.Kty, jwk.X)
+	case "RSA":
+		members = fmt.Sprintf(`{"e":%q,"kty":%q,"n":%q}`, jwk.E, jwk.Kty, jwk.N)
+	}
+
+	sum := sha256.Sum256([]byte(members))
+	jwk.Kid = base64.RawURLEncoding.EncodeToString(sum[:])
+
+	return jwk
+}
+
+// current returns the key which signs access tokens at the time, the key activated last before it.
+func (k *SigningKeyRing) current(now time.Time) (*signingKey, error) {
+	var current *signingKey
+	for _, key := range k.keys {
+		if key.activatesAt.After(now) {
+			break
+		}
+		current = key
+	}
+
+	if current == nil {
+		return nil, fmt.Errorf("no signing key is active yet, the first activates at %s", k.keys[0].activatesAt.Format(time.RFC3339))
+	}
+
+	return current, nil
+}
+
+// verificationKey returns the key with the ID if it signs tokens at the time, or signed them until less than
+// the grace period ago.
+func (k *SigningKeyRing) verificationKey(kid string, now time.Time) (*signingKey, bool) {
+	for _, key := range k.keys {
+		if key.jwk.Kid != kid {
+			continue
+		}
+
+		if key.activatesAt.After(now.Add(signingKeyClockSkew)) {
+			return nil, false
+		}
+		if !key.retiresAt.IsZero() && now.After(key.retiresAt.Add(k.gracePeriod)) {
+			return nil, false
+		}
+
+		return key, true
+	}
+
+	return nil, false
+}
+
+// acceptsSecret reports whether tokens signed with JWT_SECRET are still accepted at the time.
+func (k *SigningKeyRing) acceptsSecret(now time.Time) bool {
+	return now.Before(k.secretAcceptedUntil)
+}
+
+// PublicKeys returns the keys published in the JSON Web Key Set: the current key, the keys scheduled to replace it,
+// so verifiers have fetched them before they sign any token, and replaced keys still within their grace period.
+func (k *SigningKeyRing) PublicKeys(now time.Time) []JWK {
+	keys := make([]JWK, 0, len(k.keys))
+	for _, key := range k.keys {
+		if !key.retiresAt.IsZero() && now.After(key.retiresAt.Add(k.gracePeriod)) {
+			continue
+		}
+		keys = append(keys, key.jwk)
+	}
+
+	return keys
+}
@@ -19,16 +19,23 @@ import (
 )
 
 type AuthService struct {
+	// conn runs the transactions spanning several queries
+	conn           *sql.DB
 	queries        *database.Queries
 	Logger         *slog.Logger
 	jwtSecret      []byte
+	signingKeys    *SigningKeyRing
 	accessTokenTTL time.Duration
 }
 
-func NewAuthService(queries *database.Queries, jwtSecret string, accessTokenTTL time.Duration, logger *slog.Logger) *AuthService {
+// NewAuthService creates the service issuing and validating tokens. Access tokens are signed with the signing keys
+// when they are set up and with jwtSecret otherwise.
+func NewAuthService(conn *sql.DB, queries *database.Queries, jwtSecret string, signingKeys *SigningKeyRing, accessTokenTTL time.Duration, logger *slog.Logger) *AuthService {
 	return &AuthService{
+		conn:           conn,
 		queries:        queries,
 		jwtSecret:      []byte(jwtSecret),
+		signingKeys:    signingKeys,
 		accessTokenTTL: accessTokenTTL,
 		Logger:         logger,
 	}
@@ -78,7 +85,7 @@ func (s *AuthService) Register(ctx context.Context, email, firstName, lastName,
 	}, nil
 }
 
-func (s *AuthService) generateAccessToken(email, firstName, lastName string, userID uuid.UUID, role string, isVerified bool) (string, error) {
+func (s *AuthService) generateAccessToken(email, firstName, lastName string, userID uuid.UUID, role string, isVerified bool, sessionID uuid.UUID) (string, error) {
 	expirationTime := time.Now().Add(s.accessTokenTTL)
 
 	claims := jwt.MapClaims{
@@ -88,13 +95,24 @@ func (s *AuthService) generateAccessToken(email, firstName, lastName string, use
 		"email":       email,
 		"role":        role,
 		"is_verified": strconv.FormatBool(isVerified),
+		"sid":         sessionID.String(),
 		"exp":         expirationTime.Unix(),
 		"iat":         time.Now().Unix(),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	if s.signingKeys == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.jwtSecret)
+	}
+
+	key, err := s.signingKeys.current(time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.jwk.Kid
 
-	tokenString, err := token.SignedString(s.jwtSecret)
+	tokenString, err := token.SignedString(key.private)
 	if err != nil {
 		return "", err
 	}
@@ -104,12 +122,9 @@ func (s *AuthService) generateAccessToken(email, firstName, lastName string, use
 
 // ValidateToken parses a JWT string, validates it, and returns a ContextUser.
 func (s *AuthService) ValidateToken(tokenString string) (*security.ContextUser, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return s.jwtSecret, nil
-	})
+	validMethods := []string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodEdDSA.Alg(), jwt.SigningMethodRS256.Alg()}
+
+	token, err := jwt.Parse(tokenString, s.verificationKey, jwt.WithValidMethods(validMethods))
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
 			return nil, ErrExpiredToken
@@ -124,6 +139,47 @@ func (s *AuthService) ValidateToken(tokenString string) (*security.ContextUser,
 	return nil, ErrInvalidToken
 }
 
+// verificationKey returns the key the signature of a token is checked with, the signing key named by its kid header
+// while that key signs tokens or is within its grace period.
+func (s *AuthService) verificationKey(token *jwt.Token) (any, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if len(s.jwtSecret) == 0 {
+			return nil, errors.New("tokens signed with a secret are not accepted")
+		}
+		// Once signing keys are set up, tokens signed with the secret are only accepted until the configured cutover
+		if s.signingKeys != nil && !s.signingKeys.acceptsSecret(time.Now()) {
+			return nil, errors.New("tokens signed with the JWT secret are no longer accepted")
+		}
+		return s.jwtSecret, nil
+	}
+
+	if s.signingKeys == nil {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := s.signingKeys.verificationKey(kid, time.Now())
+	if !ok {
+		return nil, fmt.Errorf("unknown or retired signing key %q", kid)
+	}
+
+	// The algorithm of the token must be the one of the key, or the key could be used in ways it was not meant for
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	return key.private.Public(), nil
+}
+
+// PublicKeys returns the public keys access tokens are verified with, none when they are signed with a secret.
+func (s *AuthService) PublicKeys() []JWK {
+	if s.signingKeys == nil {
+		return []JWK{}
+	}
+
+	return s.signingKeys.PublicKeys(time.Now())
+}
+
 // newUserFromClaims is a helper to parse claims into a ContextUser.
 func newUserFromClaims(claims jwt.MapClaims) (*security.ContextUser, error) {
 	getStringClaim := func(key string) (string, error) {
@@ -173,11 +229,22 @@ func newUserFromClaims(claims jwt.MapClaims) (*security.ContextUser, error) {
 		return nil, ErrInvalidClaims
 	}
 
+	// Tokens issued before sessions were recorded have no session ID
+	var sessionID uuid.UUID
+	if sid, ok := claims["sid"].(string); ok {
+		sessionID, err = uuid.Parse(sid)
+		if err != nil {
+			return nil, ErrInvalidClaims
+		}
+	}
+
 	user := &security.ContextUser{
 		FirstName:   firstName,
 		LastName:    lastName,
 		Email:       email,
 		Role:        role,
+		AuthMethod:  security.AuthToken,
+		SessionID:   sessionID,
 		UserID:      userID,
 		IsActivated: verifiedBool,
 	}
@@ -185,60 +252,197 @@ func newUserFromClaims(claims jwt.MapClaims) (*security.ContextUser, error) {
 	return user, nil
 }
 
-func (s *AuthService) LoginWithRefresh(ctx context.Context, email, password string, refreshTokenTTL time.Duration) (accessToken string, refreshToken string, err error) {
+// LoginWithRefresh checks the credentials of a user and starts a session recording the user agent and IP address
+// it was started from. The result tells whether none of the user's earlier sessions were started from the user agent.
+func (s *AuthService) LoginWithRefresh(ctx context.Context, email, password, userAgent, ipAddress string, refreshTokenTTL time.Duration) (LoginResult, error) {
 	user, err := s.queries.GetUserByEmail(ctx, email)
 	if err != nil {
-		return "", "", ErrInvalidCredentials
+		return LoginResult{}, ErrInvalidCredentials
 	}
 
 	if err := security.VerifyPassword(user.PasswordHash, password); err != nil {
-		return "", "", ErrInvalidCredentials
+		return LoginResult{}, ErrInvalidCredentials
 	}
 
-	accessToken, err = s.generateAccessToken(user.Email, user.FirstName, user.LastName, user.UserID, string(user.Role), user.IsVerified)
+	counts, err := s.queries.GetSessionDeviceCounts(ctx, database.GetSessionDeviceCountsParams{
+		UserAgent: userAgent,
+		UserID:    user.UserID,
+	})
 	if err != nil {
-		return "", "", err
+		return LoginResult{}, errors.Join(utils.ErrUnexpectedError, err)
+	}
+
+	sessionID := uuid.New()
+	accessToken, err := s.generateAccessToken(user.Email, user.FirstName, user.LastName, user.UserID, string(user.Role), user.IsVerified, sessionID)
+	if err != nil {
+		return LoginResult{}, errors.Join(utils.ErrUnexpectedError, err)
 	}
 
-	token, err := s.queries.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+	// A session without a refresh token would count as a device the user logged in from and hide the alert
+	// of their next login from it, so the session is only kept together with its first token
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return LoginResult{}, errors.Join(utils.ErrUnexpectedError, err)
+	}
+	defer tx.Rollback()
+
+	q := s.queries.WithTx(tx)
+
+	err = q.CreateSession(ctx, database.CreateSessionParams{
+		SessionID: sessionID,
 		UserID:    user.UserID,
-		Token:     uuid.NewString(),
+		UserAgent: userAgent,
+		IpAddress: inet(ipAddress),
+	})
+	if err != nil {
+		return LoginResult{}, errors.Join(utils.ErrUnexpectedError, err)
+	}
+
+	refreshToken, err := s.issueRefreshToken(ctx, q, user.UserID, sessionID, refreshTokenTTL)
+	if err != nil {
+		return LoginResult{}, errors.Join(utils.ErrUnexpectedError, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return LoginResult{}, errors.Join(utils.ErrUnexpectedError, err)
+	}
+
+	return LoginResult{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		UserID:       user.UserID,
+		FirstName:    user.FirstName,
+		Email:        user.Email,
+		// The first session of a user is not from a new device, there is nothing to compare it with
+		NewDevice: counts.Sessions > 0 && counts.DeviceSessions == 0,
+	}, nil
+}
+
+// issueRefreshToken creates a refresh token in the family using q, only the hash of the token is stored.
+func (s *AuthService) issueRefreshToken(ctx context.Context, q *database.Queries, userID, familyID uuid.UUID, refreshTokenTTL time.Duration) (string, error) {
+	plainText, tokenHash := security.GenerateStringAndHash()
+
+	_, err := q.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: tokenHash,
 		ExpiresAt: time.Now().Add(refreshTokenTTL),
 		CreatedAt: time.Now(),
 		Revoked:   false,
 	})
 	if err != nil {
-		return "", "", utils.ErrUnexpectedError
+		return "", err
 	}
 
-	return accessToken, token.Token, nil
+	return plainText, nil
 }
 
-func (s *AuthService) RefreshAccessToken(ctx context.Context, refreshTokenString string) (string, error) {
-	token, err := s.queries.GetRefreshToken(ctx, refreshTokenString)
+// RefreshAccessToken exchanges a refresh token for a new access token and a new refresh token in the same family.
+// Each refresh token can only be exchanged once, presenting one again means it was stolen by someone, so every
+// token of its family is revoked and both the thief and the user have to log in again.
+func (s *AuthService) RefreshAccessToken(ctx context.Context, refreshTokenString, ipAddress string, refreshTokenTTL time.Duration) (accessToken string, refreshToken string, err error) {
+	tokenHash := sha256.Sum256([]byte(refreshTokenString))
+
+	token, err := s.queries.GetRefreshToken(ctx, tokenHash[:])
 	if err != nil {
-		return "", ErrInvalidToken
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", "", ErrInvalidToken
+		}
+		return "", "", errors.Join(utils.ErrUnexpectedError, err)
 	}
 
 	if token.Revoked {
-		return "", ErrInvalidToken
+		return "", "", ErrInvalidToken
+	}
+
+	if token.RotatedAt.Valid {
+		return "", "", s.revokeReusedToken(ctx, token.FamilyID)
 	}
 
 	if time.Now().After(token.ExpiresAt) {
-		return "", ErrExpiredToken
+		return "", "", ErrExpiredToken
 	}
 
 	user, err := s.queries.GetUserByID(ctx, token.UserID)
 	if err != nil {
-		return "", errors.Join(utils.ErrUnexpectedError, err)
+		return "", "", errors.Join(utils.ErrUnexpectedError, err)
 	}
 
-	accessToken, err := s.generateAccessToken(user.Email, user.FirstName, user.LastName, user.UserID, string(user.Role), user.IsVerified)
+	accessToken, err = s.generateAccessToken(user.Email, user.FirstName, user.LastName, user.UserID, string(user.Role), user.IsVerified, token.FamilyID)
 	if err != nil {
-		return "", err
+		return "", "", err
+	}
+
+	// The token is only marked rotated together with issuing its successor, otherwise a failure in between
+	// would leave the client without a usable token and its retry would be taken for reuse
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return "", "", errors.Join(utils.ErrUnexpectedError, err)
+	}
+	defer tx.Rollback()
+
+	q := s.queries.WithTx(tx)
+
+	_, err = q.RotateRefreshToken(ctx, token.RefreshTokenID)
+	if err != nil {
+		// A concurrent request rotated or revoked the token since it was read
+		if errors.Is(err, sql.ErrNoRows) {
+			tx.Rollback()
+			return "", "", s.revokeReusedToken(ctx, token.FamilyID)
+		}
+		return "", "", errors.Join(utils.ErrUnexpectedError, err)
+	}
+
+	refreshToken, err = s.issueRefreshToken(ctx, q, token.UserID, token.FamilyID, refreshTokenTTL)
+	if err != nil {
+		return "", "", errors.Join(utils.ErrUnexpectedError, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", "", errors.Join(utils.ErrUnexpectedError, err)
+	}
+
+	err = s.queries.TouchSession(ctx, database.TouchSessionParams{
+		SessionID: token.FamilyID,
+		IpAddress: inet(ipAddress),
+	})
+	if err != nil {
+		s.Logger.Error("failed to record session use", "error", err, "session_id", token.FamilyID)
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+// revokeReusedToken revokes the family of a refresh token which was presented after it had been rotated.
+func (s *AuthService) revokeReusedToken(ctx context.Context, familyID uuid.UUID) error {
+	err := s.queries.RevokeRefreshTokenFamily(ctx, familyID)
+	if err != nil {
+		return errors.Join(utils.ErrUnexpectedError, err)
+	}
+
+	s.Logger.Warn("refresh token reuse detected, revoked its token family", "family_id", familyID)
+
+	return ErrTokenReused
+}
+
+// Logout revokes the family of the refresh token, ending the session it was issued to.
+func (s *AuthService) Logout(ctx context.Context, refreshTokenString string) error {
+	tokenHash := sha256.Sum256([]byte(refreshTokenString))
+
+	token, err := s.queries.GetRefreshToken(ctx, tokenHash[:])
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidToken
+		}
+		return err
 	}
 
-	return accessToken, nil
+	return s.queries.RevokeRefreshTokenFamily(ctx, token.FamilyID)
+}
+
+// LogoutEverywhere revokes every refresh token of the user, ending all of their sessions.
+func (s *AuthService) LogoutEverywhere(ctx context.Context, userID uuid.UUID) error {
+	return s.queries.RevokeUserRefreshTokens(ctx, userID)
 }
 
 func (s *AuthService) SendPasswordResetLink(ctx context.Context, email string) (userID uuid.UUID, firstName, lastName, resetToken string, err error) {
@@ -308,3 +512,48 @@ func (s *AuthService) VerifyPasswordReset(ctx context.Context, userID uuid.UUID,
 
 	return true, nil
 }
+
+// ListSessions returns the sessions of the user which can still be refreshed, most recently used first.
+func (s *AuthService) ListSessions(ctx context.Context, userID, currentSessionID uuid.UUID) ([]Session, error) {
+	rows, err := s.queries.ListActiveSessions(ctx, database.ListActiveSessionsParams{
+		UserID:    userID,
+		ExpiresAt: time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]Session, 0, len(rows))
+	for _, row := range rows {
+		session := Session{
+			SessionID:  row.SessionID,
+			UserAgent:  row.UserAgent,
+			CreatedAt:  row.CreatedAt,
+			LastUsedAt: row.LastUsedAt,
+			Current:    row.SessionID == currentSessionID,
+		}
+		if row.IpAddress.Valid {
+			session.IPAddress = row.IpAddress.IPNet.IP.String()
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession logs a session of the user out, its refresh tokens stop working.
+func (s *AuthService) RevokeSession(ctx context.Context, sessionID, userID uuid.UUID) error {
+	revoked, err := s.queries.RevokeSession(ctx, database.RevokeSessionParams{
+		FamilyID: sessionID,
+		UserID:   userID,
+	})
+	if err != nil {
+		return err
+	}
+	if revoked == 0 {
+		return utils.ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package auth
 import (
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -13,6 +14,7 @@ var (
 	ErrExpiredToken       = errors.New("token has expired")
 	ErrEmailInUse         = errors.New("email already in use")
 	ErrInvalidClaims      = errors.New("token contains invalid claims")
+	ErrTokenReused        = errors.New("refresh token has already been used, the session has been logged out")
 )
 
 type ApiUser struct {
@@ -25,3 +27,41 @@ type ApiUser struct {
 	UserID          uuid.UUID    `json:"user_id"`
 	IsVerified      bool         `json:"is_verified"`
 }
+
+// APIKey describes an API key of a user without its secret. PreviousKeyExpiresAt is when the secret the key had
+// before it was last rotated stops being accepted, it is left out once that has passed.
+type APIKey struct {
+	CreatedAt            time.Time `json:"created_at"`
+	ExpiresAt            time.Time `json:"expires_at"`
+	RevokedAt            time.Time `json:"revoked_at,omitzero"`
+	LastUsedAt           time.Time `json:"last_used_at,omitzero"`
+	PreviousKeyExpiresAt time.Time `json:"previous_key_expires_at,omitzero"`
+	Name                 string    `json:"name"`
+	Prefix               string    `json:"prefix"`
+	LastUsedIP           string    `json:"last_used_ip,omitzero"`
+	Scope                []string  `json:"scope"`
+	APIKeyID             uuid.UUID `json:"api_key_id"`
+	IsRevoked            bool      `json:"is_revoked"`
+}
+
+// LoginResult holds the tokens issued by a login. NewDevice is set when the login came from a user agent
+// none of the user's earlier sessions were started from, so the user can be alerted.
+type LoginResult struct {
+	AccessToken  string
+	RefreshToken string
+	FirstName    string
+	Email        string
+	UserID       uuid.UUID
+	NewDevice    bool
+}
+
+// Session is a login of a user, kept alive by refreshing its tokens. IPAddress is the address it was last used from,
+// Current marks the session the request listing the sessions was made with.
+type Session struct {
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address,omitzero"`
+	SessionID  uuid.UUID `json:"session_id"`
+	Current    bool      `json:"current"`
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/lib/pq"
+	"github.com/sqlc-dev/pqtype"
 )
 
 const checkIfAPIKeyExists = `-- name: CheckIfAPIKeyExists :one
@@ -37,7 +38,7 @@ insert into api_keys (
 values (
     $1, $2, $3, $4, $5, $6
 )
-returning api_key_id, user_id, name, key_hash, prefix, scope, is_revoked, revoked_at, created_at, updated_at, expires_at, last_used_at, last_used_ip
+returning api_key_id, user_id, name, key_hash, prefix, scope, is_revoked, revoked_at, created_at, updated_at, expires_at, last_used_at, last_used_ip, previous_key_hash, previous_key_expires_at
 `
 
 type CreateApiKeyParams struct {
@@ -73,24 +74,39 @@ func (q *Queries) CreateApiKey(ctx context.Context, arg CreateApiKeyParams) (Api
 		&i.ExpiresAt,
 		&i.LastUsedAt,
 		&i.LastUsedIp,
+		&i.PreviousKeyHash,
+		&i.PreviousKeyExpiresAt,
 	)
 	return i, err
 }
 
-const deleteApiKey = `-- name: DeleteApiKey :exec
+const deleteApiKey = `-- name: DeleteApiKey :execrows
 delete from api_keys
     where api_key_id = $1
+        and user_id = $2
 `
 
-func (q *Queries) DeleteApiKey(ctx context.Context, apiKeyID uuid.UUID) error {
-	_, err := q.exec(ctx, q.deleteApiKeyStmt, deleteApiKey, apiKeyID)
-	return err
+type DeleteApiKeyParams struct {
+	ApiKeyID uuid.UUID `json:"api_key_id"`
+	UserID   uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) DeleteApiKey(ctx context.Context, arg DeleteApiKeyParams) (int64, error) {
+	result, err := q.exec(ctx, q.deleteApiKeyStmt, deleteApiKey, arg.ApiKeyID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 const getApiKeyByPrefix = `-- name: GetApiKeyByPrefix :one
 select
     ak.api_key_id,
     ak.key_hash,
+    ak.previous_key_hash,
+    ak.previous_key_expires_at,
+    ak.scope,
+    ak.is_revoked,
     ak.expires_at,
     u.user_id,
     u.first_name,
@@ -104,15 +120,19 @@ where prefix = $1
 `
 
 type GetApiKeyByPrefixRow struct {
-	ApiKeyID   uuid.UUID `json:"api_key_id"`
-	KeyHash    string    `json:"key_hash"`
-	ExpiresAt  time.Time `json:"expires_at"`
-	UserID     uuid.UUID `json:"user_id"`
-	FirstName  string    `json:"first_name"`
-	IsVerified bool      `json:"is_verified"`
-	LastName   string    `json:"last_name"`
-	Role       UserRole  `json:"role"`
-	Email      string    `json:"email"`
+	ApiKeyID             uuid.UUID      `json:"api_key_id"`
+	KeyHash              string         `json:"key_hash"`
+	PreviousKeyHash      sql.NullString `json:"previous_key_hash"`
+	PreviousKeyExpiresAt sql.NullTime   `json:"previous_key_expires_at"`
+	Scope                []ApiScope     `json:"scope"`
+	IsRevoked            bool           `json:"is_revoked"`
+	ExpiresAt            time.Time      `json:"expires_at"`
+	UserID               uuid.UUID      `json:"user_id"`
+	FirstName            string         `json:"first_name"`
+	IsVerified           bool           `json:"is_verified"`
+	LastName             string         `json:"last_name"`
+	Role                 UserRole       `json:"role"`
+	Email                string         `json:"email"`
 }
 
 func (q *Queries) GetApiKeyByPrefix(ctx context.Context, prefix string) (GetApiKeyByPrefixRow, error) {
@@ -121,6 +141,10 @@ func (q *Queries) GetApiKeyByPrefix(ctx context.Context, prefix string) (GetApiK
 	err := row.Scan(
 		&i.ApiKeyID,
 		&i.KeyHash,
+		&i.PreviousKeyHash,
+		&i.PreviousKeyExpiresAt,
+		pq.Array(&i.Scope),
+		&i.IsRevoked,
 		&i.ExpiresAt,
 		&i.UserID,
 		&i.FirstName,
@@ -132,6 +156,32 @@ func (q *Queries) GetApiKeyByPrefix(ctx context.Context, prefix string) (GetApiK
 	return i, err
 }
 
+const getApiKeyStatus = `-- name: GetApiKeyStatus :one
+select
+    is_revoked,
+    expires_at
+from api_keys
+where api_key_id = $1
+    and user_id = $2
+`
+
+type GetApiKeyStatusParams struct {
+	ApiKeyID uuid.UUID `json:"api_key_id"`
+	UserID   uuid.UUID `json:"user_id"`
+}
+
+type GetApiKeyStatusRow struct {
+	IsRevoked bool      `json:"is_revoked"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (q *Queries) GetApiKeyStatus(ctx context.Context, arg GetApiKeyStatusParams) (GetApiKeyStatusRow, error) {
+	row := q.queryRow(ctx, q.getApiKeyStatusStmt, getApiKeyStatus, arg.ApiKeyID, arg.UserID)
+	var i GetApiKeyStatusRow
+	err := row.Scan(&i.IsRevoked, &i.ExpiresAt)
+	return i, err
+}
+
 const listApiKeysByUser = `-- name: ListApiKeysByUser :many
 select
     api_key_id,
@@ -139,19 +189,29 @@ select
     prefix,
     scope,
     is_revoked,
-    expires_at
+    revoked_at,
+    created_at,
+    expires_at,
+    last_used_at,
+    last_used_ip,
+    previous_key_expires_at
 from api_keys
     where user_id = $1
 order by created_at desc
 `
 
 type ListApiKeysByUserRow struct {
-	ApiKeyID  uuid.UUID  `json:"api_key_id"`
-	Name      string     `json:"name"`
-	Prefix    string     `json:"prefix"`
-	Scope     []ApiScope `json:"scope"`
-	IsRevoked bool       `json:"is_revoked"`
-	ExpiresAt time.Time  `json:"expires_at"`
+	ApiKeyID             uuid.UUID    `json:"api_key_id"`
+	Name                 string       `json:"name"`
+	Prefix               string       `json:"prefix"`
+	Scope                []ApiScope   `json:"scope"`
+	IsRevoked            bool         `json:"is_revoked"`
+	RevokedAt            sql.NullTime `json:"revoked_at"`
+	CreatedAt            time.Time    `json:"created_at"`
+	ExpiresAt            time.Time    `json:"expires_at"`
+	LastUsedAt           sql.NullTime `json:"last_used_at"`
+	LastUsedIp           pqtype.Inet  `json:"last_used_ip"`
+	PreviousKeyExpiresAt sql.NullTime `json:"previous_key_expires_at"`
 }
 
 func (q *Queries) ListApiKeysByUser(ctx context.Context, userID uuid.UUID) ([]ListApiKeysByUserRow, error) {
@@ -169,7 +229,12 @@ func (q *Queries) ListApiKeysByUser(ctx context.Context, userID uuid.UUID) ([]Li
 			&i.Prefix,
 			pq.Array(&i.Scope),
 			&i.IsRevoked,
+			&i.RevokedAt,
+			&i.CreatedAt,
 			&i.ExpiresAt,
+			&i.LastUsedAt,
+			&i.LastUsedIp,
+			&i.PreviousKeyExpiresAt,
 		); err != nil {
 			return nil, err
 		}
@@ -184,30 +249,95 @@ func (q *Queries) ListApiKeysByUser(ctx context.Context, userID uuid.UUID) ([]Li
 	return items, nil
 }
 
-const revokeApiKey = `-- name: RevokeApiKey :exec
+const renameApiKey = `-- name: RenameApiKey :execrows
+update api_keys
+set name = $3,
+    updated_at = now()
+where api_key_id = $1
+    and user_id = $2
+`
+
+type RenameApiKeyParams struct {
+	ApiKeyID uuid.UUID `json:"api_key_id"`
+	UserID   uuid.UUID `json:"user_id"`
+	Name     string    `json:"name"`
+}
+
+func (q *Queries) RenameApiKey(ctx context.Context, arg RenameApiKeyParams) (int64, error) {
+	result, err := q.exec(ctx, q.renameApiKeyStmt, renameApiKey, arg.ApiKeyID, arg.UserID, arg.Name)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const revokeApiKey = `-- name: RevokeApiKey :execrows
 update api_keys
 set is_revoked = true,
-    revoked_at = now()
+    revoked_at = coalesce(revoked_at, now()),
+    updated_at = now()
 where api_key_id = $1
+    and user_id = $2
 `
 
-func (q *Queries) RevokeApiKey(ctx context.Context, apiKeyID uuid.UUID) error {
-	_, err := q.exec(ctx, q.revokeApiKeyStmt, revokeApiKey, apiKeyID)
-	return err
+type RevokeApiKeyParams struct {
+	ApiKeyID uuid.UUID `json:"api_key_id"`
+	UserID   uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) RevokeApiKey(ctx context.Context, arg RevokeApiKeyParams) (int64, error) {
+	result, err := q.exec(ctx, q.revokeApiKeyStmt, revokeApiKey, arg.ApiKeyID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const rotateApiKey = `-- name: RotateApiKey :one
+update api_keys
+set previous_key_hash = key_hash,
+    previous_key_expires_at = $3,
+    key_hash = $4,
+    updated_at = now()
+where api_key_id = $1
+    and user_id = $2
+returning prefix
+`
+
+type RotateApiKeyParams struct {
+	ApiKeyID             uuid.UUID    `json:"api_key_id"`
+	UserID               uuid.UUID    `json:"user_id"`
+	PreviousKeyExpiresAt sql.NullTime `json:"previous_key_expires_at"`
+	KeyHash              string       `json:"key_hash"`
+}
+
+// Replaces the secret of a key, the secret it replaces is still accepted until previous_key_expires_at.
+func (q *Queries) RotateApiKey(ctx context.Context, arg RotateApiKeyParams) (string, error) {
+	row := q.queryRow(ctx, q.rotateApiKeyStmt, rotateApiKey,
+		arg.ApiKeyID,
+		arg.UserID,
+		arg.PreviousKeyExpiresAt,
+		arg.KeyHash,
+	)
+	var prefix string
+	err := row.Scan(&prefix)
+	return prefix, err
 }
 
 const updateApiKeyLastUsed = `-- name: UpdateApiKeyLastUsed :exec
 update api_keys
-    set last_used_at = $2
+    set last_used_at = $2,
+        last_used_ip = $3
 where api_key_id = $1
 `
 
 type UpdateApiKeyLastUsedParams struct {
 	ApiKeyID   uuid.UUID    `json:"api_key_id"`
 	LastUsedAt sql.NullTime `json:"last_used_at"`
+	LastUsedIp pqtype.Inet  `json:"last_used_ip"`
 }
 
 func (q *Queries) UpdateApiKeyLastUsed(ctx context.Context, arg UpdateApiKeyLastUsedParams) error {
-	_, err := q.exec(ctx, q.updateApiKeyLastUsedStmt, updateApiKeyLastUsed, arg.ApiKeyID, arg.LastUsedAt)
+	_, err := q.exec(ctx, q.updateApiKeyLastUsedStmt, updateApiKeyLastUsed, arg.ApiKeyID, arg.LastUsedAt, arg.LastUsedIp)
 	return err
 }
@@ -13,11 +13,78 @@ import (
 	"github.com/lib/pq"
 )
 
+const deleteExpiredFolders = `-- name: DeleteExpiredFolders :execrows
+delete from folders
+    where is_deleted = true
+        and purge_after < now()
+`
+
+// Permanently remove trashed folders whose retention period has passed, their subfolders are removed by cascade
+func (q *Queries) DeleteExpiredFolders(ctx context.Context) (int64, error) {
+	result, err := q.exec(ctx, q.deleteExpiredFoldersStmt, deleteExpiredFolders)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getExcessFileVersions = `-- name: GetExcessFileVersions :many
+select fv.file_version_id, fv.storage_key
+from (
+    select
+        file_version_id,
+        file_id,
+        version_number,
+        storage_key,
+        row_number() over (partition by file_id order by version_number desc) as position
+    from file_versions
+) fv
+    join files f
+        on f.file_id = fv.file_id
+    where fv.position > $1::int
+        and fv.version_number <> f.current_version
+    limit $2
+`
+
+type GetExcessFileVersionsParams struct {
+	KeepVersions int32 `json:"keep_versions"`
+	MaxResults   int32 `json:"max_results"`
+}
+
+type GetExcessFileVersionsRow struct {
+	FileVersionID uuid.UUID `json:"file_version_id"`
+	StorageKey    string    `json:"storage_key"`
+}
+
+// Fetch versions beyond the newest `keep_versions` of each file, the current version of a file is always kept
+func (q *Queries) GetExcessFileVersions(ctx context.Context, arg GetExcessFileVersionsParams) ([]GetExcessFileVersionsRow, error) {
+	rows, err := q.query(ctx, q.getExcessFileVersionsStmt, getExcessFileVersions, arg.KeepVersions, arg.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetExcessFileVersionsRow{}
+	for rows.Next() {
+		var i GetExcessFileVersionsRow
+		if err := rows.Scan(&i.FileVersionID, &i.StorageKey); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getExpiredDeletedFiles = `-- name: GetExpiredDeletedFiles :many
 select file_id, storage_key, thumbnail_key
 from files
     where is_deleted = true 
-        and deleted_at < now()
+        and purge_after < now()
     limit $1
 `
 
@@ -27,7 +94,7 @@ type GetExpiredDeletedFilesRow struct {
 	ThumbnailKey sql.NullString `json:"thumbnail_key"`
 }
 
-// Fetch files that have been soft-deleted for more than specific duration (e.g., 7 days)
+// Fetch trashed files whose retention period has passed
 func (q *Queries) GetExpiredDeletedFiles(ctx context.Context, limit int32) ([]GetExpiredDeletedFilesRow, error) {
 	rows, err := q.query(ctx, q.getExpiredDeletedFilesStmt, getExpiredDeletedFiles, limit)
 	if err != nil {
@@ -51,6 +118,72 @@ func (q *Queries) GetExpiredDeletedFiles(ctx context.Context, limit int32) ([]Ge
 	return items, nil
 }
 
+const getExpiredUploads = `-- name: GetExpiredUploads :many
+select upload_id, file_id
+from uploads
+    where expires_at < now()
+    limit $1
+`
+
+type GetExpiredUploadsRow struct {
+	UploadID uuid.UUID     `json:"upload_id"`
+	FileID   uuid.NullUUID `json:"file_id"`
+}
+
+// Fetch resumable uploads which were abandoned or completed and have passed their expiry time
+func (q *Queries) GetExpiredUploads(ctx context.Context, limit int32) ([]GetExpiredUploadsRow, error) {
+	rows, err := q.query(ctx, q.getExpiredUploadsStmt, getExpiredUploads, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetExpiredUploadsRow{}
+	for rows.Next() {
+		var i GetExpiredUploadsRow
+		if err := rows.Scan(&i.UploadID, &i.FileID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFileVersionStorageKeys = `-- name: GetFileVersionStorageKeys :many
+select storage_key
+from file_versions
+    where file_id = any($1::uuid[])
+`
+
+// Fetch the storage keys of every version of the given files
+func (q *Queries) GetFileVersionStorageKeys(ctx context.Context, fileIds []uuid.UUID) ([]string, error) {
+	rows, err := q.query(ctx, q.getFileVersionStorageKeysStmt, getFileVersionStorageKeys, pq.Array(fileIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var storage_key string
+		if err := rows.Scan(&storage_key); err != nil {
+			return nil, err
+		}
+		items = append(items, storage_key)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const hardDeleteFiles = `-- name: HardDeleteFiles :exec
 delete from files where file_id = any($1::uuid[])
 `
@@ -60,3 +193,21 @@ func (q *Queries) HardDeleteFiles(ctx context.Context, fileIds []uuid.UUID) erro
 	_, err := q.exec(ctx, q.hardDeleteFilesStmt, hardDeleteFiles, pq.Array(fileIds))
 	return err
 }
+
+const hardDeleteFileVersions = `-- name: HardDeleteFileVersions :exec
+delete from file_versions where file_version_id = any($1::uuid[])
+`
+
+func (q *Queries) HardDeleteFileVersions(ctx context.Context, fileVersionIds []uuid.UUID) error {
+	_, err := q.exec(ctx, q.hardDeleteFileVersionsStmt, hardDeleteFileVersions, pq.Array(fileVersionIds))
+	return err
+}
+
+const hardDeleteUploads = `-- name: HardDeleteUploads :exec
+delete from uploads where upload_id = any($1::uuid[])
+`
+
+func (q *Queries) HardDeleteUploads(ctx context.Context, uploadIds []uuid.UUID) error {
+	_, err := q.exec(ctx, q.hardDeleteUploadsStmt, hardDeleteUploads, pq.Array(uploadIds))
+	return err
+}
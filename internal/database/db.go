@@ -27,6 +27,12 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.activateUserEmailStmt, err = db.PrepareContext(ctx, activateUserEmail); err != nil {
 		return nil, fmt.Errorf("error preparing query ActivateUserEmail: %w", err)
 	}
+	if q.addBlobReferenceStmt, err = db.PrepareContext(ctx, addBlobReference); err != nil {
+		return nil, fmt.Errorf("error preparing query AddBlobReference: %w", err)
+	}
+	if q.addFileVersionStmt, err = db.PrepareContext(ctx, addFileVersion); err != nil {
+		return nil, fmt.Errorf("error preparing query AddFileVersion: %w", err)
+	}
 	if q.changePasswordStmt, err = db.PrepareContext(ctx, changePassword); err != nil {
 		return nil, fmt.Errorf("error preparing query ChangePassword: %w", err)
 	}
@@ -36,9 +42,24 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.checkIfEmailExistsStmt, err = db.PrepareContext(ctx, checkIfEmailExists); err != nil {
 		return nil, fmt.Errorf("error preparing query CheckIfEmailExists: %w", err)
 	}
+	if q.claimUploadStmt, err = db.PrepareContext(ctx, claimUpload); err != nil {
+		return nil, fmt.Errorf("error preparing query ClaimUpload: %w", err)
+	}
+	if q.completeUploadStmt, err = db.PrepareContext(ctx, completeUpload); err != nil {
+		return nil, fmt.Errorf("error preparing query CompleteUpload: %w", err)
+	}
+	if q.countFolderChildrenStmt, err = db.PrepareContext(ctx, countFolderChildren); err != nil {
+		return nil, fmt.Errorf("error preparing query CountFolderChildren: %w", err)
+	}
 	if q.countPublicFilesStmt, err = db.PrepareContext(ctx, countPublicFiles); err != nil {
 		return nil, fmt.Errorf("error preparing query CountPublicFiles: %w", err)
 	}
+	if q.countSearchFilesStmt, err = db.PrepareContext(ctx, countSearchFiles); err != nil {
+		return nil, fmt.Errorf("error preparing query CountSearchFiles: %w", err)
+	}
+	if q.countTrashedFilesStmt, err = db.PrepareContext(ctx, countTrashedFiles); err != nil {
+		return nil, fmt.Errorf("error preparing query CountTrashedFiles: %w", err)
+	}
 	if q.countUserFilesStmt, err = db.PrepareContext(ctx, countUserFiles); err != nil {
 		return nil, fmt.Errorf("error preparing query CountUserFiles: %w", err)
 	}
@@ -48,12 +69,33 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.createApiKeyStmt, err = db.PrepareContext(ctx, createApiKey); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateApiKey: %w", err)
 	}
+	if q.createBlobStmt, err = db.PrepareContext(ctx, createBlob); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateBlob: %w", err)
+	}
+	if q.createDataKeyStmt, err = db.PrepareContext(ctx, createDataKey); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateDataKey: %w", err)
+	}
 	if q.createFileStmt, err = db.PrepareContext(ctx, createFile); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateFile: %w", err)
 	}
+	if q.createFolderStmt, err = db.PrepareContext(ctx, createFolder); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateFolder: %w", err)
+	}
+	if q.createImageRenditionStmt, err = db.PrepareContext(ctx, createImageRendition); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateImageRendition: %w", err)
+	}
 	if q.createRefreshTokenStmt, err = db.PrepareContext(ctx, createRefreshToken); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateRefreshToken: %w", err)
 	}
+	if q.createSessionStmt, err = db.PrepareContext(ctx, createSession); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateSession: %w", err)
+	}
+	if q.createShareLinkStmt, err = db.PrepareContext(ctx, createShareLink); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateShareLink: %w", err)
+	}
+	if q.createUploadStmt, err = db.PrepareContext(ctx, createUpload); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateUpload: %w", err)
+	}
 	if q.createUserStmt, err = db.PrepareContext(ctx, createUser); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateUser: %w", err)
 	}
@@ -63,11 +105,20 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.deleteApiKeyStmt, err = db.PrepareContext(ctx, deleteApiKey); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteApiKey: %w", err)
 	}
+	if q.deleteExpiredFoldersStmt, err = db.PrepareContext(ctx, deleteExpiredFolders); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteExpiredFolders: %w", err)
+	}
 	if q.deleteFileStmt, err = db.PrepareContext(ctx, deleteFile); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteFile: %w", err)
 	}
-	if q.deleteRefreshTokenStmt, err = db.PrepareContext(ctx, deleteRefreshToken); err != nil {
-		return nil, fmt.Errorf("error preparing query DeleteRefreshToken: %w", err)
+	if q.deleteFolderStmt, err = db.PrepareContext(ctx, deleteFolder); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteFolder: %w", err)
+	}
+	if q.deleteUnreferencedBlobsStmt, err = db.PrepareContext(ctx, deleteUnreferencedBlobs); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteUnreferencedBlobs: %w", err)
+	}
+	if q.deleteUploadStmt, err = db.PrepareContext(ctx, deleteUpload); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteUpload: %w", err)
 	}
 	if q.getActionTokenForUserStmt, err = db.PrepareContext(ctx, getActionTokenForUser); err != nil {
 		return nil, fmt.Errorf("error preparing query GetActionTokenForUser: %w", err)
@@ -75,9 +126,27 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.getApiKeyByPrefixStmt, err = db.PrepareContext(ctx, getApiKeyByPrefix); err != nil {
 		return nil, fmt.Errorf("error preparing query GetApiKeyByPrefix: %w", err)
 	}
+	if q.getApiKeyStatusStmt, err = db.PrepareContext(ctx, getApiKeyStatus); err != nil {
+		return nil, fmt.Errorf("error preparing query GetApiKeyStatus: %w", err)
+	}
+	if q.getArchiveFilesStmt, err = db.PrepareContext(ctx, getArchiveFiles); err != nil {
+		return nil, fmt.Errorf("error preparing query GetArchiveFiles: %w", err)
+	}
+	if q.getBlobRenditionsStmt, err = db.PrepareContext(ctx, getBlobRenditions); err != nil {
+		return nil, fmt.Errorf("error preparing query GetBlobRenditions: %w", err)
+	}
+	if q.getDataKeyStmt, err = db.PrepareContext(ctx, getDataKey); err != nil {
+		return nil, fmt.Errorf("error preparing query GetDataKey: %w", err)
+	}
+	if q.getExcessFileVersionsStmt, err = db.PrepareContext(ctx, getExcessFileVersions); err != nil {
+		return nil, fmt.Errorf("error preparing query GetExcessFileVersions: %w", err)
+	}
 	if q.getExpiredDeletedFilesStmt, err = db.PrepareContext(ctx, getExpiredDeletedFiles); err != nil {
 		return nil, fmt.Errorf("error preparing query GetExpiredDeletedFiles: %w", err)
 	}
+	if q.getExpiredUploadsStmt, err = db.PrepareContext(ctx, getExpiredUploads); err != nil {
+		return nil, fmt.Errorf("error preparing query GetExpiredUploads: %w", err)
+	}
 	if q.getFileByChecksumStmt, err = db.PrepareContext(ctx, getFileByChecksum); err != nil {
 		return nil, fmt.Errorf("error preparing query GetFileByChecksum: %w", err)
 	}
@@ -87,45 +156,213 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.getFileOwnerStmt, err = db.PrepareContext(ctx, getFileOwner); err != nil {
 		return nil, fmt.Errorf("error preparing query GetFileOwner: %w", err)
 	}
+	if q.getFileVersionStmt, err = db.PrepareContext(ctx, getFileVersion); err != nil {
+		return nil, fmt.Errorf("error preparing query GetFileVersion: %w", err)
+	}
+	if q.getFileVersionStorageKeysStmt, err = db.PrepareContext(ctx, getFileVersionStorageKeys); err != nil {
+		return nil, fmt.Errorf("error preparing query GetFileVersionStorageKeys: %w", err)
+	}
+	if q.getFolderStmt, err = db.PrepareContext(ctx, getFolder); err != nil {
+		return nil, fmt.Errorf("error preparing query GetFolder: %w", err)
+	}
+	if q.getFolderArchiveFilesStmt, err = db.PrepareContext(ctx, getFolderArchiveFiles); err != nil {
+		return nil, fmt.Errorf("error preparing query GetFolderArchiveFiles: %w", err)
+	}
+	if q.getFolderPathStmt, err = db.PrepareContext(ctx, getFolderPath); err != nil {
+		return nil, fmt.Errorf("error preparing query GetFolderPath: %w", err)
+	}
 	if q.getRefreshTokenStmt, err = db.PrepareContext(ctx, getRefreshToken); err != nil {
 		return nil, fmt.Errorf("error preparing query GetRefreshToken: %w", err)
 	}
+	if q.getServiceDataKeyStmt, err = db.PrepareContext(ctx, getServiceDataKey); err != nil {
+		return nil, fmt.Errorf("error preparing query GetServiceDataKey: %w", err)
+	}
+	if q.getSessionDeviceCountsStmt, err = db.PrepareContext(ctx, getSessionDeviceCounts); err != nil {
+		return nil, fmt.Errorf("error preparing query GetSessionDeviceCounts: %w", err)
+	}
+	if q.getShareLinkByTokenStmt, err = db.PrepareContext(ctx, getShareLinkByToken); err != nil {
+		return nil, fmt.Errorf("error preparing query GetShareLinkByToken: %w", err)
+	}
+	if q.getStorageUsageStmt, err = db.PrepareContext(ctx, getStorageUsage); err != nil {
+		return nil, fmt.Errorf("error preparing query GetStorageUsage: %w", err)
+	}
+	if q.getStripLocationStmt, err = db.PrepareContext(ctx, getStripLocation); err != nil {
+		return nil, fmt.Errorf("error preparing query GetStripLocation: %w", err)
+	}
+	if q.getTaggedArchiveFilesStmt, err = db.PrepareContext(ctx, getTaggedArchiveFiles); err != nil {
+		return nil, fmt.Errorf("error preparing query GetTaggedArchiveFiles: %w", err)
+	}
+	if q.getTrashedFilesForPurgeStmt, err = db.PrepareContext(ctx, getTrashedFilesForPurge); err != nil {
+		return nil, fmt.Errorf("error preparing query GetTrashedFilesForPurge: %w", err)
+	}
+	if q.getUnreferencedBlobsStmt, err = db.PrepareContext(ctx, getUnreferencedBlobs); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUnreferencedBlobs: %w", err)
+	}
+	if q.getUploadStmt, err = db.PrepareContext(ctx, getUpload); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUpload: %w", err)
+	}
 	if q.getUserByEmailStmt, err = db.PrepareContext(ctx, getUserByEmail); err != nil {
 		return nil, fmt.Errorf("error preparing query GetUserByEmail: %w", err)
 	}
 	if q.getUserByIDStmt, err = db.PrepareContext(ctx, getUserByID); err != nil {
 		return nil, fmt.Errorf("error preparing query GetUserByID: %w", err)
 	}
+	if q.getUserDataKeyStmt, err = db.PrepareContext(ctx, getUserDataKey); err != nil {
+		return nil, fmt.Errorf("error preparing query GetUserDataKey: %w", err)
+	}
 	if q.hardDeleteFilesStmt, err = db.PrepareContext(ctx, hardDeleteFiles); err != nil {
 		return nil, fmt.Errorf("error preparing query HardDeleteFiles: %w", err)
 	}
+	if q.hardDeleteFileVersionsStmt, err = db.PrepareContext(ctx, hardDeleteFileVersions); err != nil {
+		return nil, fmt.Errorf("error preparing query HardDeleteFileVersions: %w", err)
+	}
+	if q.hardDeleteUploadsStmt, err = db.PrepareContext(ctx, hardDeleteUploads); err != nil {
+		return nil, fmt.Errorf("error preparing query HardDeleteUploads: %w", err)
+	}
+	if q.listActiveSessionsStmt, err = db.PrepareContext(ctx, listActiveSessions); err != nil {
+		return nil, fmt.Errorf("error preparing query ListActiveSessions: %w", err)
+	}
 	if q.listApiKeysByUserStmt, err = db.PrepareContext(ctx, listApiKeysByUser); err != nil {
 		return nil, fmt.Errorf("error preparing query ListApiKeysByUser: %w", err)
 	}
+	if q.listDataKeysToRewrapStmt, err = db.PrepareContext(ctx, listDataKeysToRewrap); err != nil {
+		return nil, fmt.Errorf("error preparing query ListDataKeysToRewrap: %w", err)
+	}
+	if q.listFileShareLinksStmt, err = db.PrepareContext(ctx, listFileShareLinks); err != nil {
+		return nil, fmt.Errorf("error preparing query ListFileShareLinks: %w", err)
+	}
+	if q.listFileVersionsStmt, err = db.PrepareContext(ctx, listFileVersions); err != nil {
+		return nil, fmt.Errorf("error preparing query ListFileVersions: %w", err)
+	}
+	if q.listFolderChildrenStmt, err = db.PrepareContext(ctx, listFolderChildren); err != nil {
+		return nil, fmt.Errorf("error preparing query ListFolderChildren: %w", err)
+	}
 	if q.listPublicFilesStmt, err = db.PrepareContext(ctx, listPublicFiles); err != nil {
 		return nil, fmt.Errorf("error preparing query ListPublicFiles: %w", err)
 	}
+	if q.listRoleQuotasStmt, err = db.PrepareContext(ctx, listRoleQuotas); err != nil {
+		return nil, fmt.Errorf("error preparing query ListRoleQuotas: %w", err)
+	}
+	if q.listTrashedFilesStmt, err = db.PrepareContext(ctx, listTrashedFiles); err != nil {
+		return nil, fmt.Errorf("error preparing query ListTrashedFiles: %w", err)
+	}
 	if q.listUserFilesStmt, err = db.PrepareContext(ctx, listUserFiles); err != nil {
 		return nil, fmt.Errorf("error preparing query ListUserFiles: %w", err)
 	}
+	if q.listUserTagsStmt, err = db.PrepareContext(ctx, listUserTags); err != nil {
+		return nil, fmt.Errorf("error preparing query ListUserTags: %w", err)
+	}
+	if q.lockUserFoldersStmt, err = db.PrepareContext(ctx, lockUserFolders); err != nil {
+		return nil, fmt.Errorf("error preparing query LockUserFolders: %w", err)
+	}
+	if q.lockUserStorageStmt, err = db.PrepareContext(ctx, lockUserStorage); err != nil {
+		return nil, fmt.Errorf("error preparing query LockUserStorage: %w", err)
+	}
+	if q.moveFileStmt, err = db.PrepareContext(ctx, moveFile); err != nil {
+		return nil, fmt.Errorf("error preparing query MoveFile: %w", err)
+	}
+	if q.moveFolderStmt, err = db.PrepareContext(ctx, moveFolder); err != nil {
+		return nil, fmt.Errorf("error preparing query MoveFolder: %w", err)
+	}
+	if q.promoteFileVersionStmt, err = db.PrepareContext(ctx, promoteFileVersion); err != nil {
+		return nil, fmt.Errorf("error preparing query PromoteFileVersion: %w", err)
+	}
+	if q.quarantineContentStmt, err = db.PrepareContext(ctx, quarantineContent); err != nil {
+		return nil, fmt.Errorf("error preparing query QuarantineContent: %w", err)
+	}
+	if q.recordShareLinkAccessStmt, err = db.PrepareContext(ctx, recordShareLinkAccess); err != nil {
+		return nil, fmt.Errorf("error preparing query RecordShareLinkAccess: %w", err)
+	}
+	if q.releaseBlobsStmt, err = db.PrepareContext(ctx, releaseBlobs); err != nil {
+		return nil, fmt.Errorf("error preparing query ReleaseBlobs: %w", err)
+	}
+	if q.releaseUploadStmt, err = db.PrepareContext(ctx, releaseUpload); err != nil {
+		return nil, fmt.Errorf("error preparing query ReleaseUpload: %w", err)
+	}
+	if q.renameApiKeyStmt, err = db.PrepareContext(ctx, renameApiKey); err != nil {
+		return nil, fmt.Errorf("error preparing query RenameApiKey: %w", err)
+	}
+	if q.renameFolderStmt, err = db.PrepareContext(ctx, renameFolder); err != nil {
+		return nil, fmt.Errorf("error preparing query RenameFolder: %w", err)
+	}
+	if q.renewUploadClaimStmt, err = db.PrepareContext(ctx, renewUploadClaim); err != nil {
+		return nil, fmt.Errorf("error preparing query RenewUploadClaim: %w", err)
+	}
+	if q.replaceStrippedContentStmt, err = db.PrepareContext(ctx, replaceStrippedContent); err != nil {
+		return nil, fmt.Errorf("error preparing query ReplaceStrippedContent: %w", err)
+	}
+	if q.restoreFileStmt, err = db.PrepareContext(ctx, restoreFile); err != nil {
+		return nil, fmt.Errorf("error preparing query RestoreFile: %w", err)
+	}
 	if q.revokeApiKeyStmt, err = db.PrepareContext(ctx, revokeApiKey); err != nil {
 		return nil, fmt.Errorf("error preparing query RevokeApiKey: %w", err)
 	}
-	if q.revokeRefreshTokenStmt, err = db.PrepareContext(ctx, revokeRefreshToken); err != nil {
-		return nil, fmt.Errorf("error preparing query RevokeRefreshToken: %w", err)
+	if q.revokeRefreshTokenFamilyStmt, err = db.PrepareContext(ctx, revokeRefreshTokenFamily); err != nil {
+		return nil, fmt.Errorf("error preparing query RevokeRefreshTokenFamily: %w", err)
+	}
+	if q.revokeSessionStmt, err = db.PrepareContext(ctx, revokeSession); err != nil {
+		return nil, fmt.Errorf("error preparing query RevokeSession: %w", err)
+	}
+	if q.revokeShareLinkStmt, err = db.PrepareContext(ctx, revokeShareLink); err != nil {
+		return nil, fmt.Errorf("error preparing query RevokeShareLink: %w", err)
+	}
+	if q.revokeUserRefreshTokensStmt, err = db.PrepareContext(ctx, revokeUserRefreshTokens); err != nil {
+		return nil, fmt.Errorf("error preparing query RevokeUserRefreshTokens: %w", err)
+	}
+	if q.rewrapDataKeyStmt, err = db.PrepareContext(ctx, rewrapDataKey); err != nil {
+		return nil, fmt.Errorf("error preparing query RewrapDataKey: %w", err)
+	}
+	if q.rotateApiKeyStmt, err = db.PrepareContext(ctx, rotateApiKey); err != nil {
+		return nil, fmt.Errorf("error preparing query RotateApiKey: %w", err)
+	}
+	if q.rotateRefreshTokenStmt, err = db.PrepareContext(ctx, rotateRefreshToken); err != nil {
+		return nil, fmt.Errorf("error preparing query RotateRefreshToken: %w", err)
+	}
+	if q.searchFilesStmt, err = db.PrepareContext(ctx, searchFiles); err != nil {
+		return nil, fmt.Errorf("error preparing query SearchFiles: %w", err)
+	}
+	if q.setFileTagsStmt, err = db.PrepareContext(ctx, setFileTags); err != nil {
+		return nil, fmt.Errorf("error preparing query SetFileTags: %w", err)
 	}
 	if q.setFileVisibilityStmt, err = db.PrepareContext(ctx, setFileVisibility); err != nil {
 		return nil, fmt.Errorf("error preparing query SetFileVisibility: %w", err)
 	}
+	if q.setImageMetadataStmt, err = db.PrepareContext(ctx, setImageMetadata); err != nil {
+		return nil, fmt.Errorf("error preparing query SetImageMetadata: %w", err)
+	}
+	if q.setRoleQuotaStmt, err = db.PrepareContext(ctx, setRoleQuota); err != nil {
+		return nil, fmt.Errorf("error preparing query SetRoleQuota: %w", err)
+	}
+	if q.setScanResultStmt, err = db.PrepareContext(ctx, setScanResult); err != nil {
+		return nil, fmt.Errorf("error preparing query SetScanResult: %w", err)
+	}
+	if q.setStripLocationStmt, err = db.PrepareContext(ctx, setStripLocation); err != nil {
+		return nil, fmt.Errorf("error preparing query SetStripLocation: %w", err)
+	}
+	if q.setTrashRetentionStmt, err = db.PrepareContext(ctx, setTrashRetention); err != nil {
+		return nil, fmt.Errorf("error preparing query SetTrashRetention: %w", err)
+	}
+	if q.setUserQuotaStmt, err = db.PrepareContext(ctx, setUserQuota); err != nil {
+		return nil, fmt.Errorf("error preparing query SetUserQuota: %w", err)
+	}
+	if q.touchSessionStmt, err = db.PrepareContext(ctx, touchSession); err != nil {
+		return nil, fmt.Errorf("error preparing query TouchSession: %w", err)
+	}
 	if q.updateApiKeyLastUsedStmt, err = db.PrepareContext(ctx, updateApiKeyLastUsed); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateApiKeyLastUsed: %w", err)
 	}
+	if q.updateFileExtractedTextStmt, err = db.PrepareContext(ctx, updateFileExtractedText); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateFileExtractedText: %w", err)
+	}
 	if q.updateFileNameStmt, err = db.PrepareContext(ctx, updateFileName); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateFileName: %w", err)
 	}
 	if q.updateFileThumbnailStmt, err = db.PrepareContext(ctx, updateFileThumbnail); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateFileThumbnail: %w", err)
 	}
+	if q.updateUploadOffsetStmt, err = db.PrepareContext(ctx, updateUploadOffset); err != nil {
+		return nil, fmt.Errorf("error preparing query UpdateUploadOffset: %w", err)
+	}
 	return &q, nil
 }
 
@@ -136,6 +373,16 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing activateUserEmailStmt: %w", cerr)
 		}
 	}
+	if q.addBlobReferenceStmt != nil {
+		if cerr := q.addBlobReferenceStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing addBlobReferenceStmt: %w", cerr)
+		}
+	}
+	if q.addFileVersionStmt != nil {
+		if cerr := q.addFileVersionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing addFileVersionStmt: %w", cerr)
+		}
+	}
 	if q.changePasswordStmt != nil {
 		if cerr := q.changePasswordStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing changePasswordStmt: %w", cerr)
@@ -151,11 +398,36 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing checkIfEmailExistsStmt: %w", cerr)
 		}
 	}
+	if q.claimUploadStmt != nil {
+		if cerr := q.claimUploadStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing claimUploadStmt: %w", cerr)
+		}
+	}
+	if q.completeUploadStmt != nil {
+		if cerr := q.completeUploadStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing completeUploadStmt: %w", cerr)
+		}
+	}
+	if q.countFolderChildrenStmt != nil {
+		if cerr := q.countFolderChildrenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countFolderChildrenStmt: %w", cerr)
+		}
+	}
 	if q.countPublicFilesStmt != nil {
 		if cerr := q.countPublicFilesStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing countPublicFilesStmt: %w", cerr)
 		}
 	}
+	if q.countSearchFilesStmt != nil {
+		if cerr := q.countSearchFilesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countSearchFilesStmt: %w", cerr)
+		}
+	}
+	if q.countTrashedFilesStmt != nil {
+		if cerr := q.countTrashedFilesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing countTrashedFilesStmt: %w", cerr)
+		}
+	}
 	if q.countUserFilesStmt != nil {
 		if cerr := q.countUserFilesStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing countUserFilesStmt: %w", cerr)
@@ -171,16 +443,51 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing createApiKeyStmt: %w", cerr)
 		}
 	}
+	if q.createBlobStmt != nil {
+		if cerr := q.createBlobStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createBlobStmt: %w", cerr)
+		}
+	}
+	if q.createDataKeyStmt != nil {
+		if cerr := q.createDataKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createDataKeyStmt: %w", cerr)
+		}
+	}
 	if q.createFileStmt != nil {
 		if cerr := q.createFileStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createFileStmt: %w", cerr)
 		}
 	}
+	if q.createFolderStmt != nil {
+		if cerr := q.createFolderStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createFolderStmt: %w", cerr)
+		}
+	}
+	if q.createImageRenditionStmt != nil {
+		if cerr := q.createImageRenditionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createImageRenditionStmt: %w", cerr)
+		}
+	}
 	if q.createRefreshTokenStmt != nil {
 		if cerr := q.createRefreshTokenStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createRefreshTokenStmt: %w", cerr)
 		}
 	}
+	if q.createSessionStmt != nil {
+		if cerr := q.createSessionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createSessionStmt: %w", cerr)
+		}
+	}
+	if q.createShareLinkStmt != nil {
+		if cerr := q.createShareLinkStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createShareLinkStmt: %w", cerr)
+		}
+	}
+	if q.createUploadStmt != nil {
+		if cerr := q.createUploadStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createUploadStmt: %w", cerr)
+		}
+	}
 	if q.createUserStmt != nil {
 		if cerr := q.createUserStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createUserStmt: %w", cerr)
@@ -196,14 +503,29 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing deleteApiKeyStmt: %w", cerr)
 		}
 	}
+	if q.deleteExpiredFoldersStmt != nil {
+		if cerr := q.deleteExpiredFoldersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteExpiredFoldersStmt: %w", cerr)
+		}
+	}
 	if q.deleteFileStmt != nil {
 		if cerr := q.deleteFileStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing deleteFileStmt: %w", cerr)
 		}
 	}
-	if q.deleteRefreshTokenStmt != nil {
-		if cerr := q.deleteRefreshTokenStmt.Close(); cerr != nil {
-			err = fmt.Errorf("error closing deleteRefreshTokenStmt: %w", cerr)
+	if q.deleteFolderStmt != nil {
+		if cerr := q.deleteFolderStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteFolderStmt: %w", cerr)
+		}
+	}
+	if q.deleteUnreferencedBlobsStmt != nil {
+		if cerr := q.deleteUnreferencedBlobsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteUnreferencedBlobsStmt: %w", cerr)
+		}
+	}
+	if q.deleteUploadStmt != nil {
+		if cerr := q.deleteUploadStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteUploadStmt: %w", cerr)
 		}
 	}
 	if q.getActionTokenForUserStmt != nil {
@@ -216,11 +538,41 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getApiKeyByPrefixStmt: %w", cerr)
 		}
 	}
+	if q.getApiKeyStatusStmt != nil {
+		if cerr := q.getApiKeyStatusStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getApiKeyStatusStmt: %w", cerr)
+		}
+	}
+	if q.getArchiveFilesStmt != nil {
+		if cerr := q.getArchiveFilesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getArchiveFilesStmt: %w", cerr)
+		}
+	}
+	if q.getBlobRenditionsStmt != nil {
+		if cerr := q.getBlobRenditionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getBlobRenditionsStmt: %w", cerr)
+		}
+	}
+	if q.getDataKeyStmt != nil {
+		if cerr := q.getDataKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getDataKeyStmt: %w", cerr)
+		}
+	}
+	if q.getExcessFileVersionsStmt != nil {
+		if cerr := q.getExcessFileVersionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getExcessFileVersionsStmt: %w", cerr)
+		}
+	}
 	if q.getExpiredDeletedFilesStmt != nil {
 		if cerr := q.getExpiredDeletedFilesStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getExpiredDeletedFilesStmt: %w", cerr)
 		}
 	}
+	if q.getExpiredUploadsStmt != nil {
+		if cerr := q.getExpiredUploadsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getExpiredUploadsStmt: %w", cerr)
+		}
+	}
 	if q.getFileByChecksumStmt != nil {
 		if cerr := q.getFileByChecksumStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getFileByChecksumStmt: %w", cerr)
@@ -236,11 +588,81 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getFileOwnerStmt: %w", cerr)
 		}
 	}
+	if q.getFileVersionStmt != nil {
+		if cerr := q.getFileVersionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getFileVersionStmt: %w", cerr)
+		}
+	}
+	if q.getFileVersionStorageKeysStmt != nil {
+		if cerr := q.getFileVersionStorageKeysStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getFileVersionStorageKeysStmt: %w", cerr)
+		}
+	}
+	if q.getFolderStmt != nil {
+		if cerr := q.getFolderStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getFolderStmt: %w", cerr)
+		}
+	}
+	if q.getFolderArchiveFilesStmt != nil {
+		if cerr := q.getFolderArchiveFilesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getFolderArchiveFilesStmt: %w", cerr)
+		}
+	}
+	if q.getFolderPathStmt != nil {
+		if cerr := q.getFolderPathStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getFolderPathStmt: %w", cerr)
+		}
+	}
 	if q.getRefreshTokenStmt != nil {
 		if cerr := q.getRefreshTokenStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getRefreshTokenStmt: %w", cerr)
 		}
 	}
+	if q.getServiceDataKeyStmt != nil {
+		if cerr := q.getServiceDataKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getServiceDataKeyStmt: %w", cerr)
+		}
+	}
+	if q.getSessionDeviceCountsStmt != nil {
+		if cerr := q.getSessionDeviceCountsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getSessionDeviceCountsStmt: %w", cerr)
+		}
+	}
+	if q.getShareLinkByTokenStmt != nil {
+		if cerr := q.getShareLinkByTokenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getShareLinkByTokenStmt: %w", cerr)
+		}
+	}
+	if q.getStorageUsageStmt != nil {
+		if cerr := q.getStorageUsageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getStorageUsageStmt: %w", cerr)
+		}
+	}
+	if q.getStripLocationStmt != nil {
+		if cerr := q.getStripLocationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getStripLocationStmt: %w", cerr)
+		}
+	}
+	if q.getTaggedArchiveFilesStmt != nil {
+		if cerr := q.getTaggedArchiveFilesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getTaggedArchiveFilesStmt: %w", cerr)
+		}
+	}
+	if q.getTrashedFilesForPurgeStmt != nil {
+		if cerr := q.getTrashedFilesForPurgeStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getTrashedFilesForPurgeStmt: %w", cerr)
+		}
+	}
+	if q.getUnreferencedBlobsStmt != nil {
+		if cerr := q.getUnreferencedBlobsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUnreferencedBlobsStmt: %w", cerr)
+		}
+	}
+	if q.getUploadStmt != nil {
+		if cerr := q.getUploadStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUploadStmt: %w", cerr)
+		}
+	}
 	if q.getUserByEmailStmt != nil {
 		if cerr := q.getUserByEmailStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getUserByEmailStmt: %w", cerr)
@@ -251,34 +673,199 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getUserByIDStmt: %w", cerr)
 		}
 	}
+	if q.getUserDataKeyStmt != nil {
+		if cerr := q.getUserDataKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getUserDataKeyStmt: %w", cerr)
+		}
+	}
 	if q.hardDeleteFilesStmt != nil {
 		if cerr := q.hardDeleteFilesStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing hardDeleteFilesStmt: %w", cerr)
 		}
 	}
+	if q.hardDeleteFileVersionsStmt != nil {
+		if cerr := q.hardDeleteFileVersionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing hardDeleteFileVersionsStmt: %w", cerr)
+		}
+	}
+	if q.hardDeleteUploadsStmt != nil {
+		if cerr := q.hardDeleteUploadsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing hardDeleteUploadsStmt: %w", cerr)
+		}
+	}
+	if q.listActiveSessionsStmt != nil {
+		if cerr := q.listActiveSessionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listActiveSessionsStmt: %w", cerr)
+		}
+	}
 	if q.listApiKeysByUserStmt != nil {
 		if cerr := q.listApiKeysByUserStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listApiKeysByUserStmt: %w", cerr)
 		}
 	}
+	if q.listDataKeysToRewrapStmt != nil {
+		if cerr := q.listDataKeysToRewrapStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listDataKeysToRewrapStmt: %w", cerr)
+		}
+	}
+	if q.listFileShareLinksStmt != nil {
+		if cerr := q.listFileShareLinksStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listFileShareLinksStmt: %w", cerr)
+		}
+	}
+	if q.listFileVersionsStmt != nil {
+		if cerr := q.listFileVersionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listFileVersionsStmt: %w", cerr)
+		}
+	}
+	if q.listFolderChildrenStmt != nil {
+		if cerr := q.listFolderChildrenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listFolderChildrenStmt: %w", cerr)
+		}
+	}
 	if q.listPublicFilesStmt != nil {
 		if cerr := q.listPublicFilesStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listPublicFilesStmt: %w", cerr)
 		}
 	}
+	if q.listRoleQuotasStmt != nil {
+		if cerr := q.listRoleQuotasStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listRoleQuotasStmt: %w", cerr)
+		}
+	}
+	if q.listTrashedFilesStmt != nil {
+		if cerr := q.listTrashedFilesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listTrashedFilesStmt: %w", cerr)
+		}
+	}
 	if q.listUserFilesStmt != nil {
 		if cerr := q.listUserFilesStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listUserFilesStmt: %w", cerr)
 		}
 	}
+	if q.listUserTagsStmt != nil {
+		if cerr := q.listUserTagsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listUserTagsStmt: %w", cerr)
+		}
+	}
+	if q.lockUserFoldersStmt != nil {
+		if cerr := q.lockUserFoldersStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing lockUserFoldersStmt: %w", cerr)
+		}
+	}
+	if q.lockUserStorageStmt != nil {
+		if cerr := q.lockUserStorageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing lockUserStorageStmt: %w", cerr)
+		}
+	}
+	if q.moveFileStmt != nil {
+		if cerr := q.moveFileStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing moveFileStmt: %w", cerr)
+		}
+	}
+	if q.moveFolderStmt != nil {
+		if cerr := q.moveFolderStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing moveFolderStmt: %w", cerr)
+		}
+	}
+	if q.promoteFileVersionStmt != nil {
+		if cerr := q.promoteFileVersionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing promoteFileVersionStmt: %w", cerr)
+		}
+	}
+	if q.quarantineContentStmt != nil {
+		if cerr := q.quarantineContentStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing quarantineContentStmt: %w", cerr)
+		}
+	}
+	if q.recordShareLinkAccessStmt != nil {
+		if cerr := q.recordShareLinkAccessStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing recordShareLinkAccessStmt: %w", cerr)
+		}
+	}
+	if q.releaseBlobsStmt != nil {
+		if cerr := q.releaseBlobsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing releaseBlobsStmt: %w", cerr)
+		}
+	}
+	if q.releaseUploadStmt != nil {
+		if cerr := q.releaseUploadStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing releaseUploadStmt: %w", cerr)
+		}
+	}
+	if q.renameApiKeyStmt != nil {
+		if cerr := q.renameApiKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing renameApiKeyStmt: %w", cerr)
+		}
+	}
+	if q.renameFolderStmt != nil {
+		if cerr := q.renameFolderStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing renameFolderStmt: %w", cerr)
+		}
+	}
+	if q.renewUploadClaimStmt != nil {
+		if cerr := q.renewUploadClaimStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing renewUploadClaimStmt: %w", cerr)
+		}
+	}
+	if q.replaceStrippedContentStmt != nil {
+		if cerr := q.replaceStrippedContentStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing replaceStrippedContentStmt: %w", cerr)
+		}
+	}
+	if q.restoreFileStmt != nil {
+		if cerr := q.restoreFileStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing restoreFileStmt: %w", cerr)
+		}
+	}
 	if q.revokeApiKeyStmt != nil {
 		if cerr := q.revokeApiKeyStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing revokeApiKeyStmt: %w", cerr)
 		}
 	}
-	if q.revokeRefreshTokenStmt != nil {
-		if cerr := q.revokeRefreshTokenStmt.Close(); cerr != nil {
-			err = fmt.Errorf("error closing revokeRefreshTokenStmt: %w", cerr)
+	if q.revokeRefreshTokenFamilyStmt != nil {
+		if cerr := q.revokeRefreshTokenFamilyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing revokeRefreshTokenFamilyStmt: %w", cerr)
+		}
+	}
+	if q.revokeSessionStmt != nil {
+		if cerr := q.revokeSessionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing revokeSessionStmt: %w", cerr)
+		}
+	}
+	if q.revokeShareLinkStmt != nil {
+		if cerr := q.revokeShareLinkStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing revokeShareLinkStmt: %w", cerr)
+		}
+	}
+	if q.revokeUserRefreshTokensStmt != nil {
+		if cerr := q.revokeUserRefreshTokensStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing revokeUserRefreshTokensStmt: %w", cerr)
+		}
+	}
+	if q.rewrapDataKeyStmt != nil {
+		if cerr := q.rewrapDataKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing rewrapDataKeyStmt: %w", cerr)
+		}
+	}
+	if q.rotateApiKeyStmt != nil {
+		if cerr := q.rotateApiKeyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing rotateApiKeyStmt: %w", cerr)
+		}
+	}
+	if q.rotateRefreshTokenStmt != nil {
+		if cerr := q.rotateRefreshTokenStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing rotateRefreshTokenStmt: %w", cerr)
+		}
+	}
+	if q.searchFilesStmt != nil {
+		if cerr := q.searchFilesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing searchFilesStmt: %w", cerr)
+		}
+	}
+	if q.setFileTagsStmt != nil {
+		if cerr := q.setFileTagsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setFileTagsStmt: %w", cerr)
 		}
 	}
 	if q.setFileVisibilityStmt != nil {
@@ -286,11 +873,51 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing setFileVisibilityStmt: %w", cerr)
 		}
 	}
+	if q.setImageMetadataStmt != nil {
+		if cerr := q.setImageMetadataStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setImageMetadataStmt: %w", cerr)
+		}
+	}
+	if q.setRoleQuotaStmt != nil {
+		if cerr := q.setRoleQuotaStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setRoleQuotaStmt: %w", cerr)
+		}
+	}
+	if q.setScanResultStmt != nil {
+		if cerr := q.setScanResultStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setScanResultStmt: %w", cerr)
+		}
+	}
+	if q.setStripLocationStmt != nil {
+		if cerr := q.setStripLocationStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setStripLocationStmt: %w", cerr)
+		}
+	}
+	if q.setTrashRetentionStmt != nil {
+		if cerr := q.setTrashRetentionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setTrashRetentionStmt: %w", cerr)
+		}
+	}
+	if q.setUserQuotaStmt != nil {
+		if cerr := q.setUserQuotaStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing setUserQuotaStmt: %w", cerr)
+		}
+	}
+	if q.touchSessionStmt != nil {
+		if cerr := q.touchSessionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing touchSessionStmt: %w", cerr)
+		}
+	}
 	if q.updateApiKeyLastUsedStmt != nil {
 		if cerr := q.updateApiKeyLastUsedStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing updateApiKeyLastUsedStmt: %w", cerr)
 		}
 	}
+	if q.updateFileExtractedTextStmt != nil {
+		if cerr := q.updateFileExtractedTextStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateFileExtractedTextStmt: %w", cerr)
+		}
+	}
 	if q.updateFileNameStmt != nil {
 		if cerr := q.updateFileNameStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing updateFileNameStmt: %w", cerr)
@@ -301,6 +928,11 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing updateFileThumbnailStmt: %w", cerr)
 		}
 	}
+	if q.updateUploadOffsetStmt != nil {
+		if cerr := q.updateUploadOffsetStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing updateUploadOffsetStmt: %w", cerr)
+		}
+	}
 	return err
 }
 
@@ -338,81 +970,239 @@ func (q *Queries) queryRow(ctx context.Context, stmt *sql.Stmt, query string, ar
 }
 
 type Queries struct {
-	db                         DBTX
-	tx                         *sql.Tx
-	activateUserEmailStmt      *sql.Stmt
-	changePasswordStmt         *sql.Stmt
-	checkIfAPIKeyExistsStmt    *sql.Stmt
-	checkIfEmailExistsStmt     *sql.Stmt
-	countPublicFilesStmt       *sql.Stmt
-	countUserFilesStmt         *sql.Stmt
-	createActionTokenStmt      *sql.Stmt
-	createApiKeyStmt           *sql.Stmt
-	createFileStmt             *sql.Stmt
-	createRefreshTokenStmt     *sql.Stmt
-	createUserStmt             *sql.Stmt
-	deleteActionTokenStmt      *sql.Stmt
-	deleteApiKeyStmt           *sql.Stmt
-	deleteFileStmt             *sql.Stmt
-	deleteRefreshTokenStmt     *sql.Stmt
-	getActionTokenForUserStmt  *sql.Stmt
-	getApiKeyByPrefixStmt      *sql.Stmt
-	getExpiredDeletedFilesStmt *sql.Stmt
-	getFileByChecksumStmt      *sql.Stmt
-	getFileInfoStmt            *sql.Stmt
-	getFileOwnerStmt           *sql.Stmt
-	getRefreshTokenStmt        *sql.Stmt
-	getUserByEmailStmt         *sql.Stmt
-	getUserByIDStmt            *sql.Stmt
-	hardDeleteFilesStmt        *sql.Stmt
-	listApiKeysByUserStmt      *sql.Stmt
-	listPublicFilesStmt        *sql.Stmt
-	listUserFilesStmt          *sql.Stmt
-	revokeApiKeyStmt           *sql.Stmt
-	revokeRefreshTokenStmt     *sql.Stmt
-	setFileVisibilityStmt      *sql.Stmt
-	updateApiKeyLastUsedStmt   *sql.Stmt
-	updateFileNameStmt         *sql.Stmt
-	updateFileThumbnailStmt    *sql.Stmt
+	db                            DBTX
+	tx                            *sql.Tx
+	activateUserEmailStmt         *sql.Stmt
+	addBlobReferenceStmt          *sql.Stmt
+	addFileVersionStmt            *sql.Stmt
+	changePasswordStmt            *sql.Stmt
+	checkIfAPIKeyExistsStmt       *sql.Stmt
+	checkIfEmailExistsStmt        *sql.Stmt
+	claimUploadStmt               *sql.Stmt
+	completeUploadStmt            *sql.Stmt
+	countFolderChildrenStmt       *sql.Stmt
+	countPublicFilesStmt          *sql.Stmt
+	countSearchFilesStmt          *sql.Stmt
+	countTrashedFilesStmt         *sql.Stmt
+	countUserFilesStmt            *sql.Stmt
+	createActionTokenStmt         *sql.Stmt
+	createApiKeyStmt              *sql.Stmt
+	createBlobStmt                *sql.Stmt
+	createDataKeyStmt             *sql.Stmt
+	createFileStmt                *sql.Stmt
+	createFolderStmt              *sql.Stmt
+	createImageRenditionStmt      *sql.Stmt
+	createRefreshTokenStmt        *sql.Stmt
+	createSessionStmt             *sql.Stmt
+	createShareLinkStmt           *sql.Stmt
+	createUploadStmt              *sql.Stmt
+	createUserStmt                *sql.Stmt
+	deleteActionTokenStmt         *sql.Stmt
+	deleteApiKeyStmt              *sql.Stmt
+	deleteExpiredFoldersStmt      *sql.Stmt
+	deleteFileStmt                *sql.Stmt
+	deleteFolderStmt              *sql.Stmt
+	deleteUnreferencedBlobsStmt   *sql.Stmt
+	deleteUploadStmt              *sql.Stmt
+	getActionTokenForUserStmt     *sql.Stmt
+	getApiKeyByPrefixStmt         *sql.Stmt
+	getApiKeyStatusStmt           *sql.Stmt
+	getArchiveFilesStmt           *sql.Stmt
+	getBlobRenditionsStmt         *sql.Stmt
+	getDataKeyStmt                *sql.Stmt
+	getExcessFileVersionsStmt     *sql.Stmt
+	getExpiredDeletedFilesStmt    *sql.Stmt
+	getExpiredUploadsStmt         *sql.Stmt
+	getFileByChecksumStmt         *sql.Stmt
+	getFileInfoStmt               *sql.Stmt
+	getFileOwnerStmt              *sql.Stmt
+	getFileVersionStmt            *sql.Stmt
+	getFileVersionStorageKeysStmt *sql.Stmt
+	getFolderStmt                 *sql.Stmt
+	getFolderArchiveFilesStmt     *sql.Stmt
+	getFolderPathStmt             *sql.Stmt
+	getRefreshTokenStmt           *sql.Stmt
+	getServiceDataKeyStmt         *sql.Stmt
+	getSessionDeviceCountsStmt    *sql.Stmt
+	getShareLinkByTokenStmt       *sql.Stmt
+	getStorageUsageStmt           *sql.Stmt
+	getStripLocationStmt          *sql.Stmt
+	getTaggedArchiveFilesStmt     *sql.Stmt
+	getTrashedFilesForPurgeStmt   *sql.Stmt
+	getUnreferencedBlobsStmt      *sql.Stmt
+	getUploadStmt                 *sql.Stmt
+	getUserByEmailStmt            *sql.Stmt
+	getUserByIDStmt               *sql.Stmt
+	getUserDataKeyStmt            *sql.Stmt
+	hardDeleteFilesStmt           *sql.Stmt
+	hardDeleteFileVersionsStmt    *sql.Stmt
+	hardDeleteUploadsStmt         *sql.Stmt
+	listActiveSessionsStmt        *sql.Stmt
+	listApiKeysByUserStmt         *sql.Stmt
+	listDataKeysToRewrapStmt      *sql.Stmt
+	listFileShareLinksStmt        *sql.Stmt
+	listFileVersionsStmt          *sql.Stmt
+	listFolderChildrenStmt        *sql.Stmt
+	listPublicFilesStmt           *sql.Stmt
+	listRoleQuotasStmt            *sql.Stmt
+	listTrashedFilesStmt          *sql.Stmt
+	listUserFilesStmt             *sql.Stmt
+	listUserTagsStmt              *sql.Stmt
+	lockUserFoldersStmt           *sql.Stmt
+	lockUserStorageStmt           *sql.Stmt
+	moveFileStmt                  *sql.Stmt
+	moveFolderStmt                *sql.Stmt
+	promoteFileVersionStmt        *sql.Stmt
+	quarantineContentStmt         *sql.Stmt
+	recordShareLinkAccessStmt     *sql.Stmt
+	releaseBlobsStmt              *sql.Stmt
+	releaseUploadStmt             *sql.Stmt
+	renameApiKeyStmt              *sql.Stmt
+	renameFolderStmt              *sql.Stmt
+	renewUploadClaimStmt          *sql.Stmt
+	replaceStrippedContentStmt    *sql.Stmt
+	restoreFileStmt               *sql.Stmt
+	revokeApiKeyStmt              *sql.Stmt
+	revokeRefreshTokenFamilyStmt  *sql.Stmt
+	revokeSessionStmt             *sql.Stmt
+	revokeShareLinkStmt           *sql.Stmt
+	revokeUserRefreshTokensStmt   *sql.Stmt
+	rewrapDataKeyStmt             *sql.Stmt
+	rotateApiKeyStmt              *sql.Stmt
+	rotateRefreshTokenStmt        *sql.Stmt
+	searchFilesStmt               *sql.Stmt
+	setFileTagsStmt               *sql.Stmt
+	setFileVisibilityStmt         *sql.Stmt
+	setImageMetadataStmt          *sql.Stmt
+	setRoleQuotaStmt              *sql.Stmt
+	setScanResultStmt             *sql.Stmt
+	setStripLocationStmt          *sql.Stmt
+	setTrashRetentionStmt         *sql.Stmt
+	setUserQuotaStmt              *sql.Stmt
+	touchSessionStmt              *sql.Stmt
+	updateApiKeyLastUsedStmt      *sql.Stmt
+	updateFileExtractedTextStmt   *sql.Stmt
+	updateFileNameStmt            *sql.Stmt
+	updateFileThumbnailStmt       *sql.Stmt
+	updateUploadOffsetStmt        *sql.Stmt
 }
 
 func (q *Queries) WithTx(tx *sql.Tx) *Queries {
 	return &Queries{
-		db:                         tx,
-		tx:                         tx,
-		activateUserEmailStmt:      q.activateUserEmailStmt,
-		changePasswordStmt:         q.changePasswordStmt,
-		checkIfAPIKeyExistsStmt:    q.checkIfAPIKeyExistsStmt,
-		checkIfEmailExistsStmt:     q.checkIfEmailExistsStmt,
-		countPublicFilesStmt:       q.countPublicFilesStmt,
-		countUserFilesStmt:         q.countUserFilesStmt,
-		createActionTokenStmt:      q.createActionTokenStmt,
-		createApiKeyStmt:           q.createApiKeyStmt,
-		createFileStmt:             q.createFileStmt,
-		createRefreshTokenStmt:     q.createRefreshTokenStmt,
-		createUserStmt:             q.createUserStmt,
-		deleteActionTokenStmt:      q.deleteActionTokenStmt,
-		deleteApiKeyStmt:           q.deleteApiKeyStmt,
-		deleteFileStmt:             q.deleteFileStmt,
-		deleteRefreshTokenStmt:     q.deleteRefreshTokenStmt,
-		getActionTokenForUserStmt:  q.getActionTokenForUserStmt,
-		getApiKeyByPrefixStmt:      q.getApiKeyByPrefixStmt,
-		getExpiredDeletedFilesStmt: q.getExpiredDeletedFilesStmt,
-		getFileByChecksumStmt:      q.getFileByChecksumStmt,
-		getFileInfoStmt:            q.getFileInfoStmt,
-		getFileOwnerStmt:           q.getFileOwnerStmt,
-		getRefreshTokenStmt:        q.getRefreshTokenStmt,
-		getUserByEmailStmt:         q.getUserByEmailStmt,
-		getUserByIDStmt:            q.getUserByIDStmt,
-		hardDeleteFilesStmt:        q.hardDeleteFilesStmt,
-		listApiKeysByUserStmt:      q.listApiKeysByUserStmt,
-		listPublicFilesStmt:        q.listPublicFilesStmt,
-		listUserFilesStmt:          q.listUserFilesStmt,
-		revokeApiKeyStmt:           q.revokeApiKeyStmt,
-		revokeRefreshTokenStmt:     q.revokeRefreshTokenStmt,
-		setFileVisibilityStmt:      q.setFileVisibilityStmt,
-		updateApiKeyLastUsedStmt:   q.updateApiKeyLastUsedStmt,
-		updateFileNameStmt:         q.updateFileNameStmt,
-		updateFileThumbnailStmt:    q.updateFileThumbnailStmt,
+		db:                            tx,
+		tx:                            tx,
+		activateUserEmailStmt:         q.activateUserEmailStmt,
+		addBlobReferenceStmt:          q.addBlobReferenceStmt,
+		addFileVersionStmt:            q.addFileVersionStmt,
+		changePasswordStmt:            q.changePasswordStmt,
+		checkIfAPIKeyExistsStmt:       q.checkIfAPIKeyExistsStmt,
+		checkIfEmailExistsStmt:        q.checkIfEmailExistsStmt,
+		claimUploadStmt:               q.claimUploadStmt,
+		completeUploadStmt:            q.completeUploadStmt,
+		countFolderChildrenStmt:       q.countFolderChildrenStmt,
+		countPublicFilesStmt:          q.countPublicFilesStmt,
+		countSearchFilesStmt:          q.countSearchFilesStmt,
+		countTrashedFilesStmt:         q.countTrashedFilesStmt,
+		countUserFilesStmt:            q.countUserFilesStmt,
+		createActionTokenStmt:         q.createActionTokenStmt,
+		createApiKeyStmt:              q.createApiKeyStmt,
+		createBlobStmt:                q.createBlobStmt,
+		createDataKeyStmt:             q.createDataKeyStmt,
+		createFileStmt:                q.createFileStmt,
+		createFolderStmt:              q.createFolderStmt,
+		createImageRenditionStmt:      q.createImageRenditionStmt,
+		createRefreshTokenStmt:        q.createRefreshTokenStmt,
+		createSessionStmt:             q.createSessionStmt,
+		createShareLinkStmt:           q.createShareLinkStmt,
+		createUploadStmt:              q.createUploadStmt,
+		createUserStmt:                q.createUserStmt,
+		deleteActionTokenStmt:         q.deleteActionTokenStmt,
+		deleteApiKeyStmt:              q.deleteApiKeyStmt,
+		deleteExpiredFoldersStmt:      q.deleteExpiredFoldersStmt,
+		deleteFileStmt:                q.deleteFileStmt,
+		deleteFolderStmt:              q.deleteFolderStmt,
+		deleteUnreferencedBlobsStmt:   q.deleteUnreferencedBlobsStmt,
+		deleteUploadStmt:              q.deleteUploadStmt,
+		getActionTokenForUserStmt:     q.getActionTokenForUserStmt,
+		getApiKeyByPrefixStmt:         q.getApiKeyByPrefixStmt,
+		getApiKeyStatusStmt:           q.getApiKeyStatusStmt,
+		getArchiveFilesStmt:           q.getArchiveFilesStmt,
+		getBlobRenditionsStmt:         q.getBlobRenditionsStmt,
+		getDataKeyStmt:                q.getDataKeyStmt,
+		getExcessFileVersionsStmt:     q.getExcessFileVersionsStmt,
+		getExpiredDeletedFilesStmt:    q.getExpiredDeletedFilesStmt,
+		getExpiredUploadsStmt:         q.getExpiredUploadsStmt,
+		getFileByChecksumStmt:         q.getFileByChecksumStmt,
+		getFileInfoStmt:               q.getFileInfoStmt,
+		getFileOwnerStmt:              q.getFileOwnerStmt,
+		getFileVersionStmt:            q.getFileVersionStmt,
+		getFileVersionStorageKeysStmt: q.getFileVersionStorageKeysStmt,
+		getFolderStmt:                 q.getFolderStmt,
+		getFolderArchiveFilesStmt:     q.getFolderArchiveFilesStmt,
+		getFolderPathStmt:             q.getFolderPathStmt,
+		getRefreshTokenStmt:           q.getRefreshTokenStmt,
+		getServiceDataKeyStmt:         q.getServiceDataKeyStmt,
+		getSessionDeviceCountsStmt:    q.getSessionDeviceCountsStmt,
+		getShareLinkByTokenStmt:       q.getShareLinkByTokenStmt,
+		getStorageUsageStmt:           q.getStorageUsageStmt,
+		getStripLocationStmt:          q.getStripLocationStmt,
+		getTaggedArchiveFilesStmt:     q.getTaggedArchiveFilesStmt,
+		getTrashedFilesForPurgeStmt:   q.getTrashedFilesForPurgeStmt,
+		getUnreferencedBlobsStmt:      q.getUnreferencedBlobsStmt,
+		getUploadStmt:                 q.getUploadStmt,
+		getUserByEmailStmt:            q.getUserByEmailStmt,
+		getUserByIDStmt:               q.getUserByIDStmt,
+		getUserDataKeyStmt:            q.getUserDataKeyStmt,
+		hardDeleteFilesStmt:           q.hardDeleteFilesStmt,
+		hardDeleteFileVersionsStmt:    q.hardDeleteFileVersionsStmt,
+		hardDeleteUploadsStmt:         q.hardDeleteUploadsStmt,
+		listActiveSessionsStmt:        q.listActiveSessionsStmt,
+		listApiKeysByUserStmt:         q.listApiKeysByUserStmt,
+		listDataKeysToRewrapStmt:      q.listDataKeysToRewrapStmt,
+		listFileShareLinksStmt:        q.listFileShareLinksStmt,
+		listFileVersionsStmt:          q.listFileVersionsStmt,
+		listFolderChildrenStmt:        q.listFolderChildrenStmt,
+		listPublicFilesStmt:           q.listPublicFilesStmt,
+		listRoleQuotasStmt:            q.listRoleQuotasStmt,
+		listTrashedFilesStmt:          q.listTrashedFilesStmt,
+		listUserFilesStmt:             q.listUserFilesStmt,
+		listUserTagsStmt:              q.listUserTagsStmt,
+		lockUserFoldersStmt:           q.lockUserFoldersStmt,
+		lockUserStorageStmt:           q.lockUserStorageStmt,
+		moveFileStmt:                  q.moveFileStmt,
+		moveFolderStmt:                q.moveFolderStmt,
+		promoteFileVersionStmt:        q.promoteFileVersionStmt,
+		quarantineContentStmt:         q.quarantineContentStmt,
+		recordShareLinkAccessStmt:     q.recordShareLinkAccessStmt,
+		releaseBlobsStmt:              q.releaseBlobsStmt,
+		releaseUploadStmt:             q.releaseUploadStmt,
+		renameApiKeyStmt:              q.renameApiKeyStmt,
+		renameFolderStmt:              q.renameFolderStmt,
+		renewUploadClaimStmt:          q.renewUploadClaimStmt,
+		replaceStrippedContentStmt:    q.replaceStrippedContentStmt,
+		restoreFileStmt:               q.restoreFileStmt,
+		revokeApiKeyStmt:              q.revokeApiKeyStmt,
+		revokeRefreshTokenFamilyStmt:  q.revokeRefreshTokenFamilyStmt,
+		revokeSessionStmt:             q.revokeSessionStmt,
+		revokeShareLinkStmt:           q.revokeShareLinkStmt,
+		revokeUserRefreshTokensStmt:   q.revokeUserRefreshTokensStmt,
+		rewrapDataKeyStmt:             q.rewrapDataKeyStmt,
+		rotateApiKeyStmt:              q.rotateApiKeyStmt,
+		rotateRefreshTokenStmt:        q.rotateRefreshTokenStmt,
+		searchFilesStmt:               q.searchFilesStmt,
+		setFileTagsStmt:               q.setFileTagsStmt,
+		setFileVisibilityStmt:         q.setFileVisibilityStmt,
+		setImageMetadataStmt:          q.setImageMetadataStmt,
+		setRoleQuotaStmt:              q.setRoleQuotaStmt,
+		setScanResultStmt:             q.setScanResultStmt,
+		setStripLocationStmt:          q.setStripLocationStmt,
+		setTrashRetentionStmt:         q.setTrashRetentionStmt,
+		setUserQuotaStmt:              q.setUserQuotaStmt,
+		touchSessionStmt:              q.touchSessionStmt,
+		updateApiKeyLastUsedStmt:      q.updateApiKeyLastUsedStmt,
+		updateFileExtractedTextStmt:   q.updateFileExtractedTextStmt,
+		updateFileNameStmt:            q.updateFileNameStmt,
+		updateFileThumbnailStmt:       q.updateFileThumbnailStmt,
+		updateUploadOffsetStmt:        q.updateUploadOffsetStmt,
 	}
 }
@@ -8,6 +8,7 @@ package database
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -19,11 +20,49 @@ select
     count(*)
 from files
     where visibility = 'public'
+        and scan_status in ('clean', 'skipped')
         and is_deleted = false
+        and ($1::text[] is null or tags @> $1)
+        and ($2::text[] is null or tags && $2)
+        and ($3::text is null or mime_type like $3 || '%')
+        and ($4::bigint is null or size_bytes >= $4)
+        and ($5::bigint is null or size_bytes <= $5)
+        and ($6::timestamptz is null or created_at >= $6)
+        and ($7::timestamptz is null or created_at < $7)
 `
 
-func (q *Queries) CountPublicFiles(ctx context.Context) (int64, error) {
-	row := q.queryRow(ctx, q.countPublicFilesStmt, countPublicFiles)
+type CountPublicFilesParams struct {
+	AllTags       []string       `json:"all_tags"`
+	AnyTags       []string       `json:"any_tags"`
+	MimePrefix    sql.NullString `json:"mime_prefix"`
+	MinSize       sql.NullInt64  `json:"min_size"`
+	MaxSize       sql.NullInt64  `json:"max_size"`
+	CreatedAfter  sql.NullTime   `json:"created_after"`
+	CreatedBefore sql.NullTime   `json:"created_before"`
+}
+
+func (q *Queries) CountPublicFiles(ctx context.Context, arg CountPublicFilesParams) (int64, error) {
+	row := q.queryRow(ctx, q.countPublicFilesStmt, countPublicFiles,
+		pq.Array(arg.AllTags),
+		pq.Array(arg.AnyTags),
+		arg.MimePrefix,
+		arg.MinSize,
+		arg.MaxSize,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countTrashedFiles = `-- name: CountTrashedFiles :one
+select count(*) from files
+    where user_id = $1 and is_deleted = true
+`
+
+func (q *Queries) CountTrashedFiles(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.queryRow(ctx, q.countTrashedFilesStmt, countTrashedFiles, userID)
 	var count int64
 	err := row.Scan(&count)
 	return count, err
@@ -31,42 +70,88 @@ func (q *Queries) CountPublicFiles(ctx context.Context) (int64, error) {
 
 const countUserFiles = `-- name: CountUserFiles :one
 select count(*) from files
-    where user_id = $1 and is_deleted = false
+    where user_id = $1
+        and is_deleted = false
+        and ($2::text[] is null or tags @> $2)
+        and ($3::text[] is null or tags && $3)
+        and ($4::text is null or mime_type like $4 || '%')
+        and ($5::bigint is null or size_bytes >= $5)
+        and ($6::bigint is null or size_bytes <= $6)
+        and ($7::timestamptz is null or created_at >= $7)
+        and ($8::timestamptz is null or created_at < $8)
+        and ($9::file_visibility is null or visibility = $9)
 `
 
-func (q *Queries) CountUserFiles(ctx context.Context, userID uuid.UUID) (int64, error) {
-	row := q.queryRow(ctx, q.countUserFilesStmt, countUserFiles, userID)
+type CountUserFilesParams struct {
+	UserID        uuid.UUID          `json:"user_id"`
+	AllTags       []string           `json:"all_tags"`
+	AnyTags       []string           `json:"any_tags"`
+	MimePrefix    sql.NullString     `json:"mime_prefix"`
+	MinSize       sql.NullInt64      `json:"min_size"`
+	MaxSize       sql.NullInt64      `json:"max_size"`
+	CreatedAfter  sql.NullTime       `json:"created_after"`
+	CreatedBefore sql.NullTime       `json:"created_before"`
+	Visibility    NullFileVisibility `json:"visibility"`
+}
+
+func (q *Queries) CountUserFiles(ctx context.Context, arg CountUserFilesParams) (int64, error) {
+	row := q.queryRow(ctx, q.countUserFilesStmt, countUserFiles,
+		arg.UserID,
+		pq.Array(arg.AllTags),
+		pq.Array(arg.AnyTags),
+		arg.MimePrefix,
+		arg.MinSize,
+		arg.MaxSize,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+		arg.Visibility,
+	)
 	var count int64
 	err := row.Scan(&count)
 	return count, err
 }
 
 const createFile = `-- name: CreateFile :one
-insert into files (user_id, filename, storage_key, mime_type, size_bytes, checksum)
-    values($1, $2, $3, $4, $5, $6)
-returning file_id, filename, mime_type, size_bytes, created_at, visibility, checksum, version
+with new_file as (
+    insert into files (user_id, filename, storage_key, mime_type, size_bytes, checksum, tags, scan_status, strip_location_pending)
+        values($1, $2, $3, $4, $5, $6, $7, $8, $9)
+    returning file_id, user_id, filename, storage_key, mime_type, size_bytes, created_at, visibility, checksum, tags, version, current_version, scan_status, strip_location_pending
+), first_version as (
+    insert into file_versions (file_id, version_number, storage_key, mime_type, size_bytes, checksum, created_by)
+        select file_id, current_version, storage_key, mime_type, size_bytes, checksum, user_id
+        from new_file
+)
+select file_id, filename, mime_type, size_bytes, created_at, visibility, checksum, tags, version, scan_status, strip_location_pending
+from new_file
 `
 
 type CreateFileParams struct {
-	UserID     uuid.UUID `json:"user_id"`
-	Filename   string    `json:"filename"`
-	StorageKey string    `json:"storage_key"`
-	MimeType   string    `json:"mime_type"`
-	SizeBytes  int64     `json:"size_bytes"`
-	Checksum   string    `json:"checksum"`
+	UserID               uuid.UUID      `json:"user_id"`
+	Filename             string         `json:"filename"`
+	StorageKey           string         `json:"storage_key"`
+	MimeType             string         `json:"mime_type"`
+	SizeBytes            int64          `json:"size_bytes"`
+	Checksum             string         `json:"checksum"`
+	Tags                 []string       `json:"tags"`
+	ScanStatus           FileScanStatus `json:"scan_status"`
+	StripLocationPending bool           `json:"strip_location_pending"`
 }
 
 type CreateFileRow struct {
-	FileID     uuid.UUID      `json:"file_id"`
-	Filename   string         `json:"filename"`
-	MimeType   string         `json:"mime_type"`
-	SizeBytes  int64          `json:"size_bytes"`
-	CreatedAt  time.Time      `json:"created_at"`
-	Visibility FileVisibility `json:"visibility"`
-	Checksum   string         `json:"checksum"`
-	Version    int32          `json:"version"`
+	FileID               uuid.UUID      `json:"file_id"`
+	Filename             string         `json:"filename"`
+	MimeType             string         `json:"mime_type"`
+	SizeBytes            int64          `json:"size_bytes"`
+	CreatedAt            time.Time      `json:"created_at"`
+	Visibility           FileVisibility `json:"visibility"`
+	Checksum             string         `json:"checksum"`
+	Tags                 []string       `json:"tags"`
+	Version              int32          `json:"version"`
+	ScanStatus           FileScanStatus `json:"scan_status"`
+	StripLocationPending bool           `json:"strip_location_pending"`
 }
 
+// Creates a file together with the first entry of its version history.
 func (q *Queries) CreateFile(ctx context.Context, arg CreateFileParams) (CreateFileRow, error) {
 	row := q.queryRow(ctx, q.createFileStmt, createFile,
 		arg.UserID,
@@ -75,6 +160,9 @@ func (q *Queries) CreateFile(ctx context.Context, arg CreateFileParams) (CreateF
 		arg.MimeType,
 		arg.SizeBytes,
 		arg.Checksum,
+		pq.Array(arg.Tags),
+		arg.ScanStatus,
+		arg.StripLocationPending,
 	)
 	var i CreateFileRow
 	err := row.Scan(
@@ -85,32 +173,50 @@ func (q *Queries) CreateFile(ctx context.Context, arg CreateFileParams) (CreateF
 		&i.CreatedAt,
 		&i.Visibility,
 		&i.Checksum,
+		pq.Array(&i.Tags),
 		&i.Version,
+		&i.ScanStatus,
+		&i.StripLocationPending,
 	)
 	return i, err
 }
 
-const deleteFile = `-- name: DeleteFile :exec
-update files
+const deleteFile = `-- name: DeleteFile :one
+update files f
     set
         is_deleted = true,
-        deleted_at = $1,
+        deleted_at = now(),
+        purge_after = now() + make_interval(days => coalesce(u.trash_retention_days, $1::int)),
         updated_at = now(),
-        version = version + 1
-where file_id = $2
-    and version = $3
+        version = f.version + 1
+from users u
+where u.user_id = f.user_id
+    and f.file_id = $2
+    and f.user_id = $3
+    and f.version = $4
+    and f.is_deleted = false
+returning f.purge_after
 `
 
 type DeleteFileParams struct {
-	DeletedAt sql.NullTime `json:"deleted_at"`
-	FileID    uuid.UUID    `json:"file_id"`
-	Version   int32        `json:"version"`
+	DefaultRetentionDays int32     `json:"default_retention_days"`
+	FileID               uuid.UUID `json:"file_id"`
+	UserID               uuid.UUID `json:"user_id"`
+	Version              int32     `json:"version"`
 }
 
-// Sets file is deleted tag to true and adds a the specified date for a background task to delete it.
-func (q *Queries) DeleteFile(ctx context.Context, arg DeleteFileParams) error {
-	_, err := q.exec(ctx, q.deleteFileStmt, deleteFile, arg.DeletedAt, arg.FileID, arg.Version)
-	return err
+// Moves a file to the trash, it is purged by a background task once purge_after has passed.
+// The retention period is the owner's trash_retention_days, or the deployment default.
+func (q *Queries) DeleteFile(ctx context.Context, arg DeleteFileParams) (sql.NullTime, error) {
+	row := q.queryRow(ctx, q.deleteFileStmt, deleteFile,
+		arg.DefaultRetentionDays,
+		arg.FileID,
+		arg.UserID,
+		arg.Version,
+	)
+	var purge_after sql.NullTime
+	err := row.Scan(&purge_after)
+	return purge_after, err
 }
 
 const getFileByChecksum = `-- name: GetFileByChecksum :one
@@ -154,24 +260,36 @@ select
     thumbnail_key,
     checksum,
     tags,
-    version
+    version,
+    current_version,
+    folder_id,
+    updated_at,
+    scan_status,
+    strip_location_pending,
+    coalesce(image_metadata, 'null')::jsonb as image_metadata
 from files
     where is_deleted = false
         and file_id = $1
 `
 
 type GetFileInfoRow struct {
-	FileID       uuid.UUID      `json:"file_id"`
-	OwnerID      uuid.UUID      `json:"owner_id"`
-	Filename     string         `json:"filename"`
-	MimeType     string         `json:"mime_type"`
-	StorageKey   string         `json:"storage_key"`
-	SizeBytes    int64          `json:"size_bytes"`
-	Visibility   FileVisibility `json:"visibility"`
-	ThumbnailKey sql.NullString `json:"thumbnail_key"`
-	Checksum     string         `json:"checksum"`
-	Tags         []string       `json:"tags"`
-	Version      int32          `json:"version"`
+	FileID               uuid.UUID       `json:"file_id"`
+	OwnerID              uuid.UUID       `json:"owner_id"`
+	Filename             string          `json:"filename"`
+	MimeType             string          `json:"mime_type"`
+	StorageKey           string          `json:"storage_key"`
+	SizeBytes            int64           `json:"size_bytes"`
+	Visibility           FileVisibility  `json:"visibility"`
+	ThumbnailKey         sql.NullString  `json:"thumbnail_key"`
+	Checksum             string          `json:"checksum"`
+	Tags                 []string        `json:"tags"`
+	Version              int32           `json:"version"`
+	CurrentVersion       int32           `json:"current_version"`
+	FolderID             uuid.NullUUID   `json:"folder_id"`
+	UpdatedAt            time.Time       `json:"updated_at"`
+	ScanStatus           FileScanStatus  `json:"scan_status"`
+	StripLocationPending bool            `json:"strip_location_pending"`
+	ImageMetadata        json.RawMessage `json:"image_metadata"`
 }
 
 // Retrieve metadata of a file from the database.
@@ -190,6 +308,12 @@ func (q *Queries) GetFileInfo(ctx context.Context, fileID uuid.UUID) (GetFileInf
 		&i.Checksum,
 		pq.Array(&i.Tags),
 		&i.Version,
+		&i.CurrentVersion,
+		&i.FolderID,
+		&i.UpdatedAt,
+		&i.ScanStatus,
+		&i.StripLocationPending,
+		&i.ImageMetadata,
 	)
 	return i, err
 }
@@ -225,6 +349,48 @@ func (q *Queries) GetFileOwner(ctx context.Context, fileID uuid.UUID) (GetFileOw
 	return i, err
 }
 
+const getTrashedFilesForPurge = `-- name: GetTrashedFilesForPurge :many
+select file_id, thumbnail_key
+from files
+    where user_id = $1
+        and is_deleted = true
+        and ($2::uuid is null or file_id = $2)
+`
+
+type GetTrashedFilesForPurgeParams struct {
+	UserID uuid.UUID     `json:"user_id"`
+	FileID uuid.NullUUID `json:"file_id"`
+}
+
+type GetTrashedFilesForPurgeRow struct {
+	FileID       uuid.UUID      `json:"file_id"`
+	ThumbnailKey sql.NullString `json:"thumbnail_key"`
+}
+
+// Fetch a user's trashed files, or a single trashed file when file_id is set.
+func (q *Queries) GetTrashedFilesForPurge(ctx context.Context, arg GetTrashedFilesForPurgeParams) ([]GetTrashedFilesForPurgeRow, error) {
+	rows, err := q.query(ctx, q.getTrashedFilesForPurgeStmt, getTrashedFilesForPurge, arg.UserID, arg.FileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetTrashedFilesForPurgeRow{}
+	for rows.Next() {
+		var i GetTrashedFilesForPurgeRow
+		if err := rows.Scan(&i.FileID, &i.ThumbnailKey); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listPublicFiles = `-- name: ListPublicFiles :many
 select
     u.user_id as owner_id,
@@ -237,19 +403,52 @@ select
     f.thumbnail_key,
     f.checksum,
     f.tags,
+    f.created_at,
+    f.updated_at,
     f.version
 from files f
     join users u
         on f.user_id = u.user_id
     where f.visibility = 'public'
+        and f.scan_status in ('clean', 'skipped')
         and f.is_deleted = false
-    order by f.created_at desc
-    limit $1 offset $2
+        and ($1::text[] is null or f.tags @> $1)
+        and ($2::text[] is null or f.tags && $2)
+        and ($3::text is null or f.mime_type like $3 || '%')
+        and ($4::bigint is null or f.size_bytes >= $4)
+        and ($5::bigint is null or f.size_bytes <= $5)
+        and ($6::timestamptz is null or f.created_at >= $6)
+        and ($7::timestamptz is null or f.created_at < $7)
+        and ($8::timestamptz is null or $9::uuid is null
+            or ($10::text = 'created_at' and (f.created_at, f.file_id) > ($8, $9))
+            or ($10::text = '-created_at' and (f.created_at, f.file_id) < ($8, $9)))
+    order by
+        case when $10::text = 'name' then lower(f.filename) end,
+        case when $10::text = '-name' then lower(f.filename) end desc,
+        case when $10::text = 'size' then f.size_bytes end,
+        case when $10::text = '-size' then f.size_bytes end desc,
+        case when $10::text = 'created_at' then f.created_at end,
+        case when $10::text = '-created_at' then f.created_at end desc,
+        case when $10::text = 'updated_at' then f.updated_at end,
+        case when $10::text = '-updated_at' then f.updated_at end desc,
+        case when $10::text = 'created_at' then f.file_id end,
+        f.file_id desc
+    limit $11 offset $12
 `
 
 type ListPublicFilesParams struct {
-	Limit  int32 `json:"limit"`
-	Offset int32 `json:"offset"`
+	AllTags       []string       `json:"all_tags"`
+	AnyTags       []string       `json:"any_tags"`
+	MimePrefix    sql.NullString `json:"mime_prefix"`
+	MinSize       sql.NullInt64  `json:"min_size"`
+	MaxSize       sql.NullInt64  `json:"max_size"`
+	CreatedAfter  sql.NullTime   `json:"created_after"`
+	CreatedBefore sql.NullTime   `json:"created_before"`
+	CursorTime    sql.NullTime   `json:"cursor_time"`
+	CursorID      uuid.NullUUID  `json:"cursor_id"`
+	Sort          string         `json:"sort"`
+	PageLimit     int32          `json:"page_limit"`
+	PageOffset    int32          `json:"page_offset"`
 }
 
 type ListPublicFilesRow struct {
@@ -263,11 +462,31 @@ type ListPublicFilesRow struct {
 	ThumbnailKey sql.NullString `json:"thumbnail_key"`
 	Checksum     string         `json:"checksum"`
 	Tags         []string       `json:"tags"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
 	Version      int32          `json:"version"`
 }
 
+// Only files which passed the malware scan, or were stored without one, are listed.
+// Files can be filtered by tags, MIME type prefix, size and creation date; a null filter is not applied.
+// all_tags requires every tag and any_tags at least one of them.
+// sort is one of the columns name, size, created_at or updated_at, prefixed with - for descending order.
+// When sorting by created_at, cursor_time and cursor_id select the files after that position in the sort order.
 func (q *Queries) ListPublicFiles(ctx context.Context, arg ListPublicFilesParams) ([]ListPublicFilesRow, error) {
-	rows, err := q.query(ctx, q.listPublicFilesStmt, listPublicFiles, arg.Limit, arg.Offset)
+	rows, err := q.query(ctx, q.listPublicFilesStmt, listPublicFiles,
+		pq.Array(arg.AllTags),
+		pq.Array(arg.AnyTags),
+		arg.MimePrefix,
+		arg.MinSize,
+		arg.MaxSize,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.Sort,
+		arg.PageLimit,
+		arg.PageOffset,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -286,6 +505,83 @@ func (q *Queries) ListPublicFiles(ctx context.Context, arg ListPublicFilesParams
 			&i.ThumbnailKey,
 			&i.Checksum,
 			pq.Array(&i.Tags),
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Version,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTrashedFiles = `-- name: ListTrashedFiles :many
+select
+    file_id, filename, mime_type, size_bytes, deleted_at, purge_after, version
+from files
+    where user_id = $1
+        and is_deleted = true
+        and ($2::timestamptz is null or $3::uuid is null
+            or ($4::bool and (deleted_at, file_id) > ($2, $3))
+            or (not $4::bool and (deleted_at, file_id) < ($2, $3)))
+    order by
+        case when $4::bool then deleted_at end,
+        case when $4::bool then file_id end,
+        deleted_at desc,
+        file_id desc
+    limit $5 offset $6
+`
+
+type ListTrashedFilesParams struct {
+	UserID     uuid.UUID     `json:"user_id"`
+	CursorTime sql.NullTime  `json:"cursor_time"`
+	CursorID   uuid.NullUUID `json:"cursor_id"`
+	Ascending  bool          `json:"ascending"`
+	PageLimit  int32         `json:"page_limit"`
+	PageOffset int32         `json:"page_offset"`
+}
+
+type ListTrashedFilesRow struct {
+	FileID     uuid.UUID    `json:"file_id"`
+	Filename   string       `json:"filename"`
+	MimeType   string       `json:"mime_type"`
+	SizeBytes  int64        `json:"size_bytes"`
+	DeletedAt  sql.NullTime `json:"deleted_at"`
+	PurgeAfter sql.NullTime `json:"purge_after"`
+	Version    int32        `json:"version"`
+}
+
+// cursor_time and cursor_id select the files deleted before that position, or after it when ascending is set.
+func (q *Queries) ListTrashedFiles(ctx context.Context, arg ListTrashedFilesParams) ([]ListTrashedFilesRow, error) {
+	rows, err := q.query(ctx, q.listTrashedFilesStmt, listTrashedFiles,
+		arg.UserID,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.Ascending,
+		arg.PageLimit,
+		arg.PageOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListTrashedFilesRow{}
+	for rows.Next() {
+		var i ListTrashedFilesRow
+		if err := rows.Scan(
+			&i.FileID,
+			&i.Filename,
+			&i.MimeType,
+			&i.SizeBytes,
+			&i.DeletedAt,
+			&i.PurgeAfter,
 			&i.Version,
 		); err != nil {
 			return nil, err
@@ -303,18 +599,50 @@ func (q *Queries) ListPublicFiles(ctx context.Context, arg ListPublicFilesParams
 
 const listUserFiles = `-- name: ListUserFiles :many
 select 
-    f.file_id, f.filename, f.mime_type, f.size_bytes, f.visibility, f.created_at, f.tags
+    f.file_id, f.filename, f.mime_type, f.size_bytes, f.visibility, f.created_at, f.updated_at, f.tags, f.scan_status
 from files f
     where f.user_id = $1
         and f.is_deleted = false
-    order by f.created_at desc
-    limit $2 offset $3
+        and ($2::text[] is null or f.tags @> $2)
+        and ($3::text[] is null or f.tags && $3)
+        and ($4::text is null or f.mime_type like $4 || '%')
+        and ($5::bigint is null or f.size_bytes >= $5)
+        and ($6::bigint is null or f.size_bytes <= $6)
+        and ($7::timestamptz is null or f.created_at >= $7)
+        and ($8::timestamptz is null or f.created_at < $8)
+        and ($9::file_visibility is null or f.visibility = $9)
+        and ($10::timestamptz is null or $11::uuid is null
+            or ($12::text = 'created_at' and (f.created_at, f.file_id) > ($10, $11))
+            or ($12::text = '-created_at' and (f.created_at, f.file_id) < ($10, $11)))
+    order by
+        case when $12::text = 'name' then lower(f.filename) end,
+        case when $12::text = '-name' then lower(f.filename) end desc,
+        case when $12::text = 'size' then f.size_bytes end,
+        case when $12::text = '-size' then f.size_bytes end desc,
+        case when $12::text = 'created_at' then f.created_at end,
+        case when $12::text = '-created_at' then f.created_at end desc,
+        case when $12::text = 'updated_at' then f.updated_at end,
+        case when $12::text = '-updated_at' then f.updated_at end desc,
+        case when $12::text = 'created_at' then f.file_id end,
+        f.file_id desc
+    limit $13 offset $14
 `
 
 type ListUserFilesParams struct {
-	UserID uuid.UUID `json:"user_id"`
-	Limit  int32     `json:"limit"`
-	Offset int32     `json:"offset"`
+	UserID        uuid.UUID          `json:"user_id"`
+	AllTags       []string           `json:"all_tags"`
+	AnyTags       []string           `json:"any_tags"`
+	MimePrefix    sql.NullString     `json:"mime_prefix"`
+	MinSize       sql.NullInt64      `json:"min_size"`
+	MaxSize       sql.NullInt64      `json:"max_size"`
+	CreatedAfter  sql.NullTime       `json:"created_after"`
+	CreatedBefore sql.NullTime       `json:"created_before"`
+	Visibility    NullFileVisibility `json:"visibility"`
+	CursorTime    sql.NullTime       `json:"cursor_time"`
+	CursorID      uuid.NullUUID      `json:"cursor_id"`
+	Sort          string             `json:"sort"`
+	PageLimit     int32              `json:"page_limit"`
+	PageOffset    int32              `json:"page_offset"`
 }
 
 type ListUserFilesRow struct {
@@ -324,11 +652,32 @@ type ListUserFilesRow struct {
 	SizeBytes  int64          `json:"size_bytes"`
 	Visibility FileVisibility `json:"visibility"`
 	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
 	Tags       []string       `json:"tags"`
+	ScanStatus FileScanStatus `json:"scan_status"`
 }
 
+// Files can be filtered by tags, MIME type prefix, size and creation date; a null filter is not applied.
+// all_tags requires every tag and any_tags at least one of them.
+// sort is one of the columns name, size, created_at or updated_at, prefixed with - for descending order.
+// When sorting by created_at, cursor_time and cursor_id select the files after that position in the sort order.
 func (q *Queries) ListUserFiles(ctx context.Context, arg ListUserFilesParams) ([]ListUserFilesRow, error) {
-	rows, err := q.query(ctx, q.listUserFilesStmt, listUserFiles, arg.UserID, arg.Limit, arg.Offset)
+	rows, err := q.query(ctx, q.listUserFilesStmt, listUserFiles,
+		arg.UserID,
+		pq.Array(arg.AllTags),
+		pq.Array(arg.AnyTags),
+		arg.MimePrefix,
+		arg.MinSize,
+		arg.MaxSize,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+		arg.Visibility,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.Sort,
+		arg.PageLimit,
+		arg.PageOffset,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -343,7 +692,9 @@ func (q *Queries) ListUserFiles(ctx context.Context, arg ListUserFilesParams) ([
 			&i.SizeBytes,
 			&i.Visibility,
 			&i.CreatedAt,
+			&i.UpdatedAt,
 			pq.Array(&i.Tags),
+			&i.ScanStatus,
 		); err != nil {
 			return nil, err
 		}
@@ -358,6 +709,152 @@ func (q *Queries) ListUserFiles(ctx context.Context, arg ListUserFilesParams) ([
 	return items, nil
 }
 
+const listUserTags = `-- name: ListUserTags :many
+select
+    tag::text as tag,
+    count(*) as file_count
+from files f
+    cross join unnest(f.tags) as tag
+    where f.user_id = $1
+        and f.is_deleted = false
+    group by tag
+    order by file_count desc, tag
+`
+
+type ListUserTagsRow struct {
+	Tag       string `json:"tag"`
+	FileCount int64  `json:"file_count"`
+}
+
+// Lists the distinct tags on a user's files together with the number of files carrying each tag.
+func (q *Queries) ListUserTags(ctx context.Context, userID uuid.UUID) ([]ListUserTagsRow, error) {
+	rows, err := q.query(ctx, q.listUserTagsStmt, listUserTags, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListUserTagsRow{}
+	for rows.Next() {
+		var i ListUserTagsRow
+		if err := rows.Scan(&i.Tag, &i.FileCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const moveFile = `-- name: MoveFile :one
+update files
+    set
+        folder_id = $1,
+        updated_at = now(),
+        version = version + 1
+where file_id = $2
+    and user_id = $3
+    and version = $4
+    and is_deleted = false
+returning folder_id, version
+`
+
+type MoveFileParams struct {
+	FolderID uuid.NullUUID `json:"folder_id"`
+	FileID   uuid.UUID     `json:"file_id"`
+	UserID   uuid.UUID     `json:"user_id"`
+	Version  int32         `json:"version"`
+}
+
+type MoveFileRow struct {
+	FolderID uuid.NullUUID `json:"folder_id"`
+	Version  int32         `json:"version"`
+}
+
+// Moves a file into a folder, or to the root folder when folder_id is null.
+func (q *Queries) MoveFile(ctx context.Context, arg MoveFileParams) (MoveFileRow, error) {
+	row := q.queryRow(ctx, q.moveFileStmt, moveFile,
+		arg.FolderID,
+		arg.FileID,
+		arg.UserID,
+		arg.Version,
+	)
+	var i MoveFileRow
+	err := row.Scan(&i.FolderID, &i.Version)
+	return i, err
+}
+
+const restoreFile = `-- name: RestoreFile :one
+update files
+    set
+        is_deleted = false,
+        deleted_at = null,
+        purge_after = null,
+        folder_id = (select folder_id from folders where folder_id = files.folder_id and is_deleted = false),
+        updated_at = now(),
+        version = version + 1
+where file_id = $1
+    and user_id = $2
+    and version = $3
+    and is_deleted = true
+returning version
+`
+
+type RestoreFileParams struct {
+	FileID  uuid.UUID `json:"file_id"`
+	UserID  uuid.UUID `json:"user_id"`
+	Version int32     `json:"version"`
+}
+
+// Takes a file out of the trash before it is purged, files whose folder was deleted are restored to the root folder.
+func (q *Queries) RestoreFile(ctx context.Context, arg RestoreFileParams) (int32, error) {
+	row := q.queryRow(ctx, q.restoreFileStmt, restoreFile, arg.FileID, arg.UserID, arg.Version)
+	var version int32
+	err := row.Scan(&version)
+	return version, err
+}
+
+const setFileTags = `-- name: SetFileTags :one
+update files
+    set
+        tags = $1,
+        updated_at = now(),
+        version = version + 1
+where file_id = $2
+    and user_id = $3
+    and version = $4
+    and is_deleted = false
+returning tags, version
+`
+
+type SetFileTagsParams struct {
+	Tags    []string  `json:"tags"`
+	FileID  uuid.UUID `json:"file_id"`
+	UserID  uuid.UUID `json:"user_id"`
+	Version int32     `json:"version"`
+}
+
+type SetFileTagsRow struct {
+	Tags    []string `json:"tags"`
+	Version int32    `json:"version"`
+}
+
+func (q *Queries) SetFileTags(ctx context.Context, arg SetFileTagsParams) (SetFileTagsRow, error) {
+	row := q.queryRow(ctx, q.setFileTagsStmt, setFileTags,
+		pq.Array(arg.Tags),
+		arg.FileID,
+		arg.UserID,
+		arg.Version,
+	)
+	var i SetFileTagsRow
+	err := row.Scan(pq.Array(&i.Tags), &i.Version)
+	return i, err
+}
+
 const setFileVisibility = `-- name: SetFileVisibility :one
 update files
     set
@@ -365,21 +862,34 @@ update files
         updated_at = now(),
         version = version + 1
 where file_id = $2
-    and version = $3
-    returning visibility
+    and user_id = $3
+    and version = $4
+    and is_deleted = false
+returning visibility, version
 `
 
 type SetFileVisibilityParams struct {
 	Visibility FileVisibility `json:"visibility"`
 	FileID     uuid.UUID      `json:"file_id"`
+	UserID     uuid.UUID      `json:"user_id"`
 	Version    int32          `json:"version"`
 }
 
-func (q *Queries) SetFileVisibility(ctx context.Context, arg SetFileVisibilityParams) (FileVisibility, error) {
-	row := q.queryRow(ctx, q.setFileVisibilityStmt, setFileVisibility, arg.Visibility, arg.FileID, arg.Version)
-	var visibility FileVisibility
-	err := row.Scan(&visibility)
-	return visibility, err
+type SetFileVisibilityRow struct {
+	Visibility FileVisibility `json:"visibility"`
+	Version    int32          `json:"version"`
+}
+
+func (q *Queries) SetFileVisibility(ctx context.Context, arg SetFileVisibilityParams) (SetFileVisibilityRow, error) {
+	row := q.queryRow(ctx, q.setFileVisibilityStmt, setFileVisibility,
+		arg.Visibility,
+		arg.FileID,
+		arg.UserID,
+		arg.Version,
+	)
+	var i SetFileVisibilityRow
+	err := row.Scan(&i.Visibility, &i.Version)
+	return i, err
 }
 
 const updateFileName = `-- name: UpdateFileName :one
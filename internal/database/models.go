@@ -57,6 +57,50 @@ func (ns NullApiScope) Value() (driver.Value, error) {
 	return string(ns.ApiScope), nil
 }
 
+type FileScanStatus string
+
+const (
+	FileScanStatusPending  FileScanStatus = "pending"
+	FileScanStatusClean    FileScanStatus = "clean"
+	FileScanStatusInfected FileScanStatus = "infected"
+	FileScanStatusSkipped  FileScanStatus = "skipped"
+)
+
+func (e *FileScanStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = FileScanStatus(s)
+	case string:
+		*e = FileScanStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for FileScanStatus: %T", src)
+	}
+	return nil
+}
+
+type NullFileScanStatus struct {
+	FileScanStatus FileScanStatus `json:"file_scan_status"`
+	Valid          bool           `json:"valid"` // Valid is true if FileScanStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullFileScanStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.FileScanStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.FileScanStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullFileScanStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.FileScanStatus), nil
+}
+
 type FileVisibility string
 
 const (
@@ -193,58 +237,175 @@ type ActionToken struct {
 }
 
 type ApiKey struct {
-	ApiKeyID   uuid.UUID    `json:"api_key_id"`
-	UserID     uuid.UUID    `json:"user_id"`
-	Name       string       `json:"name"`
-	KeyHash    string       `json:"key_hash"`
-	Prefix     string       `json:"prefix"`
-	Scope      []ApiScope   `json:"scope"`
-	IsRevoked  bool         `json:"is_revoked"`
-	RevokedAt  sql.NullTime `json:"revoked_at"`
-	CreatedAt  time.Time    `json:"created_at"`
-	UpdatedAt  time.Time    `json:"updated_at"`
-	ExpiresAt  time.Time    `json:"expires_at"`
-	LastUsedAt sql.NullTime `json:"last_used_at"`
-	LastUsedIp pqtype.Inet  `json:"last_used_ip"`
+	ApiKeyID             uuid.UUID      `json:"api_key_id"`
+	UserID               uuid.UUID      `json:"user_id"`
+	Name                 string         `json:"name"`
+	KeyHash              string         `json:"key_hash"`
+	Prefix               string         `json:"prefix"`
+	Scope                []ApiScope     `json:"scope"`
+	IsRevoked            bool           `json:"is_revoked"`
+	RevokedAt            sql.NullTime   `json:"revoked_at"`
+	CreatedAt            time.Time      `json:"created_at"`
+	UpdatedAt            time.Time      `json:"updated_at"`
+	ExpiresAt            time.Time      `json:"expires_at"`
+	LastUsedAt           sql.NullTime   `json:"last_used_at"`
+	LastUsedIp           pqtype.Inet    `json:"last_used_ip"`
+	PreviousKeyHash      sql.NullString `json:"previous_key_hash"`
+	PreviousKeyExpiresAt sql.NullTime   `json:"previous_key_expires_at"`
+}
+
+type Blob struct {
+	StorageKey string    `json:"storage_key"`
+	UserID     uuid.UUID `json:"user_id"`
+	Checksum   string    `json:"checksum"`
+	SizeBytes  int64     `json:"size_bytes"`
+	RefCount   int32     `json:"ref_count"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type DataKey struct {
+	KeyID       uuid.UUID     `json:"key_id"`
+	UserID      uuid.NullUUID `json:"user_id"`
+	IsService   bool          `json:"is_service"`
+	WrappedKey  []byte        `json:"wrapped_key"`
+	MasterKeyID string        `json:"master_key_id"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
 }
 
 type File struct {
-	FileID       uuid.UUID      `json:"file_id"`
-	UserID       uuid.UUID      `json:"user_id"`
-	Filename     string         `json:"filename"`
-	StorageKey   string         `json:"storage_key"`
-	MimeType     string         `json:"mime_type"`
-	SizeBytes    int64          `json:"size_bytes"`
-	Visibility   FileVisibility `json:"visibility"`
-	ThumbnailKey sql.NullString `json:"thumbnail_key"`
-	Checksum     string         `json:"checksum"`
-	Tags         []string       `json:"tags"`
-	IsDeleted    bool           `json:"is_deleted"`
-	DeletedAt    sql.NullTime   `json:"deleted_at"`
-	CreatedAt    time.Time      `json:"created_at"`
-	UpdatedAt    time.Time      `json:"updated_at"`
-	Version      int32          `json:"version"`
+	FileID               uuid.UUID             `json:"file_id"`
+	UserID               uuid.UUID             `json:"user_id"`
+	Filename             string                `json:"filename"`
+	StorageKey           string                `json:"storage_key"`
+	MimeType             string                `json:"mime_type"`
+	SizeBytes            int64                 `json:"size_bytes"`
+	Visibility           FileVisibility        `json:"visibility"`
+	ThumbnailKey         sql.NullString        `json:"thumbnail_key"`
+	Checksum             string                `json:"checksum"`
+	Tags                 []string              `json:"tags"`
+	IsDeleted            bool                  `json:"is_deleted"`
+	DeletedAt            sql.NullTime          `json:"deleted_at"`
+	CreatedAt            time.Time             `json:"created_at"`
+	UpdatedAt            time.Time             `json:"updated_at"`
+	Version              int32                 `json:"version"`
+	CurrentVersion       int32                 `json:"current_version"`
+	PurgeAfter           sql.NullTime          `json:"purge_after"`
+	FolderID             uuid.NullUUID         `json:"folder_id"`
+	ExtractedText        sql.NullString        `json:"extracted_text"`
+	SearchVector         interface{}           `json:"search_vector"`
+	ScanStatus           FileScanStatus        `json:"scan_status"`
+	ScannedAt            sql.NullTime          `json:"scanned_at"`
+	ImageMetadata        pqtype.NullRawMessage `json:"image_metadata"`
+	StripLocationPending bool                  `json:"strip_location_pending"`
+}
+
+type FileVersion struct {
+	FileVersionID uuid.UUID     `json:"file_version_id"`
+	FileID        uuid.UUID     `json:"file_id"`
+	VersionNumber int32         `json:"version_number"`
+	StorageKey    string        `json:"storage_key"`
+	MimeType      string        `json:"mime_type"`
+	SizeBytes     int64         `json:"size_bytes"`
+	Checksum      string        `json:"checksum"`
+	CreatedBy     uuid.NullUUID `json:"created_by"`
+	CreatedAt     time.Time     `json:"created_at"`
+}
+
+type Folder struct {
+	FolderID   uuid.UUID     `json:"folder_id"`
+	UserID     uuid.UUID     `json:"user_id"`
+	ParentID   uuid.NullUUID `json:"parent_id"`
+	Name       string        `json:"name"`
+	IsDeleted  bool          `json:"is_deleted"`
+	DeletedAt  sql.NullTime  `json:"deleted_at"`
+	PurgeAfter sql.NullTime  `json:"purge_after"`
+	CreatedAt  time.Time     `json:"created_at"`
+	UpdatedAt  time.Time     `json:"updated_at"`
+	Version    int32         `json:"version"`
+}
+
+type ImageRendition struct {
+	RenditionKey string    `json:"rendition_key"`
+	StorageKey   string    `json:"storage_key"`
+	SizeBytes    int64     `json:"size_bytes"`
+	CreatedAt    time.Time `json:"created_at"`
 }
 
 type RefreshToken struct {
-	RefreshTokenID uuid.UUID `json:"refresh_token_id"`
-	UserID         uuid.UUID `json:"user_id"`
-	Token          string    `json:"token"`
-	ExpiresAt      time.Time `json:"expires_at"`
-	CreatedAt      time.Time `json:"created_at"`
-	Revoked        bool      `json:"revoked"`
+	RefreshTokenID uuid.UUID    `json:"refresh_token_id"`
+	UserID         uuid.UUID    `json:"user_id"`
+	ExpiresAt      time.Time    `json:"expires_at"`
+	CreatedAt      time.Time    `json:"created_at"`
+	Revoked        bool         `json:"revoked"`
+	TokenHash      []byte       `json:"token_hash"`
+	FamilyID       uuid.UUID    `json:"family_id"`
+	RotatedAt      sql.NullTime `json:"rotated_at"`
+}
+
+type RoleQuota struct {
+	Role      UserRole      `json:"role"`
+	MaxBytes  sql.NullInt64 `json:"max_bytes"`
+	MaxFiles  sql.NullInt64 `json:"max_files"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+type Session struct {
+	SessionID  uuid.UUID   `json:"session_id"`
+	UserID     uuid.UUID   `json:"user_id"`
+	UserAgent  string      `json:"user_agent"`
+	IpAddress  pqtype.Inet `json:"ip_address"`
+	CreatedAt  time.Time   `json:"created_at"`
+	LastUsedAt time.Time   `json:"last_used_at"`
+}
+
+type ShareLink struct {
+	ShareLinkID    uuid.UUID      `json:"share_link_id"`
+	FileID         uuid.UUID      `json:"file_id"`
+	UserID         uuid.UUID      `json:"user_id"`
+	TokenHash      []byte         `json:"token_hash"`
+	PasswordHash   sql.NullString `json:"password_hash"`
+	ExpiresAt      sql.NullTime   `json:"expires_at"`
+	MaxDownloads   sql.NullInt32  `json:"max_downloads"`
+	DownloadCount  int32          `json:"download_count"`
+	IsRevoked      bool           `json:"is_revoked"`
+	RevokedAt      sql.NullTime   `json:"revoked_at"`
+	LastAccessedAt sql.NullTime   `json:"last_accessed_at"`
+	CreatedAt      time.Time      `json:"created_at"`
+}
+
+type Upload struct {
+	UploadID          uuid.UUID     `json:"upload_id"`
+	UserID            uuid.UUID     `json:"user_id"`
+	FileID            uuid.NullUUID `json:"file_id"`
+	Filename          string        `json:"filename"`
+	MimeType          string        `json:"mime_type"`
+	Metadata          string        `json:"metadata"`
+	UploadLength      int64         `json:"upload_length"`
+	UploadOffset      int64         `json:"upload_offset"`
+	HashState         []byte        `json:"hash_state"`
+	WriteToken        uuid.NullUUID `json:"write_token"`
+	WriteClaimedUntil sql.NullTime  `json:"write_claimed_until"`
+	ExpiresAt         time.Time     `json:"expires_at"`
+	CreatedAt         time.Time     `json:"created_at"`
+	UpdatedAt         time.Time     `json:"updated_at"`
 }
 
 type User struct {
-	UserID       uuid.UUID    `json:"user_id"`
-	LastName     string       `json:"last_name"`
-	FirstName    string       `json:"first_name"`
-	Email        string       `json:"email"`
-	IsVerified   bool         `json:"is_verified"`
-	Role         UserRole     `json:"role"`
-	PasswordHash string       `json:"-"`
-	CreatedAt    time.Time    `json:"created_at"`
-	UpdatedAt    time.Time    `json:"updated_at"`
-	LastLogin    sql.NullTime `json:"last_login"`
-	Version      int32        `json:"version"`
+	UserID             uuid.UUID     `json:"user_id"`
+	LastName           string        `json:"last_name"`
+	FirstName          string        `json:"first_name"`
+	Email              string        `json:"email"`
+	IsVerified         bool          `json:"is_verified"`
+	Role               UserRole      `json:"role"`
+	PasswordHash       string        `json:"-"`
+	CreatedAt          time.Time     `json:"created_at"`
+	UpdatedAt          time.Time     `json:"updated_at"`
+	LastLogin          sql.NullTime  `json:"last_login"`
+	Version            int32         `json:"version"`
+	TrashRetentionDays sql.NullInt32 `json:"trash_retention_days"`
+	QuotaMaxBytes      sql.NullInt64 `json:"quota_max_bytes"`
+	QuotaMaxFiles      sql.NullInt64 `json:"quota_max_files"`
+	StripLocation      bool          `json:"strip_location"`
 }
@@ -7,20 +7,22 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 const createRefreshToken = `-- name: CreateRefreshToken :one
-insert into refresh_tokens(user_id, token, expires_at, created_at, revoked)
-    values ($1, $2, $3, $4, $5)
-returning refresh_token_id, user_id, token, expires_at, created_at, revoked
+insert into refresh_tokens(user_id, family_id, token_hash, expires_at, created_at, revoked)
+    values ($1, $2, $3, $4, $5, $6)
+returning refresh_token_id, user_id, expires_at, created_at, revoked, token_hash, family_id, rotated_at
 `
 
 type CreateRefreshTokenParams struct {
 	UserID    uuid.UUID `json:"user_id"`
-	Token     string    `json:"token"`
+	FamilyID  uuid.UUID `json:"family_id"`
+	TokenHash []byte    `json:"token_hash"`
 	ExpiresAt time.Time `json:"expires_at"`
 	CreatedAt time.Time `json:"created_at"`
 	Revoked   bool      `json:"revoked"`
@@ -29,7 +31,8 @@ type CreateRefreshTokenParams struct {
 func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (RefreshToken, error) {
 	row := q.queryRow(ctx, q.createRefreshTokenStmt, createRefreshToken,
 		arg.UserID,
-		arg.Token,
+		arg.FamilyID,
+		arg.TokenHash,
 		arg.ExpiresAt,
 		arg.CreatedAt,
 		arg.Revoked,
@@ -38,56 +41,91 @@ func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshToken
 	err := row.Scan(
 		&i.RefreshTokenID,
 		&i.UserID,
-		&i.Token,
 		&i.ExpiresAt,
 		&i.CreatedAt,
 		&i.Revoked,
+		&i.TokenHash,
+		&i.FamilyID,
+		&i.RotatedAt,
 	)
 	return i, err
 }
 
-const deleteRefreshToken = `-- name: DeleteRefreshToken :exec
-delete from refresh_tokens where token = $1
-`
-
-func (q *Queries) DeleteRefreshToken(ctx context.Context, token string) error {
-	_, err := q.exec(ctx, q.deleteRefreshTokenStmt, deleteRefreshToken, token)
-	return err
-}
-
 const getRefreshToken = `-- name: GetRefreshToken :one
 select
     refresh_token_id,
     user_id,
-    token,
+    family_id,
     expires_at,
     created_at,
-    revoked
+    revoked,
+    rotated_at
 from refresh_tokens
-    where token = $1
+    where token_hash = $1
 `
 
-func (q *Queries) GetRefreshToken(ctx context.Context, token string) (RefreshToken, error) {
-	row := q.queryRow(ctx, q.getRefreshTokenStmt, getRefreshToken, token)
-	var i RefreshToken
+type GetRefreshTokenRow struct {
+	RefreshTokenID uuid.UUID    `json:"refresh_token_id"`
+	UserID         uuid.UUID    `json:"user_id"`
+	FamilyID       uuid.UUID    `json:"family_id"`
+	ExpiresAt      time.Time    `json:"expires_at"`
+	CreatedAt      time.Time    `json:"created_at"`
+	Revoked        bool         `json:"revoked"`
+	RotatedAt      sql.NullTime `json:"rotated_at"`
+}
+
+func (q *Queries) GetRefreshToken(ctx context.Context, tokenHash []byte) (GetRefreshTokenRow, error) {
+	row := q.queryRow(ctx, q.getRefreshTokenStmt, getRefreshToken, tokenHash)
+	var i GetRefreshTokenRow
 	err := row.Scan(
 		&i.RefreshTokenID,
 		&i.UserID,
-		&i.Token,
+		&i.FamilyID,
 		&i.ExpiresAt,
 		&i.CreatedAt,
 		&i.Revoked,
+		&i.RotatedAt,
 	)
 	return i, err
 }
 
-const revokeRefreshToken = `-- name: RevokeRefreshToken :exec
+const revokeRefreshTokenFamily = `-- name: RevokeRefreshTokenFamily :exec
+update refresh_tokens
+    set revoked = true
+where family_id = $1
+    and revoked = false
+`
+
+func (q *Queries) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	_, err := q.exec(ctx, q.revokeRefreshTokenFamilyStmt, revokeRefreshTokenFamily, familyID)
+	return err
+}
+
+const revokeUserRefreshTokens = `-- name: RevokeUserRefreshTokens :exec
 update refresh_tokens
     set revoked = true
-        where token = $1
+where user_id = $1
+    and revoked = false
 `
 
-func (q *Queries) RevokeRefreshToken(ctx context.Context, token string) error {
-	_, err := q.exec(ctx, q.revokeRefreshTokenStmt, revokeRefreshToken, token)
+func (q *Queries) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.exec(ctx, q.revokeUserRefreshTokensStmt, revokeUserRefreshTokens, userID)
 	return err
 }
+
+const rotateRefreshToken = `-- name: RotateRefreshToken :one
+update refresh_tokens
+    set rotated_at = now()
+where refresh_token_id = $1
+    and rotated_at is null
+    and revoked = false
+returning refresh_token_id
+`
+
+// Marks a token as exchanged for a new one, a token can only be rotated once.
+func (q *Queries) RotateRefreshToken(ctx context.Context, refreshTokenID uuid.UUID) (uuid.UUID, error) {
+	row := q.queryRow(ctx, q.rotateRefreshTokenStmt, rotateRefreshToken, refreshTokenID)
+	var refresh_token_id uuid.UUID
+	err := row.Scan(&refresh_token_id)
+	return refresh_token_id, err
+}
@@ -73,7 +73,7 @@ insert into users (first_name, last_name, email,  password_hash)
     values($1, $2, $3, $4)
 on conflict(email)
     do nothing
-returning user_id, last_name, first_name, email, is_verified, role, password_hash, created_at, updated_at, last_login, version
+returning user_id, last_name, first_name, email, is_verified, role, password_hash, created_at, updated_at, last_login, version, trash_retention_days, quota_max_bytes, quota_max_files, strip_location
 `
 
 type CreateUserParams struct {
@@ -104,10 +104,27 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.UpdatedAt,
 		&i.LastLogin,
 		&i.Version,
+		&i.TrashRetentionDays,
+		&i.QuotaMaxBytes,
+		&i.QuotaMaxFiles,
+		&i.StripLocation,
 	)
 	return i, err
 }
 
+const getStripLocation = `-- name: GetStripLocation :one
+select strip_location
+from users
+    where user_id = $1
+`
+
+func (q *Queries) GetStripLocation(ctx context.Context, userID uuid.UUID) (bool, error) {
+	row := q.queryRow(ctx, q.getStripLocationStmt, getStripLocation, userID)
+	var strip_location bool
+	err := row.Scan(&strip_location)
+	return strip_location, err
+}
+
 const getUserByEmail = `-- name: GetUserByEmail :one
 select
     user_id,
@@ -157,18 +174,22 @@ select
     first_name,
     last_name,
     is_verified,
-    role
+    role,
+    trash_retention_days,
+    strip_location
 from users
     where user_id = $1
 `
 
 type GetUserByIDRow struct {
-	UserID     uuid.UUID `json:"user_id"`
-	Email      string    `json:"email"`
-	FirstName  string    `json:"first_name"`
-	LastName   string    `json:"last_name"`
-	IsVerified bool      `json:"is_verified"`
-	Role       UserRole  `json:"role"`
+	UserID             uuid.UUID     `json:"user_id"`
+	Email              string        `json:"email"`
+	FirstName          string        `json:"first_name"`
+	LastName           string        `json:"last_name"`
+	IsVerified         bool          `json:"is_verified"`
+	Role               UserRole      `json:"role"`
+	TrashRetentionDays sql.NullInt32 `json:"trash_retention_days"`
+	StripLocation      bool          `json:"strip_location"`
 }
 
 func (q *Queries) GetUserByID(ctx context.Context, userID uuid.UUID) (GetUserByIDRow, error) {
@@ -181,6 +202,46 @@ func (q *Queries) GetUserByID(ctx context.Context, userID uuid.UUID) (GetUserByI
 		&i.LastName,
 		&i.IsVerified,
 		&i.Role,
+		&i.TrashRetentionDays,
+		&i.StripLocation,
 	)
 	return i, err
 }
+
+const setTrashRetention = `-- name: SetTrashRetention :exec
+update users
+    set
+        trash_retention_days = $1,
+        updated_at = now()
+where user_id = $2
+`
+
+type SetTrashRetentionParams struct {
+	TrashRetentionDays sql.NullInt32 `json:"trash_retention_days"`
+	UserID             uuid.UUID     `json:"user_id"`
+}
+
+// Sets the number of days a user's deleted files are kept in the trash, null uses the deployment default.
+func (q *Queries) SetTrashRetention(ctx context.Context, arg SetTrashRetentionParams) error {
+	_, err := q.exec(ctx, q.setTrashRetentionStmt, setTrashRetention, arg.TrashRetentionDays, arg.UserID)
+	return err
+}
+
+const setStripLocation = `-- name: SetStripLocation :exec
+update users
+    set
+        strip_location = $1,
+        updated_at = now()
+where user_id = $2
+`
+
+type SetStripLocationParams struct {
+	StripLocation bool      `json:"strip_location"`
+	UserID        uuid.UUID `json:"user_id"`
+}
+
+// Sets whether location metadata is removed from the images the user uploads by default.
+func (q *Queries) SetStripLocation(ctx context.Context, arg SetStripLocationParams) error {
+	_, err := q.exec(ctx, q.setStripLocationStmt, setStripLocation, arg.StripLocation, arg.UserID)
+	return err
+}
@@ -22,7 +22,12 @@ select
     prefix,
     scope,
     is_revoked,
-    expires_at
+    revoked_at,
+    created_at,
+    expires_at,
+    last_used_at,
+    last_used_ip,
+    previous_key_expires_at
 from api_keys
     where user_id = $1
 order by created_at desc;
@@ -31,6 +36,10 @@ order by created_at desc;
 select
     ak.api_key_id,
     ak.key_hash,
+    ak.previous_key_hash,
+    ak.previous_key_expires_at,
+    ak.scope,
+    ak.is_revoked,
     ak.expires_at,
     u.user_id,
     u.first_name,
@@ -42,19 +51,47 @@ from api_keys ak
     join users u using(user_id)
 where prefix = $1;
 
--- name: RevokeApiKey :exec
+-- name: GetApiKeyStatus :one
+select
+    is_revoked,
+    expires_at
+from api_keys
+where api_key_id = $1
+    and user_id = $2;
+
+-- name: RevokeApiKey :execrows
 update api_keys
 set is_revoked = true,
-    revoked_at = now()
-where api_key_id = $1;
+    revoked_at = coalesce(revoked_at, now()),
+    updated_at = now()
+where api_key_id = $1
+    and user_id = $2;
 
+-- name: RenameApiKey :execrows
+update api_keys
+set name = $3,
+    updated_at = now()
+where api_key_id = $1
+    and user_id = $2;
+
+-- name: RotateApiKey :one
+-- Replaces the secret of a key, the secret it replaces is still accepted until previous_key_expires_at.
+update api_keys
+set previous_key_hash = key_hash,
+    previous_key_expires_at = $3,
+    key_hash = $4,
+    updated_at = now()
+where api_key_id = $1
+    and user_id = $2
+returning prefix;
 
 -- name: UpdateApiKeyLastUsed :exec
 update api_keys
-    set last_used_at = $2
+    set last_used_at = $2,
+        last_used_ip = $3
 where api_key_id = $1;
 
--- name: DeleteApiKey :exec
+-- name: DeleteApiKey :execrows
 delete from api_keys
-    where api_key_id = $1;
-
+    where api_key_id = $1
+        and user_id = $2;
@@ -0,0 +1,66 @@
+-- name: GetArchiveFiles :many
+-- Fetch the files selected for an archive by ID, files which do not exist or are in the trash are left out.
+select
+    file_id,
+    user_id,
+    filename,
+    mime_type,
+    size_bytes,
+    storage_key,
+    visibility,
+    scan_status,
+    strip_location_pending,
+    updated_at
+from files
+    where file_id = any(sqlc.arg(file_ids)::uuid[])
+        and is_deleted = false;
+
+-- name: GetFolderArchiveFiles :many
+-- Fetch the files in a folder and its subfolders, with the path of the folder holding each file relative to
+-- the parent of the selected folder. Nothing is returned when the folder does not belong to the user.
+with recursive subtree as (
+    select folder_id, name || '/' as folder_path
+    from folders
+        where folder_id = sqlc.arg(folder_id)
+            and user_id = sqlc.arg(user_id)
+            and is_deleted = false
+    union all
+    select f.folder_id, s.folder_path || f.name || '/'
+    from folders f
+        join subtree s
+            on f.parent_id = s.folder_id
+        where f.is_deleted = false
+) cycle folder_id set is_cycle using visited
+select
+    fi.file_id,
+    fi.filename,
+    fi.mime_type,
+    fi.size_bytes,
+    fi.storage_key,
+    fi.scan_status,
+    fi.updated_at,
+    s.folder_path::text as folder_path
+from files fi
+    join subtree s
+        on fi.folder_id = s.folder_id
+    where fi.is_deleted = false
+        and not s.is_cycle
+    order by s.folder_path, fi.filename
+    limit sqlc.arg(max_files);
+
+-- name: GetTaggedArchiveFiles :many
+-- Fetch the files of the user with every one of the tags.
+select
+    file_id,
+    filename,
+    mime_type,
+    size_bytes,
+    storage_key,
+    scan_status,
+    updated_at
+from files
+    where user_id = sqlc.arg(user_id)
+        and is_deleted = false
+        and tags @> sqlc.arg(tags)::text[]
+    order by filename
+    limit sqlc.arg(max_files);
@@ -0,0 +1,44 @@
+-- name: AddBlobReference :one
+-- Adds a reference to the user's referenced blob with the checksum, blobs without references are never reused.
+update blobs
+    set ref_count = ref_count + 1
+where user_id = $1
+    and checksum = $2
+    and ref_count > 0
+returning storage_key;
+
+-- name: CreateBlob :one
+-- Registers newly stored content as a blob with one reference,
+-- nothing is returned when a blob of the user with the same checksum was created concurrently.
+insert into blobs (storage_key, user_id, checksum, size_bytes)
+    values ($1, $2, $3, $4)
+on conflict (user_id, checksum) where ref_count > 0
+    do nothing
+returning storage_key;
+
+-- name: ReleaseBlobs :many
+-- Removes one reference from the blob for every occurrence of its storage key,
+-- returning the storage keys of the blobs left without references.
+with released as (
+    update blobs b
+        set ref_count = greatest(b.ref_count - r.refs, 0)
+    from (
+        select key, count(*) as refs
+        from unnest(sqlc.arg(storage_keys)::text[]) as key
+        group by key
+    ) r
+    where b.storage_key = r.key
+    returning b.storage_key, b.ref_count
+)
+select storage_key from released
+    where ref_count = 0;
+
+-- name: GetUnreferencedBlobs :many
+select storage_key from blobs
+    where ref_count = 0
+    limit $1;
+
+-- name: DeleteUnreferencedBlobs :exec
+delete from blobs
+    where storage_key = any(sqlc.arg(storage_keys)::text[])
+        and ref_count = 0;
@@ -1,11 +1,54 @@
 -- name: GetExpiredDeletedFiles :many
--- Fetch files that have been soft-deleted for more than specific duration (e.g., 7 days)
+-- Fetch trashed files whose retention period has passed
 select file_id, storage_key, thumbnail_key
 from files
     where is_deleted = true 
-        and deleted_at < now()
+        and purge_after < now()
     limit $1;
 
 -- name: HardDeleteFiles :exec
 -- Permanently remove file records
 delete from files where file_id = any(sqlc.arg(file_ids)::uuid[]);
+
+-- name: GetExpiredUploads :many
+-- Fetch resumable uploads which were abandoned or completed and have passed their expiry time
+select upload_id, file_id
+from uploads
+    where expires_at < now()
+    limit $1;
+
+-- name: HardDeleteUploads :exec
+delete from uploads where upload_id = any(sqlc.arg(upload_ids)::uuid[]);
+
+-- name: GetFileVersionStorageKeys :many
+-- Fetch the storage keys of every version of the given files
+select storage_key
+from file_versions
+    where file_id = any(sqlc.arg(file_ids)::uuid[]);
+
+-- name: GetExcessFileVersions :many
+-- Fetch versions beyond the newest `keep_versions` of each file, the current version of a file is always kept
+select fv.file_version_id, fv.storage_key
+from (
+    select
+        file_version_id,
+        file_id,
+        version_number,
+        storage_key,
+        row_number() over (partition by file_id order by version_number desc) as position
+    from file_versions
+) fv
+    join files f
+        on f.file_id = fv.file_id
+    where fv.position > sqlc.arg(keep_versions)::int
+        and fv.version_number <> f.current_version
+    limit sqlc.arg(max_results);
+
+-- name: HardDeleteFileVersions :exec
+delete from file_versions where file_version_id = any(sqlc.arg(file_version_ids)::uuid[]);
+
+-- name: DeleteExpiredFolders :execrows
+-- Permanently remove trashed folders whose retention period has passed, their subfolders are removed by cascade
+delete from folders
+    where is_deleted = true
+        and purge_after < now();
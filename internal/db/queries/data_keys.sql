@@ -0,0 +1,34 @@
+-- name: GetDataKey :one
+select key_id, wrapped_key, master_key_id from data_keys
+    where key_id = $1;
+
+-- name: GetUserDataKey :one
+select key_id, wrapped_key, master_key_id from data_keys
+    where user_id = $1;
+
+-- name: GetServiceDataKey :one
+select key_id, wrapped_key, master_key_id from data_keys
+    where is_service;
+
+-- name: CreateDataKey :one
+-- Stores a new wrapped data key, nothing is returned when the owner's key was created concurrently.
+insert into data_keys (user_id, is_service, wrapped_key, master_key_id)
+    values ($1, $2, $3, $4)
+on conflict do nothing
+returning key_id;
+
+-- name: ListDataKeysToRewrap :many
+-- Lists data keys wrapped by a master key other than the current one, ordered by key_id so they can be paged through.
+select key_id, wrapped_key, master_key_id from data_keys
+    where master_key_id <> sqlc.arg(current_master_key_id)
+        and key_id > sqlc.arg(after_key_id)
+order by key_id
+limit sqlc.arg(batch_size);
+
+-- name: RewrapDataKey :execrows
+-- Replaces the wrapped form of a data key, unless it has been re-wrapped concurrently.
+update data_keys
+    set wrapped_key = sqlc.arg(wrapped_key),
+        master_key_id = sqlc.arg(master_key_id)
+where key_id = sqlc.arg(key_id)
+    and master_key_id = sqlc.arg(previous_master_key_id);
@@ -0,0 +1,97 @@
+-- name: AddFileVersion :one
+-- Makes new content the current version of a file and records it in the version history.
+-- No version is recorded when the optimistic lock check on the file fails.
+with next_version as (
+    select coalesce(max(version_number), 0) + 1 as version_number
+    from file_versions
+        where file_id = sqlc.arg(file_id)
+), updated_file as (
+    update files f
+        set
+            storage_key = sqlc.arg(storage_key),
+            mime_type = sqlc.arg(mime_type),
+            size_bytes = sqlc.arg(size_bytes),
+            checksum = sqlc.arg(checksum),
+            scan_status = sqlc.arg(scan_status),
+            strip_location_pending = sqlc.arg(strip_location_pending),
+            current_version = nv.version_number,
+            thumbnail_key = null,
+            extracted_text = null,
+            image_metadata = null,
+            updated_at = now(),
+            version = f.version + 1
+    from next_version nv
+    where f.file_id = sqlc.arg(file_id)
+        and f.user_id = sqlc.arg(user_id)
+        and f.version = sqlc.arg(version)
+        and f.is_deleted = false
+    returning f.file_id, f.user_id, f.storage_key, f.mime_type, f.size_bytes, f.checksum, f.current_version, f.version
+), new_version as (
+    insert into file_versions (file_id, version_number, storage_key, mime_type, size_bytes, checksum, created_by)
+        select file_id, current_version, storage_key, mime_type, size_bytes, checksum, user_id
+        from updated_file
+    returning file_id, version_number, mime_type, size_bytes, checksum, created_at
+)
+select
+    nv.file_id,
+    nv.version_number,
+    nv.mime_type,
+    nv.size_bytes,
+    nv.checksum,
+    nv.created_at,
+    uf.version
+from new_version nv
+    join updated_file uf
+        on uf.file_id = nv.file_id;
+
+-- name: GetFileVersion :one
+select
+    version_number,
+    storage_key,
+    mime_type,
+    size_bytes,
+    checksum,
+    created_at
+from file_versions
+    where file_id = $1
+        and version_number = $2;
+
+-- name: ListFileVersions :many
+select
+    fv.version_number,
+    fv.mime_type,
+    fv.size_bytes,
+    fv.checksum,
+    fv.created_by,
+    fv.created_at,
+    (fv.version_number = f.current_version)::boolean as is_current
+from file_versions fv
+    join files f
+        on f.file_id = fv.file_id
+    where fv.file_id = $1
+order by fv.version_number desc;
+
+-- name: PromoteFileVersion :one
+-- Makes an earlier version the current content of a file without copying it.
+update files f
+    set
+        storage_key = fv.storage_key,
+        mime_type = fv.mime_type,
+        size_bytes = fv.size_bytes,
+        checksum = fv.checksum,
+        scan_status = sqlc.arg(scan_status),
+        strip_location_pending = sqlc.arg(strip_location_pending),
+        current_version = fv.version_number,
+        thumbnail_key = null,
+        extracted_text = null,
+        image_metadata = null,
+        updated_at = now(),
+        version = f.version + 1
+from file_versions fv
+where fv.file_id = f.file_id
+    and fv.version_number = sqlc.arg(version_number)
+    and f.file_id = sqlc.arg(file_id)
+    and f.user_id = sqlc.arg(user_id)
+    and f.version = sqlc.arg(version)
+    and f.is_deleted = false
+returning f.current_version, f.storage_key, f.mime_type, f.version;
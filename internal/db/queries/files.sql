@@ -10,9 +10,18 @@ from files
     group by storage_key;
 
 -- name: CreateFile :one
-insert into files (user_id, filename, storage_key, mime_type, size_bytes, checksum)
-    values($1, $2, $3, $4, $5, $6)
-returning file_id, filename, mime_type, size_bytes, created_at, visibility, checksum, version;
+-- Creates a file together with the first entry of its version history.
+with new_file as (
+    insert into files (user_id, filename, storage_key, mime_type, size_bytes, checksum, tags, scan_status, strip_location_pending)
+        values($1, $2, $3, $4, $5, $6, $7, $8, $9)
+    returning file_id, user_id, filename, storage_key, mime_type, size_bytes, created_at, visibility, checksum, tags, version, current_version, scan_status, strip_location_pending
+), first_version as (
+    insert into file_versions (file_id, version_number, storage_key, mime_type, size_bytes, checksum, created_by)
+        select file_id, current_version, storage_key, mime_type, size_bytes, checksum, user_id
+        from new_file
+)
+select file_id, filename, mime_type, size_bytes, created_at, visibility, checksum, tags, version, scan_status, strip_location_pending
+from new_file;
 
 -- name: GetFileInfo :one
 -- Retrieve metadata of a file from the database.
@@ -27,7 +36,13 @@ select
     thumbnail_key,
     checksum,
     tags,
-    version
+    version,
+    current_version,
+    folder_id,
+    updated_at,
+    scan_status,
+    strip_location_pending,
+    coalesce(image_metadata, 'null')::jsonb as image_metadata
 from files
     where is_deleted = false
         and file_id = $1;
@@ -44,6 +59,11 @@ from users u
         and f.file_id = $1;
 
 -- name: ListPublicFiles :many
+-- Only files which passed the malware scan, or were stored without one, are listed.
+-- Files can be filtered by tags, MIME type prefix, size and creation date; a null filter is not applied.
+-- all_tags requires every tag and any_tags at least one of them.
+-- sort is one of the columns name, size, created_at or updated_at, prefixed with - for descending order.
+-- When sorting by created_at, cursor_time and cursor_id select the files after that position in the sort order.
 select
     u.user_id as owner_id,
     u.last_name,
@@ -55,34 +75,99 @@ select
     f.thumbnail_key,
     f.checksum,
     f.tags,
+    f.created_at,
+    f.updated_at,
     f.version
 from files f
     join users u
         on f.user_id = u.user_id
     where f.visibility = 'public'
+        and f.scan_status in ('clean', 'skipped')
         and f.is_deleted = false
-    order by f.created_at desc
-    limit $1 offset $2;
+        and (sqlc.narg(all_tags)::text[] is null or f.tags @> sqlc.narg(all_tags))
+        and (sqlc.narg(any_tags)::text[] is null or f.tags && sqlc.narg(any_tags))
+        and (sqlc.narg(mime_prefix)::text is null or f.mime_type like sqlc.narg(mime_prefix) || '%')
+        and (sqlc.narg(min_size)::bigint is null or f.size_bytes >= sqlc.narg(min_size))
+        and (sqlc.narg(max_size)::bigint is null or f.size_bytes <= sqlc.narg(max_size))
+        and (sqlc.narg(created_after)::timestamptz is null or f.created_at >= sqlc.narg(created_after))
+        and (sqlc.narg(created_before)::timestamptz is null or f.created_at < sqlc.narg(created_before))
+        and (sqlc.narg(cursor_time)::timestamptz is null or sqlc.narg(cursor_id)::uuid is null
+            or (sqlc.arg(sort)::text = 'created_at' and (f.created_at, f.file_id) > (sqlc.narg(cursor_time), sqlc.narg(cursor_id)))
+            or (sqlc.arg(sort)::text = '-created_at' and (f.created_at, f.file_id) < (sqlc.narg(cursor_time), sqlc.narg(cursor_id))))
+    order by
+        case when sqlc.arg(sort)::text = 'name' then lower(f.filename) end,
+        case when sqlc.arg(sort)::text = '-name' then lower(f.filename) end desc,
+        case when sqlc.arg(sort)::text = 'size' then f.size_bytes end,
+        case when sqlc.arg(sort)::text = '-size' then f.size_bytes end desc,
+        case when sqlc.arg(sort)::text = 'created_at' then f.created_at end,
+        case when sqlc.arg(sort)::text = '-created_at' then f.created_at end desc,
+        case when sqlc.arg(sort)::text = 'updated_at' then f.updated_at end,
+        case when sqlc.arg(sort)::text = '-updated_at' then f.updated_at end desc,
+        case when sqlc.arg(sort)::text = 'created_at' then f.file_id end,
+        f.file_id desc
+    limit sqlc.arg(page_limit) offset sqlc.arg(page_offset);
 
 -- name: CountPublicFiles :one
 select
     count(*)
 from files
     where visibility = 'public'
-        and is_deleted = false;
+        and scan_status in ('clean', 'skipped')
+        and is_deleted = false
+        and (sqlc.narg(all_tags)::text[] is null or tags @> sqlc.narg(all_tags))
+        and (sqlc.narg(any_tags)::text[] is null or tags && sqlc.narg(any_tags))
+        and (sqlc.narg(mime_prefix)::text is null or mime_type like sqlc.narg(mime_prefix) || '%')
+        and (sqlc.narg(min_size)::bigint is null or size_bytes >= sqlc.narg(min_size))
+        and (sqlc.narg(max_size)::bigint is null or size_bytes <= sqlc.narg(max_size))
+        and (sqlc.narg(created_after)::timestamptz is null or created_at >= sqlc.narg(created_after))
+        and (sqlc.narg(created_before)::timestamptz is null or created_at < sqlc.narg(created_before));
 
 -- name: ListUserFiles :many
+-- Files can be filtered by tags, MIME type prefix, size and creation date; a null filter is not applied.
+-- all_tags requires every tag and any_tags at least one of them.
+-- sort is one of the columns name, size, created_at or updated_at, prefixed with - for descending order.
+-- When sorting by created_at, cursor_time and cursor_id select the files after that position in the sort order.
 select 
-    f.file_id, f.filename, f.mime_type, f.size_bytes, f.visibility, f.created_at, f.tags
+    f.file_id, f.filename, f.mime_type, f.size_bytes, f.visibility, f.created_at, f.updated_at, f.tags, f.scan_status
 from files f
-    where f.user_id = $1
+    where f.user_id = sqlc.arg(user_id)
         and f.is_deleted = false
-    order by f.created_at desc
-    limit $2 offset $3;
+        and (sqlc.narg(all_tags)::text[] is null or f.tags @> sqlc.narg(all_tags))
+        and (sqlc.narg(any_tags)::text[] is null or f.tags && sqlc.narg(any_tags))
+        and (sqlc.narg(mime_prefix)::text is null or f.mime_type like sqlc.narg(mime_prefix) || '%')
+        and (sqlc.narg(min_size)::bigint is null or f.size_bytes >= sqlc.narg(min_size))
+        and (sqlc.narg(max_size)::bigint is null or f.size_bytes <= sqlc.narg(max_size))
+        and (sqlc.narg(created_after)::timestamptz is null or f.created_at >= sqlc.narg(created_after))
+        and (sqlc.narg(created_before)::timestamptz is null or f.created_at < sqlc.narg(created_before))
+        and (sqlc.narg(visibility)::file_visibility is null or f.visibility = sqlc.narg(visibility))
+        and (sqlc.narg(cursor_time)::timestamptz is null or sqlc.narg(cursor_id)::uuid is null
+            or (sqlc.arg(sort)::text = 'created_at' and (f.created_at, f.file_id) > (sqlc.narg(cursor_time), sqlc.narg(cursor_id)))
+            or (sqlc.arg(sort)::text = '-created_at' and (f.created_at, f.file_id) < (sqlc.narg(cursor_time), sqlc.narg(cursor_id))))
+    order by
+        case when sqlc.arg(sort)::text = 'name' then lower(f.filename) end,
+        case when sqlc.arg(sort)::text = '-name' then lower(f.filename) end desc,
+        case when sqlc.arg(sort)::text = 'size' then f.size_bytes end,
+        case when sqlc.arg(sort)::text = '-size' then f.size_bytes end desc,
+        case when sqlc.arg(sort)::text = 'created_at' then f.created_at end,
+        case when sqlc.arg(sort)::text = '-created_at' then f.created_at end desc,
+        case when sqlc.arg(sort)::text = 'updated_at' then f.updated_at end,
+        case when sqlc.arg(sort)::text = '-updated_at' then f.updated_at end desc,
+        case when sqlc.arg(sort)::text = 'created_at' then f.file_id end,
+        f.file_id desc
+    limit sqlc.arg(page_limit) offset sqlc.arg(page_offset);
 
 -- name: CountUserFiles :one
 select count(*) from files
-    where user_id = $1 and is_deleted = false;
+    where user_id = sqlc.arg(user_id)
+        and is_deleted = false
+        and (sqlc.narg(all_tags)::text[] is null or tags @> sqlc.narg(all_tags))
+        and (sqlc.narg(any_tags)::text[] is null or tags && sqlc.narg(any_tags))
+        and (sqlc.narg(mime_prefix)::text is null or mime_type like sqlc.narg(mime_prefix) || '%')
+        and (sqlc.narg(min_size)::bigint is null or size_bytes >= sqlc.narg(min_size))
+        and (sqlc.narg(max_size)::bigint is null or size_bytes <= sqlc.narg(max_size))
+        and (sqlc.narg(created_after)::timestamptz is null or created_at >= sqlc.narg(created_after))
+        and (sqlc.narg(created_before)::timestamptz is null or created_at < sqlc.narg(created_before))
+        and (sqlc.narg(visibility)::file_visibility is null or visibility = sqlc.narg(visibility));
 
 -- name: UpdateFileName :one
 update files
@@ -101,19 +186,110 @@ update files
         updated_at = now(),
         version = version + 1
 where file_id = $2
+    and user_id = $3
+    and version = $4
+    and is_deleted = false
+returning visibility, version;
+
+-- name: DeleteFile :one
+-- Moves a file to the trash, it is purged by a background task once purge_after has passed.
+-- The retention period is the owner's trash_retention_days, or the deployment default.
+update files f
+    set
+        is_deleted = true,
+        deleted_at = now(),
+        purge_after = now() + make_interval(days => coalesce(u.trash_retention_days, sqlc.arg(default_retention_days)::int)),
+        updated_at = now(),
+        version = f.version + 1
+from users u
+where u.user_id = f.user_id
+    and f.file_id = sqlc.arg(file_id)
+    and f.user_id = sqlc.arg(user_id)
+    and f.version = sqlc.arg(version)
+    and f.is_deleted = false
+returning f.purge_after;
+
+-- name: ListTrashedFiles :many
+-- cursor_time and cursor_id select the files deleted before that position, or after it when ascending is set.
+select
+    file_id, filename, mime_type, size_bytes, deleted_at, purge_after, version
+from files
+    where user_id = sqlc.arg(user_id)
+        and is_deleted = true
+        and (sqlc.narg(cursor_time)::timestamptz is null or sqlc.narg(cursor_id)::uuid is null
+            or (sqlc.arg(ascending)::bool and (deleted_at, file_id) > (sqlc.narg(cursor_time), sqlc.narg(cursor_id)))
+            or (not sqlc.arg(ascending)::bool and (deleted_at, file_id) < (sqlc.narg(cursor_time), sqlc.narg(cursor_id))))
+    order by
+        case when sqlc.arg(ascending)::bool then deleted_at end,
+        case when sqlc.arg(ascending)::bool then file_id end,
+        deleted_at desc,
+        file_id desc
+    limit sqlc.arg(page_limit) offset sqlc.arg(page_offset);
+
+-- name: CountTrashedFiles :one
+select count(*) from files
+    where user_id = $1 and is_deleted = true;
+
+-- name: RestoreFile :one
+-- Takes a file out of the trash before it is purged, files whose folder was deleted are restored to the root folder.
+update files
+    set
+        is_deleted = false,
+        deleted_at = null,
+        purge_after = null,
+        folder_id = (select folder_id from folders where folder_id = files.folder_id and is_deleted = false),
+        updated_at = now(),
+        version = version + 1
+where file_id = $1
+    and user_id = $2
     and version = $3
-    returning visibility;
+    and is_deleted = true
+returning version;
+
+-- name: GetTrashedFilesForPurge :many
+-- Fetch a user's trashed files, or a single trashed file when file_id is set.
+select file_id, thumbnail_key
+from files
+    where user_id = sqlc.arg(user_id)
+        and is_deleted = true
+        and (sqlc.narg(file_id)::uuid is null or file_id = sqlc.narg(file_id));
 
--- name: DeleteFile :exec
--- Sets file is deleted tag to true and adds a the specified date for a background task to delete it.
+-- name: MoveFile :one
+-- Moves a file into a folder, or to the root folder when folder_id is null.
 update files
     set
-        is_deleted = true,
-        deleted_at = $1,
+        folder_id = $1,
+        updated_at = now(),
+        version = version + 1
+where file_id = $2
+    and user_id = $3
+    and version = $4
+    and is_deleted = false
+returning folder_id, version;
+
+-- name: SetFileTags :one
+update files
+    set
+        tags = $1,
         updated_at = now(),
         version = version + 1
 where file_id = $2
-    and version = $3;
+    and user_id = $3
+    and version = $4
+    and is_deleted = false
+returning tags, version;
+
+-- name: ListUserTags :many
+-- Lists the distinct tags on a user's files together with the number of files carrying each tag.
+select
+    tag::text as tag,
+    count(*) as file_count
+from files f
+    cross join unnest(f.tags) as tag
+    where f.user_id = $1
+        and f.is_deleted = false
+    group by tag
+    order by file_count desc, tag;
 
 -- name: UpdateFileThumbnail :exec
 update files
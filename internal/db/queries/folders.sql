@@ -0,0 +1,174 @@
+-- name: CreateFolder :one
+-- Returns no rows if the parent folder already has a folder with the same name.
+insert into folders (user_id, parent_id, name)
+    values ($1, $2, $3)
+on conflict do nothing
+returning folder_id, parent_id, name, created_at, version;
+
+-- name: GetFolder :one
+select
+    folder_id,
+    user_id,
+    parent_id,
+    name,
+    created_at,
+    updated_at,
+    version
+from folders
+    where folder_id = $1
+        and is_deleted = false;
+
+-- name: GetFolderPath :many
+-- Returns the folder and all its ancestors, starting from the top level folder.
+-- The walk stops at a folder already visited, should the hierarchy ever contain a loop.
+with recursive ancestors as (
+    select folder_id, parent_id, name, 0 as depth
+    from folders
+        where folder_id = $1
+    union all
+    select f.folder_id, f.parent_id, f.name, a.depth + 1
+    from folders f
+        join ancestors a
+            on f.folder_id = a.parent_id
+) cycle folder_id set is_cycle using visited
+select folder_id, name
+from ancestors
+    where not is_cycle
+order by depth desc;
+
+-- name: RenameFolder :one
+update folders
+    set
+        name = $1,
+        updated_at = now(),
+        version = version + 1
+where folder_id = $2
+    and user_id = $3
+    and version = $4
+    and is_deleted = false
+returning name, version;
+
+-- name: LockUserFolders :exec
+-- Locks every folder of the user until the end of the transaction, so the hierarchy can be checked before changing it.
+select folder_id
+from folders
+    where user_id = $1
+for update;
+
+-- name: MoveFolder :one
+update folders
+    set
+        parent_id = $1,
+        updated_at = now(),
+        version = version + 1
+where folder_id = $2
+    and user_id = $3
+    and version = $4
+    and is_deleted = false
+returning parent_id, version;
+
+-- name: DeleteFolder :one
+-- Moves a folder, its subfolders and all files within them to the trash.
+-- Everything is purged together by the cleanup job once purge_after has passed.
+with recursive subtree as (
+    select folder_id
+    from folders
+        where folder_id = sqlc.arg(folder_id)
+            and user_id = sqlc.arg(user_id)
+            and version = sqlc.arg(version)
+            and is_deleted = false
+    union all
+    select f.folder_id
+    from folders f
+        join subtree s
+            on f.parent_id = s.folder_id
+        where f.is_deleted = false
+) cycle folder_id set is_cycle using visited, retention as (
+    select now() + make_interval(days => coalesce(trash_retention_days, sqlc.arg(default_retention_days)::int)) as purge_after
+    from users
+        where user_id = sqlc.arg(user_id)
+), deleted_files as (
+    update files
+        set
+            is_deleted = true,
+            deleted_at = now(),
+            purge_after = r.purge_after,
+            updated_at = now(),
+            version = version + 1
+    from retention r
+    where folder_id in (select folder_id from subtree)
+        and is_deleted = false
+    returning file_id
+), deleted_folders as (
+    update folders
+        set
+            is_deleted = true,
+            deleted_at = now(),
+            purge_after = r.purge_after,
+            updated_at = now(),
+            version = version + 1
+    from retention r
+    where folder_id in (select folder_id from subtree)
+    returning folder_id
+)
+select
+    (select count(*) from deleted_folders) as deleted_folders,
+    (select count(*) from deleted_files) as deleted_files;
+
+-- name: ListFolderChildren :many
+-- Lists the subfolders and files of a folder, or of the root folder when parent_id is null. Folders come first.
+-- cursor_is_file, cursor_name and cursor_id select the children after that position, or before it when backward is set.
+select *
+from (
+    select
+        'folder'::text as kind,
+        folder_id as id,
+        name,
+        null::varchar as mime_type,
+        null::bigint as size_bytes,
+        created_at,
+        updated_at,
+        version
+    from folders
+        where user_id = sqlc.arg(user_id)
+            and parent_id is not distinct from sqlc.narg(parent_id)
+            and is_deleted = false
+    union all
+    select
+        'file'::text as kind,
+        file_id as id,
+        filename as name,
+        mime_type,
+        size_bytes,
+        created_at,
+        updated_at,
+        version
+    from files
+        where user_id = sqlc.arg(user_id)
+            and folder_id is not distinct from sqlc.narg(parent_id)
+            and is_deleted = false
+) children
+    where sqlc.narg(cursor_id)::uuid is null
+        or (not sqlc.arg(backward)::bool
+            and (kind = 'file', lower(name), id) > (sqlc.arg(cursor_is_file)::bool, lower(sqlc.arg(cursor_name)::text), sqlc.narg(cursor_id)))
+        or (sqlc.arg(backward)::bool
+            and (kind = 'file', lower(name), id) < (sqlc.arg(cursor_is_file)::bool, lower(sqlc.arg(cursor_name)::text), sqlc.narg(cursor_id)))
+order by
+    case when sqlc.arg(backward)::bool then kind = 'file' end desc,
+    case when sqlc.arg(backward)::bool then lower(name) end desc,
+    case when sqlc.arg(backward)::bool then id end desc,
+    kind = 'file',
+    lower(name),
+    id
+limit sqlc.arg(page_limit) offset sqlc.arg(page_offset);
+
+-- name: CountFolderChildren :one
+select
+    (select count(*) from folders
+        where user_id = sqlc.arg(user_id)
+            and parent_id is not distinct from sqlc.narg(parent_id)
+            and is_deleted = false)
+    + (select count(*) from files
+        where user_id = sqlc.arg(user_id)
+            and folder_id is not distinct from sqlc.narg(parent_id)
+            and is_deleted = false) as total;
@@ -0,0 +1,36 @@
+-- name: SetImageMetadata :exec
+-- Stores the metadata extracted from the content at storage_key, whose location metadata has been removed if requested.
+-- Nothing is changed when the file's content has changed since it was read.
+update files
+    set
+        image_metadata = sqlc.arg(image_metadata),
+        strip_location_pending = false
+where file_id = sqlc.arg(file_id)
+    and storage_key = sqlc.arg(storage_key);
+
+-- name: ReplaceStrippedContent :execrows
+-- Replaces the current content of a file and of its current version with a copy without location metadata.
+-- Nothing is changed when the file's content has changed since it was read.
+with updated_version as (
+    update file_versions v
+        set
+            storage_key = sqlc.arg(new_storage_key),
+            size_bytes = sqlc.arg(size_bytes),
+            checksum = sqlc.arg(checksum)
+    from files f
+    where v.file_id = f.file_id
+        and v.version_number = f.current_version
+        and f.file_id = sqlc.arg(file_id)
+        and f.storage_key = sqlc.arg(storage_key)
+)
+update files
+    set
+        storage_key = sqlc.arg(new_storage_key),
+        size_bytes = sqlc.arg(size_bytes),
+        checksum = sqlc.arg(checksum),
+        scan_status = sqlc.arg(scan_status),
+        image_metadata = sqlc.arg(image_metadata),
+        strip_location_pending = false,
+        updated_at = now()
+where file_id = sqlc.arg(file_id)
+    and storage_key = sqlc.arg(storage_key);
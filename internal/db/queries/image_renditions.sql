@@ -0,0 +1,9 @@
+-- name: CreateImageRendition :exec
+-- Records a rendition cached in storage, so it is deleted along with the blob it was generated from.
+insert into image_renditions (rendition_key, storage_key, size_bytes)
+    values ($1, $2, $3)
+on conflict (rendition_key) do nothing;
+
+-- name: GetBlobRenditions :many
+select rendition_key from image_renditions
+    where storage_key = any(sqlc.arg(storage_keys)::text[]);
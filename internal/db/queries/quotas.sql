@@ -0,0 +1,44 @@
+-- name: GetStorageUsage :one
+-- Reports the storage used by a user with the limits that apply to them, their own limits take precedence over those of their role.
+-- Every stored version of a file counts towards the bytes used and files in the trash count until they are purged.
+select
+    (select coalesce(sum(v.size_bytes), 0)
+        from file_versions v
+            join files f on f.file_id = v.file_id
+        where f.user_id = u.user_id)::bigint as used_bytes,
+    (select count(*) from files f where f.user_id = u.user_id) as used_files,
+    coalesce(u.quota_max_bytes, q.max_bytes) as max_bytes,
+    coalesce(u.quota_max_files, q.max_files) as max_files
+from users u
+    left join role_quotas q
+        on q.role = u.role
+where u.user_id = $1;
+
+-- name: LockUserStorage :exec
+-- Locks the user until the end of the transaction, so concurrent uploads of the user check their quota one after another.
+select user_id
+from users
+    where user_id = $1
+for update;
+
+-- name: ListRoleQuotas :many
+select role, max_bytes, max_files, updated_at from role_quotas
+    order by role;
+
+-- name: SetRoleQuota :one
+-- Sets the storage limits of a role, null limits are unlimited.
+insert into role_quotas (role, max_bytes, max_files)
+    values ($1, $2, $3)
+on conflict (role)
+    do update set
+        max_bytes = excluded.max_bytes,
+        max_files = excluded.max_files
+returning role, max_bytes, max_files, updated_at;
+
+-- name: SetUserQuota :execrows
+-- Overrides the storage limits of the user's role, null limits use the role limit.
+update users
+    set
+        quota_max_bytes = $1,
+        quota_max_files = $2
+where user_id = $3;
@@ -1,23 +1,37 @@
 -- name: CreateRefreshToken :one
-insert into refresh_tokens(user_id, token, expires_at, created_at, revoked)
-    values ($1, $2, $3, $4, $5)
+insert into refresh_tokens(user_id, family_id, token_hash, expires_at, created_at, revoked)
+    values ($1, $2, $3, $4, $5, $6)
 returning *;
 
 -- name: GetRefreshToken :one
 select
     refresh_token_id,
     user_id,
-    token,
+    family_id,
     expires_at,
     created_at,
-    revoked
+    revoked,
+    rotated_at
 from refresh_tokens
-    where token = $1;
+    where token_hash = $1;
 
--- name: RevokeRefreshToken :exec
+-- name: RotateRefreshToken :one
+-- Marks a token as exchanged for a new one, a token can only be rotated once.
+update refresh_tokens
+    set rotated_at = now()
+where refresh_token_id = $1
+    and rotated_at is null
+    and revoked = false
+returning refresh_token_id;
+
+-- name: RevokeRefreshTokenFamily :exec
 update refresh_tokens
     set revoked = true
-        where token = $1;
+where family_id = $1
+    and revoked = false;
 
--- name: DeleteRefreshToken :exec
-delete from refresh_tokens where token = $1;
+-- name: RevokeUserRefreshTokens :exec
+update refresh_tokens
+    set revoked = true
+where user_id = $1
+    and revoked = false;
@@ -0,0 +1,30 @@
+-- name: SetScanResult :execrows
+-- Records the scan result of content for every file still waiting for it to be scanned,
+-- files whose content has since changed are left pending for their own scan.
+update files
+    set
+        scan_status = sqlc.arg(scan_status),
+        scanned_at = now()
+where storage_key = sqlc.arg(storage_key)
+    and scan_status = 'pending';
+
+-- name: QuarantineContent :many
+-- Points the blob, file versions and files holding infected content at its new storage key under the quarantine prefix.
+-- Files whose current content is infected are marked as such and returned, so their owners can be notified.
+with moved_blob as (
+    update blobs
+        set storage_key = sqlc.arg(quarantine_key)
+    where storage_key = sqlc.arg(storage_key)
+), moved_versions as (
+    update file_versions
+        set storage_key = sqlc.arg(quarantine_key)
+    where storage_key = sqlc.arg(storage_key)
+)
+update files
+    set
+        storage_key = sqlc.arg(quarantine_key),
+        scan_status = 'infected',
+        scanned_at = now()
+where storage_key = sqlc.arg(storage_key)
+    and scan_status <> 'infected'
+returning file_id, user_id, filename;
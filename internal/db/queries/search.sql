@@ -0,0 +1,80 @@
+-- name: SearchFiles :many
+-- Full-text search over the files a user can see, their own files and public files.
+-- Anonymous users pass a null user_id and only search public files.
+-- Snippets highlight matches with <mark> tags and are computed for the returned page only.
+-- cursor_rank and cursor_id select the results ranked below that position, or above it when ascending is set.
+select
+    m.file_id,
+    m.owner_id,
+    m.filename,
+    m.mime_type,
+    m.size_bytes,
+    m.visibility,
+    m.tags,
+    m.created_at,
+    m.rank,
+    ts_headline(
+        'english',
+        coalesce(m.extracted_text, m.filename),
+        websearch_to_tsquery('english', sqlc.arg(query)),
+        'MaxFragments=2, MaxWords=20, MinWords=5, StartSel=<mark>, StopSel=</mark>'
+    )::text as snippet
+from (
+    select
+        r.file_id,
+        r.owner_id,
+        r.filename,
+        r.mime_type,
+        r.size_bytes,
+        r.visibility,
+        r.tags,
+        r.created_at,
+        r.extracted_text,
+        r.rank
+    from (
+        select
+            f.file_id,
+            f.user_id as owner_id,
+            f.filename,
+            f.mime_type,
+            f.size_bytes,
+            f.visibility,
+            f.tags,
+            f.created_at,
+            f.extracted_text,
+            ts_rank_cd(f.search_vector, websearch_to_tsquery('english', sqlc.arg(query)))::real as rank
+        from files f
+            where f.search_vector @@ websearch_to_tsquery('english', sqlc.arg(query))
+                and f.is_deleted = false
+                and (f.user_id = sqlc.narg(user_id) or (f.visibility = 'public' and f.scan_status in ('clean', 'skipped')))
+    ) r
+        where sqlc.narg(cursor_id)::uuid is null
+            or (not sqlc.arg(ascending)::bool and (r.rank, r.file_id) < (sqlc.arg(cursor_rank)::real, sqlc.narg(cursor_id)))
+            or (sqlc.arg(ascending)::bool and (r.rank, r.file_id) > (sqlc.arg(cursor_rank)::real, sqlc.narg(cursor_id)))
+        order by
+            case when sqlc.arg(ascending)::bool then r.rank end,
+            case when sqlc.arg(ascending)::bool then r.file_id end,
+            r.rank desc,
+            r.file_id desc
+        limit sqlc.arg(page_limit) offset sqlc.arg(page_offset)
+) m
+order by
+    case when sqlc.arg(ascending)::bool then m.rank end,
+    case when sqlc.arg(ascending)::bool then m.file_id end,
+    m.rank desc,
+    m.file_id desc;
+
+-- name: CountSearchFiles :one
+select count(*)
+from files
+    where search_vector @@ websearch_to_tsquery('english', sqlc.arg(query))
+        and is_deleted = false
+        and (user_id = sqlc.narg(user_id) or (visibility = 'public' and scan_status in ('clean', 'skipped')));
+
+-- name: UpdateFileExtractedText :exec
+-- Stores text extracted from a file's content, unless the file has since been changed to different content.
+-- The version is left unchanged as the update is not an edit by the owner.
+update files
+    set extracted_text = $1
+where file_id = $2
+    and storage_key = $3;
@@ -0,0 +1,43 @@
+-- name: CreateSession :exec
+insert into sessions (session_id, user_id, user_agent, ip_address)
+    values ($1, $2, $3, $4);
+
+-- name: GetSessionDeviceCounts :one
+-- Counts the sessions of the user, and those started from the user agent.
+select
+    count(*) as sessions,
+    count(*) filter (where user_agent = sqlc.arg(user_agent)) as device_sessions
+from sessions
+    where user_id = sqlc.arg(user_id);
+
+-- name: TouchSession :exec
+update sessions
+    set last_used_at = now(),
+        ip_address = $2
+where session_id = $1;
+
+-- name: ListActiveSessions :many
+-- Sessions with a refresh token which can still be used.
+select
+    s.session_id,
+    s.user_agent,
+    s.ip_address,
+    s.created_at,
+    s.last_used_at
+from sessions s
+    where s.user_id = $1
+        and exists (
+            select 1 from refresh_tokens rt
+                where rt.family_id = s.session_id
+                    and rt.revoked = false
+                    and rt.rotated_at is null
+                    and rt.expires_at > $2
+        )
+order by s.last_used_at desc;
+
+-- name: RevokeSession :execrows
+update refresh_tokens
+    set revoked = true
+where family_id = $1
+    and user_id = $2
+    and revoked = false;
@@ -0,0 +1,56 @@
+-- name: CreateShareLink :one
+insert into share_links (file_id, user_id, token_hash, password_hash, expires_at, max_downloads)
+    values ($1, $2, $3, $4, $5, $6)
+returning share_link_id, expires_at, max_downloads, download_count, created_at;
+
+-- name: ListFileShareLinks :many
+select
+    share_link_id,
+    (password_hash is not null)::boolean as has_password,
+    expires_at,
+    max_downloads,
+    download_count,
+    is_revoked,
+    revoked_at,
+    last_accessed_at,
+    created_at
+from share_links
+    where file_id = $1
+        and user_id = $2
+order by created_at desc;
+
+-- name: GetShareLinkByToken :one
+select
+    share_link_id,
+    file_id,
+    password_hash,
+    expires_at,
+    max_downloads,
+    download_count,
+    is_revoked,
+    last_accessed_at
+from share_links
+    where token_hash = $1;
+
+-- name: RecordShareLinkAccess :one
+-- Counts a download only while the link is still usable, so concurrent requests can not exceed max_downloads.
+update share_links
+    set
+        download_count = download_count + 1,
+        last_accessed_at = now()
+where share_link_id = $1
+    and is_revoked = false
+    and (expires_at is null or expires_at > now())
+    and (max_downloads is null or download_count < max_downloads)
+returning download_count;
+
+-- name: RevokeShareLink :one
+update share_links
+    set
+        is_revoked = true,
+        revoked_at = now()
+where share_link_id = $1
+    and file_id = $2
+    and user_id = $3
+    and is_revoked = false
+returning share_link_id;
@@ -0,0 +1,71 @@
+-- name: CreateUpload :one
+insert into uploads (user_id, filename, metadata, upload_length, expires_at)
+    values ($1, $2, $3, $4, $5)
+returning upload_id, filename, upload_length, upload_offset, expires_at;
+
+-- name: GetUpload :one
+select
+    upload_id,
+    user_id,
+    file_id,
+    filename,
+    mime_type,
+    metadata,
+    upload_length,
+    upload_offset,
+    hash_state,
+    expires_at
+from uploads
+    where upload_id = $1
+        and user_id = $2;
+
+-- name: ClaimUpload :execrows
+-- Claims the upload for a request writing a chunk at current_offset until claimed_until, so requests writing to it run
+-- one at a time. Nothing is claimed when the upload is at another offset, complete or claimed by a request whose claim has not lapsed.
+update uploads
+    set
+        write_token = sqlc.arg(write_token),
+        write_claimed_until = sqlc.arg(claimed_until)
+where upload_id = sqlc.arg(upload_id)
+    and user_id = sqlc.arg(user_id)
+    and upload_offset = sqlc.arg(current_offset)
+    and file_id is null
+    and (write_claimed_until is null or write_claimed_until < now());
+
+-- name: RenewUploadClaim :execrows
+-- Extends a claim on the upload, nothing is renewed once another request has taken over the lapsed claim.
+update uploads
+    set write_claimed_until = sqlc.arg(claimed_until)
+where upload_id = sqlc.arg(upload_id)
+    and write_token = sqlc.arg(write_token);
+
+-- name: ReleaseUpload :exec
+update uploads
+    set
+        write_token = null,
+        write_claimed_until = null
+where upload_id = sqlc.arg(upload_id)
+    and write_token = sqlc.arg(write_token);
+
+-- name: UpdateUploadOffset :one
+-- Advances the upload offset only if the request still holds its claim and no other request has written to the upload in the meantime.
+update uploads
+    set
+        upload_offset = sqlc.arg(new_offset),
+        hash_state = sqlc.arg(hash_state),
+        mime_type = sqlc.arg(mime_type),
+        expires_at = sqlc.arg(expires_at)
+where upload_id = sqlc.arg(upload_id)
+    and upload_offset = sqlc.arg(current_offset)
+    and write_token = sqlc.arg(write_token)
+returning upload_offset;
+
+-- name: CompleteUpload :exec
+update uploads
+    set file_id = $1
+where upload_id = $2;
+
+-- name: DeleteUpload :exec
+delete from uploads
+    where upload_id = $1
+        and user_id = $2;
@@ -50,8 +50,31 @@ select
     first_name,
     last_name,
     is_verified,
-    role
+    role,
+    trash_retention_days,
+    strip_location
+from users
+    where user_id = $1;
+
+-- name: SetTrashRetention :exec
+-- Sets the number of days a user's deleted files are kept in the trash, null uses the deployment default.
+update users
+    set
+        trash_retention_days = $1,
+        updated_at = now()
+where user_id = $2;
+
+-- name: GetStripLocation :one
+select strip_location
 from users
     where user_id = $1;
 
+-- name: SetStripLocation :exec
+-- Sets whether location metadata is removed from the images the user uploads by default.
+update users
+    set
+        strip_location = $1,
+        updated_at = now()
+where user_id = $2;
+
 
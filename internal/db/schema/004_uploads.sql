@@ -0,0 +1,34 @@
+-- +goose Up
+
+-- Uploads table: Tracks resumable (tus) uploads until all chunks have been received
+CREATE TABLE uploads (
+    upload_id UUID PRIMARY KEY DEFAULT uuidv7(),
+    user_id UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    file_id UUID REFERENCES files(file_id) ON DELETE SET NULL,
+    filename VARCHAR(255) NOT NULL,
+    mime_type VARCHAR(100) NOT NULL DEFAULT 'application/octet-stream',
+    metadata TEXT NOT NULL DEFAULT '',
+    upload_length BIGINT NOT NULL,
+    upload_offset BIGINT NOT NULL DEFAULT 0,
+    hash_state BYTEA,
+    -- A request writing a chunk claims the upload until write_claimed_until, renewing the claim while it streams
+    write_token UUID,
+    write_claimed_until TIMESTAMPTZ,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT uploads_offset_check CHECK (upload_offset >= 0 AND upload_offset <= upload_length)
+);
+
+CREATE INDEX idx_uploads_user_id ON uploads(user_id);
+CREATE INDEX idx_uploads_expires_at ON uploads(expires_at);
+
+CREATE TRIGGER trigger_set_updated_at_uploads
+    BEFORE UPDATE ON uploads
+        FOR EACH ROW
+            EXECUTE FUNCTION set_updated_at();
+
+
+-- +goose Down
+DROP TRIGGER IF EXISTS trigger_set_updated_at_uploads ON uploads;
+DROP TABLE IF EXISTS uploads;
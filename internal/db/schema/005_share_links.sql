@@ -0,0 +1,23 @@
+-- +goose Up
+
+-- Share links table: Grants access to a single file through an unguessable token
+CREATE TABLE share_links (
+    share_link_id UUID PRIMARY KEY DEFAULT uuidv7(),
+    file_id UUID NOT NULL REFERENCES files(file_id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    token_hash BYTEA NOT NULL UNIQUE,
+    password_hash VARCHAR(255),
+    expires_at TIMESTAMPTZ,
+    max_downloads INT CHECK (max_downloads > 0),
+    download_count INT NOT NULL DEFAULT 0,
+    is_revoked BOOLEAN NOT NULL DEFAULT FALSE,
+    revoked_at TIMESTAMPTZ,
+    last_accessed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_share_links_file_id ON share_links(file_id);
+
+
+-- +goose Down
+DROP TABLE IF EXISTS share_links;
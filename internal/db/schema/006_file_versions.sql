@@ -0,0 +1,28 @@
+-- +goose Up
+
+-- current_version is the version_number of the file_versions row whose content the file currently serves.
+-- It is separate from files.version, which is only used for optimistic locking.
+ALTER TABLE files ADD COLUMN current_version INT NOT NULL DEFAULT 1;
+
+-- File versions table: Keeps the content history of a file
+CREATE TABLE file_versions (
+    file_version_id UUID PRIMARY KEY DEFAULT uuidv7(),
+    file_id UUID NOT NULL REFERENCES files(file_id) ON DELETE CASCADE,
+    version_number INT NOT NULL,
+    storage_key TEXT NOT NULL UNIQUE,
+    mime_type VARCHAR(100) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    checksum TEXT NOT NULL,
+    created_by UUID REFERENCES users(user_id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (file_id, version_number)
+);
+
+-- Existing files become the first version of their history
+INSERT INTO file_versions (file_id, version_number, storage_key, mime_type, size_bytes, checksum, created_by, created_at)
+SELECT file_id, 1, storage_key, mime_type, size_bytes, checksum, user_id, created_at FROM files;
+
+
+-- +goose Down
+DROP TABLE IF EXISTS file_versions;
+ALTER TABLE files DROP COLUMN IF EXISTS current_version;
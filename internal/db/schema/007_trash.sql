@@ -0,0 +1,29 @@
+-- +goose Up
+
+-- deleted_at is when a file was moved to the trash and purge_after is when the cleanup job permanently deletes it.
+ALTER TABLE files ADD COLUMN purge_after TIMESTAMPTZ;
+
+-- deleted_at used to hold the purge time of a file, deletions were kept for a fixed 7 days
+UPDATE files
+    SET
+        purge_after = deleted_at,
+        deleted_at = deleted_at - INTERVAL '7 days'
+WHERE is_deleted = TRUE
+    AND deleted_at IS NOT NULL;
+
+CREATE INDEX idx_files_purge_after ON files(purge_after) WHERE is_deleted = TRUE;
+
+-- Overrides the deployment wide trash retention period for a user's files, NULL uses the deployment default
+ALTER TABLE users ADD COLUMN trash_retention_days INT CHECK (trash_retention_days BETWEEN 1 AND 365);
+
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN IF EXISTS trash_retention_days;
+
+DROP INDEX IF EXISTS idx_files_purge_after;
+
+UPDATE files
+    SET deleted_at = purge_after
+WHERE is_deleted = TRUE;
+
+ALTER TABLE files DROP COLUMN IF EXISTS purge_after;
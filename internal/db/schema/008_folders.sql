@@ -0,0 +1,37 @@
+-- +goose Up
+
+-- Folders table: Organises a user's files into a hierarchy, a NULL parent_id is the user's root folder.
+-- Folders only exist in the database, storage keys of files are not affected by moving them.
+CREATE TABLE folders (
+    folder_id UUID PRIMARY KEY DEFAULT uuidv7(),
+    user_id UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    parent_id UUID REFERENCES folders(folder_id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    is_deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    deleted_at TIMESTAMPTZ,
+    purge_after TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    version INT NOT NULL DEFAULT 1,
+    CHECK (parent_id <> folder_id)
+);
+
+-- Folder names are unique within their parent folder
+CREATE UNIQUE INDEX idx_folders_parent_name ON folders(user_id, parent_id, name) NULLS NOT DISTINCT WHERE is_deleted = FALSE;
+CREATE INDEX idx_folders_parent_id ON folders(parent_id);
+CREATE INDEX idx_folders_purge_after ON folders(purge_after) WHERE is_deleted = TRUE;
+
+ALTER TABLE files ADD COLUMN folder_id UUID REFERENCES folders(folder_id) ON DELETE SET NULL;
+CREATE INDEX idx_files_folder_id ON files(folder_id);
+
+CREATE TRIGGER trigger_set_updated_at_folders
+    BEFORE UPDATE ON folders
+        FOR EACH ROW
+            EXECUTE FUNCTION set_updated_at();
+
+
+-- +goose Down
+DROP TRIGGER IF EXISTS trigger_set_updated_at_folders ON folders;
+DROP INDEX IF EXISTS idx_files_folder_id;
+ALTER TABLE files DROP COLUMN IF EXISTS folder_id;
+DROP TABLE IF EXISTS folders;
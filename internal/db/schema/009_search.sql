@@ -0,0 +1,29 @@
+-- +goose Up
+
+-- Text extracted from documents by a background task, searched together with the filename and tags
+ALTER TABLE files ADD COLUMN extracted_text TEXT;
+
+-- +goose StatementBegin
+-- Builds the full-text search document of a file, matches in the filename rank above tags and document text.
+-- Filenames are indexed both whole and split on separators so "q3_report.pdf" matches a search for "report".
+-- Declared immutable so it can be used in a generated column, array_to_string is stable only because of its generic element type.
+CREATE OR REPLACE FUNCTION files_search_vector(filename TEXT, tags TEXT[], extracted_text TEXT)
+RETURNS TSVECTOR AS $$
+    SELECT
+        setweight(to_tsvector('english', filename || ' ' || regexp_replace(filename, '[._-]+', ' ', 'g')), 'A')
+        || setweight(to_tsvector('english', coalesce(array_to_string(tags, ' '), '')), 'B')
+        || setweight(to_tsvector('english', coalesce(extracted_text, '')), 'C');
+$$ LANGUAGE sql IMMUTABLE;
+-- +goose StatementEnd
+
+ALTER TABLE files ADD COLUMN search_vector TSVECTOR
+    GENERATED ALWAYS AS (files_search_vector(filename, tags, extracted_text)) STORED;
+
+CREATE INDEX idx_files_search_vector ON files USING GIN (search_vector);
+
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_files_search_vector;
+ALTER TABLE files DROP COLUMN IF EXISTS search_vector;
+DROP FUNCTION IF EXISTS files_search_vector(TEXT, TEXT[], TEXT);
+ALTER TABLE files DROP COLUMN IF EXISTS extracted_text;
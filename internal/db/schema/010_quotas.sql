@@ -0,0 +1,31 @@
+-- +goose Up
+
+-- Storage limits of the users with a role, a NULL limit is unlimited
+CREATE TABLE role_quotas (
+    role user_role PRIMARY KEY,
+    max_bytes BIGINT CHECK (max_bytes >= 0),
+    max_files BIGINT CHECK (max_files >= 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TRIGGER trigger_set_updated_at_role_quotas
+    BEFORE UPDATE ON role_quotas
+        FOR EACH ROW
+            EXECUTE FUNCTION set_updated_at();
+
+-- Users get 5 GiB and 10,000 files by default, admins are unlimited
+INSERT INTO role_quotas (role, max_bytes, max_files) VALUES
+    ('admin', NULL, NULL),
+    ('user', 5368709120, 10000);
+
+-- Override the limits of the user's role, NULL uses the role limit
+ALTER TABLE users ADD COLUMN quota_max_bytes BIGINT CHECK (quota_max_bytes >= 0);
+ALTER TABLE users ADD COLUMN quota_max_files BIGINT CHECK (quota_max_files >= 0);
+
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN IF EXISTS quota_max_files;
+ALTER TABLE users DROP COLUMN IF EXISTS quota_max_bytes;
+
+DROP TRIGGER IF EXISTS trigger_set_updated_at_role_quotas ON role_quotas;
+DROP TABLE IF EXISTS role_quotas;
@@ -0,0 +1,75 @@
+-- +goose Up
+
+-- Blobs table: Stored content shared by every file version of a user with the same checksum.
+-- Content is only shared between files of the same user, since it is encrypted with the owner's data key.
+-- ref_count is the number of file versions pointing at the blob, a blob whose count drops to zero is never
+-- referenced again and waits for the cleanup job to remove its content from storage.
+CREATE TABLE blobs (
+    storage_key TEXT PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    checksum TEXT NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    ref_count INT NOT NULL DEFAULT 1 CHECK (ref_count >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TRIGGER trigger_set_updated_at_blobs
+    BEFORE UPDATE ON blobs
+        FOR EACH ROW
+            EXECUTE FUNCTION set_updated_at();
+
+-- Files and versions with the same content now share a storage key
+ALTER TABLE files DROP CONSTRAINT IF EXISTS files_storage_key_key;
+ALTER TABLE file_versions DROP CONSTRAINT IF EXISTS file_versions_storage_key_key;
+CREATE INDEX idx_files_storage_key ON files(storage_key);
+CREATE INDEX idx_file_versions_storage_key ON file_versions(storage_key);
+
+-- Existing content becomes blobs, duplicates are repointed to a single copy per user and checksum
+-- and left without references so the cleanup job deletes them from storage
+INSERT INTO blobs (storage_key, user_id, checksum, size_bytes, ref_count)
+SELECT v.storage_key, f.user_id, v.checksum, v.size_bytes, 0
+FROM file_versions v
+    JOIN files f ON f.file_id = v.file_id;
+
+WITH canonical AS (
+    SELECT f.user_id, v.checksum, min(v.storage_key) AS storage_key
+    FROM file_versions v
+        JOIN files f ON f.file_id = v.file_id
+    GROUP BY f.user_id, v.checksum
+)
+UPDATE file_versions v
+    SET storage_key = c.storage_key
+FROM files f, canonical c
+WHERE f.file_id = v.file_id
+    AND c.user_id = f.user_id
+    AND v.checksum = c.checksum
+    AND v.storage_key <> c.storage_key;
+
+UPDATE files f
+    SET storage_key = v.storage_key
+FROM file_versions v
+WHERE v.file_id = f.file_id
+    AND v.version_number = f.current_version
+    AND f.storage_key <> v.storage_key;
+
+UPDATE blobs b
+    SET ref_count = r.refs
+FROM (
+    SELECT storage_key, count(*) AS refs
+    FROM file_versions
+    GROUP BY storage_key
+) r
+WHERE b.storage_key = r.storage_key;
+
+-- New content is shared with the user's referenced blob of the same checksum
+CREATE UNIQUE INDEX idx_blobs_checksum_referenced ON blobs(user_id, checksum) WHERE ref_count > 0;
+CREATE INDEX idx_blobs_unreferenced ON blobs(storage_key) WHERE ref_count = 0;
+
+
+-- +goose Down
+-- Content stays shared between files, so storage keys are no longer made unique again
+DROP INDEX IF EXISTS idx_file_versions_storage_key;
+DROP INDEX IF EXISTS idx_files_storage_key;
+DROP TRIGGER IF EXISTS trigger_set_updated_at_blobs ON blobs;
+DROP TABLE IF EXISTS blobs;
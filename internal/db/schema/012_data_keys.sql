@@ -0,0 +1,30 @@
+-- +goose Up
+
+-- Data keys table: Keys file content is encrypted with at rest, content stored for a user is encrypted with their own key.
+-- Data keys are only stored wrapped by a master key, so rotating the master key re-wraps them
+-- without the encrypted content having to be rewritten.
+CREATE TABLE data_keys (
+    key_id UUID PRIMARY KEY DEFAULT uuidv7(),
+    user_id UUID REFERENCES users(user_id) ON DELETE CASCADE,
+    is_service BOOLEAN NOT NULL DEFAULT FALSE,
+    wrapped_key BYTEA NOT NULL,
+    master_key_id TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TRIGGER trigger_set_updated_at_data_keys
+    BEFORE UPDATE ON data_keys
+        FOR EACH ROW
+            EXECUTE FUNCTION set_updated_at();
+
+-- Content is never shared between users, so the key of a deleted user is removed along with their files
+CREATE UNIQUE INDEX idx_data_keys_user_id ON data_keys(user_id) WHERE user_id IS NOT NULL;
+
+-- Content written in the background, e.g. thumbnails, is encrypted with a single service key
+CREATE UNIQUE INDEX idx_data_keys_service ON data_keys(is_service) WHERE is_service;
+
+
+-- +goose Down
+DROP TRIGGER IF EXISTS trigger_set_updated_at_data_keys ON data_keys;
+DROP TABLE IF EXISTS data_keys;
@@ -0,0 +1,19 @@
+-- +goose Up
+
+-- Files are scanned for malware in the background, their content can only be downloaded or published once it is clean.
+-- skipped marks files stored while no scanner was configured, including every file uploaded before scanning was introduced.
+CREATE TYPE file_scan_status AS ENUM ('pending', 'clean', 'infected', 'skipped');
+
+ALTER TABLE files
+    ADD COLUMN scan_status file_scan_status NOT NULL DEFAULT 'skipped',
+    ADD COLUMN scanned_at TIMESTAMPTZ;
+
+ALTER TABLE files ALTER COLUMN scan_status SET DEFAULT 'pending';
+
+
+-- +goose Down
+ALTER TABLE files
+    DROP COLUMN IF EXISTS scanned_at,
+    DROP COLUMN IF EXISTS scan_status;
+
+DROP TYPE IF EXISTS file_scan_status;
@@ -0,0 +1,18 @@
+-- +goose Up
+
+-- Metadata extracted from images in the background: dimensions, orientation, capture time, camera and location.
+ALTER TABLE files ADD COLUMN image_metadata JSONB;
+
+-- Location metadata is being removed from the current content of a file, until then only its owner can download it.
+ALTER TABLE files ADD COLUMN strip_location_pending BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Removes location metadata from every image the user uploads, unless an upload says otherwise.
+ALTER TABLE users ADD COLUMN strip_location BOOLEAN NOT NULL DEFAULT FALSE;
+
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN IF EXISTS strip_location;
+
+ALTER TABLE files
+    DROP COLUMN IF EXISTS strip_location_pending,
+    DROP COLUMN IF EXISTS image_metadata;
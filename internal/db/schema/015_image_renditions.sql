@@ -0,0 +1,17 @@
+-- +goose Up
+
+-- Image renditions table: Resized and converted copies of images generated on request and cached in storage.
+-- Renditions are generated from a blob and shared by every file holding it, they are removed from storage
+-- when the blob is deleted.
+CREATE TABLE image_renditions (
+    rendition_key TEXT PRIMARY KEY,
+    storage_key TEXT NOT NULL REFERENCES blobs(storage_key) ON UPDATE CASCADE ON DELETE CASCADE,
+    size_bytes BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_image_renditions_storage_key ON image_renditions(storage_key);
+
+
+-- +goose Down
+DROP TABLE IF EXISTS image_renditions;
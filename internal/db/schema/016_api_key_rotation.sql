@@ -0,0 +1,12 @@
+-- +goose Up
+
+-- The secret a rotated API key had before, still accepted until the overlap window of the rotation ends.
+ALTER TABLE api_keys
+    ADD COLUMN previous_key_hash VARCHAR(255),
+    ADD COLUMN previous_key_expires_at TIMESTAMPTZ;
+
+
+-- +goose Down
+ALTER TABLE api_keys
+    DROP COLUMN IF EXISTS previous_key_expires_at,
+    DROP COLUMN IF EXISTS previous_key_hash;
@@ -0,0 +1,35 @@
+-- +goose Up
+
+-- Refresh tokens are stored as SHA-256 hashes, existing tokens are hashed so sessions stay signed in.
+ALTER TABLE refresh_tokens ADD COLUMN token_hash BYTEA;
+UPDATE refresh_tokens SET token_hash = sha256(convert_to(token, 'UTF8'));
+ALTER TABLE refresh_tokens ALTER COLUMN token_hash SET NOT NULL;
+ALTER TABLE refresh_tokens ADD CONSTRAINT refresh_tokens_token_hash_key UNIQUE (token_hash);
+
+DROP INDEX IF EXISTS idx_refresh_tokens_token;
+ALTER TABLE refresh_tokens DROP COLUMN token;
+
+-- Every token issued by refreshing another belongs to the family started by the login, a token is rotated
+-- once it has been exchanged for a new one and presenting it again revokes its whole family.
+ALTER TABLE refresh_tokens ADD COLUMN family_id UUID;
+UPDATE refresh_tokens SET family_id = refresh_token_id;
+ALTER TABLE refresh_tokens ALTER COLUMN family_id SET NOT NULL;
+ALTER TABLE refresh_tokens ADD COLUMN rotated_at TIMESTAMPTZ;
+
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens(family_id);
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_refresh_tokens_user_id;
+DROP INDEX IF EXISTS idx_refresh_tokens_family_id;
+
+ALTER TABLE refresh_tokens
+    DROP COLUMN IF EXISTS rotated_at,
+    DROP COLUMN IF EXISTS family_id;
+
+-- Hashed tokens can not be turned back into tokens, every session has to sign in again
+DELETE FROM refresh_tokens;
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS token_hash;
+ALTER TABLE refresh_tokens ADD COLUMN token VARCHAR(255) UNIQUE NOT NULL;
+CREATE INDEX idx_refresh_tokens_token ON refresh_tokens(token);
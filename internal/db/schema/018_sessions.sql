@@ -0,0 +1,30 @@
+-- +goose Up
+
+-- A session is started by every login and kept alive by refreshing its tokens, it is the family of its refresh tokens.
+-- Sessions are kept after their tokens expire, so logins from devices the user has not used before can be told apart.
+CREATE TABLE sessions (
+    session_id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip_address INET,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_sessions_user_id ON sessions(user_id);
+
+INSERT INTO sessions (session_id, user_id, created_at, last_used_at)
+SELECT family_id, user_id, min(created_at), max(created_at)
+FROM refresh_tokens
+GROUP BY family_id, user_id;
+
+ALTER TABLE refresh_tokens
+    ADD CONSTRAINT refresh_tokens_family_id_fkey
+    FOREIGN KEY (family_id) REFERENCES sessions(session_id) ON DELETE CASCADE;
+
+
+-- +goose Down
+ALTER TABLE refresh_tokens DROP CONSTRAINT IF EXISTS refresh_tokens_family_id_fkey;
+
+DROP INDEX IF EXISTS idx_sessions_user_id;
+DROP TABLE IF EXISTS sessions;
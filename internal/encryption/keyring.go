@@ -0,0 +1,136 @@
+package encryption
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// masterKeySize is the size of master keys in bytes, which are AES-256 keys.
+const masterKeySize = 32
+
+// ErrUnknownMasterKey is returned when a data key was wrapped by a master key the provider does not hold.
+var ErrUnknownMasterKey = errors.New("unknown master key")
+
+// LocalKeyring is a KeyProvider which holds its master keys in memory.
+// New data keys are wrapped with the current master key, retired master keys are only kept
+// to unwrap data keys until the rotation job has re-wrapped them with the current one.
+type LocalKeyring struct {
+	currentID string
+	keys      map[string]cipher.AEAD
+}
+
+// NewLocalKeyring creates a keyring with the hex encoded masterKey as its current master key.
+// retiredKeysFile is the optional path of a file holding retired master keys,
+// one hex encoded key per line, blank lines and lines starting with # are ignored.
+func NewLocalKeyring(masterKey, retiredKeysFile string) (*LocalKeyring, error) {
+	k := &LocalKeyring{keys: make(map[string]cipher.AEAD)}
+
+	currentID, err := k.add(masterKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid master key: %w", err)
+	}
+	k.currentID = currentID
+
+	if retiredKeysFile == "" {
+		return k, nil
+	}
+
+	data, err := os.ReadFile(retiredKeysFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyring file: %w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		if _, err := k.add(entry); err != nil {
+			return nil, fmt.Errorf("invalid master key on line %d of keyring file: %w", line, err)
+		}
+	}
+
+	return k, scanner.Err()
+}
+
+// masterKeyID identifies a master key by its fingerprint, so IDs never need to be configured alongside the keys.
+func masterKeyID(key []byte) string {
+	sum := sha256.Sum256(append([]byte("fileShare master key:"), key...))
+	return hex.EncodeToString(sum[:8])
+}
+
+// add decodes a hex encoded master key and adds it to the keyring, returning its ID.
+func (k *LocalKeyring) add(encoded string) (string, error) {
+	key, err := hex.DecodeString(encoded)
+	if err != nil {
+		return "", errors.New("master key must be hex encoded")
+	}
+	if len(key) != masterKeySize {
+		return "", fmt.Errorf("master key must be %d bytes long", masterKeySize)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+
+	id := masterKeyID(key)
+	k.keys[id] = aead
+
+	return id, nil
+}
+
+// CurrentKeyID returns the ID of the master key new data keys are wrapped with.
+func (k *LocalKeyring) CurrentKeyID() string {
+	return k.currentID
+}
+
+// Wrap encrypts a data key with the current master key using AES-GCM.
+// The master key ID is authenticated with the data key, so it can not be swapped for another one.
+func (k *LocalKeyring) Wrap(ctx context.Context, dataKey []byte) (wrapped []byte, masterKeyID string, err error) {
+	aead := k.keys[k.currentID]
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, "", err
+	}
+
+	return aead.Seal(nonce, nonce, dataKey, []byte(k.currentID)), k.currentID, nil
+}
+
+// Unwrap decrypts a data key wrapped by the master key masterKeyID.
+func (k *LocalKeyring) Unwrap(ctx context.Context, wrapped []byte, masterKeyID string) ([]byte, error) {
+	aead, ok := k.keys[masterKeyID]
+	if !ok {
+		return nil, fmt.Errorf("%w %s", ErrUnknownMasterKey, masterKeyID)
+	}
+
+	if len(wrapped) < aead.NonceSize() {
+		return nil, errors.New("wrapped data key is too short")
+	}
+
+	nonce, sealed := wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():]
+	dataKey, err := aead.Open(nil, nonce, sealed, []byte(masterKeyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+
+	return dataKey, nil
+}
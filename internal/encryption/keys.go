@@ -0,0 +1,227 @@
+package encryption
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/i-christian/fileShare/internal/database"
+	"github.com/i-christian/fileShare/internal/filestore"
+	"github.com/i-christian/fileShare/internal/utils"
+)
+
+// dataKeySize is the size of data keys in bytes.
+const dataKeySize = 32
+
+// KeyService manages the data keys content is encrypted with and implements filestore.KeyResolver.
+// Every user gets a data key when content is first stored for them, content stored without an owner,
+// such as thumbnails, uses a single service key.
+type KeyService struct {
+	db       *database.Queries
+	provider KeyProvider
+	logger   *slog.Logger
+
+	mu sync.RWMutex
+	// keys caches unwrapped data keys by their ID, a data key never changes once created.
+	keys map[uuid.UUID][]byte
+	// owners caches the ID of each owner's data key, the service key is cached under uuid.Nil.
+	owners map[uuid.UUID]uuid.UUID
+}
+
+// NewKeyService is a constructor for KeyService.
+func NewKeyService(db *database.Queries, provider KeyProvider, logger *slog.Logger) *KeyService {
+	return &KeyService{
+		db:       db,
+		provider: provider,
+		logger:   logger,
+		keys:     make(map[uuid.UUID][]byte),
+		owners:   make(map[uuid.UUID]uuid.UUID),
+	}
+}
+
+// cached returns the data key of the owner from the cache.
+func (s *KeyService) cached(owner uuid.UUID) (uuid.UUID, []byte, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keyID, ok := s.owners[owner]
+	if !ok {
+		return uuid.Nil, nil, false
+	}
+
+	key, ok := s.keys[keyID]
+	return keyID, key, ok
+}
+
+// cache stores an unwrapped data key, along with its owner when there is one.
+func (s *KeyService) cache(owner *uuid.UUID, keyID uuid.UUID, key []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys[keyID] = key
+	if owner != nil {
+		s.owners[*owner] = keyID
+	}
+}
+
+// EncryptionKey returns the data key of the owner attached to ctx with filestore.WithOwner,
+// or the service key when there is none. The data key is created on first use.
+func (s *KeyService) EncryptionKey(ctx context.Context) (uuid.UUID, []byte, error) {
+	owner, _ := filestore.OwnerFromContext(ctx)
+	if keyID, key, ok := s.cached(owner); ok {
+		return keyID, key, nil
+	}
+
+	// A key created concurrently for the same owner is picked up on the next attempt
+	for range 3 {
+		keyID, wrapped, masterKeyID, err := s.ownerKey(ctx, owner)
+		if err == nil {
+			key, err := s.provider.Unwrap(ctx, wrapped, masterKeyID)
+			if err != nil {
+				return uuid.Nil, nil, err
+			}
+
+			s.cache(&owner, keyID, key)
+			return keyID, key, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, nil, err
+		}
+
+		keyID, key, err := s.createKey(ctx, owner)
+		if err == nil {
+			s.cache(&owner, keyID, key)
+			return keyID, key, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, nil, err
+		}
+	}
+
+	return uuid.Nil, nil, fmt.Errorf("failed to create data key for owner %s", owner)
+}
+
+// ownerKey fetches the wrapped data key of the owner, uuid.Nil being the service.
+func (s *KeyService) ownerKey(ctx context.Context, owner uuid.UUID) (keyID uuid.UUID, wrapped []byte, masterKeyID string, err error) {
+	if owner == uuid.Nil {
+		k, err := s.db.GetServiceDataKey(ctx)
+		return k.KeyID, k.WrappedKey, k.MasterKeyID, err
+	}
+
+	k, err := s.db.GetUserDataKey(ctx, uuid.NullUUID{UUID: owner, Valid: true})
+	return k.KeyID, k.WrappedKey, k.MasterKeyID, err
+}
+
+// createKey generates a data key for the owner and stores it wrapped by the current master key.
+// sql.ErrNoRows is returned when the owner's key was created concurrently.
+func (s *KeyService) createKey(ctx context.Context, owner uuid.UUID) (uuid.UUID, []byte, error) {
+	key := make([]byte, dataKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	wrapped, masterKeyID, err := s.provider.Wrap(ctx, key)
+	if err != nil {
+		return uuid.Nil, nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+
+	keyID, err := s.db.CreateDataKey(ctx, database.CreateDataKeyParams{
+		UserID:      uuid.NullUUID{UUID: owner, Valid: owner != uuid.Nil},
+		IsService:   owner == uuid.Nil,
+		WrappedKey:  wrapped,
+		MasterKeyID: masterKeyID,
+	})
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	return keyID, key, nil
+}
+
+// DecryptionKey returns the data key identified by keyID.
+func (s *KeyService) DecryptionKey(ctx context.Context, keyID uuid.UUID) ([]byte, error) {
+	s.mu.RLock()
+	key, ok := s.keys[keyID]
+	s.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+
+	k, err := s.db.GetDataKey(ctx, keyID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, utils.ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	key, err = s.provider.Unwrap(ctx, k.WrappedKey, k.MasterKeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.cache(nil, keyID, key)
+	return key, nil
+}
+
+// RewrapDataKeys re-wraps every data key which is not wrapped by the current master key, `batchSize` keys at a time.
+// Only the wrapped keys change, content encrypted with the data keys is left as it is.
+// Keys which fail to be re-wrapped, e.g. because their master key is no longer in the keyring, are counted and skipped.
+func (s *KeyService) RewrapDataKeys(ctx context.Context, batchSize int32) (rewrapped, failed int, err error) {
+	currentID := s.provider.CurrentKeyID()
+	after := uuid.Nil
+
+	for {
+		keys, err := s.db.ListDataKeysToRewrap(ctx, database.ListDataKeysToRewrapParams{
+			CurrentMasterKeyID: currentID,
+			AfterKeyID:         after,
+			BatchSize:          batchSize,
+		})
+		if err != nil {
+			return rewrapped, failed, fmt.Errorf("failed to fetch data keys to rewrap: %w", err)
+		}
+
+		for _, k := range keys {
+			after = k.KeyID
+
+			if err := s.rewrap(ctx, k); err != nil {
+				s.logger.Error("failed to rewrap data key", "key_id", k.KeyID, "master_key_id", k.MasterKeyID, "error", err)
+				failed++
+				continue
+			}
+			rewrapped++
+		}
+
+		if len(keys) < int(batchSize) {
+			return rewrapped, failed, nil
+		}
+	}
+}
+
+// rewrap unwraps a data key with its old master key and stores it wrapped by the current one.
+func (s *KeyService) rewrap(ctx context.Context, k database.ListDataKeysToRewrapRow) error {
+	key, err := s.provider.Unwrap(ctx, k.WrappedKey, k.MasterKeyID)
+	if err != nil {
+		return err
+	}
+
+	wrapped, masterKeyID, err := s.provider.Wrap(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	// A key re-wrapped concurrently by another worker is left as it is
+	_, err = s.db.RewrapDataKey(ctx, database.RewrapDataKeyParams{
+		WrappedKey:          wrapped,
+		MasterKeyID:         masterKeyID,
+		KeyID:               k.KeyID,
+		PreviousMasterKeyID: k.MasterKeyID,
+	})
+
+	return err
+}
@@ -0,0 +1,65 @@
+// Package encryption manages the keys stored file content is encrypted with.
+// Content is encrypted with per-user data keys, which are only ever stored wrapped by a master key held by a KeyProvider.
+package encryption
+
+import (
+	"context"
+	"log/slog"
+	"os"
+
+	"github.com/i-christian/fileShare/internal/utils"
+)
+
+// ProviderType defines key providers supported by application
+type ProviderType string
+
+const (
+	ProviderLocal ProviderType = "local"
+)
+
+// KeyProvider wraps and unwraps data keys with the master keys it holds.
+// The local keyring holds master keys in memory, a provider backed by a key management service
+// implements the same operations as remote calls, without the master keys ever leaving the service.
+type KeyProvider interface {
+	// CurrentKeyID returns the ID of the master key new data keys are wrapped with.
+	CurrentKeyID() string
+
+	// Wrap encrypts a data key with the current master key, returning the ID of the master key used.
+	Wrap(ctx context.Context, dataKey []byte) (wrapped []byte, masterKeyID string, err error)
+
+	// Unwrap decrypts a data key which was wrapped by the master key masterKeyID.
+	Unwrap(ctx context.Context, wrapped []byte, masterKeyID string) ([]byte, error)
+}
+
+// SetUpKeyProvider initializes the key provider based on env config.
+// It returns nil when no master key is configured, in which case content is stored unencrypted.
+func SetUpKeyProvider(logger *slog.Logger) KeyProvider {
+	masterKey := utils.GetEnvOrFile("ENCRYPTION_MASTER_KEY")
+	if masterKey == "" {
+		logger.Warn("ENCRYPTION_MASTER_KEY is not set, file content is stored unencrypted")
+		return nil
+	}
+
+	providerType := ProviderType(utils.GetEnvOrFile("ENCRYPTION_KEY_PROVIDER"))
+	if providerType == "" {
+		providerType = ProviderLocal
+	}
+
+	switch providerType {
+	case ProviderLocal:
+		keyring, err := NewLocalKeyring(masterKey, utils.GetEnvOrFile("ENCRYPTION_KEYRING_FILE"))
+		if err != nil {
+			utils.WriteServerError(logger, "failed to load encryption keyring", err)
+			os.Exit(1)
+		}
+
+		logger.Info("Initialised local encryption keyring", "master key", keyring.CurrentKeyID())
+		return keyring
+
+	default:
+		logger.Error("unsupported encryption key provider", "provider", providerType)
+		os.Exit(1)
+	}
+
+	return nil
+}
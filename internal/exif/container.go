@@ -0,0 +1,179 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+type blockKind int
+
+const (
+	exifBlock blockKind = iota
+	// xmpBlock is an XMP packet, which may repeat the EXIF location and hold place names
+	xmpBlock
+	// iptcBlock is a Photoshop resource block holding IPTC metadata, which may hold place names
+	iptcBlock
+)
+
+type format int
+
+const (
+	formatTIFF format = iota
+	formatJPEG
+	formatPNG
+	formatWebP
+)
+
+// block is a metadata segment of a JPEG, or a metadata chunk of a PNG or WebP image.
+// start and end are the bounds of the whole segment in the image, dataStart and dataEnd those of its payload.
+type block struct {
+	kind      blockKind
+	start     int
+	end       int
+	dataStart int
+	dataEnd   int
+}
+
+var (
+	jpegExifPrefix     = []byte("Exif\x00\x00")
+	jpegXMPPrefix      = []byte("http://ns.adobe.com/xap/1.0/\x00")
+	jpegExtendedPrefix = []byte("http://ns.adobe.com/xmp/extension/\x00")
+	jpegIPTCPrefix     = []byte("Photoshop 3.0\x00")
+	pngSignature       = []byte("\x89PNG\r\n\x1a\n")
+	pngXMPKeyword      = []byte("XML:com.adobe.xmp\x00")
+)
+
+// scan finds the metadata blocks of an image.
+func scan(data []byte) (format, []block, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xff, 0xd8}):
+		blocks, err := scanJPEG(data)
+		return formatJPEG, blocks, err
+	case bytes.HasPrefix(data, pngSignature):
+		blocks, err := scanPNG(data)
+		return formatPNG, blocks, err
+	case len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		blocks, err := scanWebP(data)
+		return formatWebP, blocks, err
+	case bytes.HasPrefix(data, []byte("II*\x00")), bytes.HasPrefix(data, []byte("MM\x00*")):
+		return formatTIFF, []block{{kind: exifBlock, end: len(data), dataEnd: len(data)}}, nil
+	default:
+		return 0, nil, ErrUnsupported
+	}
+}
+
+// scanJPEG reads the segments up to the start of the image data, which metadata segments precede.
+func scanJPEG(data []byte) ([]block, error) {
+	var blocks []block
+
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xff {
+			return nil, fmt.Errorf("%w: invalid JPEG marker", ErrMalformed)
+		}
+
+		marker := data[pos+1]
+		switch {
+		case marker == 0xff:
+			// Markers may be preceded by fill bytes
+			pos++
+			continue
+		case marker == 0x01, marker >= 0xd0 && marker <= 0xd8:
+			// Markers without a length
+			pos += 2
+			continue
+		case marker == 0xd9, marker == 0xda:
+			return blocks, nil
+		}
+
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return nil, fmt.Errorf("%w: JPEG segment out of range", ErrMalformed)
+		}
+
+		payload := data[pos+4 : end]
+		b := block{start: pos, end: end, dataStart: pos + 4, dataEnd: end}
+		switch {
+		case marker == 0xe1 && bytes.HasPrefix(payload, jpegExifPrefix):
+			b.kind = exifBlock
+			b.dataStart += len(jpegExifPrefix)
+			blocks = append(blocks, b)
+		case marker == 0xe1 && (bytes.HasPrefix(payload, jpegXMPPrefix) || bytes.HasPrefix(payload, jpegExtendedPrefix)):
+			b.kind = xmpBlock
+			blocks = append(blocks, b)
+		case marker == 0xed && bytes.HasPrefix(payload, jpegIPTCPrefix):
+			b.kind = iptcBlock
+			blocks = append(blocks, b)
+		}
+
+		pos = end
+	}
+
+	return blocks, nil
+}
+
+// scanPNG reads the chunks of a PNG image, a chunk's payload is followed by its CRC.
+func scanPNG(data []byte) ([]block, error) {
+	var blocks []block
+
+	pos := len(pngSignature)
+	for pos+12 <= len(data) {
+		length := uint64(binary.BigEndian.Uint32(data[pos:]))
+		if uint64(pos)+12+length > uint64(len(data)) {
+			return nil, fmt.Errorf("%w: PNG chunk out of range", ErrMalformed)
+		}
+
+		chunkType := string(data[pos+4 : pos+8])
+		dataEnd := pos + 8 + int(length)
+		b := block{start: pos, end: dataEnd + 4, dataStart: pos + 8, dataEnd: dataEnd}
+		switch {
+		case chunkType == "eXIf":
+			b.kind = exifBlock
+			blocks = append(blocks, b)
+		case chunkType == "iTXt" && bytes.HasPrefix(data[b.dataStart:dataEnd], pngXMPKeyword):
+			b.kind = xmpBlock
+			blocks = append(blocks, b)
+		case chunkType == "IEND":
+			return blocks, nil
+		}
+
+		pos = b.end
+	}
+
+	return blocks, nil
+}
+
+// scanWebP reads the chunks of a WebP image, chunks are padded to an even size.
+func scanWebP(data []byte) ([]block, error) {
+	var blocks []block
+
+	pos := 12
+	for pos+8 <= len(data) {
+		size := uint64(binary.LittleEndian.Uint32(data[pos+4:]))
+		if uint64(pos)+8+size > uint64(len(data)) {
+			return nil, fmt.Errorf("%w: WebP chunk out of range", ErrMalformed)
+		}
+
+		dataEnd := pos + 8 + int(size)
+		end := min(dataEnd+int(size%2), len(data))
+		b := block{start: pos, end: end, dataStart: pos + 8, dataEnd: dataEnd}
+		switch string(data[pos : pos+4]) {
+		case "EXIF":
+			b.kind = exifBlock
+			// Some encoders keep the prefix of the JPEG segment
+			if bytes.HasPrefix(data[b.dataStart:dataEnd], jpegExifPrefix) {
+				b.dataStart += len(jpegExifPrefix)
+			}
+			blocks = append(blocks, b)
+		case "XMP ":
+			b.kind = xmpBlock
+			blocks = append(blocks, b)
+		}
+
+		pos = end
+	}
+
+	return blocks, nil
+}
@@ -0,0 +1,300 @@
+// Package exif reads the metadata cameras embed in images and removes location metadata from them.
+// JPEG, PNG, WebP and TIFF images are supported.
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"time"
+)
+
+// MaxImageSize is the largest image metadata is read from or stripped from, images are read into memory.
+const MaxImageSize = 100 << 20
+
+var (
+	ErrUnsupported = errors.New("image format does not support metadata")
+	ErrMalformed   = errors.New("malformed image metadata")
+)
+
+// Metadata is the EXIF metadata of an image, fields missing from the image are left empty.
+type Metadata struct {
+	// Orientation is the EXIF orientation, from 1 for upright images to 8
+	Orientation int `json:"orientation,omitempty"`
+	// CapturedAt is an RFC 3339 timestamp, without a time zone when the camera did not record one
+	CapturedAt string  `json:"captured_at,omitempty"`
+	Camera     *Camera `json:"camera,omitempty"`
+	GPS        *GPS    `json:"gps,omitempty"`
+}
+
+type Camera struct {
+	Make  string `json:"make,omitempty"`
+	Model string `json:"model,omitempty"`
+	Lens  string `json:"lens,omitempty"`
+}
+
+// GPS is the location an image was taken at in decimal degrees, and its altitude in meters if recorded.
+type GPS struct {
+	Latitude  float64  `json:"latitude"`
+	Longitude float64  `json:"longitude"`
+	Altitude  *float64 `json:"altitude,omitempty"`
+}
+
+// Supported reports whether metadata can be read from and stripped from an image of the detected MIME type.
+func Supported(mimeType string) bool {
+	switch mimeType {
+	case "image/jpeg", "image/png", "image/webp", "image/tiff":
+		return true
+	default:
+		return false
+	}
+}
+
+// Read extracts the EXIF metadata of an image, an image without EXIF metadata has empty Metadata.
+func Read(data []byte) (Metadata, error) {
+	_, blocks, err := scan(data)
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	for _, b := range blocks {
+		if b.kind == exifBlock {
+			return readTIFF(data[b.dataStart:b.dataEnd])
+		}
+	}
+
+	return Metadata{}, nil
+}
+
+func readTIFF(data []byte) (Metadata, error) {
+	t, err := newTIFF(data)
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	entries, err := t.ifd0()
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	var m Metadata
+	if e, ok := find(entries, tagOrientation); ok {
+		if orientation, ok := t.uint(e); ok && orientation >= 1 && orientation <= 8 {
+			m.Orientation = int(orientation)
+		}
+	}
+
+	var camera Camera
+	if e, ok := find(entries, tagMake); ok {
+		camera.Make = t.ascii(e)
+	}
+	if e, ok := find(entries, tagModel); ok {
+		camera.Model = t.ascii(e)
+	}
+
+	var captured, offset string
+	if e, ok := find(entries, tagDateTime); ok {
+		captured = t.ascii(e)
+	}
+
+	// A broken sub-IFD only loses the metadata it holds
+	if exifIFD, ok := subIFD(t, entries, tagExifIFD); ok {
+		if e, ok := find(exifIFD, tagDateTimeOriginal); ok {
+			captured = t.ascii(e)
+		}
+		if e, ok := find(exifIFD, tagOffsetTimeOriginal); ok {
+			offset = t.ascii(e)
+		}
+		if e, ok := find(exifIFD, tagLensModel); ok {
+			camera.Lens = t.ascii(e)
+		}
+	}
+
+	m.CapturedAt = captureTime(captured, offset)
+	if camera != (Camera{}) {
+		m.Camera = &camera
+	}
+
+	if gpsIFD, ok := subIFD(t, entries, tagGPSIFD); ok {
+		m.GPS = readGPS(t, gpsIFD)
+	}
+
+	return m, nil
+}
+
+// subIFD reads the IFD an entry of the first IFD points at.
+func subIFD(t *tiff, entries []entry, tag uint16) ([]entry, bool) {
+	e, ok := find(entries, tag)
+	if !ok {
+		return nil, false
+	}
+
+	offset, ok := t.uint(e)
+	if !ok {
+		return nil, false
+	}
+
+	ifd, err := t.ifd(offset)
+	return ifd, err == nil
+}
+
+func readGPS(t *tiff, entries []entry) *GPS {
+	latitude, ok := t.coordinate(entries, tagGPSLatitudeRef, tagGPSLatitude)
+	if !ok {
+		return nil
+	}
+	longitude, ok := t.coordinate(entries, tagGPSLongitudeRef, tagGPSLongitude)
+	if !ok {
+		return nil
+	}
+
+	gps := &GPS{Latitude: latitude, Longitude: longitude}
+	if e, ok := find(entries, tagGPSAltitude); ok {
+		if altitude, ok := t.rationals(e); ok && len(altitude) == 1 {
+			// A reference of 1 is below sea level
+			if ref, ok := find(entries, tagGPSAltitudeRef); ok {
+				if below, _ := t.uint(ref); below == 1 {
+					altitude[0] = -altitude[0]
+				}
+			}
+			gps.Altitude = &altitude[0]
+		}
+	}
+
+	return gps
+}
+
+// captureTime converts an EXIF date and time, and the time zone offset recorded with it, to RFC 3339.
+func captureTime(value, offset string) string {
+	if offset != "" {
+		if t, err := time.Parse("2006:01:02 15:04:05-07:00", value+offset); err == nil {
+			return t.Format(time.RFC3339)
+		}
+	}
+
+	t, err := time.Parse("2006:01:02 15:04:05", value)
+	if err != nil {
+		return ""
+	}
+	return t.Format("2006-01-02T15:04:05")
+}
+
+// StripLocation returns a copy of the image without location metadata: the EXIF GPS IFD is emptied
+// in place, and XMP and IPTC metadata, which may repeat the location or name the place, is removed.
+// changed is false when the image held no location metadata.
+func StripLocation(data []byte) (stripped []byte, changed bool, err error) {
+	f, blocks, err := scan(data)
+	if err != nil {
+		return nil, false, err
+	}
+
+	stripped = bytes.Clone(data)
+
+	var removed []block
+	for _, b := range blocks {
+		if b.kind != exifBlock {
+			removed = append(removed, b)
+			continue
+		}
+
+		t, err := newTIFF(stripped[b.dataStart:b.dataEnd])
+		if err != nil {
+			return nil, false, err
+		}
+
+		wiped, err := wipeGPS(t)
+		if err != nil {
+			return nil, false, err
+		}
+		if !wiped {
+			continue
+		}
+		changed = true
+
+		// The CRC of a PNG chunk covers its type and payload
+		if f == formatPNG {
+			binary.BigEndian.PutUint32(stripped[b.dataEnd:], crc32.ChecksumIEEE(stripped[b.start+4:b.dataEnd]))
+		}
+	}
+
+	if len(removed) == 0 {
+		return stripped, changed, nil
+	}
+
+	return removeBlocks(f, stripped, removed), true, nil
+}
+
+// wipeGPS empties the GPS IFD, zeroing its entries and their values. Leaving the IFD in place keeps
+// every offset in the TIFF data valid, the IFD is read as having no entries.
+func wipeGPS(t *tiff) (bool, error) {
+	entries, err := t.ifd0()
+	if err != nil {
+		return false, err
+	}
+
+	e, ok := find(entries, tagGPSIFD)
+	if !ok {
+		return false, nil
+	}
+
+	offset, ok := t.uint(e)
+	if !ok {
+		return false, ErrMalformed
+	}
+
+	gpsEntries, err := t.ifd(offset)
+	if err != nil {
+		return false, err
+	}
+	if len(gpsEntries) == 0 {
+		return false, nil
+	}
+
+	// Zeroing a value placed over the header would leave data which is no longer TIFF
+	for _, gps := range gpsEntries {
+		if gps.size > 0 && gps.valueOffset < 8 {
+			return false, fmt.Errorf("%w: value of tag %#04x overlaps the header", ErrMalformed, gps.tag)
+		}
+	}
+
+	for _, gps := range gpsEntries {
+		clear(t.value(gps))
+	}
+
+	// With no entries left, the offset of a next IFD is read from the zeroed first entry
+	start := int(offset)
+	clear(t.data[start : start+2+len(gpsEntries)*12])
+
+	return true, nil
+}
+
+// removeBlocks returns the image without the blocks, which are in the order they appear in the image.
+func removeBlocks(f format, data []byte, blocks []block) []byte {
+	out := make([]byte, 0, len(data))
+
+	pos := 0
+	for _, b := range blocks {
+		out = append(out, data[pos:b.start]...)
+		pos = b.end
+	}
+	out = append(out, data[pos:]...)
+
+	if f == formatWebP {
+		binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+
+		// The extended header flags the presence of XMP metadata
+		if len(out) >= 21 && string(out[12:16]) == "VP8X" {
+			out[20] &^= 0x04
+		}
+	}
+
+	return out
+}
+
+// SwapsDimensions reports whether an image with the orientation is displayed rotated by 90 degrees,
+// with its width and height swapped.
+func SwapsDimensions(orientation int) bool {
+	return orientation >= 5
+}
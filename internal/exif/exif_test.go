@@ -0,0 +1,388 @@
+package exif
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/i-christian/fileShare/internal/webp"
+	_ "golang.org/x/image/webp"
+)
+
+// byteOrder both writes into and appends to the TIFF data.
+type byteOrder interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}
+
+// tiffTag is an IFD entry written by buildTIFF, value holds the encoded values.
+type tiffTag struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+func asciiTag(tag uint16, s string) tiffTag {
+	return tiffTag{tag: tag, typ: 2, count: uint32(len(s) + 1), value: append([]byte(s), 0)}
+}
+
+func rationalTag(order byteOrder, tag uint16, values ...uint32) tiffTag {
+	var value []byte
+	for _, v := range values {
+		value = order.AppendUint32(value, v)
+		value = order.AppendUint32(value, 1)
+	}
+	return tiffTag{tag: tag, typ: 5, count: uint32(len(values)), value: value}
+}
+
+// appendIFD appends an IFD holding the tags to data, followed by the values which do not fit in their entries.
+func appendIFD(order byteOrder, data []byte, tags []tiffTag) []byte {
+	valuePos := len(data) + 2 + len(tags)*12 + 4
+
+	var values []byte
+	data = order.AppendUint16(data, uint16(len(tags)))
+	for _, tag := range tags {
+		data = order.AppendUint16(data, tag.tag)
+		data = order.AppendUint16(data, tag.typ)
+		data = order.AppendUint32(data, tag.count)
+		if len(tag.value) <= 4 {
+			data = append(data, tag.value...)
+			data = append(data, make([]byte, 4-len(tag.value))...)
+			continue
+		}
+		data = order.AppendUint32(data, uint32(valuePos+len(values)))
+		values = append(values, tag.value...)
+		if len(values)%2 == 1 {
+			values = append(values, 0)
+		}
+	}
+
+	data = order.AppendUint32(data, 0)
+	return append(data, values...)
+}
+
+// buildTIFF returns EXIF metadata recording the camera and orientation, and a location in the
+// southern and western hemispheres when withGPS is set.
+func buildTIFF(order byteOrder, withGPS bool) []byte {
+	data := []byte("II*\x00")
+	if order == binary.BigEndian {
+		data = []byte("MM\x00*")
+	}
+	data = append(data, make([]byte, 4)...)
+
+	ifd0 := []tiffTag{
+		asciiTag(tagMake, "Canon"),
+		asciiTag(tagModel, "EOS R5"),
+		{tag: tagOrientation, typ: 3, count: 1, value: order.AppendUint16(nil, 6)},
+	}
+
+	if withGPS {
+		gpsOffset := len(data)
+		data = appendIFD(order, data, []tiffTag{
+			asciiTag(tagGPSLatitudeRef, "S"),
+			rationalTag(order, tagGPSLatitude, 33, 52, 3),
+			asciiTag(tagGPSLongitudeRef, "W"),
+			rationalTag(order, tagGPSLongitude, 70, 40, 12),
+			{tag: tagGPSAltitudeRef, typ: 1, count: 1, value: []byte{0}},
+			rationalTag(order, tagGPSAltitude, 520),
+		})
+		ifd0 = append(ifd0, tiffTag{tag: tagGPSIFD, typ: 4, count: 1, value: order.AppendUint32(nil, uint32(gpsOffset))})
+	}
+
+	order.PutUint32(data[4:], uint32(len(data)))
+	return appendIFD(order, data, ifd0)
+}
+
+var testXMP = []byte(`<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:Description photoshop:City="Santiago"/></x:xmpmeta>`)
+
+// testImage returns a small image with a gradient, so that any change to its compressed data shows in its pixels.
+func testImage() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 12))
+	for y := range 12 {
+		for x := range 16 {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x * 16), G: uint8(y * 20), B: uint8(x * y), A: 0xff})
+		}
+	}
+	return img
+}
+
+// jpegSegment returns a JPEG marker segment holding the payload.
+func jpegSegment(marker byte, payload ...[]byte) []byte {
+	data := bytes.Join(payload, nil)
+	segment := []byte{0xff, marker}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(data)+2))
+	return append(segment, data...)
+}
+
+// buildJPEG returns a JPEG image with the EXIF metadata, followed by XMP and IPTC segments when xmp is set.
+func buildJPEG(t testing.TB, tiffData []byte, xmp bool) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, testImage(), &jpeg.Options{Quality: 90}); err != nil {
+		t.Fatal(err)
+	}
+	encoded := buf.Bytes()
+
+	data := append([]byte{}, encoded[:2]...)
+	data = append(data, jpegSegment(0xe1, jpegExifPrefix, tiffData)...)
+	if xmp {
+		data = append(data, jpegSegment(0xe1, jpegXMPPrefix, testXMP)...)
+		data = append(data, jpegSegment(0xed, jpegIPTCPrefix, []byte("8BIM\x04\x04\x00\x00\x00\x00\x00\x00"))...)
+	}
+	return append(data, encoded[2:]...)
+}
+
+// pngChunk returns a PNG chunk holding the payload, followed by its CRC.
+func pngChunk(chunkType string, payload ...[]byte) []byte {
+	data := bytes.Join(payload, nil)
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	chunk = append(chunk, chunkType...)
+	chunk = append(chunk, data...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+// buildPNG returns a PNG image with the EXIF metadata in an eXIf chunk, followed by an XMP chunk when xmp is set.
+func buildPNG(t testing.TB, tiffData []byte, xmp bool) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, testImage()); err != nil {
+		t.Fatal(err)
+	}
+	encoded := buf.Bytes()
+
+	// The metadata chunks are placed after the IHDR chunk, which must come first
+	ihdrEnd := len(pngSignature) + 8 + 13 + 4
+	data := append([]byte{}, encoded[:ihdrEnd]...)
+	data = append(data, pngChunk("eXIf", tiffData)...)
+	if xmp {
+		data = append(data, pngChunk("iTXt", pngXMPKeyword, []byte{0, 0, 0, 0}, testXMP)...)
+	}
+	return append(data, encoded[ihdrEnd:]...)
+}
+
+// webpChunk returns a WebP chunk holding the payload, padded to an even size.
+func webpChunk(fourCC string, payload []byte) []byte {
+	chunk := append([]byte(fourCC), binary.LittleEndian.AppendUint32(nil, uint32(len(payload)))...)
+	chunk = append(chunk, payload...)
+	if len(payload)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+// buildWebP returns an extended WebP image with the EXIF metadata, followed by an XMP chunk when xmp is set.
+func buildWebP(t testing.TB, tiffData []byte, xmp bool) []byte {
+	t.Helper()
+
+	img := testImage()
+	var buf bytes.Buffer
+	if err := webp.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	// The encoder writes a simple file holding only the VP8L chunk
+	bitstream := buf.Bytes()[12:]
+
+	flags := byte(0x08)
+	if xmp {
+		flags |= 0x04
+	}
+	vp8x := []byte{flags, 0, 0, 0}
+	b := img.Bounds()
+	vp8x = binary.LittleEndian.AppendUint32(vp8x, uint32(b.Dx()-1))[:7]
+	vp8x = binary.LittleEndian.AppendUint32(vp8x, uint32(b.Dy()-1))[:10]
+
+	data := []byte("RIFF\x00\x00\x00\x00WEBP")
+	data = append(data, webpChunk("VP8X", vp8x)...)
+	data = append(data, bitstream...)
+	data = append(data, webpChunk("EXIF", tiffData)...)
+	if xmp {
+		data = append(data, webpChunk("XMP ", testXMP)...)
+	}
+	binary.LittleEndian.PutUint32(data[4:], uint32(len(data)-8))
+	return data
+}
+
+// samePixels decodes both images and checks their pixels are identical.
+func samePixels(t *testing.T, want, got []byte) {
+	t.Helper()
+
+	wantImg, _, err := image.Decode(bytes.NewReader(want))
+	if err != nil {
+		t.Fatalf("decoding the original image: %v", err)
+	}
+	gotImg, _, err := image.Decode(bytes.NewReader(got))
+	if err != nil {
+		t.Fatalf("decoding the stripped image: %v", err)
+	}
+
+	if wantImg.Bounds() != gotImg.Bounds() {
+		t.Fatalf("stripped image bounds = %v, want %v", gotImg.Bounds(), wantImg.Bounds())
+	}
+	b := wantImg.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if w, g := wantImg.At(x, y), gotImg.At(x, y); color.NRGBAModel.Convert(w) != color.NRGBAModel.Convert(g) {
+				t.Fatalf("pixel (%d, %d) = %v, want %v", x, y, g, w)
+			}
+		}
+	}
+}
+
+func TestStripLocation(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(t testing.TB, tiffData []byte, xmp bool) []byte
+		order byteOrder
+	}{
+		{"JPEG", buildJPEG, binary.BigEndian},
+		{"PNG", buildPNG, binary.LittleEndian},
+		{"WebP", buildWebP, binary.LittleEndian},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := tt.build(t, buildTIFF(tt.order, true), true)
+
+			m, err := Read(data)
+			if err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+			if m.GPS == nil || m.GPS.Latitude != -33.8675 || m.GPS.Longitude != -70.67 || m.GPS.Altitude == nil || *m.GPS.Altitude != 520 {
+				t.Fatalf("GPS = %+v, want the recorded location", m.GPS)
+			}
+
+			stripped, changed, err := StripLocation(data)
+			if err != nil {
+				t.Fatalf("StripLocation: %v", err)
+			}
+			if !changed {
+				t.Error("StripLocation reported no change")
+			}
+			for _, s := range [][]byte{testXMP, []byte("Santiago"), jpegIPTCPrefix} {
+				if bytes.Contains(stripped, s) {
+					t.Errorf("stripped image still contains %q", s)
+				}
+			}
+
+			samePixels(t, data, stripped)
+
+			m, err = Read(stripped)
+			if err != nil {
+				t.Fatalf("Read of the stripped image: %v", err)
+			}
+			if m.GPS != nil {
+				t.Errorf("stripped image still has GPS %+v", m.GPS)
+			}
+			if m.Orientation != 6 {
+				t.Errorf("Orientation = %d, want 6", m.Orientation)
+			}
+			if m.Camera == nil || *m.Camera != (Camera{Make: "Canon", Model: "EOS R5"}) {
+				t.Errorf("Camera = %+v, want the recorded camera", m.Camera)
+			}
+
+			// Stripping is idempotent
+			again, changed, err := StripLocation(stripped)
+			if err != nil {
+				t.Fatalf("StripLocation of the stripped image: %v", err)
+			}
+			if changed || !bytes.Equal(again, stripped) {
+				t.Error("stripping the stripped image changed it")
+			}
+		})
+	}
+}
+
+func TestStripLocationWithoutLocation(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(t testing.TB, tiffData []byte, xmp bool) []byte
+	}{
+		{"JPEG", buildJPEG},
+		{"PNG", buildPNG},
+		{"WebP", buildWebP},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := tt.build(t, buildTIFF(binary.LittleEndian, false), false)
+
+			stripped, changed, err := StripLocation(data)
+			if err != nil {
+				t.Fatalf("StripLocation: %v", err)
+			}
+			if changed {
+				t.Error("StripLocation reported a change")
+			}
+			if !bytes.Equal(stripped, data) {
+				t.Error("image without location metadata was modified")
+			}
+		})
+	}
+}
+
+func TestStripLocationRejects(t *testing.T) {
+	withGPS := buildTIFF(binary.LittleEndian, true)
+
+	// The GPS IFD pointer is the last entry of the first IFD
+	badPointer := bytes.Clone(withGPS)
+	ifd0 := int(binary.LittleEndian.Uint32(badPointer[4:]))
+	binary.LittleEndian.PutUint32(badPointer[ifd0+2+3*12+8:], uint32(len(badPointer)))
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"unknown format", []byte("GIF89a"), ErrUnsupported},
+		{"truncated JPEG segment", []byte{0xff, 0xd8, 0xff, 0xe1, 0x10, 0x00, 'E'}, ErrMalformed},
+		{"GPS IFD out of range", badPointer, ErrMalformed},
+		{"IFD out of range", []byte("II*\x00\xff\xff\x00\x00"), ErrMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := StripLocation(tt.data); !errors.Is(err, tt.want) {
+				t.Errorf("StripLocation error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// FuzzStripLocation checks that the container and TIFF walkers never read out of range, and that
+// an image stripped without an error reads back without a location.
+func FuzzStripLocation(f *testing.F) {
+	f.Add(buildTIFF(binary.LittleEndian, true))
+	f.Add(buildTIFF(binary.BigEndian, true))
+	f.Add(buildJPEG(f, buildTIFF(binary.BigEndian, true), true))
+	f.Add(buildPNG(f, buildTIFF(binary.LittleEndian, true), true))
+	f.Add(buildWebP(f, buildTIFF(binary.LittleEndian, true), true))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, readErr := Read(data)
+
+		stripped, _, err := StripLocation(data)
+		if err != nil {
+			return
+		}
+
+		m, err := Read(stripped)
+		if err != nil {
+			if readErr == nil {
+				t.Fatalf("Read of the stripped image: %v", err)
+			}
+			return
+		}
+		if m.GPS != nil {
+			t.Fatalf("stripped image still has GPS %+v", m.GPS)
+		}
+	})
+}
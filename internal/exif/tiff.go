@@ -0,0 +1,217 @@
+package exif
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// IFD entry tags read from images or removed when stripping location metadata.
+const (
+	tagMake               = 0x010f
+	tagModel              = 0x0110
+	tagOrientation        = 0x0112
+	tagDateTime           = 0x0132
+	tagExifIFD            = 0x8769
+	tagGPSIFD             = 0x8825
+	tagDateTimeOriginal   = 0x9003
+	tagOffsetTimeOriginal = 0x9011
+	tagLensModel          = 0xa434
+
+	tagGPSLatitudeRef  = 0x0001
+	tagGPSLatitude     = 0x0002
+	tagGPSLongitudeRef = 0x0003
+	tagGPSLongitude    = 0x0004
+	tagGPSAltitudeRef  = 0x0005
+	tagGPSAltitude     = 0x0006
+)
+
+// maxEntries bounds the number of entries read from a single IFD, real images have a few dozen.
+const maxEntries = 1000
+
+// typeSizes are the sizes in bytes of the IFD entry value types, indexed by type.
+var typeSizes = [...]int{
+	1:  1, // BYTE
+	2:  1, // ASCII
+	3:  2, // SHORT
+	4:  4, // LONG
+	5:  8, // RATIONAL
+	6:  1, // SBYTE
+	7:  1, // UNDEFINED
+	8:  2, // SSHORT
+	9:  4, // SLONG
+	10: 8, // SRATIONAL
+	11: 4, // FLOAT
+	12: 8, // DOUBLE
+}
+
+// tiff is the TIFF structure EXIF metadata is stored in. data is a subslice of the image,
+// so changes made to it while stripping are made to the image itself.
+type tiff struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+// entry is an IFD entry. offset is the position of the entry in the TIFF data and
+// valueOffset the position of its value, which is inside the entry for values of up to 4 bytes.
+type entry struct {
+	tag         uint16
+	typ         uint16
+	count       uint32
+	offset      int
+	valueOffset int
+	size        int
+}
+
+func newTIFF(data []byte) (*tiff, error) {
+	if len(data) < 8 {
+		return nil, ErrMalformed
+	}
+
+	t := &tiff{data: data}
+	switch string(data[:4]) {
+	case "II*\x00":
+		t.order = binary.LittleEndian
+	case "MM\x00*":
+		t.order = binary.BigEndian
+	default:
+		return nil, ErrMalformed
+	}
+
+	return t, nil
+}
+
+// ifd0 reads the entries of the first IFD, which describes the main image.
+func (t *tiff) ifd0() ([]entry, error) {
+	return t.ifd(t.order.Uint32(t.data[4:8]))
+}
+
+// ifd reads the entries of the IFD at offset.
+func (t *tiff) ifd(offset uint32) ([]entry, error) {
+	if uint64(offset)+2 > uint64(len(t.data)) {
+		return nil, fmt.Errorf("%w: IFD offset out of range", ErrMalformed)
+	}
+
+	start := int(offset)
+	count := int(t.order.Uint16(t.data[start:]))
+	if count > maxEntries || start+2+count*12 > len(t.data) {
+		return nil, fmt.Errorf("%w: IFD entries out of range", ErrMalformed)
+	}
+
+	entries := make([]entry, 0, count)
+	for i := range count {
+		pos := start + 2 + i*12
+		e := entry{
+			tag:    t.order.Uint16(t.data[pos:]),
+			typ:    t.order.Uint16(t.data[pos+2:]),
+			count:  t.order.Uint32(t.data[pos+4:]),
+			offset: pos,
+		}
+
+		// Values of unknown types are kept but never read
+		if int(e.typ) < len(typeSizes) && typeSizes[e.typ] > 0 {
+			size := uint64(typeSizes[e.typ]) * uint64(e.count)
+			if size <= 4 {
+				e.valueOffset = pos + 8
+			} else {
+				valueOffset := uint64(t.order.Uint32(t.data[pos+8:]))
+				if valueOffset+size > uint64(len(t.data)) {
+					return nil, fmt.Errorf("%w: value of tag %#04x out of range", ErrMalformed, e.tag)
+				}
+				e.valueOffset = int(valueOffset)
+			}
+			e.size = int(size)
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+// find returns the entry with the tag, if any.
+func find(entries []entry, tag uint16) (entry, bool) {
+	for _, e := range entries {
+		if e.tag == tag {
+			return e, true
+		}
+	}
+	return entry{}, false
+}
+
+func (t *tiff) value(e entry) []byte {
+	return t.data[e.valueOffset : e.valueOffset+e.size]
+}
+
+// ascii returns the value of an ASCII entry without its NUL terminator and padding.
+func (t *tiff) ascii(e entry) string {
+	if e.typ != 2 {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimRight(string(t.value(e)), "\x00"))
+}
+
+// uint returns the first value of a BYTE, SHORT or LONG entry.
+func (t *tiff) uint(e entry) (uint32, bool) {
+	if e.size == 0 {
+		return 0, false
+	}
+
+	switch e.typ {
+	case 1, 7:
+		return uint32(t.data[e.valueOffset]), true
+	case 3:
+		return uint32(t.order.Uint16(t.data[e.valueOffset:])), true
+	case 4:
+		return t.order.Uint32(t.data[e.valueOffset:]), true
+	default:
+		return 0, false
+	}
+}
+
+// rationals returns the values of a RATIONAL entry, a zero denominator makes the entry invalid.
+func (t *tiff) rationals(e entry) ([]float64, bool) {
+	if e.typ != 5 {
+		return nil, false
+	}
+
+	values := make([]float64, e.count)
+	for i := range values {
+		pos := e.valueOffset + i*8
+		numerator := t.order.Uint32(t.data[pos:])
+		denominator := t.order.Uint32(t.data[pos+4:])
+		if denominator == 0 {
+			return nil, false
+		}
+		values[i] = float64(numerator) / float64(denominator)
+	}
+
+	return values, true
+}
+
+// coordinate converts a GPS latitude or longitude in degrees, minutes and seconds to decimal degrees,
+// negative for the southern and western hemispheres.
+func (t *tiff) coordinate(entries []entry, refTag, valueTag uint16) (float64, bool) {
+	ref, ok := find(entries, refTag)
+	if !ok {
+		return 0, false
+	}
+	value, ok := find(entries, valueTag)
+	if !ok {
+		return 0, false
+	}
+
+	dms, ok := t.rationals(value)
+	if !ok || len(dms) != 3 {
+		return 0, false
+	}
+
+	degrees := dms[0] + dms[1]/60 + dms[2]/3600
+	switch t.ascii(ref) {
+	case "S", "W":
+		degrees = -degrees
+	}
+
+	return math.Round(degrees*1e6) / 1e6, true
+}
@@ -0,0 +1,110 @@
+// Package extract pulls plain text out of documents so their content can be indexed for search.
+package extract
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// MaxDocumentSize is the largest document text is extracted from, documents are read into memory.
+	MaxDocumentSize = 20 << 20
+	// MaxTextLength is the maximum number of bytes of text kept from a document.
+	MaxTextLength = 256 << 10
+)
+
+var ErrUnsupported = errors.New("text extraction is not supported for this file type")
+
+// officeParts lists the archive members holding the text of zip based office documents by file extension.
+var officeParts = map[string]func(name string) bool{
+	".docx": func(name string) bool { return name == "word/document.xml" },
+	".pptx": func(name string) bool {
+		return strings.HasPrefix(name, "ppt/slides/slide") && strings.HasSuffix(name, ".xml")
+	},
+	".xlsx": func(name string) bool { return name == "xl/sharedStrings.xml" },
+	".odt":  func(name string) bool { return name == "content.xml" },
+	".ods":  func(name string) bool { return name == "content.xml" },
+	".odp":  func(name string) bool { return name == "content.xml" },
+}
+
+// Supported reports whether text can be extracted from a file with the detected MIME type and filename.
+func Supported(mimeType, filename string) bool {
+	switch {
+	case strings.HasPrefix(mimeType, "text/"):
+		return true
+	case strings.HasPrefix(mimeType, "application/pdf"):
+		return true
+	case strings.HasPrefix(mimeType, "application/zip"):
+		_, ok := officeParts[strings.ToLower(filepath.Ext(filename))]
+		return ok
+	default:
+		return false
+	}
+}
+
+// Text extracts up to MaxTextLength bytes of text from a document.
+// Extraction from PDFs is best effort, text drawn with embedded CID fonts can not be recovered.
+func Text(r io.Reader, mimeType, filename string) (string, error) {
+	if !Supported(mimeType, filename) {
+		return "", ErrUnsupported
+	}
+
+	if strings.HasPrefix(mimeType, "text/") {
+		data, err := io.ReadAll(io.LimitReader(r, MaxTextLength))
+		if err != nil {
+			return "", err
+		}
+		return clean(data), nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, MaxDocumentSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > MaxDocumentSize {
+		return "", errors.New("document is too large for text extraction")
+	}
+
+	var text []byte
+	if strings.HasPrefix(mimeType, "application/pdf") {
+		text = pdfText(data)
+	} else {
+		text, err = officeText(data, officeParts[strings.ToLower(filepath.Ext(filename))])
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return clean(text), nil
+}
+
+// clean converts extracted text to valid UTF-8 without control characters,
+// collapsing whitespace and truncating it to MaxTextLength bytes.
+func clean(data []byte) string {
+	var b strings.Builder
+	space := true
+	for len(data) > 0 && b.Len() < MaxTextLength {
+		r, size := utf8.DecodeRune(data)
+		data = data[size:]
+
+		switch {
+		case r == utf8.RuneError && size <= 1:
+			continue
+		case unicode.IsSpace(r) || unicode.IsControl(r):
+			if !space {
+				b.WriteByte(' ')
+				space = true
+			}
+		default:
+			b.WriteRune(r)
+			space = false
+		}
+	}
+
+	return string(bytes.TrimSpace([]byte(b.String())))
+}
@@ -0,0 +1,152 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTextPDF(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		want string
+	}{
+		{"uncompressed content stream", "plain.pdf", "Quarterly report Revenue grew (again) by 12%"},
+		{"compressed pages with TJ arrays and UTF-16", "compressed.pdf", "Compressed Café second page"},
+		{"images, unsupported filters and hex strings", "skipped.pdf", "Visible"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			got, err := Text(f, "application/pdf", tt.file)
+			if err != nil {
+				t.Fatalf("Text: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Text = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// flateStream returns a PDF content stream object compressed with FlateDecode.
+func flateStream(t *testing.T, content []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	if _, err := zw.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	obj := fmt.Appendf(nil, "1 0 obj\n<< /Filter /FlateDecode /Length %d >>\nstream\n", buf.Len())
+	obj = append(obj, buf.Bytes()...)
+	return append(obj, "\nendstream\nendobj\n"...)
+}
+
+func TestPDFTextLimits(t *testing.T) {
+	t.Run("text is capped within a single stream", func(t *testing.T) {
+		content := bytes.Repeat([]byte("(lorem ipsum dolor) Tj "), MaxTextLength)
+		text := pdfText(flateStream(t, content))
+		if len(text) > MaxTextLength+64 {
+			t.Errorf("extracted %d bytes, want at most about %d", len(text), MaxTextLength)
+		}
+	})
+
+	t.Run("inflated size is capped across streams", func(t *testing.T) {
+		// Each stream inflates to just over half of MaxDocumentSize, the limit is reached within the second one
+		padding := bytes.Repeat([]byte{' '}, MaxDocumentSize/2)
+		var data []byte
+		for i := range 5 {
+			data = append(data, flateStream(t, append(padding, fmt.Sprintf("(stream%d) Tj", i)...))...)
+		}
+
+		text := string(pdfText(data))
+		if !strings.Contains(text, "stream0") {
+			t.Errorf("text of the first stream is missing from %q", text)
+		}
+		for _, later := range []string{"stream2", "stream3", "stream4"} {
+			if strings.Contains(text, later) {
+				t.Errorf("text %q was decoded after the inflate limit", later)
+			}
+		}
+	})
+}
+
+// officeDocument returns a zip archive holding the named members.
+func officeDocument(t *testing.T, members map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range members {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestTextOffice(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		members  map[string]string
+		want     string
+	}{
+		{
+			name:     "docx paragraphs",
+			filename: "report.docx",
+			members: map[string]string{
+				"word/document.xml": `<w:document xmlns:w="w"><w:body><w:p><w:r><w:t>First</w:t></w:r></w:p><w:p><w:r><w:t>Second</w:t></w:r></w:p></w:body></w:document>`,
+				"word/styles.xml":   `<w:styles xmlns:w="w"><w:name>Ignored</w:name></w:styles>`,
+			},
+			want: "First Second",
+		},
+		{
+			name:     "pptx slides in order",
+			filename: "deck.pptx",
+			members: map[string]string{
+				"ppt/slides/slide10.xml": `<p:sld xmlns:p="p"><a:p xmlns:a="a"><a:r><a:t>ten</a:t></a:r></a:p></p:sld>`,
+				"ppt/slides/slide2.xml":  `<p:sld xmlns:p="p"><a:p xmlns:a="a"><a:r><a:t>two</a:t></a:r></a:p></p:sld>`,
+				"ppt/slides/slide1.xml":  `<p:sld xmlns:p="p"><a:p xmlns:a="a"><a:r><a:t>one</a:t></a:r></a:p></p:sld>`,
+			},
+			want: "one two ten",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := officeDocument(t, tt.members)
+			got, err := Text(bytes.NewReader(data), "application/zip", tt.filename)
+			if err != nil {
+				t.Fatalf("Text: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Text = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"sort"
+)
+
+// breakElements are the XML elements of office documents which end a paragraph, cell or line of text.
+var breakElements = map[string]bool{
+	"p": true, "h": true, "br": true, "tab": true, "si": true, "tc": true, "line-break": true,
+}
+
+// officeText collects the character data of the archive members selected by isTextPart.
+func officeText(data []byte, isTextPart func(name string) bool) ([]byte, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	var parts []*zip.File
+	for _, f := range archive.File {
+		if isTextPart(f.Name) {
+			parts = append(parts, f)
+		}
+	}
+	if len(parts) == 0 {
+		return nil, errors.New("document does not contain any text")
+	}
+
+	// Slides are numbered slide1.xml, slide2.xml, ... and should be read in that order
+	sort.Slice(parts, func(i, j int) bool {
+		if len(parts[i].Name) != len(parts[j].Name) {
+			return len(parts[i].Name) < len(parts[j].Name)
+		}
+		return parts[i].Name < parts[j].Name
+	})
+
+	var text bytes.Buffer
+	for _, part := range parts {
+		if text.Len() >= MaxTextLength {
+			break
+		}
+
+		rc, err := part.Open()
+		if err != nil {
+			return nil, err
+		}
+		err = xmlText(&text, io.LimitReader(rc, MaxDocumentSize))
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return text.Bytes(), nil
+}
+
+// xmlText writes the character data of an XML document to w, separating paragraphs with new lines.
+func xmlText(w *bytes.Buffer, r io.Reader) error {
+	decoder := xml.NewDecoder(r)
+	for w.Len() < MaxTextLength {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.CharData:
+			w.Write(t)
+		case xml.EndElement:
+			if breakElements[t.Name.Local] {
+				w.WriteByte('\n')
+			}
+		}
+	}
+
+	return nil
+}
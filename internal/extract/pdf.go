@@ -0,0 +1,182 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"regexp"
+	"unicode/utf16"
+)
+
+var (
+	streamStart = regexp.MustCompile(`stream\r?\n`)
+	// skippedStreams marks stream dictionaries which never contain page text, such as fonts, images and object streams.
+	skippedStreams = regexp.MustCompile(`/(Length1|Length2|Length3|FontFile[23]?|Subtype\s*/Image|Type\s*/(XObject|ObjStm|XRef|Metadata|EmbeddedFile))\b`)
+)
+
+// pdfText extracts the text shown by the content streams of a PDF document.
+// At most MaxDocumentSize bytes are inflated across all compressed streams.
+func pdfText(data []byte) []byte {
+	var text bytes.Buffer
+	inflated := int64(0)
+
+	for text.Len() < MaxTextLength {
+		loc := streamStart.FindIndex(data)
+		if loc == nil {
+			break
+		}
+
+		dict := data[max(0, loc[0]-512):loc[0]]
+		if i := bytes.LastIndex(dict, []byte("obj")); i >= 0 {
+			dict = dict[i:]
+		}
+
+		data = data[loc[1]:]
+		end := bytes.Index(data, []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		content := data[:end]
+		// Skip the keyword as well, the stream in "endstream" would otherwise be taken for the start of another stream
+		data = data[end+len("endstream"):]
+
+		if skippedStreams.Match(dict) {
+			continue
+		}
+
+		if bytes.Contains(dict, []byte("/FlateDecode")) {
+			if inflated >= MaxDocumentSize {
+				break
+			}
+			zr, err := zlib.NewReader(bytes.NewReader(content))
+			if err != nil {
+				continue
+			}
+			// Truncated streams still yield the text decoded so far
+			content, _ = io.ReadAll(io.LimitReader(zr, MaxDocumentSize-inflated))
+			zr.Close()
+			inflated += int64(len(content))
+		} else if bytes.Contains(dict, []byte("/Filter")) {
+			continue
+		}
+
+		contentText(&text, content)
+	}
+
+	return text.Bytes()
+}
+
+// contentText writes the strings passed to the text showing operators of a content stream to w,
+// stopping once w holds MaxTextLength bytes.
+// Hex strings are skipped, they usually hold glyph IDs of embedded fonts rather than characters.
+func contentText(w *bytes.Buffer, content []byte) {
+	var pending [][]byte
+
+	for i := 0; i < len(content) && w.Len() < MaxTextLength; {
+		c := content[i]
+		switch {
+		case c == '(':
+			s, n := literalString(content[i:])
+			pending = append(pending, s)
+			i += n
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case isRegular(c):
+			start := i
+			for i < len(content) && isRegular(content[i]) {
+				i++
+			}
+
+			switch string(content[start:i]) {
+			case "Tj", "TJ", "'", "\"":
+				for _, s := range pending {
+					writeString(w, s)
+				}
+				w.WriteByte(' ')
+				pending = pending[:0]
+			case "T*", "Td", "TD", "ET":
+				w.WriteByte('\n')
+				pending = pending[:0]
+			}
+		default:
+			i++
+		}
+	}
+}
+
+// writeString writes a decoded PDF string as UTF-8, strings are either UTF-16BE with a byte order mark or single byte encoded.
+func writeString(w *bytes.Buffer, s []byte) {
+	if len(s) >= 2 && s[0] == 0xfe && s[1] == 0xff {
+		units := make([]uint16, 0, len(s)/2)
+		for i := 2; i+1 < len(s); i += 2 {
+			units = append(units, uint16(s[i])<<8|uint16(s[i+1]))
+		}
+		for _, r := range utf16.Decode(units) {
+			w.WriteRune(r)
+		}
+		return
+	}
+
+	for _, c := range s {
+		w.WriteRune(rune(c))
+	}
+}
+
+// isRegular reports whether c is part of a PDF operator or operand rather than a delimiter or whitespace.
+func isRegular(c byte) bool {
+	switch c {
+	case ' ', '\t', '\r', '\n', '\f', 0, '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return false
+	}
+	return true
+}
+
+// literalString decodes the PDF literal string at the start of data, returning it and the number of bytes consumed.
+func literalString(data []byte) ([]byte, int) {
+	var s []byte
+	depth := 0
+
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch c {
+		case '(':
+			if depth > 0 {
+				s = append(s, c)
+			}
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return s, i + 1
+			}
+			s = append(s, c)
+		case '\\':
+			i++
+			if i >= len(data) {
+				return s, i
+			}
+			switch e := data[i]; e {
+			case 'n', 'r', 't', 'f', 'b':
+				s = append(s, ' ')
+			case '\r', '\n':
+				// Line continuation
+			case '0', '1', '2', '3', '4', '5', '6', '7':
+				v := 0
+				j := i
+				for ; j < len(data) && j < i+3 && data[j] >= '0' && data[j] <= '7'; j++ {
+					v = v*8 + int(data[j]-'0')
+				}
+				s = append(s, byte(v))
+				i = j - 1
+			default:
+				s = append(s, e)
+			}
+		default:
+			s = append(s, c)
+		}
+	}
+
+	return s, len(data)
+}
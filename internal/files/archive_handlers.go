@@ -0,0 +1,105 @@
+package files
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/i-christian/fileShare/internal/utils"
+	"github.com/i-christian/fileShare/internal/utils/security"
+	"github.com/i-christian/fileShare/internal/validator"
+)
+
+// maxArchiveFileIDs is the largest number of files which can be selected by ID for a single archive.
+const maxArchiveFileIDs = 1000
+
+// Archive streams the selected files as a single ZIP or tar.gz archive
+func (h *FileHandler) Archive(w http.ResponseWriter, r *http.Request) {
+	user, ok := security.GetUserFromContext(r)
+	if !ok || user.IsAnonymous() {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	var input struct {
+		FileIDs  []uuid.UUID `json:"file_ids"`
+		FolderID *uuid.UUID  `json:"folder_id"`
+		Tags     []string    `json:"tags"`
+		Format   string      `json:"format"`
+	}
+
+	err := utils.ReadJSON(w, r, &input)
+	if err != nil {
+		utils.BadRequestResponse(w, err)
+		return
+	}
+
+	if input.Format == "" {
+		input.Format = "zip"
+	}
+	tags := validator.NormalizeTags(input.Tags)
+
+	selections := 0
+	for _, selected := range []bool{len(input.FileIDs) > 0, input.FolderID != nil, len(tags) > 0} {
+		if selected {
+			selections++
+		}
+	}
+
+	v := validator.New()
+	v.Check(selections == 1, "selection", "exactly one of file_ids, folder_id or tags must be provided")
+	v.Check(len(input.FileIDs) <= maxArchiveFileIDs, "file_ids", fmt.Sprintf("must not contain more than %d files", maxArchiveFileIDs))
+	validator.ValidateTags(v, "tags", tags)
+	v.Check(validator.PermittedValue(input.Format, "zip", "tar.gz"), "format", "must be zip or tar.gz")
+	if !v.Valid() {
+		utils.FailedValidationResponse(w, v.Errors)
+		return
+	}
+
+	selection := ArchiveSelection{FileIDs: input.FileIDs, Tags: tags}
+	if input.FolderID != nil {
+		selection.FolderID = uuid.NullUUID{UUID: *input.FolderID, Valid: true}
+	}
+
+	entries, err := h.service.PrepareArchive(r.Context(), user.UserID, selection)
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrRecordNotFound):
+			utils.WriteErrorJSON(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, utils.ErrNotPermitted):
+			utils.WriteErrorJSON(w, http.StatusForbidden, err.Error())
+		case errors.Is(err, utils.ErrArchiveTooLarge):
+			utils.FailedValidationResponse(w, map[string]string{"selection": err.Error()})
+		case errors.Is(err, utils.ErrScanPending), errors.Is(err, utils.ErrFileInfected), errors.Is(err, utils.ErrStripPending):
+			withheldResponse(w, err)
+		default:
+			utils.WriteServerError(h.logger, "failed to prepare archive", err)
+			utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+		}
+		return
+	}
+
+	filename, contentType := "files.zip", "application/zip"
+	if input.Format == "tar.gz" {
+		filename, contentType = "files.tar.gz", "application/gzip"
+	}
+
+	// Large archives take longer to stream than the server's write timeout allows
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		utils.WriteServerError(h.logger, "failed to clear the write deadline of an archive download", err)
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(http.StatusOK)
+
+	err = h.service.WriteArchive(r.Context(), w, input.Format, entries)
+	if err != nil {
+		// The archive is incomplete, aborting the response keeps the client from taking it for a whole one
+		utils.WriteServerError(h.logger, "failed to stream archive", err)
+		panic(http.ErrAbortHandler)
+	}
+}
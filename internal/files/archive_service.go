@@ -0,0 +1,338 @@
+package files
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/flate"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/i-christian/fileShare/internal/database"
+	"github.com/i-christian/fileShare/internal/utils"
+)
+
+// maxArchiveFiles is the largest number of files downloaded together as a single archive.
+const maxArchiveFiles = 10000
+
+// ArchiveSelection selects the files downloaded as an archive, either by ID,
+// every file in one of the user's folders and its subfolders, or the user's files with all of the tags.
+type ArchiveSelection struct {
+	FileIDs  []uuid.UUID
+	FolderID uuid.NullUUID
+	Tags     []string
+}
+
+// ArchiveEntry is a file written to an archive. Name is its path in the archive, unique within the archive.
+type ArchiveEntry struct {
+	Name       string
+	MimeType   string
+	StorageKey string
+	SizeBytes  int64
+	Modified   time.Time
+}
+
+// PrepareArchive checks the user can download every selected file, applying the same checks as DownloadFile,
+// and names the entries of the archive. Files selected by ID which can not be downloaded fail the whole
+// archive, while quarantined files in a selected folder or with the selected tags are left out of it.
+func (s *FileService) PrepareArchive(ctx context.Context, userID uuid.UUID, selection ArchiveSelection) ([]ArchiveEntry, error) {
+	var entries []ArchiveEntry
+	var err error
+
+	switch {
+	case selection.FolderID.Valid:
+		entries, err = s.folderArchiveEntries(ctx, userID, selection.FolderID.UUID)
+	case len(selection.Tags) > 0:
+		entries, err = s.taggedArchiveEntries(ctx, userID, selection.Tags)
+	default:
+		entries, err = s.selectedArchiveEntries(ctx, userID, selection.FileIDs)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Names are compared case insensitively, as archives are often extracted to case insensitive file systems
+	used := make(map[string]bool, len(entries))
+	for i := range entries {
+		entries[i].Name = uniqueArchiveName(used, entries[i].Name)
+	}
+
+	return entries, nil
+}
+
+func (s *FileService) selectedArchiveEntries(ctx context.Context, userID uuid.UUID, fileIDs []uuid.UUID) ([]ArchiveEntry, error) {
+	rows, err := s.db.GetArchiveFiles(ctx, fileIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uuid.UUID]database.GetArchiveFilesRow, len(rows))
+	for _, row := range rows {
+		byID[row.FileID] = row
+	}
+
+	entries := make([]ArchiveEntry, 0, len(fileIDs))
+	seen := make(map[uuid.UUID]bool, len(fileIDs))
+	for _, fileID := range fileIDs {
+		if seen[fileID] {
+			continue
+		}
+		seen[fileID] = true
+
+		file, ok := byID[fileID]
+		if !ok {
+			return nil, fmt.Errorf("file %s: %w", fileID, utils.ErrRecordNotFound)
+		}
+
+		isOwner := file.UserID == userID
+		if !isOwner && file.Visibility == database.FileVisibilityPrivate {
+			return nil, fmt.Errorf("file %s: %w", fileID, utils.ErrNotPermitted)
+		}
+
+		if err := checkScanStatus(file.ScanStatus); err != nil {
+			return nil, fmt.Errorf("file %s: %w", fileID, err)
+		}
+
+		if file.StripLocationPending && !isOwner {
+			return nil, fmt.Errorf("file %s: %w", fileID, utils.ErrStripPending)
+		}
+
+		entries = append(entries, ArchiveEntry{
+			Name:       archiveName("", file.Filename),
+			MimeType:   file.MimeType,
+			StorageKey: file.StorageKey,
+			SizeBytes:  file.SizeBytes,
+			Modified:   file.UpdatedAt,
+		})
+	}
+
+	return entries, nil
+}
+
+func (s *FileService) folderArchiveEntries(ctx context.Context, userID, folderID uuid.UUID) ([]ArchiveEntry, error) {
+	if _, err := s.ownedFolder(ctx, folderID, userID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.GetFolderArchiveFiles(ctx, database.GetFolderArchiveFilesParams{
+		FolderID: folderID,
+		UserID:   userID,
+		MaxFiles: maxArchiveFiles + 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) > maxArchiveFiles {
+		return nil, utils.ErrArchiveTooLarge
+	}
+
+	entries := make([]ArchiveEntry, 0, len(rows))
+	for _, file := range rows {
+		if file.ScanStatus == database.FileScanStatusInfected {
+			continue
+		}
+		if err := checkScanStatus(file.ScanStatus); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, ArchiveEntry{
+			Name:       archiveName(file.FolderPath, file.Filename),
+			MimeType:   file.MimeType,
+			StorageKey: file.StorageKey,
+			SizeBytes:  file.SizeBytes,
+			Modified:   file.UpdatedAt,
+		})
+	}
+
+	return entries, nil
+}
+
+func (s *FileService) taggedArchiveEntries(ctx context.Context, userID uuid.UUID, tags []string) ([]ArchiveEntry, error) {
+	rows, err := s.db.GetTaggedArchiveFiles(ctx, database.GetTaggedArchiveFilesParams{
+		UserID:   userID,
+		Tags:     tags,
+		MaxFiles: maxArchiveFiles + 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) > maxArchiveFiles {
+		return nil, utils.ErrArchiveTooLarge
+	}
+
+	entries := make([]ArchiveEntry, 0, len(rows))
+	for _, file := range rows {
+		if file.ScanStatus == database.FileScanStatusInfected {
+			continue
+		}
+		if err := checkScanStatus(file.ScanStatus); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, ArchiveEntry{
+			Name:       archiveName("", file.Filename),
+			MimeType:   file.MimeType,
+			StorageKey: file.StorageKey,
+			SizeBytes:  file.SizeBytes,
+			Modified:   file.UpdatedAt,
+		})
+	}
+
+	return entries, nil
+}
+
+// archiveName returns the path of a file in an archive. Slashes and dot segments are replaced,
+// so no entry is extracted outside of the directory the archive is extracted to.
+func archiveName(folderPath, filename string) string {
+	var segments []string
+	for _, segment := range append(strings.Split(folderPath, "/"), filename) {
+		segment = strings.ReplaceAll(segment, `\`, "_")
+		switch segment {
+		case "":
+			continue
+		case ".", "..":
+			segment = "_"
+		}
+		segments = append(segments, segment)
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// uniqueArchiveName returns the name, numbered like "report (2).pdf" when an earlier entry already has it.
+func uniqueArchiveName(used map[string]bool, name string) string {
+	ext := path.Ext(name)
+	if ext == name || strings.HasSuffix(name, "/"+ext) {
+		ext = ""
+	}
+	base := strings.TrimSuffix(name, ext)
+
+	unique := name
+	for n := 2; used[strings.ToLower(unique)]; n++ {
+		unique = base + " (" + strconv.Itoa(n) + ")" + ext
+	}
+	used[strings.ToLower(unique)] = true
+
+	return unique
+}
+
+// compressible reports whether content of the MIME type is worth compressing,
+// most images, videos and archives are compressed already.
+func compressible(mimeType string) bool {
+	switch {
+	case strings.HasPrefix(mimeType, "text/"),
+		mimeType == "application/json",
+		mimeType == "application/xml",
+		mimeType == "image/svg+xml",
+		mimeType == "image/bmp",
+		mimeType == "image/tiff",
+		mimeType == "application/pdf",
+		mimeType == "application/msword",
+		mimeType == "application/rtf":
+		return true
+	default:
+		return false
+	}
+}
+
+// WriteArchive streams the content of the entries into a ZIP archive, or a gzip compressed tar archive when
+// format is tar.gz. Content is copied straight from storage, nothing is buffered on disk. ZIP archives switch
+// to ZIP64 records as soon as an entry or the archive itself grows beyond 4 GiB or 65535 entries.
+func (s *FileService) WriteArchive(ctx context.Context, w io.Writer, format string, entries []ArchiveEntry) error {
+	if format == "tar.gz" {
+		return s.writeTarGz(ctx, w, entries)
+	}
+	return s.writeZip(ctx, w, entries)
+}
+
+func (s *FileService) writeZip(ctx context.Context, w io.Writer, entries []ArchiveEntry) error {
+	zw := zip.NewWriter(w)
+	zw.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(out, flate.BestSpeed)
+	})
+
+	for _, entry := range entries {
+		method := zip.Store
+		if compressible(entry.MimeType) {
+			method = zip.Deflate
+		}
+
+		header := &zip.FileHeader{
+			Name:     entry.Name,
+			Method:   method,
+			Modified: entry.Modified,
+		}
+		// Without a mode some tools extract files no one can read
+		header.SetMode(0o644)
+
+		ew, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		if err := s.copyEntry(ctx, ew, entry); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+func (s *FileService) writeTarGz(ctx context.Context, w io.Writer, entries []ArchiveEntry) error {
+	gw, err := gzip.NewWriterLevel(w, gzip.BestSpeed)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(gw)
+
+	for _, entry := range entries {
+		header := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     entry.Name,
+			Size:     entry.SizeBytes,
+			Mode:     0o644,
+			ModTime:  entry.Modified,
+			// PAX headers hold long and non ASCII names and sizes beyond 8 GiB
+			Format: tar.FormatPAX,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if err := s.copyEntry(ctx, tw, entry); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// copyEntry copies the content of an entry from storage, failing when it is not the size recorded for it.
+func (s *FileService) copyEntry(ctx context.Context, w io.Writer, entry ArchiveEntry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	content, err := s.store.Get(ctx, entry.StorageKey)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", entry.Name, err)
+	}
+	defer content.Close()
+
+	copied, err := io.Copy(w, content)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", entry.Name, err)
+	}
+	if copied != entry.SizeBytes {
+		return fmt.Errorf("failed to write %s: content size %d does not match the file size %d", entry.Name, copied, entry.SizeBytes)
+	}
+
+	return nil
+}
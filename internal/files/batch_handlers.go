@@ -0,0 +1,95 @@
+package files
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/i-christian/fileShare/internal/database"
+	"github.com/i-christian/fileShare/internal/utils"
+	"github.com/i-christian/fileShare/internal/utils/security"
+	"github.com/i-christian/fileShare/internal/validator"
+)
+
+// maxBatchOperations is the largest number of operations a single batch request can apply.
+const maxBatchOperations = 100
+
+// Batch applies visibility changes, tag changes, moves and deletions to several files in a single transaction,
+// responding with the result of every operation
+func (h *FileHandler) Batch(w http.ResponseWriter, r *http.Request) {
+	user, ok := security.GetUserFromContext(r)
+	if !ok || user.IsAnonymous() {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	var input struct {
+		Operations []struct {
+			FileID     uuid.UUID  `json:"file_id"`
+			Version    int32      `json:"version"`
+			Action     string     `json:"action"`
+			Visibility string     `json:"visibility"`
+			Tags       []string   `json:"tags"`
+			FolderID   *uuid.UUID `json:"folder_id"`
+		} `json:"operations"`
+	}
+
+	err := utils.ReadJSON(w, r, &input)
+	if err != nil {
+		utils.BadRequestResponse(w, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(len(input.Operations) > 0, "operations", "must be provided")
+	v.Check(len(input.Operations) <= maxBatchOperations, "operations", fmt.Sprintf("must not contain more than %d operations", maxBatchOperations))
+
+	operations := make([]BatchOperation, 0, len(input.Operations))
+	for i, in := range input.Operations {
+		key := fmt.Sprintf("operations[%d]", i)
+		op := BatchOperation{
+			FileID:     in.FileID,
+			Version:    in.Version,
+			Action:     in.Action,
+			Visibility: database.FileVisibility(in.Visibility),
+			Tags:       validator.NormalizeTags(in.Tags),
+		}
+		if in.FolderID != nil {
+			op.FolderID = uuid.NullUUID{UUID: *in.FolderID, Valid: true}
+		}
+
+		v.Check(op.FileID != uuid.Nil, key+".file_id", "must be provided")
+		v.Check(op.Version > 0, key+".version", "must be greater than zero")
+		v.Check(validator.PermittedValue(op.Action, BatchVisibility, BatchAddTags, BatchRemoveTags, BatchReplaceTags, BatchMove, BatchDelete),
+			key+".action", "must be visibility, add_tags, remove_tags, replace_tags, move or delete")
+
+		switch op.Action {
+		case BatchVisibility:
+			v.Check(in.Visibility == "public" || in.Visibility == "private", key+".visibility", "must be either private or public")
+		case BatchAddTags, BatchRemoveTags:
+			v.Check(len(op.Tags) > 0, key+".tags", "must be provided")
+			validator.ValidateTags(v, key+".tags", op.Tags)
+		case BatchReplaceTags:
+			validator.ValidateTags(v, key+".tags", op.Tags)
+		}
+
+		operations = append(operations, op)
+	}
+
+	if !v.Valid() {
+		utils.FailedValidationResponse(w, v.Errors)
+		return
+	}
+
+	results, err := h.service.ApplyBatch(r.Context(), user.UserID, operations)
+	if err != nil {
+		utils.WriteServerError(h.logger, "failed to apply batch operations", err)
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"results": results}, nil)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+	}
+}
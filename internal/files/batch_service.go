@@ -0,0 +1,195 @@
+package files
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/i-christian/fileShare/internal/database"
+	"github.com/i-christian/fileShare/internal/utils"
+)
+
+// Actions a batch operation can apply to a file.
+const (
+	BatchVisibility  = "visibility"
+	BatchAddTags     = "add_tags"
+	BatchRemoveTags  = "remove_tags"
+	BatchReplaceTags = "replace_tags"
+	BatchMove        = "move"
+	BatchDelete      = "delete"
+)
+
+// Statuses of the result of a batch operation.
+const (
+	BatchOK              = "ok"
+	BatchNotFound        = "not_found"
+	BatchVersionConflict = "version_conflict"
+	BatchForbidden       = "forbidden"
+	BatchInvalid         = "invalid"
+)
+
+// BatchOperation is a change to a file owned by the user, applied only if Version is still the current version
+// of the file. Visibility, Tags and FolderID are the arguments of the visibility, tag and move actions.
+type BatchOperation struct {
+	FileID     uuid.UUID
+	Version    int32
+	Action     string
+	Visibility database.FileVisibility
+	Tags       []string
+	FolderID   uuid.NullUUID
+}
+
+// BatchResult is the outcome of a batch operation, Version is the new version of the file when it was applied.
+type BatchResult struct {
+	FileID  uuid.UUID `json:"file_id"`
+	Action  string    `json:"action"`
+	Status  string    `json:"status"`
+	Version int32     `json:"version,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// ApplyBatch applies the operations in order in a single transaction. Operations on files which do not exist,
+// belong to another user or have changed since the given version are skipped and reported in their result,
+// the others are committed together. Several operations on the same file each expect the version left by
+// the one before. Nothing is applied when the transaction fails.
+func (s *FileService) ApplyBatch(ctx context.Context, userID uuid.UUID, operations []BatchOperation) ([]BatchResult, error) {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	q := s.db.WithTx(tx)
+
+	results := make([]BatchResult, 0, len(operations))
+	for _, op := range operations {
+		result := BatchResult{FileID: op.FileID, Action: op.Action, Status: BatchOK}
+
+		version, err := s.applyBatchOperation(ctx, q, userID, op)
+		switch {
+		case err == nil:
+			result.Version = version
+		case errors.Is(err, utils.ErrRecordNotFound):
+			result.Status, result.Error = BatchNotFound, err.Error()
+		case errors.Is(err, utils.ErrNotPermitted):
+			result.Status, result.Error = BatchForbidden, err.Error()
+		case errors.Is(err, utils.ErrEditConflict):
+			result.Status, result.Error = BatchVersionConflict, err.Error()
+		case errors.Is(err, utils.ErrTooManyTags):
+			result.Status, result.Error = BatchInvalid, err.Error()
+		default:
+			return nil, fmt.Errorf("failed to %s file %s: %w", op.Action, op.FileID, err)
+		}
+
+		results = append(results, result)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// applyBatchOperation applies a single operation within the batch transaction, returning the new version of the file.
+func (s *FileService) applyBatchOperation(ctx context.Context, q *database.Queries, userID uuid.UUID, op BatchOperation) (int32, error) {
+	file, err := q.GetFileInfo(ctx, op.FileID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, utils.ErrRecordNotFound
+		}
+		return 0, err
+	}
+
+	if file.OwnerID != userID {
+		return 0, utils.ErrNotPermitted
+	}
+
+	if file.Version != op.Version {
+		return 0, utils.ErrEditConflict
+	}
+
+	var version int32
+	switch op.Action {
+	case BatchVisibility:
+		var updated database.SetFileVisibilityRow
+		updated, err = q.SetFileVisibility(ctx, database.SetFileVisibilityParams{
+			Visibility: op.Visibility,
+			FileID:     op.FileID,
+			UserID:     userID,
+			Version:    op.Version,
+		})
+		version = updated.Version
+
+	case BatchAddTags, BatchRemoveTags, BatchReplaceTags:
+		var tags []string
+		tags, err = changedTags(file.Tags, func(current []string) []string {
+			switch op.Action {
+			case BatchAddTags:
+				return withTags(current, op.Tags)
+			case BatchRemoveTags:
+				return withoutTags(current, op.Tags)
+			}
+			return op.Tags
+		})
+		if err != nil {
+			return 0, err
+		}
+
+		var updated database.SetFileTagsRow
+		updated, err = q.SetFileTags(ctx, database.SetFileTagsParams{
+			Tags:    tags,
+			FileID:  op.FileID,
+			UserID:  userID,
+			Version: op.Version,
+		})
+		version = updated.Version
+
+	case BatchMove:
+		if op.FolderID.Valid {
+			folder, err := q.GetFolder(ctx, op.FolderID.UUID)
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return 0, fmt.Errorf("folder %s: %w", op.FolderID.UUID, utils.ErrRecordNotFound)
+				}
+				return 0, err
+			}
+			if folder.UserID != userID {
+				return 0, fmt.Errorf("folder %s: %w", op.FolderID.UUID, utils.ErrNotPermitted)
+			}
+		}
+
+		var moved database.MoveFileRow
+		moved, err = q.MoveFile(ctx, database.MoveFileParams{
+			FolderID: op.FolderID,
+			FileID:   op.FileID,
+			UserID:   userID,
+			Version:  op.Version,
+		})
+		version = moved.Version
+
+	case BatchDelete:
+		_, err = q.DeleteFile(ctx, database.DeleteFileParams{
+			DefaultRetentionDays: s.trashRetentionDays,
+			FileID:               op.FileID,
+			UserID:               userID,
+			Version:              op.Version,
+		})
+		version = op.Version + 1
+
+	default:
+		return 0, fmt.Errorf("unknown batch action %q", op.Action)
+	}
+
+	if err != nil {
+		// The file was changed by a concurrent request since it was read
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, utils.ErrEditConflict
+		}
+		return 0, err
+	}
+
+	return version, nil
+}
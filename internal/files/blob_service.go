@@ -0,0 +1,104 @@
+package files
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/i-christian/fileShare/internal/database"
+	"github.com/i-christian/fileShare/internal/utils"
+)
+
+// referenceBlob adds a reference to the blob holding the content just saved under storageKey, returning the
+// storage key the file version should point at. Content which the user already stores as a blob is removed
+// again in favour of the existing blob, otherwise the saved content becomes a new blob. Blobs are never
+// shared between users, as content is encrypted with the data key of its owner.
+func (s *FileService) referenceBlob(ctx context.Context, userID uuid.UUID, storageKey, checksum string, size int64) (string, error) {
+	// A blob created concurrently by an upload of the same content is picked up on the next attempt
+	for range 3 {
+		blobKey, err := s.db.AddBlobReference(ctx, database.AddBlobReferenceParams{
+			UserID:   userID,
+			Checksum: checksum,
+		})
+		if err == nil {
+			_, _, _ = s.store.Delete(ctx, []string{storageKey})
+			return blobKey, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return "", err
+		}
+
+		blobKey, err = s.db.CreateBlob(ctx, database.CreateBlobParams{
+			StorageKey: storageKey,
+			UserID:     userID,
+			Checksum:   checksum,
+			SizeBytes:  size,
+		})
+		if err == nil {
+			return blobKey, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return "", err
+		}
+	}
+
+	return "", fmt.Errorf("failed to reference blob with checksum %s", checksum)
+}
+
+// releaseBlobs removes one reference from the blob at each storage key
+// and deletes the content of the blobs left without references.
+func (s *FileService) releaseBlobs(ctx context.Context, storageKeys []string) error {
+	if len(storageKeys) == 0 {
+		return nil
+	}
+
+	unreferenced, err := s.db.ReleaseBlobs(ctx, storageKeys)
+	if err != nil {
+		return fmt.Errorf("failed to release blobs: %w", err)
+	}
+
+	if _, err := s.deleteBlobs(ctx, unreferenced); err != nil {
+		utils.WriteServerError(s.logger, "failed to delete unreferenced blobs, the cleanup job will retry", err)
+	}
+
+	return nil
+}
+
+// deleteBlobs removes the content of unreferenced blobs from storage followed by their records.
+// The records are kept when storage fails, so the deletion is retried by the cleanup job.
+func (s *FileService) deleteBlobs(ctx context.Context, storageKeys []string) (int, error) {
+	if len(storageKeys) == 0 {
+		return 0, nil
+	}
+
+	_, failed, err := s.store.Delete(ctx, storageKeys)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete blobs from storage: %w", err)
+	}
+	if failed > 0 {
+		return 0, fmt.Errorf("failed to delete %d of %d blobs from storage", failed, len(storageKeys))
+	}
+
+	// The records of renditions are deleted along with the blob records, so their content goes first
+	if err := s.deleteRenditions(ctx, storageKeys); err != nil {
+		return 0, err
+	}
+
+	if err := s.db.DeleteUnreferencedBlobs(ctx, storageKeys); err != nil {
+		return 0, fmt.Errorf("failed to delete blob records: %w", err)
+	}
+
+	return len(storageKeys), nil
+}
+
+// CleanupUnreferencedBlobs deletes the content of blobs which no file version references anymore
+func (s *FileService) CleanupUnreferencedBlobs(ctx context.Context, limit int32) (deletedBlobs int, err error) {
+	storageKeys, err := s.db.GetUnreferencedBlobs(ctx, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch unreferenced blobs: %w", err)
+	}
+
+	return s.deleteBlobs(ctx, storageKeys)
+}
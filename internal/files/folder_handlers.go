@@ -0,0 +1,349 @@
+package files
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/i-christian/fileShare/internal/utils"
+	"github.com/i-christian/fileShare/internal/utils/security"
+	"github.com/i-christian/fileShare/internal/validator"
+)
+
+// readFolderID parses the folder ID path parameter, "root" refers to the user's root folder
+func readFolderID(r *http.Request) (uuid.NullUUID, error) {
+	param := r.PathValue("id")
+	if param == "root" {
+		return uuid.NullUUID{}, nil
+	}
+
+	folderID, err := uuid.Parse(param)
+	if err != nil {
+		return uuid.NullUUID{}, errors.New("invalid folder ID parameter")
+	}
+
+	return uuid.NullUUID{UUID: folderID, Valid: true}, nil
+}
+
+// writeFolderError maps errors returned by folder operations to a response
+func (h *FileHandler) writeFolderError(w http.ResponseWriter, msg string, err error) {
+	switch {
+	case errors.Is(err, utils.ErrRecordNotFound):
+		utils.NotFoundResponse(w)
+	case errors.Is(err, utils.ErrNotPermitted):
+		utils.NotPermittedResponse(w)
+	case errors.Is(err, utils.ErrEditConflict):
+		utils.EditConflictResponse(w)
+	case errors.Is(err, utils.ErrRecordExists):
+		utils.WriteErrorJSON(w, http.StatusConflict, "a folder with this name already exists")
+	case errors.Is(err, utils.ErrFolderCycle):
+		utils.FailedValidationResponse(w, map[string]string{"parent_id": err.Error()})
+	default:
+		utils.WriteServerError(h.logger, "failed to "+msg, err)
+		utils.ServerErrorResponse(w, "failed to "+msg)
+	}
+}
+
+// CreateFolder creates a new folder, inside parent_id when it is provided
+func (h *FileHandler) CreateFolder(w http.ResponseWriter, r *http.Request) {
+	user, ok := security.GetUserFromContext(r)
+	if !ok || user.IsAnonymous() {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	var input struct {
+		Name     string        `json:"name"`
+		ParentID uuid.NullUUID `json:"parent_id"`
+	}
+
+	err := utils.ReadJSON(w, r, &input)
+	if err != nil {
+		utils.BadRequestResponse(w, err)
+		return
+	}
+
+	v := validator.New()
+	if validator.ValidateFolderName(v, validator.FolderInfo{Name: input.Name}); !v.Valid() {
+		utils.FailedValidationResponse(w, v.Errors)
+		return
+	}
+
+	folder, err := h.service.CreateFolder(r.Context(), user.UserID, input.ParentID, input.Name)
+	if err != nil {
+		h.writeFolderError(w, "create folder", err)
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"folder": folder}, nil)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+	}
+}
+
+// GetFolder retrieves a folder and the path of folders leading to it
+func (h *FileHandler) GetFolder(w http.ResponseWriter, r *http.Request) {
+	folderID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		utils.BadRequestResponse(w, errors.New("invalid folder ID parameter"))
+		return
+	}
+
+	user, ok := security.GetUserFromContext(r)
+	if !ok || user.IsAnonymous() {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	folder, path, err := h.service.GetFolder(r.Context(), folderID, user.UserID)
+	if err != nil {
+		h.writeFolderError(w, "retrieve folder", err)
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"folder": folder,
+		"path":   path,
+	}, nil)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+	}
+}
+
+// ListFolderChildren retrieves the subfolders and files inside a folder with pagination
+func (h *FileHandler) ListFolderChildren(w http.ResponseWriter, r *http.Request) {
+	folderID, err := readFolderID(r)
+	if err != nil {
+		utils.BadRequestResponse(w, err)
+		return
+	}
+
+	user, ok := security.GetUserFromContext(r)
+	if !ok || user.IsAnonymous() {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	qs := r.URL.Query()
+	input := validator.Filters{
+		Page:     utils.ReadInt(qs, "page", 1),
+		PageSize: utils.ReadInt(qs, "page_size", 20),
+	}
+
+	v := validator.New()
+	useCursor, cursor := readCursor(qs, v)
+	if validator.ValidateFilters(v, input); !v.Valid() {
+		utils.FailedValidationResponse(w, v.Errors)
+		return
+	}
+
+	filters := utils.Filters{
+		Page:         input.Page,
+		PageSize:     input.PageSize,
+		UseCursor:    useCursor,
+		Cursor:       cursor,
+		IncludeTotal: utils.ReadBool(qs, "include_total", false),
+	}
+
+	children, metadata, err := h.service.ListFolderChildren(r.Context(), folderID, user.UserID, filters)
+	if err != nil {
+		h.writeFolderError(w, "fetch folder contents", err)
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"metadata": metadata,
+		"children": children,
+	}, nil)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+	}
+}
+
+// RenameFolder changes the name of a folder
+func (h *FileHandler) RenameFolder(w http.ResponseWriter, r *http.Request) {
+	folderID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		utils.BadRequestResponse(w, errors.New("invalid folder ID parameter"))
+		return
+	}
+
+	user, ok := security.GetUserFromContext(r)
+	if !ok || user.IsAnonymous() {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	var input struct {
+		Version int32  `json:"version"`
+		Name    string `json:"name"`
+	}
+
+	err = utils.ReadJSON(w, r, &input)
+	if err != nil {
+		utils.BadRequestResponse(w, err)
+		return
+	}
+
+	v := validator.New()
+	f := validator.FolderInfo{
+		Name:    input.Name,
+		Version: input.Version,
+	}
+	if validator.ValidateRenameFolder(v, f); !v.Valid() {
+		utils.FailedValidationResponse(w, v.Errors)
+		return
+	}
+
+	renamed, err := h.service.RenameFolder(r.Context(), folderID, user.UserID, input.Name, input.Version)
+	if err != nil {
+		h.writeFolderError(w, "rename folder", err)
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"message": "folder renamed successfully",
+		"folder":  renamed,
+	}, nil)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+	}
+}
+
+// MoveFolder moves a folder and everything inside it into another folder, a null parent_id moves it to the root folder
+func (h *FileHandler) MoveFolder(w http.ResponseWriter, r *http.Request) {
+	folderID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		utils.BadRequestResponse(w, errors.New("invalid folder ID parameter"))
+		return
+	}
+
+	user, ok := security.GetUserFromContext(r)
+	if !ok || user.IsAnonymous() {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	var input struct {
+		Version  int32         `json:"version"`
+		ParentID uuid.NullUUID `json:"parent_id"`
+	}
+
+	err = utils.ReadJSON(w, r, &input)
+	if err != nil {
+		utils.BadRequestResponse(w, err)
+		return
+	}
+
+	v := validator.New()
+	if validator.ValidateDeleteFile(v, validator.FileInfo{Version: input.Version}); !v.Valid() {
+		utils.FailedValidationResponse(w, v.Errors)
+		return
+	}
+
+	moved, err := h.service.MoveFolder(r.Context(), folderID, user.UserID, input.ParentID, input.Version)
+	if err != nil {
+		h.writeFolderError(w, "move folder", err)
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"message": "folder moved successfully",
+		"folder":  moved,
+	}, nil)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+	}
+}
+
+// DeleteFolder moves a folder, its subfolders and their files to the trash
+func (h *FileHandler) DeleteFolder(w http.ResponseWriter, r *http.Request) {
+	folderID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		utils.BadRequestResponse(w, errors.New("invalid folder ID parameter"))
+		return
+	}
+
+	user, ok := security.GetUserFromContext(r)
+	if !ok || user.IsAnonymous() {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	var input struct {
+		Version int32 `json:"version"`
+	}
+
+	err = utils.ReadJSON(w, r, &input)
+	if err != nil {
+		utils.BadRequestResponse(w, err)
+		return
+	}
+
+	v := validator.New()
+	if validator.ValidateDeleteFile(v, validator.FileInfo{Version: input.Version}); !v.Valid() {
+		utils.FailedValidationResponse(w, v.Errors)
+		return
+	}
+
+	deleted, err := h.service.DeleteFolder(r.Context(), folderID, user.UserID, input.Version)
+	if err != nil {
+		h.writeFolderError(w, "delete folder", err)
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"message":         "folder moved to trash",
+		"deleted_folders": deleted.DeletedFolders,
+		"deleted_files":   deleted.DeletedFiles,
+	}, nil)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+	}
+}
+
+// MoveFile moves a file into a folder, a null folder_id moves it to the root folder
+func (h *FileHandler) MoveFile(w http.ResponseWriter, r *http.Request) {
+	fileID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		utils.BadRequestResponse(w, errors.New("invalid file ID parameter"))
+		return
+	}
+
+	user, ok := security.GetUserFromContext(r)
+	if !ok || user.IsAnonymous() {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	var input struct {
+		Version  int32         `json:"version"`
+		FolderID uuid.NullUUID `json:"folder_id"`
+	}
+
+	err = utils.ReadJSON(w, r, &input)
+	if err != nil {
+		utils.BadRequestResponse(w, err)
+		return
+	}
+
+	v := validator.New()
+	if validator.ValidateDeleteFile(v, validator.FileInfo{Version: input.Version}); !v.Valid() {
+		utils.FailedValidationResponse(w, v.Errors)
+		return
+	}
+
+	moved, err := h.service.MoveFile(r.Context(), fileID, user.UserID, input.FolderID, input.Version)
+	if err != nil {
+		h.writeFolderError(w, "move file", err)
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"message": "file moved successfully",
+		"file":    moved,
+	}, nil)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+	}
+}
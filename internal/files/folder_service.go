@@ -0,0 +1,272 @@
+package files
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/i-christian/fileShare/internal/database"
+	"github.com/i-christian/fileShare/internal/utils"
+	"github.com/lib/pq"
+)
+
+// isUniqueViolation reports whether err was caused by a unique constraint in the database.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// ownedFolder retrieves a folder which must belong to the user.
+func (s *FileService) ownedFolder(ctx context.Context, folderID, userID uuid.UUID) (database.GetFolderRow, error) {
+	folder, err := s.db.GetFolder(ctx, folderID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return database.GetFolderRow{}, utils.ErrRecordNotFound
+		}
+		return database.GetFolderRow{}, err
+	}
+
+	if folder.UserID != userID {
+		return database.GetFolderRow{}, utils.ErrNotPermitted
+	}
+
+	return folder, nil
+}
+
+// CreateFolder creates a folder for the user inside parentID, or in the root folder when parentID is not set
+func (s *FileService) CreateFolder(ctx context.Context, userID uuid.UUID, parentID uuid.NullUUID, name string) (database.CreateFolderRow, error) {
+	if parentID.Valid {
+		if _, err := s.ownedFolder(ctx, parentID.UUID, userID); err != nil {
+			return database.CreateFolderRow{}, err
+		}
+	}
+
+	folder, err := s.db.CreateFolder(ctx, database.CreateFolderParams{
+		UserID:   userID,
+		ParentID: parentID,
+		Name:     name,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return database.CreateFolderRow{}, utils.ErrRecordExists
+		}
+		return database.CreateFolderRow{}, err
+	}
+
+	return folder, nil
+}
+
+// GetFolder retrieves a folder owned by the user together with the path leading to it
+func (s *FileService) GetFolder(ctx context.Context, folderID, userID uuid.UUID) (database.GetFolderRow, []database.GetFolderPathRow, error) {
+	folder, err := s.ownedFolder(ctx, folderID, userID)
+	if err != nil {
+		return database.GetFolderRow{}, nil, err
+	}
+
+	path, err := s.db.GetFolderPath(ctx, folderID)
+	if err != nil {
+		return database.GetFolderRow{}, nil, err
+	}
+
+	return folder, path, nil
+}
+
+// RenameFolder changes the name of a folder owned by the user
+func (s *FileService) RenameFolder(ctx context.Context, folderID, userID uuid.UUID, name string, version int32) (database.RenameFolderRow, error) {
+	if _, err := s.ownedFolder(ctx, folderID, userID); err != nil {
+		return database.RenameFolderRow{}, err
+	}
+
+	renamed, err := s.db.RenameFolder(ctx, database.RenameFolderParams{
+		Name:     name,
+		FolderID: folderID,
+		UserID:   userID,
+		Version:  version,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return database.RenameFolderRow{}, utils.ErrEditConflict
+		case isUniqueViolation(err):
+			return database.RenameFolderRow{}, utils.ErrRecordExists
+		default:
+			return database.RenameFolderRow{}, err
+		}
+	}
+
+	return renamed, nil
+}
+
+// MoveFolder moves a folder owned by the user, together with its contents, into parentID.
+// A folder can not be moved into itself or any of its subfolders.
+func (s *FileService) MoveFolder(ctx context.Context, folderID, userID uuid.UUID, parentID uuid.NullUUID, version int32) (database.MoveFolderRow, error) {
+	if _, err := s.ownedFolder(ctx, folderID, userID); err != nil {
+		return database.MoveFolderRow{}, err
+	}
+
+	if parentID.Valid {
+		if _, err := s.ownedFolder(ctx, parentID.UUID, userID); err != nil {
+			return database.MoveFolderRow{}, err
+		}
+	}
+
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return database.MoveFolderRow{}, err
+	}
+	defer tx.Rollback()
+	q := s.db.WithTx(tx)
+
+	// a concurrent move of another of the user's folders could otherwise create a cycle between the check and the update
+	if err := q.LockUserFolders(ctx, userID); err != nil {
+		return database.MoveFolderRow{}, err
+	}
+
+	if parentID.Valid {
+		path, err := q.GetFolderPath(ctx, parentID.UUID)
+		if err != nil {
+			return database.MoveFolderRow{}, err
+		}
+		for _, ancestor := range path {
+			if ancestor.FolderID == folderID {
+				return database.MoveFolderRow{}, utils.ErrFolderCycle
+			}
+		}
+	}
+
+	moved, err := q.MoveFolder(ctx, database.MoveFolderParams{
+		ParentID: parentID,
+		FolderID: folderID,
+		UserID:   userID,
+		Version:  version,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return database.MoveFolderRow{}, utils.ErrEditConflict
+		case isUniqueViolation(err):
+			return database.MoveFolderRow{}, utils.ErrRecordExists
+		default:
+			return database.MoveFolderRow{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return database.MoveFolderRow{}, err
+	}
+
+	return moved, nil
+}
+
+// DeleteFolder moves a folder owned by the user, its subfolders and their files to the trash.
+// They are purged by the cleanup job once the user's trash retention period has passed.
+func (s *FileService) DeleteFolder(ctx context.Context, folderID, userID uuid.UUID, version int32) (database.DeleteFolderRow, error) {
+	if _, err := s.ownedFolder(ctx, folderID, userID); err != nil {
+		return database.DeleteFolderRow{}, err
+	}
+
+	deleted, err := s.db.DeleteFolder(ctx, database.DeleteFolderParams{
+		FolderID:             folderID,
+		UserID:               userID,
+		Version:              version,
+		DefaultRetentionDays: s.trashRetentionDays,
+	})
+	if err != nil {
+		return database.DeleteFolderRow{}, err
+	}
+
+	if deleted.DeletedFolders == 0 {
+		return database.DeleteFolderRow{}, utils.ErrEditConflict
+	}
+
+	return deleted, nil
+}
+
+// ListFolderChildren lists the subfolders and files inside a folder owned by the user,
+// or inside the user's root folder when folderID is not set.
+// With cursor pagination the children are only counted when filters.IncludeTotal is set.
+func (s *FileService) ListFolderChildren(ctx context.Context, folderID uuid.NullUUID, userID uuid.UUID, filters utils.Filters) ([]database.ListFolderChildrenRow, utils.Metadata, error) {
+	if folderID.Valid {
+		if _, err := s.ownedFolder(ctx, folderID.UUID, userID); err != nil {
+			return []database.ListFolderChildrenRow{}, utils.Metadata{}, err
+		}
+	}
+
+	limit, offset := pageBounds(filters)
+
+	var count int64
+	if !filters.UseCursor || filters.IncludeTotal {
+		var err error
+		count, err = s.db.CountFolderChildren(ctx, database.CountFolderChildrenParams{
+			UserID:   userID,
+			ParentID: folderID,
+		})
+		if err != nil {
+			return []database.ListFolderChildrenRow{}, utils.Metadata{}, err
+		}
+	}
+
+	params := database.ListFolderChildrenParams{
+		UserID:     userID,
+		ParentID:   folderID,
+		PageLimit:  limit,
+		PageOffset: offset,
+	}
+	if filters.Cursor != nil {
+		params.CursorID = uuid.NullUUID{UUID: filters.Cursor.ID, Valid: true}
+		params.CursorIsFile = filters.Cursor.IsFile
+		params.CursorName = filters.Cursor.Name
+		params.Backward = filters.Cursor.Backward
+	}
+
+	children, err := s.db.ListFolderChildren(ctx, params)
+	if err != nil {
+		return []database.ListFolderChildrenRow{}, utils.Metadata{}, err
+	}
+
+	if filters.UseCursor {
+		children, meta := cursorPage(children, filters, int(count), func(c database.ListFolderChildrenRow) utils.Cursor {
+			return utils.Cursor{IsFile: c.Kind == "file", Name: c.Name, ID: c.ID}
+		})
+		return children, meta, nil
+	}
+
+	meta := utils.CalculateMetadata(int(count), filters.Page, filters.PageSize)
+
+	return children, meta, nil
+}
+
+// MoveFile moves a file owned by the user into folderID, or into the root folder when folderID is not set
+func (s *FileService) MoveFile(ctx context.Context, fileID, userID uuid.UUID, folderID uuid.NullUUID, version int32) (database.MoveFileRow, error) {
+	if _, err := s.ownedFile(ctx, fileID, userID); err != nil {
+		return database.MoveFileRow{}, err
+	}
+
+	if folderID.Valid {
+		if _, err := s.ownedFolder(ctx, folderID.UUID, userID); err != nil {
+			return database.MoveFileRow{}, err
+		}
+	}
+
+	moved, err := s.db.MoveFile(ctx, database.MoveFileParams{
+		FolderID: folderID,
+		FileID:   fileID,
+		UserID:   userID,
+		Version:  version,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return database.MoveFileRow{}, utils.ErrEditConflict
+		}
+		return database.MoveFileRow{}, err
+	}
+
+	return moved, nil
+}
+
+// CleanupExpiredFolders permanently removes trashed folders whose retention period has passed.
+// Their files are purged separately by CleanupExpiredSoftDeleted.
+func (s *FileService) CleanupExpiredFolders(ctx context.Context) (deletedFolders int64, err error) {
+	return s.db.DeleteExpiredFolders(ctx)
+}
@@ -1,12 +1,16 @@
 package files
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/i-christian/fileShare/internal/database"
@@ -45,6 +49,13 @@ func (h *FileHandler) Upload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// An optional file_id field, sent before the file, uploads the file as a new version of an existing file
+	var targetFileID uuid.UUID
+	// An optional comma separated tags field, sent before the file, tags a newly uploaded file
+	tags := []string{}
+	// An optional strip_location field, sent before the file, overrides the user's location stripping setting
+	var stripLocation sql.NullBool
+
 	for {
 		part, err := reader.NextPart()
 		if err == io.EOF {
@@ -55,6 +66,55 @@ func (h *FileHandler) Upload(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if part.FormName() == "file_id" {
+			value, err := io.ReadAll(io.LimitReader(part, 64))
+			part.Close()
+			if err != nil {
+				utils.BadRequestResponse(w, errors.New("error reading multipart body"))
+				return
+			}
+
+			targetFileID, err = uuid.Parse(strings.TrimSpace(string(value)))
+			if err != nil {
+				utils.BadRequestResponse(w, errors.New("invalid file_id field"))
+				return
+			}
+			continue
+		}
+
+		if part.FormName() == "tags" {
+			value, err := io.ReadAll(io.LimitReader(part, 2048))
+			part.Close()
+			if err != nil {
+				utils.BadRequestResponse(w, errors.New("error reading multipart body"))
+				return
+			}
+
+			tags = validator.NormalizeTags(strings.Split(string(value), ","))
+			v := validator.New()
+			if validator.ValidateTags(v, "tags", tags); !v.Valid() {
+				utils.FailedValidationResponse(w, v.Errors)
+				return
+			}
+			continue
+		}
+
+		if part.FormName() == "strip_location" {
+			value, err := io.ReadAll(io.LimitReader(part, 16))
+			part.Close()
+			if err != nil {
+				utils.BadRequestResponse(w, errors.New("error reading multipart body"))
+				return
+			}
+
+			stripLocation, err = parseStripLocation(string(value))
+			if err != nil {
+				utils.FailedValidationResponse(w, map[string]string{"strip_location": err.Error()})
+				return
+			}
+			continue
+		}
+
 		if part.FormName() == "file" {
 			defer part.Close()
 
@@ -71,14 +131,25 @@ func (h *FileHandler) Upload(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 
+			if targetFileID != uuid.Nil {
+				h.uploadFileVersion(w, r, targetFileID, user.UserID, fileStream, contentType, filename, stripLocation)
+				return
+			}
+
 			uploadedFile, err := h.service.UploadFile(
 				user.UserID,
 				fileStream,
 				contentType,
 				filename,
+				tags,
+				stripLocation,
 				int64(h.maxUploadSize),
 			)
 			if err != nil {
+				if errors.Is(err, utils.ErrQuotaExceeded) {
+					utils.WriteErrorJSON(w, http.StatusRequestEntityTooLarge, err.Error())
+					return
+				}
 				utils.WriteServerError(h.logger, "failed to upload file", err)
 				if errors.Is(err, utils.ErrDuplicateUpload) {
 					utils.ServerErrorResponse(w, err.Error())
@@ -99,21 +170,149 @@ func (h *FileHandler) Upload(w http.ResponseWriter, r *http.Request) {
 	utils.BadRequestResponse(w, errors.New("missing 'file' field in form data"))
 }
 
-// ListPublicFiles retrieves public files with pagination validation
-func (h *FileHandler) ListPublicFiles(w http.ResponseWriter, r *http.Request) {
+// uploadFileVersion stores an uploaded file as the new current version of an existing file
+func (h *FileHandler) uploadFileVersion(w http.ResponseWriter, r *http.Request, fileID, userID uuid.UUID, fileStream io.Reader, contentType, filename string, stripLocation sql.NullBool) {
+	fileVersion, err := h.service.UploadFileVersion(r.Context(), fileID, userID, fileStream, contentType, filename, stripLocation, int64(h.maxUploadSize))
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrRecordNotFound):
+			utils.NotFoundResponse(w)
+		case errors.Is(err, utils.ErrNotPermitted):
+			utils.NotPermittedResponse(w)
+		case errors.Is(err, utils.ErrEditConflict):
+			utils.EditConflictResponse(w)
+		case errors.Is(err, utils.ErrDuplicateUpload):
+			utils.WriteErrorJSON(w, http.StatusConflict, "the uploaded content is identical to the current version")
+		case errors.Is(err, utils.ErrQuotaExceeded):
+			utils.WriteErrorJSON(w, http.StatusRequestEntityTooLarge, err.Error())
+		default:
+			utils.WriteServerError(h.logger, "failed to upload file version", err)
+			utils.ServerErrorResponse(w, "failed to process upload")
+		}
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{
+		"message":      "File version uploaded successfully",
+		"file_version": fileVersion,
+	}, nil)
+}
+
+// parseStripLocation reads the optional strip_location upload option,
+// an empty value leaves it to the user's location stripping setting
+func parseStripLocation(value string) (sql.NullBool, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return sql.NullBool{}, nil
+	}
+
+	strip, err := strconv.ParseBool(value)
+	if err != nil {
+		return sql.NullBool{}, errors.New("must be true or false")
+	}
+
+	return sql.NullBool{Bool: strip, Valid: true}, nil
+}
+
+// fileSortSafelist lists the accepted sort values of file listings
+var fileSortSafelist = []string{"name", "size", "created_at", "updated_at", "-name", "-size", "-created_at", "-updated_at"}
+
+// readDate reads a query parameter holding a date (2006-01-02) or an RFC 3339 timestamp,
+// returning the zero time when it is missing and recording an error in v when it is invalid
+func readDate(qs url.Values, key string, v *validator.Validator) time.Time {
+	value := utils.ReadString(qs, key, "")
+	if value == "" {
+		return time.Time{}
+	}
+
+	for _, layout := range []string{time.DateOnly, time.RFC3339} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+
+	v.AddError(key, "must be a date (2006-01-02) or an RFC 3339 timestamp")
+	return time.Time{}
+}
+
+// readCursor reads the cursor pagination parameter, an empty cursor requests the first page.
+// Listings are paginated by page number when the parameter is missing.
+func readCursor(qs url.Values, v *validator.Validator) (useCursor bool, cursor *utils.Cursor) {
+	if !qs.Has("cursor") {
+		return false, nil
+	}
+
+	value := qs.Get("cursor")
+	if value == "" {
+		return true, nil
+	}
+
+	c, err := utils.DecodeCursor(value)
+	if err != nil {
+		v.AddError("cursor", "must be a cursor returned with a previous page")
+		return true, nil
+	}
+
+	return true, &c
+}
+
+// readListFilters reads and validates the pagination, sorting and filtering parameters of a file listing,
+// writing a validation error response and returning false when they are invalid
+func readListFilters(w http.ResponseWriter, r *http.Request) (utils.Filters, bool) {
+	qs := r.URL.Query()
+
 	input := validator.Filters{
-		Page:     utils.ReadInt(r.URL.Query(), "page", 1),
-		PageSize: utils.ReadInt(r.URL.Query(), "page_size", 20),
+		Page:         utils.ReadInt(qs, "page", 1),
+		PageSize:     utils.ReadInt(qs, "page_size", 20),
+		Sort:         utils.ReadString(qs, "sort", "-created_at"),
+		SortSafelist: fileSortSafelist,
+		Tags:         validator.NormalizeTags(utils.ReadCSV(qs, "tags", []string{})),
+		TagMatch:     utils.ReadString(qs, "tag_match", "any"),
+		MimePrefix:   strings.ToLower(utils.ReadString(qs, "mime_type", "")),
+		MinSize:      int64(utils.ReadInt(qs, "min_size", -1)),
+		MaxSize:      int64(utils.ReadInt(qs, "max_size", -1)),
+		Visibility:   utils.ReadString(qs, "visibility", ""),
 	}
 
 	v := validator.New()
+	useCursor, cursor := readCursor(qs, v)
+	input.UseCursor = useCursor
+	createdAfter := readDate(qs, "created_after", v)
+	createdBefore := readDate(qs, "created_before", v)
+	if !createdAfter.IsZero() && !createdBefore.IsZero() {
+		v.Check(createdBefore.After(createdAfter), "created_before", "must be later than created_after")
+	}
+
 	if validator.ValidateFilters(v, input); !v.Valid() {
 		utils.FailedValidationResponse(w, v.Errors)
+		return utils.Filters{}, false
+	}
+
+	return utils.Filters{
+		Page:          input.Page,
+		PageSize:      input.PageSize,
+		Sort:          input.Sort,
+		Tags:          input.Tags,
+		MatchAllTags:  input.TagMatch == "all",
+		MimePrefix:    input.MimePrefix,
+		MinSize:       input.MinSize,
+		MaxSize:       input.MaxSize,
+		CreatedAfter:  createdAfter,
+		CreatedBefore: createdBefore,
+		Visibility:    input.Visibility,
+		UseCursor:     useCursor,
+		Cursor:        cursor,
+		IncludeTotal:  utils.ReadBool(qs, "include_total", false),
+	}, true
+}
+
+// ListPublicFiles retrieves public files with pagination validation
+func (h *FileHandler) ListPublicFiles(w http.ResponseWriter, r *http.Request) {
+	filters, ok := readListFilters(w, r)
+	if !ok {
 		return
 	}
 
-	filters := utils.Filters{Page: input.Page, PageSize: input.PageSize}
-
 	files, metadata, err := h.service.ListPublicFiles(r.Context(), filters)
 	if err != nil {
 		utils.WriteServerError(h.logger, "failed to fetch public files", err)
@@ -138,19 +337,11 @@ func (h *FileHandler) ListMyFiles(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	input := validator.Filters{
-		Page:     utils.ReadInt(r.URL.Query(), "page", 1),
-		PageSize: utils.ReadInt(r.URL.Query(), "page_size", 20),
-	}
-
-	v := validator.New()
-	if validator.ValidateFilters(v, input); !v.Valid() {
-		utils.FailedValidationResponse(w, v.Errors)
+	filters, ok := readListFilters(w, r)
+	if !ok {
 		return
 	}
 
-	filters := utils.Filters{Page: input.Page, PageSize: input.PageSize}
-
 	files, metadata, err := h.service.ListUserFiles(r.Context(), user.UserID, filters)
 	if err != nil {
 		utils.WriteServerError(h.logger, "failed to fetch user files", err)
@@ -204,7 +395,7 @@ func (h *FileHandler) GetMetadata(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// Download streams the file to the client
+// Download streams the file, or the requested byte ranges of it, to the client
 func (h *FileHandler) Download(w http.ResponseWriter, r *http.Request) {
 	fileID, err := uuid.Parse(r.PathValue("id"))
 	if err != nil {
@@ -227,21 +418,43 @@ func (h *FileHandler) Download(w http.ResponseWriter, r *http.Request) {
 		} else if errors.Is(err, utils.ErrNotPermitted) {
 			utils.NotPermittedResponse(w)
 			return
+		} else if errors.Is(err, utils.ErrScanPending) || errors.Is(err, utils.ErrFileInfected) || errors.Is(err, utils.ErrStripPending) {
+			withheldResponse(w, err)
+			return
 		}
 
 		utils.ServerErrorResponse(w, "file unavailable")
 		return
 	}
 
+	serveFile(w, r, stream, fileInfo)
+}
+
+// serveFile writes the file content from stream as an attachment and closes the stream
+func serveFile(w http.ResponseWriter, r *http.Request, stream io.ReadSeekCloser, fileInfo database.GetFileInfoRow) {
 	defer stream.Close()
 
 	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, fileInfo.Filename))
 	w.Header().Set("Content-Type", fileInfo.MimeType)
-	w.Header().Set("Content-Length", strconv.FormatInt(fileInfo.SizeBytes, 10))
+	w.Header().Set("ETag", strconv.Quote(fileInfo.Checksum))
+	if fileInfo.Visibility == database.FileVisibilityPrivate {
+		w.Header().Set("Cache-Control", "private, no-cache")
+	}
+
+	// ServeContent handles Range, If-Range and the conditional request headers using the ETag and modification time
+	http.ServeContent(w, r, fileInfo.Filename, fileInfo.UpdatedAt, stream)
+}
 
-	if _, err := io.Copy(w, stream); err != nil {
-		h.logger.Error("connection dropped during download", "error", err)
+// withheldResponse tells the client why content withheld by the malware scan or location stripping cannot be served.
+// Content waiting to be scanned or stripped is a 409 with a Retry-After hint, infected content is a 403.
+func withheldResponse(w http.ResponseWriter, err error) {
+	if errors.Is(err, utils.ErrScanPending) || errors.Is(err, utils.ErrStripPending) {
+		w.Header().Set("Retry-After", "30")
+		utils.WriteErrorJSON(w, http.StatusConflict, err.Error())
+		return
 	}
+
+	utils.WriteErrorJSON(w, http.StatusForbidden, err.Error())
 }
 
 // SetFileVisibility toggles file visibility status
@@ -279,7 +492,7 @@ func (h *FileHandler) SetFileVisibility(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	newVis, err := h.service.SetFileVisibility(r.Context(), fileID, input.Version, database.FileVisibility(input.Visibility))
+	newVis, err := h.service.SetFileVisibility(r.Context(), fileID, user.UserID, input.Version, database.FileVisibility(input.Visibility))
 	if err != nil {
 		utils.WriteServerError(h.logger, "failed to change file visibility status", err)
 		if errors.Is(err, utils.ErrRecordNotFound) {
@@ -350,7 +563,7 @@ func (h *FileHandler) UpdateFileName(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// Delete handles soft deletion
+// Delete moves a file to the trash
 func (h *FileHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	fileID, err := uuid.Parse(r.PathValue("id"))
 	if err != nil {
@@ -383,7 +596,8 @@ func (h *FileHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.service.DeleteFile(r.Context(), fileID, user.UserID, input.Version); err != nil {
+	purgeAfter, err := h.service.DeleteFile(r.Context(), fileID, user.UserID, input.Version)
+	if err != nil {
 		if errors.Is(err, utils.ErrRecordNotFound) {
 			utils.NotFoundResponse(w)
 			return
@@ -393,7 +607,10 @@ func (h *FileHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "file deleted successfully"}, nil)
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"message":     "file moved to trash",
+		"purge_after": purgeAfter,
+	}, nil)
 	if err != nil {
 		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
 	}
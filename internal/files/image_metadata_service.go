@@ -0,0 +1,198 @@
+package files
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/i-christian/fileShare/internal/database"
+	"github.com/i-christian/fileShare/internal/exif"
+	"github.com/i-christian/fileShare/internal/filestore"
+	"github.com/i-christian/fileShare/internal/utils"
+	"github.com/i-christian/fileShare/internal/worker"
+	"github.com/sqlc-dev/pqtype"
+)
+
+// ImageMetadata is the metadata extracted from an image, stored as JSON with its file.
+// Width and Height are the dimensions the image is displayed in, after applying its orientation.
+type ImageMetadata struct {
+	Width  int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
+	exif.Metadata
+}
+
+// stripsLocation reports whether location metadata is removed from new content of the type, as requested
+// for the upload or, when the upload leaves it unset, as the user chose for all of their uploads.
+func (s *FileService) stripsLocation(ctx context.Context, userID uuid.UUID, contentType string, requested sql.NullBool) (bool, error) {
+	if !exif.Supported(contentType) {
+		return false, nil
+	}
+
+	if requested.Valid {
+		return requested.Bool, nil
+	}
+
+	return s.db.GetStripLocation(ctx, userID)
+}
+
+// enqueueImageMetadata schedules metadata extraction, and location stripping if it is pending,
+// for the content at storageKey if it is an image metadata can be read from.
+func (s *FileService) enqueueImageMetadata(fileID uuid.UUID, storageKey, contentType string) {
+	if !exif.Supported(contentType) {
+		return
+	}
+
+	taskPayload := &worker.ImageMetadataPayload{
+		FileID:     fileID,
+		StorageKey: storageKey,
+	}
+
+	opts := []asynq.Option{
+		asynq.MaxRetry(3),
+		asynq.Queue("default"),
+		asynq.Timeout(time.Minute),
+	}
+
+	err := s.taskDistributor.DistributeExtractImageMetadata(context.Background(), taskPayload, opts...)
+	if err != nil {
+		utils.WriteServerError(s.logger, "failed to enqueue image metadata task", err)
+	}
+}
+
+// ExtractImageMetadata records the metadata of an image file. When location stripping is pending the image is
+// first replaced by a copy without location metadata, which the metadata is then extracted from.
+// Nothing is done when the file has been deleted or its content replaced since the task was scheduled.
+func (s *FileService) ExtractImageMetadata(ctx context.Context, fileID uuid.UUID, storageKey string) error {
+	file, err := s.db.GetFileInfo(ctx, fileID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	if file.StorageKey != storageKey {
+		return nil
+	}
+
+	// Images awaiting stripping which are too large to strip stay withheld from everyone but their owner
+	if file.SizeBytes > exif.MaxImageSize {
+		s.logger.Warn("image is too large to extract metadata from", "file_id", fileID, "size", file.SizeBytes)
+		return nil
+	}
+
+	content, err := s.store.Get(ctx, storageKey)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(content)
+	content.Close()
+	if err != nil {
+		return err
+	}
+
+	if file.StripLocationPending {
+		stripped, changed, err := exif.StripLocation(data)
+		if err != nil {
+			// Retrying will not make malformed metadata readable, the image stays withheld
+			return fmt.Errorf("failed to strip location metadata: %w: %w", err, asynq.SkipRetry)
+		}
+		if changed {
+			return s.replaceStrippedContent(ctx, file, stripped)
+		}
+	}
+
+	metadata, err := imageMetadata(data)
+	if err != nil {
+		return err
+	}
+
+	return s.db.SetImageMetadata(ctx, database.SetImageMetadataParams{
+		ImageMetadata: metadata,
+		FileID:        fileID,
+		StorageKey:    storageKey,
+	})
+}
+
+// replaceStrippedContent stores an image without its location metadata as the current content of the file.
+// The copy is new content, so it is scanned and its thumbnails are generated again.
+func (s *FileService) replaceStrippedContent(ctx context.Context, file database.GetFileInfoRow, stripped []byte) error {
+	metadata, err := imageMetadata(stripped)
+	if err != nil {
+		return err
+	}
+
+	storageKey := newStorageKey()
+
+	// Stripping only removes metadata, so the copy is not checked against the upload size or quota again
+	fileSize, checksum, err := s.saveStream(filestore.WithOwner(ctx, file.OwnerID), bytes.NewReader(stripped), storageKey, math.MaxInt64, math.MaxInt64)
+	if err != nil {
+		return err
+	}
+
+	blobKey, err := s.referenceBlob(ctx, file.OwnerID, storageKey, checksum, fileSize)
+	if err != nil {
+		_, _, _ = s.store.Delete(ctx, []string{storageKey})
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	replaced, err := s.db.ReplaceStrippedContent(ctx, database.ReplaceStrippedContentParams{
+		NewStorageKey: blobKey,
+		SizeBytes:     fileSize,
+		Checksum:      checksum,
+		FileID:        file.FileID,
+		StorageKey:    file.StorageKey,
+		ScanStatus:    s.initialScanStatus(),
+		ImageMetadata: metadata,
+	})
+	if err != nil || replaced == 0 {
+		// The file has been given other content in the meantime
+		if err := s.releaseBlobs(ctx, []string{blobKey}); err != nil {
+			utils.WriteServerError(s.logger, "failed to release blob of stripped content", err)
+		}
+		return err
+	}
+
+	if err := s.releaseBlobs(ctx, []string{file.StorageKey}); err != nil {
+		utils.WriteServerError(s.logger, "failed to release blob of content with location metadata", err)
+	}
+
+	s.enqueueScan(file.FileID, blobKey)
+	s.enqueueThumbnail(file.FileID, blobKey, file.MimeType, file.Filename)
+
+	return nil
+}
+
+// imageMetadata extracts the metadata of an image as JSON. The dimensions of images without
+// EXIF metadata, or with metadata which can not be read, are still recorded.
+func imageMetadata(data []byte) (pqtype.NullRawMessage, error) {
+	var metadata ImageMetadata
+
+	if config, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		metadata.Width, metadata.Height = config.Width, config.Height
+	}
+
+	if m, err := exif.Read(data); err == nil {
+		metadata.Metadata = m
+	}
+
+	if exif.SwapsDimensions(metadata.Orientation) {
+		metadata.Width, metadata.Height = metadata.Height, metadata.Width
+	}
+
+	raw, err := json.Marshal(metadata)
+	if err != nil {
+		return pqtype.NullRawMessage{}, err
+	}
+
+	return pqtype.NullRawMessage{RawMessage: raw, Valid: true}, nil
+}
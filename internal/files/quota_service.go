@@ -0,0 +1,71 @@
+package files
+
+import (
+	"context"
+	"io"
+	"math"
+
+	"github.com/google/uuid"
+	"github.com/i-christian/fileShare/internal/database"
+	"github.com/i-christian/fileShare/internal/utils"
+)
+
+// remainingQuota returns how many more bytes the user may store, or math.MaxInt64 when their storage is unlimited.
+// utils.ErrQuotaExceeded is returned when adding newFiles files would exceed the user's file limit.
+func (s *FileService) remainingQuota(ctx context.Context, userID uuid.UUID, newFiles int64) (int64, error) {
+	usage, err := s.db.GetStorageUsage(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	if usage.MaxFiles.Valid && usage.UsedFiles+newFiles > usage.MaxFiles.Int64 {
+		return 0, utils.ErrQuotaExceeded
+	}
+
+	if !usage.MaxBytes.Valid {
+		return math.MaxInt64, nil
+	}
+
+	return max(usage.MaxBytes.Int64-usage.UsedBytes, 0), nil
+}
+
+// reserveQuota checks within the transaction of q that the user may store newFiles more files holding size bytes.
+// The user stays locked until the transaction ends, so uploads finishing concurrently can not exceed the quota together.
+func (s *FileService) reserveQuota(ctx context.Context, q *database.Queries, userID uuid.UUID, newFiles, size int64) error {
+	if err := q.LockUserStorage(ctx, userID); err != nil {
+		return err
+	}
+
+	usage, err := q.GetStorageUsage(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if usage.MaxFiles.Valid && usage.UsedFiles+newFiles > usage.MaxFiles.Int64 {
+		return utils.ErrQuotaExceeded
+	}
+	if usage.MaxBytes.Valid && usage.UsedBytes+size > usage.MaxBytes.Int64 {
+		return utils.ErrQuotaExceeded
+	}
+
+	return nil
+}
+
+// quotaReader fails with utils.ErrQuotaExceeded as soon as more than `remaining` bytes are read,
+// so an upload is aborted before more content than the user's quota allows is written to storage.
+type quotaReader struct {
+	r         io.Reader
+	remaining int64
+	exceeded  bool
+}
+
+func (q *quotaReader) Read(p []byte) (int, error) {
+	n, err := q.r.Read(p)
+	q.remaining -= int64(n)
+	if q.remaining < 0 {
+		q.exceeded = true
+		return n, utils.ErrQuotaExceeded
+	}
+
+	return n, err
+}
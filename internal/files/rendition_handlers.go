@@ -0,0 +1,120 @@
+package files
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/i-christian/fileShare/internal/database"
+	"github.com/i-christian/fileShare/internal/utils"
+	"github.com/i-christian/fileShare/internal/utils/security"
+	"github.com/i-christian/fileShare/internal/validator"
+)
+
+// Image serves a resized and converted rendition of an image. Only renditions whose URL
+// was signed by ImageURL are generated, any other combination of parameters is rejected.
+func (h *FileHandler) Image(w http.ResponseWriter, r *http.Request) {
+	fileID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		utils.BadRequestResponse(w, errors.New("invalid file ID parameter"))
+		return
+	}
+
+	user, ok := security.GetUserFromContext(r)
+	if !ok {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	qs := r.URL.Query()
+	opts := ReadRenditionOptions(qs)
+
+	v := validator.New()
+	if ValidateRenditionOptions(v, opts); !v.Valid() {
+		utils.FailedValidationResponse(w, v.Errors)
+		return
+	}
+
+	if !h.service.VerifyRenditionSignature(fileID, opts, qs.Get("sig")) {
+		utils.WriteErrorJSON(w, http.StatusForbidden, utils.ErrBadSignature.Error())
+		return
+	}
+
+	rendition, err := h.service.GetRendition(r.Context(), fileID, user.UserID, opts)
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrRecordNotFound):
+			utils.NotFoundResponse(w)
+		case errors.Is(err, utils.ErrNotPermitted):
+			utils.NotPermittedResponse(w)
+		case errors.Is(err, utils.ErrNotAnImage), errors.Is(err, utils.ErrImageTooLarge):
+			utils.WriteErrorJSON(w, http.StatusUnprocessableEntity, err.Error())
+		case errors.Is(err, utils.ErrScanPending), errors.Is(err, utils.ErrFileInfected), errors.Is(err, utils.ErrStripPending):
+			withheldResponse(w, err)
+		default:
+			utils.WriteServerError(h.logger, "failed to get image rendition", err)
+			utils.ServerErrorResponse(w, "image unavailable")
+		}
+		return
+	}
+
+	scope := "public"
+	if rendition.Visibility == database.FileVisibilityPrivate {
+		scope = "private"
+	}
+
+	// Renditions keep their URL when a new version of a file is uploaded, like thumbnails
+	w.Header().Set("Cache-Control", fmt.Sprintf("%s, max-age=%d", scope, thumbnailMaxAge))
+	w.Header().Set("Content-Type", rendition.ContentType)
+	w.Header().Set("ETag", strconv.Quote(rendition.ETag))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	http.ServeContent(w, r, "", rendition.UpdatedAt, bytes.NewReader(rendition.Content))
+}
+
+// ImageURL signs the URL of a rendition of an image the user can access, within the bounds renditions are limited to
+func (h *FileHandler) ImageURL(w http.ResponseWriter, r *http.Request) {
+	fileID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		utils.BadRequestResponse(w, errors.New("invalid file ID parameter"))
+		return
+	}
+
+	user, ok := security.GetUserFromContext(r)
+	if !ok || user.IsAnonymous() {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	opts := ReadRenditionOptions(r.URL.Query())
+
+	v := validator.New()
+	if ValidateRenditionOptions(v, opts); !v.Valid() {
+		utils.FailedValidationResponse(w, v.Errors)
+		return
+	}
+
+	imageURL, err := h.service.RenditionURL(r.Context(), fileID, user.UserID, opts)
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrRecordNotFound):
+			utils.NotFoundResponse(w)
+		case errors.Is(err, utils.ErrNotPermitted):
+			utils.NotPermittedResponse(w)
+		case errors.Is(err, utils.ErrNotAnImage):
+			utils.WriteErrorJSON(w, http.StatusUnprocessableEntity, err.Error())
+		default:
+			utils.WriteServerError(h.logger, "failed to sign image URL", err)
+			utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+		}
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"url": imageURL}, nil)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+	}
+}
@@ -0,0 +1,309 @@
+package files
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/disintegration/imaging"
+	"github.com/google/uuid"
+	"github.com/i-christian/fileShare/internal/database"
+	"github.com/i-christian/fileShare/internal/utils"
+	"github.com/i-christian/fileShare/internal/validator"
+	"github.com/i-christian/fileShare/internal/webp"
+)
+
+const (
+	// maxRenditionDimension is the largest width or height a rendition can be requested in.
+	maxRenditionDimension = 2048
+	// maxRenditionSourceSize is the largest image renditions are generated from, images are read into memory.
+	maxRenditionSourceSize = 50 << 20
+	// maxRenditionSourcePixels is the largest number of pixels an image renditions are generated from may have,
+	// which keeps small but highly compressed images from taking up too much memory once decoded.
+	maxRenditionSourcePixels = 50_000_000
+	// defaultRenditionQuality is the JPEG quality renditions are encoded with unless another is requested.
+	defaultRenditionQuality = 85
+)
+
+// renditionSources are the MIME types of the images renditions can be generated from.
+var renditionSources = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+	"image/bmp":  true,
+	"image/tiff": true,
+}
+
+// RenditionOptions describe a rendition of an image. A missing width or height is derived from the aspect ratio
+// of the image. Fit is one of contain, which scales the image down to fit within the dimensions, cover, which
+// scales and crops it to fill them, or fill, which stretches it to them. Quality only applies to JPEG renditions.
+type RenditionOptions struct {
+	Width   int
+	Height  int
+	Fit     string
+	Format  string
+	Quality int
+}
+
+// ReadRenditionOptions reads the options of a rendition from query parameters, applying the defaults.
+func ReadRenditionOptions(qs url.Values) RenditionOptions {
+	opts := RenditionOptions{
+		Width:   utils.ReadInt(qs, "w", 0),
+		Height:  utils.ReadInt(qs, "h", 0),
+		Fit:     utils.ReadString(qs, "fit", "contain"),
+		Format:  utils.ReadString(qs, "format", "jpeg"),
+		Quality: utils.ReadInt(qs, "q", defaultRenditionQuality),
+	}
+
+	// Other formats are lossless, the quality of their renditions never differs
+	if opts.Format != "jpeg" {
+		opts.Quality = 0
+	}
+
+	return opts
+}
+
+// ValidateRenditionOptions bounds the dimensions and quality renditions can be requested in.
+func ValidateRenditionOptions(v *validator.Validator, opts RenditionOptions) {
+	v.Check(opts.Width > 0 || opts.Height > 0, "w", "a width or height must be provided")
+	v.Check(opts.Width >= 0 && opts.Width <= maxRenditionDimension, "w", fmt.Sprintf("must be between 1 and %d", maxRenditionDimension))
+	v.Check(opts.Height >= 0 && opts.Height <= maxRenditionDimension, "h", fmt.Sprintf("must be between 1 and %d", maxRenditionDimension))
+	v.Check(validator.PermittedValue(opts.Fit, "contain", "cover", "fill"), "fit", "must be contain, cover or fill")
+	v.Check(opts.Fit != "cover" || (opts.Width > 0 && opts.Height > 0), "fit", "cover requires both a width and a height")
+	v.Check(validator.PermittedValue(opts.Format, "jpeg", "png", "webp"), "format", "must be jpeg, png or webp")
+	if opts.Format == "jpeg" {
+		v.Check(opts.Quality >= 1 && opts.Quality <= 100, "q", "must be between 1 and 100")
+	}
+}
+
+// query returns the options as the query parameters of a rendition URL, in a fixed order as they are signed.
+func (opts RenditionOptions) query() string {
+	qs := fmt.Sprintf("w=%d&h=%d&fit=%s&format=%s", opts.Width, opts.Height, opts.Fit, opts.Format)
+	if opts.Format == "jpeg" {
+		qs += "&q=" + strconv.Itoa(opts.Quality)
+	}
+	return qs
+}
+
+// Rendition is a resized and converted copy of an image.
+type Rendition struct {
+	Content     []byte
+	ContentType string
+	// ETag changes whenever the rendition is generated from other content
+	ETag       string
+	Visibility database.FileVisibility
+	UpdatedAt  time.Time
+}
+
+// signRendition returns the signature of the URL of a rendition of the file.
+func (s *FileService) signRendition(fileID uuid.UUID, opts RenditionOptions) string {
+	mac := hmac.New(sha256.New, s.imageURLKey)
+	mac.Write([]byte(fileID.String() + "?" + opts.query()))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyRenditionSignature reports whether the signature approves generating the rendition of the file.
+func (s *FileService) VerifyRenditionSignature(fileID uuid.UUID, opts RenditionOptions, signature string) bool {
+	expected := s.signRendition(fileID, opts)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// RenditionURL returns the signed URL of a rendition of an image the user can access.
+// The URL stays valid for as long as the file exists and serves renditions of its current content.
+func (s *FileService) RenditionURL(ctx context.Context, fileID, userID uuid.UUID, opts RenditionOptions) (string, error) {
+	fileInfo, err := s.db.GetFileInfo(ctx, fileID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", utils.ErrRecordNotFound
+		}
+		return "", err
+	}
+
+	if fileInfo.OwnerID != userID && fileInfo.Visibility == database.FileVisibilityPrivate {
+		return "", utils.ErrNotPermitted
+	}
+
+	if !renditionSources[fileInfo.MimeType] {
+		return "", utils.ErrNotAnImage
+	}
+
+	signature := s.signRendition(fileID, opts)
+
+	return fmt.Sprintf("/api/v1/files/%s/image?%s&sig=%s", fileID, opts.query(), signature), nil
+}
+
+// renditionKey returns the storage key a rendition of the content at storageKey is cached under.
+func renditionKey(storageKey string, opts RenditionOptions) string {
+	sum := sha256.Sum256([]byte(storageKey + "?" + opts.query()))
+	ext := opts.Format
+	if ext == "jpeg" {
+		ext = "jpg"
+	}
+
+	return "renditions/" + hex.EncodeToString(sum[:16]) + "." + ext
+}
+
+// GetRendition returns a rendition of an image, applying the same checks as DownloadFile. Renditions are
+// generated on the first request and cached in storage, for every file holding the same content.
+func (s *FileService) GetRendition(ctx context.Context, fileID, userID uuid.UUID, opts RenditionOptions) (Rendition, error) {
+	fileInfo, err := s.db.GetFileInfo(ctx, fileID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Rendition{}, utils.ErrRecordNotFound
+		}
+		return Rendition{}, err
+	}
+
+	isOwner := userID != uuid.Nil && fileInfo.OwnerID == userID
+	if !isOwner && fileInfo.Visibility == database.FileVisibilityPrivate {
+		return Rendition{}, utils.ErrNotPermitted
+	}
+
+	if err := checkScanStatus(fileInfo.ScanStatus); err != nil {
+		return Rendition{}, err
+	}
+
+	if fileInfo.StripLocationPending && !isOwner {
+		return Rendition{}, utils.ErrStripPending
+	}
+
+	if !renditionSources[fileInfo.MimeType] {
+		return Rendition{}, utils.ErrNotAnImage
+	}
+
+	key := renditionKey(fileInfo.StorageKey, opts)
+	sum := sha256.Sum256([]byte(key))
+	rendition := Rendition{
+		ContentType: "image/" + opts.Format,
+		ETag:        hex.EncodeToString(sum[:8]),
+		Visibility:  fileInfo.Visibility,
+		UpdatedAt:   fileInfo.UpdatedAt,
+	}
+
+	if cached, err := s.store.Get(ctx, key); err == nil {
+		rendition.Content, err = io.ReadAll(cached)
+		cached.Close()
+		if err == nil {
+			return rendition, nil
+		}
+	}
+
+	rendition.Content, err = s.generateRendition(ctx, fileInfo, opts)
+	if err != nil {
+		return Rendition{}, err
+	}
+
+	// A failure to cache the rendition only means it is generated again on the next request
+	if _, err := s.store.Save(ctx, bytes.NewReader(rendition.Content), key); err != nil {
+		utils.WriteServerError(s.logger, "failed to cache image rendition", err)
+		return rendition, nil
+	}
+
+	err = s.db.CreateImageRendition(ctx, database.CreateImageRenditionParams{
+		RenditionKey: key,
+		StorageKey:   fileInfo.StorageKey,
+		SizeBytes:    int64(len(rendition.Content)),
+	})
+	if err != nil {
+		// The blob may have been deleted meanwhile, an unrecorded rendition would never be deleted
+		utils.WriteServerError(s.logger, "failed to record image rendition", err)
+		_, _, _ = s.store.Delete(ctx, []string{key})
+	}
+
+	return rendition, nil
+}
+
+// generateRendition reads an image, scales it as the options say and encodes it in the requested format.
+func (s *FileService) generateRendition(ctx context.Context, fileInfo database.GetFileInfoRow, opts RenditionOptions) ([]byte, error) {
+	if fileInfo.SizeBytes > maxRenditionSourceSize {
+		return nil, utils.ErrImageTooLarge
+	}
+
+	content, err := s.store.Get(ctx, fileInfo.StorageKey)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(content)
+	content.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, utils.ErrNotAnImage
+	}
+	if config.Width*config.Height > maxRenditionSourcePixels {
+		return nil, utils.ErrImageTooLarge
+	}
+
+	img, err := imaging.Decode(bytes.NewReader(data), imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, utils.ErrNotAnImage
+	}
+
+	switch opts.Fit {
+	case "cover":
+		img = imaging.Fill(img, opts.Width, opts.Height, imaging.Center, imaging.Lanczos)
+	case "fill":
+		img = imaging.Resize(img, opts.Width, opts.Height, imaging.Lanczos)
+	default:
+		// A missing dimension does not bound the image, contained images are never enlarged
+		width, height := opts.Width, opts.Height
+		if width == 0 {
+			width = img.Bounds().Dx()
+		}
+		if height == 0 {
+			height = img.Bounds().Dy()
+		}
+		img = imaging.Fit(img, width, height, imaging.Lanczos)
+	}
+
+	buf := new(bytes.Buffer)
+	switch opts.Format {
+	case "png":
+		err = imaging.Encode(buf, img, imaging.PNG)
+	case "webp":
+		err = webp.Encode(buf, img)
+	default:
+		err = imaging.Encode(buf, img, imaging.JPEG, imaging.JPEGQuality(opts.Quality))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// deleteRenditions removes the renditions generated from blobs from storage.
+func (s *FileService) deleteRenditions(ctx context.Context, storageKeys []string) error {
+	renditionKeys, err := s.db.GetBlobRenditions(ctx, storageKeys)
+	if err != nil {
+		return fmt.Errorf("failed to fetch image renditions: %w", err)
+	}
+	if len(renditionKeys) == 0 {
+		return nil
+	}
+
+	_, failed, err := s.store.Delete(ctx, renditionKeys)
+	if err != nil {
+		return fmt.Errorf("failed to delete image renditions from storage: %w", err)
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to delete %d of %d image renditions from storage", failed, len(renditionKeys))
+	}
+
+	return nil
+}
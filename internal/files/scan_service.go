@@ -0,0 +1,181 @@
+package files
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/i-christian/fileShare/internal/database"
+	"github.com/i-christian/fileShare/internal/utils"
+	"github.com/i-christian/fileShare/internal/worker"
+)
+
+// quarantineDir is the storage prefix infected content is moved to, where it is kept but never served.
+const quarantineDir = "quarantine"
+
+// initialScanStatus returns the scan status of newly stored content, which is only scanned when a scanner is configured.
+func (s *FileService) initialScanStatus() database.FileScanStatus {
+	if s.scanner == nil {
+		return database.FileScanStatusSkipped
+	}
+	return database.FileScanStatusPending
+}
+
+// enqueueScan schedules a malware scan of the content at storageKey if a scanner is configured.
+func (s *FileService) enqueueScan(fileID uuid.UUID, storageKey string) {
+	if s.scanner == nil {
+		return
+	}
+
+	taskPayload := &worker.ScanFilePayload{
+		FileID:     fileID,
+		StorageKey: storageKey,
+	}
+
+	opts := []asynq.Option{
+		asynq.MaxRetry(5),
+		asynq.Queue("default"),
+		asynq.Timeout(3 * time.Minute),
+	}
+
+	err := s.taskDistributor.DistributeScanFile(context.Background(), taskPayload, opts...)
+	if err != nil {
+		utils.WriteServerError(s.logger, "failed to enqueue scan task", err)
+	}
+}
+
+// checkScanStatus returns an error when content with the scan status must not be served.
+func checkScanStatus(status database.FileScanStatus) error {
+	switch status {
+	case database.FileScanStatusPending:
+		return utils.ErrScanPending
+	case database.FileScanStatusInfected:
+		return utils.ErrFileInfected
+	default:
+		return nil
+	}
+}
+
+// isQuarantined reports whether the content at storageKey was quarantined.
+func isQuarantined(storageKey string) bool {
+	return strings.HasPrefix(storageKey, quarantineDir+"/")
+}
+
+// ScanFile scans a file's content for malware, the content is quarantined when it is infected.
+// Nothing is done when the file has been deleted or its content replaced since the task was scheduled.
+func (s *FileService) ScanFile(ctx context.Context, fileID uuid.UUID, storageKey string) error {
+	if s.scanner == nil {
+		return nil
+	}
+
+	file, err := s.db.GetFileInfo(ctx, fileID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	if file.StorageKey != storageKey || file.ScanStatus != database.FileScanStatusPending {
+		return nil
+	}
+
+	// Content uploaded again after it was quarantined shares the quarantined blob
+	if isQuarantined(storageKey) {
+		return s.quarantine(ctx, storageKey, "")
+	}
+
+	content, err := s.store.Get(ctx, storageKey)
+	if err != nil {
+		return fmt.Errorf("failed to open file content: %w", err)
+	}
+	defer content.Close()
+
+	result, err := s.scanner.Scan(ctx, content)
+	if err != nil {
+		return fmt.Errorf("failed to scan file: %w", err)
+	}
+
+	if !result.Infected {
+		_, err = s.db.SetScanResult(ctx, database.SetScanResultParams{
+			ScanStatus: database.FileScanStatusClean,
+			StorageKey: storageKey,
+		})
+		return err
+	}
+
+	s.logger.Warn("malware detected in file", "file_id", fileID, "storage_key", storageKey, "signature", result.Signature)
+
+	return s.quarantine(ctx, storageKey, result.Signature)
+}
+
+// quarantine moves infected content under the quarantine prefix, marks every file holding it as infected
+// and notifies their owners. The content is moved back when the files cannot be updated, so the scan can be retried.
+func (s *FileService) quarantine(ctx context.Context, storageKey, signature string) error {
+	quarantineKey := storageKey
+	if !isQuarantined(storageKey) {
+		quarantineKey = filepath.Join(quarantineDir, filepath.Base(storageKey))
+
+		if err := s.store.Move(ctx, storageKey, quarantineKey); err != nil {
+			return fmt.Errorf("failed to move infected content to quarantine: %w", err)
+		}
+	}
+
+	files, err := s.db.QuarantineContent(ctx, database.QuarantineContentParams{
+		QuarantineKey: quarantineKey,
+		StorageKey:    storageKey,
+	})
+	if err != nil {
+		if quarantineKey != storageKey {
+			if err := s.store.Move(ctx, quarantineKey, storageKey); err != nil {
+				utils.WriteServerError(s.logger, "failed to move infected content back from quarantine", err)
+			}
+		}
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	for _, file := range files {
+		s.notifyQuarantined(ctx, file, signature)
+	}
+
+	return nil
+}
+
+// notifyQuarantined emails the owner of a file whose content was quarantined.
+func (s *FileService) notifyQuarantined(ctx context.Context, file database.QuarantineContentRow, signature string) {
+	owner, err := s.db.GetFileOwner(ctx, file.FileID)
+	if err != nil {
+		utils.WriteServerError(s.logger, "failed to fetch owner of quarantined file", err)
+		return
+	}
+
+	data := map[string]any{
+		"AppName":   utils.GetEnvOrFile("PROJECT_NAME"),
+		"FirstName": owner.FirstName,
+		"Filename":  file.Filename,
+		"FileID":    file.FileID,
+		"Signature": signature,
+		"Year":      time.Now().Year(),
+	}
+	payload := &worker.EmailPayload{
+		Recipient:    owner.Email,
+		UserID:       owner.UserID,
+		TemplateFile: "file_quarantined.tmpl",
+		Data:         data,
+	}
+	opts := []asynq.Option{
+		asynq.Queue("critical"),
+		asynq.MaxRetry(5),
+	}
+
+	err = s.taskDistributor.DistributeSendEmail(context.Background(), payload, opts...)
+	if err != nil {
+		utils.WriteServerError(s.logger, "failed to queue quarantine email", err)
+	}
+}
@@ -0,0 +1,64 @@
+package files
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/i-christian/fileShare/internal/utils"
+	"github.com/i-christian/fileShare/internal/utils/security"
+	"github.com/i-christian/fileShare/internal/validator"
+)
+
+// Search finds files by filename, tags and document text, ranked by relevance.
+// Signed in users search their own files and public files, anonymous users only public files.
+func (h *FileHandler) Search(w http.ResponseWriter, r *http.Request) {
+	user, ok := security.GetUserFromContext(r)
+	if !ok {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	qs := r.URL.Query()
+	query := utils.ReadString(qs, "q", "")
+
+	input := validator.Filters{
+		Page:     utils.ReadInt(qs, "page", 1),
+		PageSize: utils.ReadInt(qs, "page_size", 20),
+	}
+
+	v := validator.New()
+	useCursor, cursor := readCursor(qs, v)
+	validator.ValidateSearchQuery(v, query)
+	if validator.ValidateFilters(v, input); !v.Valid() {
+		utils.FailedValidationResponse(w, v.Errors)
+		return
+	}
+
+	filters := utils.Filters{
+		Page:         input.Page,
+		PageSize:     input.PageSize,
+		UseCursor:    useCursor,
+		Cursor:       cursor,
+		IncludeTotal: utils.ReadBool(qs, "include_total", false),
+	}
+
+	var userID uuid.NullUUID
+	if !user.IsAnonymous() {
+		userID = uuid.NullUUID{UUID: user.UserID, Valid: true}
+	}
+
+	results, metadata, err := h.service.SearchFiles(r.Context(), userID, query, filters)
+	if err != nil {
+		utils.WriteServerError(h.logger, "failed to search files", err)
+		utils.ServerErrorResponse(w, "failed to search files")
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"metadata": metadata,
+		"files":    results,
+	}, nil)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+	}
+}
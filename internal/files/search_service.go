@@ -0,0 +1,135 @@
+package files
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/i-christian/fileShare/internal/database"
+	"github.com/i-christian/fileShare/internal/extract"
+	"github.com/i-christian/fileShare/internal/utils"
+	"github.com/i-christian/fileShare/internal/worker"
+)
+
+// enqueueTextExtraction schedules extraction of the searchable text of the content at storageKey if its type is supported.
+func (s *FileService) enqueueTextExtraction(fileID uuid.UUID, storageKey, contentType, fileName string) {
+	if !extract.Supported(contentType, fileName) {
+		return
+	}
+
+	taskPayload := &worker.ExtractTextPayload{
+		FileID:     fileID,
+		StorageKey: storageKey,
+	}
+
+	opts := []asynq.Option{
+		asynq.MaxRetry(3),
+		asynq.Queue("low"),
+		asynq.Timeout(2 * time.Minute),
+	}
+
+	err := s.taskDistributor.DistributeExtractText(context.Background(), taskPayload, opts...)
+	if err != nil {
+		utils.WriteServerError(s.logger, "failed to enqueue text extraction task", err)
+	}
+}
+
+// ExtractText indexes the text of a file's content for search.
+// Nothing is done when the file has been deleted or its content replaced since the task was scheduled.
+func (s *FileService) ExtractText(ctx context.Context, fileID uuid.UUID, storageKey string) error {
+	file, err := s.db.GetFileInfo(ctx, fileID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	if file.StorageKey != storageKey || file.SizeBytes > extract.MaxDocumentSize {
+		return nil
+	}
+
+	content, err := s.store.Get(ctx, storageKey)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	text, err := extract.Text(content, file.MimeType, file.Filename)
+	if err != nil {
+		// Retrying will not make a malformed document readable
+		return fmt.Errorf("%w: %w", err, asynq.SkipRetry)
+	}
+
+	return s.db.UpdateFileExtractedText(ctx, database.UpdateFileExtractedTextParams{
+		ExtractedText: sql.NullString{String: text, Valid: text != ""},
+		FileID:        fileID,
+		StorageKey:    storageKey,
+	})
+}
+
+// SearchFiles runs a full-text search over the user's own files and public files.
+// Anonymous users, with an unset userID, only search public files.
+// With cursor pagination the results are only counted when filters.IncludeTotal is set.
+func (s *FileService) SearchFiles(ctx context.Context, userID uuid.NullUUID, query string, filters utils.Filters) ([]database.SearchFilesRow, utils.Metadata, error) {
+	limit, offset := pageBounds(filters)
+
+	var count int64
+	if !filters.UseCursor || filters.IncludeTotal {
+		var err error
+		count, err = s.db.CountSearchFiles(ctx, database.CountSearchFilesParams{
+			Query:  query,
+			UserID: userID,
+		})
+		if err != nil {
+			return []database.SearchFilesRow{}, utils.Metadata{}, err
+		}
+	}
+
+	params := database.SearchFilesParams{
+		Query:      query,
+		UserID:     userID,
+		PageLimit:  limit,
+		PageOffset: offset,
+	}
+	if filters.Cursor != nil {
+		params.CursorID = uuid.NullUUID{UUID: filters.Cursor.ID, Valid: true}
+		params.CursorRank = filters.Cursor.Rank
+		params.Ascending = filters.Cursor.Backward
+	}
+
+	results, err := s.db.SearchFiles(ctx, params)
+	if err != nil {
+		return []database.SearchFilesRow{}, utils.Metadata{}, err
+	}
+
+	for i := range results {
+		results[i].Snippet = escapeSnippet(results[i].Snippet)
+	}
+
+	if filters.UseCursor {
+		results, meta := cursorPage(results, filters, int(count), func(r database.SearchFilesRow) utils.Cursor {
+			return utils.Cursor{Rank: r.Rank, ID: r.FileID}
+		})
+		return results, meta, nil
+	}
+
+	meta := utils.CalculateMetadata(int(count), filters.Page, filters.PageSize)
+
+	return results, meta, nil
+}
+
+// snippetMarks restores the highlighting tags of a snippet after it has been HTML escaped
+var snippetMarks = strings.NewReplacer("&lt;mark&gt;", "<mark>", "&lt;/mark&gt;", "</mark>")
+
+// escapeSnippet HTML escapes the document text in a search snippet so it is safe to render,
+// keeping the <mark> tags which highlight matches.
+func escapeSnippet(snippet string) string {
+	return snippetMarks.Replace(html.EscapeString(snippet))
+}
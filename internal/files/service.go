@@ -2,7 +2,6 @@
 package files
 
 import (
-	"bytes"
 	"context"
 	"crypto/sha256"
 	"database/sql"
@@ -12,57 +11,113 @@ import (
 	"io"
 	"log/slog"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
-	"github.com/disintegration/imaging"
 	"github.com/google/uuid"
-	"github.com/hibiken/asynq"
 	"github.com/i-christian/fileShare/internal/database"
 	"github.com/i-christian/fileShare/internal/filestore"
+	"github.com/i-christian/fileShare/internal/preview"
+	"github.com/i-christian/fileShare/internal/scanner"
 	"github.com/i-christian/fileShare/internal/utils"
 	"github.com/i-christian/fileShare/internal/worker"
 )
 
 type FileService struct {
+	// conn runs the transactions spanning several queries
+	conn            *sql.DB
 	db              *database.Queries
 	store           filestore.FileStorage
 	logger          *slog.Logger
 	taskDistributor worker.Distributor
+	// scanner checks uploaded content for malware, content is not scanned when it is nil
+	scanner scanner.Scanner
+	// previewers render the images thumbnails are generated from, by MIME type
+	previewers *preview.Registry
+	// imageURLKey signs the URLs of image renditions, so only approved renditions are generated
+	imageURLKey []byte
+	// resumeKey signs the tokens continuing share link downloads, so continuations are not counted again
+	resumeKey       []byte
+	maxFileVersions int32
+	// trashRetentionDays is how long deleted files stay in the trash for users without their own retention period
+	trashRetentionDays int32
 }
 
-func NewFileService(db *database.Queries, store filestore.FileStorage, logger *slog.Logger, taskDist worker.Distributor) *FileService {
+func NewFileService(conn *sql.DB, db *database.Queries, store filestore.FileStorage, logger *slog.Logger, taskDist worker.Distributor, fileScanner scanner.Scanner, previewers *preview.Registry, imageURLKey, resumeKey []byte, maxFileVersions, trashRetentionDays int32) *FileService {
 	return &FileService{
-		db:              db,
-		store:           store,
-		logger:          logger,
-		taskDistributor: taskDist,
+		conn:               conn,
+		db:                 db,
+		store:              store,
+		logger:             logger,
+		taskDistributor:    taskDist,
+		scanner:            fileScanner,
+		previewers:         previewers,
+		imageURLKey:        imageURLKey,
+		resumeKey:          resumeKey,
+		maxFileVersions:    maxFileVersions,
+		trashRetentionDays: trashRetentionDays,
 	}
 }
 
-// UploadFile streams the file to storage while calculating the checksum simultaneously.
-func (s *FileService) UploadFile(userID uuid.UUID, fileStream io.Reader, contentType string, fileName string, maxUploadSize int64) (database.CreateFileRow, error) {
-	uniqueFilename := uuid.New().String() + filepath.Ext(fileName)
-	dirPath := filepath.Join("users", userID.String())
-	storageKey := filepath.Join(dirPath, uniqueFilename)
+// newStorageKey returns a unique storage key for uploaded content. Once its checksum is known the content
+// either becomes a blob shared by all of the user's files with the same content or is replaced by their existing blob.
+func newStorageKey() string {
+	return filepath.Join("blobs", uuid.New().String())
+}
 
+// saveStream streams content to storage under storageKey while calculating its checksum simultaneously.
+// The stored content is removed if it can not be saved completely, exceeds maxUploadSize or
+// is larger than the `quota` bytes the user has left, in which case utils.ErrQuotaExceeded is returned.
+func (s *FileService) saveStream(ctx context.Context, fileStream io.Reader, storageKey string, maxUploadSize, quota int64) (fileSize int64, checksum string, err error) {
 	hasher := sha256.New()
-	tee := io.TeeReader(fileStream, hasher)
+	limited := &quotaReader{r: fileStream, remaining: quota}
+	tee := io.TeeReader(limited, hasher)
 
-	fCtx := context.Background()
-	fileSize, err := s.store.Save(fCtx, tee, storageKey)
+	fileSize, err = s.store.Save(ctx, tee, storageKey)
+	if limited.exceeded {
+		_, _, _ = s.store.Delete(ctx, []string{storageKey})
+		return 0, "", utils.ErrQuotaExceeded
+	}
 	if err != nil {
 		s.logger.Error("failed to save file to storage", "key", storageKey, "error", err)
-		_, _, _ = s.store.Delete(fCtx, []string{storageKey})
-		return database.CreateFileRow{}, fmt.Errorf("storage error")
+		_, _, _ = s.store.Delete(ctx, []string{storageKey})
+		return 0, "", fmt.Errorf("storage error")
 	}
 	if fileSize > maxUploadSize {
-		_, _, _ = s.store.Delete(fCtx, []string{storageKey})
-		return database.CreateFileRow{}, fmt.Errorf("file size is too large")
+		_, _, _ = s.store.Delete(ctx, []string{storageKey})
+		return 0, "", fmt.Errorf("file size is too large")
+	}
+
+	return fileSize, hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// UploadFile streams the file to storage while calculating the checksum simultaneously.
+// The upload is aborted with utils.ErrQuotaExceeded once it would exceed the user's storage quota.
+// stripLocation requests removing location metadata from an image, when unset the user's setting applies.
+func (s *FileService) UploadFile(userID uuid.UUID, fileStream io.Reader, contentType string, fileName string, tags []string, stripLocation sql.NullBool, maxUploadSize int64) (database.CreateFileRow, error) {
+	ctx := filestore.WithOwner(context.Background(), userID)
+
+	quota, err := s.remainingQuota(ctx, userID, 1)
+	if err != nil {
+		return database.CreateFileRow{}, err
 	}
 
-	hashBytes := hasher.Sum(nil)
-	checksum := hex.EncodeToString(hashBytes)
+	storageKey := newStorageKey()
+
+	fileSize, checksum, err := s.saveStream(ctx, fileStream, storageKey, maxUploadSize, quota)
+	if err != nil {
+		return database.CreateFileRow{}, err
+	}
+
+	return s.createFileRecord(userID, storageKey, contentType, fileName, tags, stripLocation, fileSize, checksum)
+}
+
+// createFileRecord registers a file which has already been written to storage under storageKey.
+// The content is stored as a blob shared with identical content uploaded by the same user, and
+// removed again if the file is a duplicate or the record cannot be created.
+func (s *FileService) createFileRecord(userID uuid.UUID, storageKey, contentType, fileName string, tags []string, stripLocation sql.NullBool, fileSize int64, checksum string) (database.CreateFileRow, error) {
+	fCtx := context.Background()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -77,82 +132,74 @@ func (s *FileService) UploadFile(userID uuid.UUID, fileStream io.Reader, content
 		return database.CreateFileRow{}, utils.ErrDuplicateUpload
 	}
 
-	params := database.CreateFileParams{
-		UserID:     userID,
-		Filename:   fileName,
-		StorageKey: storageKey,
-		MimeType:   contentType,
-		SizeBytes:  fileSize,
-		Checksum:   checksum,
+	stripPending, err := s.stripsLocation(ctx, userID, contentType, stripLocation)
+	if err != nil {
+		_, _, _ = s.store.Delete(fCtx, []string{storageKey})
+		return database.CreateFileRow{}, fmt.Errorf("database error: %w", err)
 	}
 
-	fileRec, err := s.db.CreateFile(ctx, params)
+	blobKey, err := s.referenceBlob(ctx, userID, storageKey, checksum, fileSize)
 	if err != nil {
 		_, _, _ = s.store.Delete(fCtx, []string{storageKey})
 		return database.CreateFileRow{}, fmt.Errorf("database error: %w", err)
 	}
+	storageKey = blobKey
 
-	if strings.HasPrefix(contentType, "image/") {
-		taskPayload := &worker.ThumbnailPayload{
-			FileID:     fileRec.FileID,
-			StorageKey: storageKey,
-		}
+	params := database.CreateFileParams{
+		UserID:               userID,
+		Filename:             fileName,
+		StorageKey:           storageKey,
+		MimeType:             contentType,
+		SizeBytes:            fileSize,
+		Checksum:             checksum,
+		Tags:                 tags,
+		ScanStatus:           s.initialScanStatus(),
+		StripLocationPending: stripPending,
+	}
 
-		opts := []asynq.Option{
-			asynq.MaxRetry(3),
-			asynq.Queue("default"),
-			asynq.Timeout(20 * time.Second),
+	fileRec, err := s.insertFile(ctx, params)
+	if err != nil {
+		if err := s.releaseBlobs(fCtx, []string{storageKey}); err != nil {
+			utils.WriteServerError(s.logger, "failed to release blob of a file which could not be created", err)
 		}
-
-		err := s.taskDistributor.DistributeGenerateThumbnail(context.Background(), taskPayload, opts...)
-		if err != nil {
-			utils.WriteServerError(s.logger, "failed to enqueue thumbnail task", err)
+		if errors.Is(err, utils.ErrQuotaExceeded) {
+			return database.CreateFileRow{}, err
 		}
+		return database.CreateFileRow{}, fmt.Errorf("database error: %w", err)
 	}
 
+	s.enqueueScan(fileRec.FileID, storageKey)
+	s.enqueueThumbnail(fileRec.FileID, storageKey, contentType, fileName)
+	s.enqueueImageMetadata(fileRec.FileID, storageKey, contentType)
+	s.enqueueTextExtraction(fileRec.FileID, storageKey, contentType, fileName)
+
 	return fileRec, nil
 }
 
-// GenerateThumbnail creates a thumbnail for an image file
-func (s *FileService) GenerateThumbnail(fileID uuid.UUID, storageKey string) error {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	originalFile, err := s.store.Get(ctx, storageKey)
-	if err != nil {
-		return err
-	}
-	defer originalFile.Close()
-
-	img, err := imaging.Decode(originalFile)
+// insertFile creates the file record once the user's quota, checked again in the same transaction, leaves room for it.
+// The quota checked before the upload started may have been used up by uploads which finished in the meantime.
+func (s *FileService) insertFile(ctx context.Context, params database.CreateFileParams) (database.CreateFileRow, error) {
+	tx, err := s.conn.BeginTx(ctx, nil)
 	if err != nil {
-		return err
+		return database.CreateFileRow{}, err
 	}
+	defer tx.Rollback()
+	q := s.db.WithTx(tx)
 
-	thumbnail := imaging.Resize(img, 300, 0, imaging.Lanczos)
-
-	buf := new(bytes.Buffer)
-	err = imaging.Encode(buf, thumbnail, imaging.JPEG)
-	if err != nil {
-		return err
+	if err := s.reserveQuota(ctx, q, params.UserID, 1, params.SizeBytes); err != nil {
+		return database.CreateFileRow{}, err
 	}
 
-	thumbKey := "thumbnails/" + uuid.New().String() + ".jpg"
-	_, err = s.store.Save(ctx, buf, thumbKey)
+	fileRec, err := q.CreateFile(ctx, params)
 	if err != nil {
-		return err
+		return database.CreateFileRow{}, err
 	}
 
-	err = s.db.UpdateFileThumbnail(ctx, database.UpdateFileThumbnailParams{
-		ThumbnailKey: sql.NullString{String: thumbKey, Valid: true},
-		FileID:       fileID,
-	})
-	if err != nil {
-		_, _, _ = s.store.Delete(ctx, []string{thumbKey})
-		return err
+	if err := tx.Commit(); err != nil {
+		return database.CreateFileRow{}, err
 	}
 
-	return nil
+	return fileRec, nil
 }
 
 // GetFileMetadata retrieves file info ensuring the user owns it
@@ -172,8 +219,8 @@ func (s *FileService) GetFileMetadata(ctx context.Context, fileID uuid.UUID, use
 	return file, nil
 }
 
-// DownloadFile returns the file stream
-func (s *FileService) DownloadFile(ctx context.Context, fileID, userID uuid.UUID) (reader io.ReadCloser, fileInfo database.GetFileInfoRow, err error) {
+// DownloadFile returns a seekable stream of the file content, which allows byte ranges of it to be served
+func (s *FileService) DownloadFile(ctx context.Context, fileID, userID uuid.UUID) (reader io.ReadSeekCloser, fileInfo database.GetFileInfoRow, err error) {
 	fileInfo, err = s.db.GetFileInfo(ctx, fileID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -187,33 +234,131 @@ func (s *FileService) DownloadFile(ctx context.Context, fileID, userID uuid.UUID
 		return nil, database.GetFileInfoRow{}, utils.ErrNotPermitted
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	if err := checkScanStatus(fileInfo.ScanStatus); err != nil {
+		return nil, database.GetFileInfoRow{}, err
+	}
 
-	stream, err := s.store.Get(ctx, fileInfo.StorageKey)
-	if err != nil {
-		utils.WriteServerError(s.logger, fmt.Sprintf("file found in database but missing in storage key=%s", fileInfo.StorageKey), err)
+	if fileInfo.StripLocationPending && !isOwner {
+		return nil, database.GetFileInfoRow{}, utils.ErrStripPending
+	}
 
-		return nil, database.GetFileInfoRow{}, errors.New("file content missing")
+	stream, err := filestore.NewRangeReader(ctx, s.store, fileInfo.StorageKey, fileInfo.SizeBytes, s.logger)
+	if err != nil {
+		return nil, database.GetFileInfoRow{}, err
 	}
 
 	return stream, fileInfo, nil
 }
 
-// ListUserFiles returns a list of files for the user
+// nullString converts an optional filter value to a query argument, an empty string is not applied.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// nullSize converts an optional size filter to a query argument, a negative size is not applied.
+func nullSize(size int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: size, Valid: size >= 0}
+}
+
+// nullTime converts an optional date filter to a query argument, the zero time is not applied.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+// pageBounds returns the limit and offset to query a page with. With cursor pagination
+// one extra record is fetched to find out whether another page follows.
+func pageBounds(filters utils.Filters) (limit, offset int32) {
+	if filters.UseCursor {
+		return int32(filters.PageSize + 1), 0
+	}
+	return int32(filters.PageSize), int32((filters.Page - 1) * filters.PageSize)
+}
+
+// cursorArgs converts the pagination cursor to query arguments, which are null on the first page.
+func cursorArgs(filters utils.Filters) (sql.NullTime, uuid.NullUUID) {
+	if filters.Cursor == nil {
+		return sql.NullTime{}, uuid.NullUUID{}
+	}
+	return sql.NullTime{Time: filters.Cursor.Time, Valid: true}, uuid.NullUUID{UUID: filters.Cursor.ID, Valid: true}
+}
+
+// cursorSort returns the order to query a page in, which is reversed to fetch the page before a backward cursor.
+func cursorSort(filters utils.Filters) string {
+	if filters.Cursor == nil || !filters.Cursor.Backward {
+		return filters.Sort
+	}
+	if sort, ok := strings.CutPrefix(filters.Sort, "-"); ok {
+		return sort
+	}
+	return "-" + filters.Sort
+}
+
+// cursorPage drops the extra record fetched by pageBounds, restores the order of a page fetched backwards and
+// calculates its pagination metadata. position returns the cursor position of a record.
+func cursorPage[T any](rows []T, filters utils.Filters, totalRecords int, position func(T) utils.Cursor) ([]T, utils.Metadata) {
+	hasMore := len(rows) > filters.PageSize
+	if hasMore {
+		rows = rows[:filters.PageSize]
+	}
+	if filters.Cursor != nil && filters.Cursor.Backward {
+		slices.Reverse(rows)
+	}
+
+	var first, last utils.Cursor
+	if len(rows) > 0 {
+		first, last = position(rows[0]), position(rows[len(rows)-1])
+	}
+
+	return rows, utils.CalculateCursorMetadata(filters.Cursor, first, last, len(rows), hasMore, filters.PageSize, totalRecords)
+}
+
+// ListUserFiles returns a list of files for the user.
+// With cursor pagination the files are only counted when filters.IncludeTotal is set.
 func (s *FileService) ListUserFiles(ctx context.Context, userID uuid.UUID, filters utils.Filters) ([]database.ListUserFilesRow, utils.Metadata, error) {
-	limit := filters.PageSize
-	offset := (filters.Page - 1) * filters.PageSize
+	limit, offset := pageBounds(filters)
 
-	count, err := s.db.CountUserFiles(ctx, userID)
-	if err != nil {
-		return []database.ListUserFilesRow{}, utils.Metadata{}, err
+	allTags, anyTags := tagFilters(filters)
+
+	visibility := database.NullFileVisibility{
+		FileVisibility: database.FileVisibility(filters.Visibility),
+		Valid:          filters.Visibility != "",
 	}
 
+	var count int64
+	if !filters.UseCursor || filters.IncludeTotal {
+		var err error
+		count, err = s.db.CountUserFiles(ctx, database.CountUserFilesParams{
+			UserID:        userID,
+			AllTags:       allTags,
+			AnyTags:       anyTags,
+			MimePrefix:    nullString(filters.MimePrefix),
+			MinSize:       nullSize(filters.MinSize),
+			MaxSize:       nullSize(filters.MaxSize),
+			CreatedAfter:  nullTime(filters.CreatedAfter),
+			CreatedBefore: nullTime(filters.CreatedBefore),
+			Visibility:    visibility,
+		})
+		if err != nil {
+			return []database.ListUserFilesRow{}, utils.Metadata{}, err
+		}
+	}
+
+	cursorTime, cursorID := cursorArgs(filters)
 	files, err := s.db.ListUserFiles(ctx, database.ListUserFilesParams{
-		UserID: userID,
-		Limit:  int32(limit),
-		Offset: int32(offset),
+		UserID:        userID,
+		AllTags:       allTags,
+		AnyTags:       anyTags,
+		MimePrefix:    nullString(filters.MimePrefix),
+		MinSize:       nullSize(filters.MinSize),
+		MaxSize:       nullSize(filters.MaxSize),
+		CreatedAfter:  nullTime(filters.CreatedAfter),
+		CreatedBefore: nullTime(filters.CreatedBefore),
+		Visibility:    visibility,
+		CursorTime:    cursorTime,
+		CursorID:      cursorID,
+		Sort:          cursorSort(filters),
+		PageLimit:     limit,
+		PageOffset:    offset,
 	})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -222,23 +367,56 @@ func (s *FileService) ListUserFiles(ctx context.Context, userID uuid.UUID, filte
 		return []database.ListUserFilesRow{}, utils.Metadata{}, err
 	}
 
+	if filters.UseCursor {
+		files, meta := cursorPage(files, filters, int(count), func(f database.ListUserFilesRow) utils.Cursor {
+			return utils.Cursor{Time: f.CreatedAt, ID: f.FileID}
+		})
+		return files, meta, nil
+	}
+
 	meta := utils.CalculateMetadata(int(count), filters.Page, filters.PageSize)
 
 	return files, meta, nil
 }
 
-// ListPublicFiles returns a list of files
+// ListPublicFiles returns a list of files, the visibility filter does not apply to public files.
+// With cursor pagination the files are only counted when filters.IncludeTotal is set.
 func (s *FileService) ListPublicFiles(ctx context.Context, filters utils.Filters) ([]database.ListPublicFilesRow, utils.Metadata, error) {
-	limit := filters.PageSize
-	offset := (filters.Page - 1) * filters.PageSize
-
-	count, err := s.db.CountPublicFiles(ctx)
-	if err != nil {
-		return []database.ListPublicFilesRow{}, utils.Metadata{}, err
+	limit, offset := pageBounds(filters)
+
+	allTags, anyTags := tagFilters(filters)
+
+	var count int64
+	if !filters.UseCursor || filters.IncludeTotal {
+		var err error
+		count, err = s.db.CountPublicFiles(ctx, database.CountPublicFilesParams{
+			AllTags:       allTags,
+			AnyTags:       anyTags,
+			MimePrefix:    nullString(filters.MimePrefix),
+			MinSize:       nullSize(filters.MinSize),
+			MaxSize:       nullSize(filters.MaxSize),
+			CreatedAfter:  nullTime(filters.CreatedAfter),
+			CreatedBefore: nullTime(filters.CreatedBefore),
+		})
+		if err != nil {
+			return []database.ListPublicFilesRow{}, utils.Metadata{}, err
+		}
 	}
+
+	cursorTime, cursorID := cursorArgs(filters)
 	files, err := s.db.ListPublicFiles(ctx, database.ListPublicFilesParams{
-		Limit:  int32(limit),
-		Offset: int32(offset),
+		AllTags:       allTags,
+		AnyTags:       anyTags,
+		MimePrefix:    nullString(filters.MimePrefix),
+		MinSize:       nullSize(filters.MinSize),
+		MaxSize:       nullSize(filters.MaxSize),
+		CreatedAfter:  nullTime(filters.CreatedAfter),
+		CreatedBefore: nullTime(filters.CreatedBefore),
+		CursorTime:    cursorTime,
+		CursorID:      cursorID,
+		Sort:          cursorSort(filters),
+		PageLimit:     limit,
+		PageOffset:    offset,
 	})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -247,16 +425,24 @@ func (s *FileService) ListPublicFiles(ctx context.Context, filters utils.Filters
 		return []database.ListPublicFilesRow{}, utils.Metadata{}, err
 	}
 
+	if filters.UseCursor {
+		files, meta := cursorPage(files, filters, int(count), func(f database.ListPublicFilesRow) utils.Cursor {
+			return utils.Cursor{Time: f.CreatedAt, ID: f.FileID}
+		})
+		return files, meta, nil
+	}
+
 	meta := utils.CalculateMetadata(int(count), filters.Page, filters.PageSize)
 
 	return files, meta, nil
 }
 
 // SetFileVisibility toggles file visibility status by file owner
-func (s *FileService) SetFileVisibility(ctx context.Context, fileID uuid.UUID, version int32, visibility database.FileVisibility) (string, error) {
-	newVisibility, err := s.db.SetFileVisibility(ctx, database.SetFileVisibilityParams{
+func (s *FileService) SetFileVisibility(ctx context.Context, fileID, userID uuid.UUID, version int32, visibility database.FileVisibility) (string, error) {
+	updated, err := s.db.SetFileVisibility(ctx, database.SetFileVisibilityParams{
 		Visibility: visibility,
 		FileID:     fileID,
+		UserID:     userID,
 		Version:    version,
 	})
 	if err != nil {
@@ -266,7 +452,7 @@ func (s *FileService) SetFileVisibility(ctx context.Context, fileID uuid.UUID, v
 		return "", err
 	}
 
-	return string(newVisibility), nil
+	return string(updated.Visibility), nil
 }
 
 // UpdateFileName method updates a file name
@@ -287,24 +473,22 @@ func (s *FileService) UpdateFileName(ctx context.Context, fileID uuid.UUID, file
 	return newName, nil
 }
 
-// DeleteFile performs a soft delete
-func (s *FileService) DeleteFile(ctx context.Context, fileID uuid.UUID, userID uuid.UUID, version int32) error {
-	delTime := sql.NullTime{Time: time.Now().Add(7 * 24 * time.Hour), Valid: true}
-
-	err := s.db.DeleteFile(ctx, database.DeleteFileParams{
-		DeletedAt: delTime,
-		FileID:    fileID,
-		Version:   version,
+// DeleteFile moves a file owned by the user to the trash, returning the time it will be permanently deleted
+func (s *FileService) DeleteFile(ctx context.Context, fileID uuid.UUID, userID uuid.UUID, version int32) (purgeAfter time.Time, err error) {
+	purgeTime, err := s.db.DeleteFile(ctx, database.DeleteFileParams{
+		DefaultRetentionDays: s.trashRetentionDays,
+		FileID:               fileID,
+		UserID:               userID,
+		Version:              version,
 	})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return utils.ErrRecordNotFound
+			return time.Time{}, utils.ErrRecordNotFound
 		}
-		return err
-
+		return time.Time{}, err
 	}
 
-	return nil
+	return purgeTime.Time, nil
 }
 
 // CleanupExpiredSoftDeleted handles hard deletion of files by a cron job
@@ -319,21 +503,38 @@ func (s *FileService) CleanupExpiredSoftDeleted(ctx context.Context, limit int32
 	}
 
 	var fileIDs []uuid.UUID
-	var storagePaths []string
+	var thumbnailKeys []string
 
 	for _, f := range files {
 		fileIDs = append(fileIDs, f.FileID)
-		storagePaths = append(storagePaths, f.StorageKey)
 		if f.ThumbnailKey.Valid {
-			storagePaths = append(storagePaths, f.ThumbnailKey.String)
+			thumbnailKeys = append(thumbnailKeys, f.ThumbnailKey.String)
 		}
 	}
 
-	s.store.Delete(context.Background(), storagePaths)
+	if err := s.purgeFiles(ctx, fileIDs, thumbnailKeys); err != nil {
+		return 0, err
+	}
+
+	return len(files), nil
+}
+
+// purgeFiles permanently deletes file records together with their thumbnails and releases the blobs of all
+// their versions. Content is only removed from storage once no other file version references its blob.
+func (s *FileService) purgeFiles(ctx context.Context, fileIDs []uuid.UUID, thumbnailKeys []string) error {
+	// The version history includes the current content of each file
+	storagePaths, err := s.db.GetFileVersionStorageKeys(ctx, fileIDs)
+	if err != nil {
+		return fmt.Errorf("failed to fetch file version keys: %w", err)
+	}
 
 	if err := s.db.HardDeleteFiles(ctx, fileIDs); err != nil {
-		return 0, fmt.Errorf("failed to hard delete file records: %w", err)
+		return fmt.Errorf("failed to hard delete file records: %w", err)
 	}
 
-	return len(files), nil
+	if len(thumbnailKeys) > 0 {
+		s.store.Delete(context.Background(), thumbnailObjects(thumbnailKeys...))
+	}
+
+	return s.releaseBlobs(ctx, storagePaths)
 }
@@ -0,0 +1,179 @@
+package files
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/i-christian/fileShare/internal/utils"
+	"github.com/i-christian/fileShare/internal/utils/security"
+	"github.com/i-christian/fileShare/internal/validator"
+)
+
+// CreateShareLink creates a share link for a file owned by the user
+func (h *FileHandler) CreateShareLink(w http.ResponseWriter, r *http.Request) {
+	fileID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		utils.BadRequestResponse(w, errors.New("invalid file ID parameter"))
+		return
+	}
+
+	user, ok := security.GetUserFromContext(r)
+	if !ok || user.IsAnonymous() {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	var input validator.ShareLink
+	err = utils.ReadJSON(w, r, &input)
+	if err != nil {
+		utils.BadRequestResponse(w, err)
+		return
+	}
+
+	v := validator.New()
+	if validator.ValidateShareLink(v, &input); !v.Valid() {
+		utils.FailedValidationResponse(w, v.Errors)
+		return
+	}
+
+	token, link, err := h.service.CreateShareLink(r.Context(), fileID, user.UserID, input.Expires, input.MaxDownloads, input.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrRecordNotFound):
+			utils.NotFoundResponse(w)
+		case errors.Is(err, utils.ErrNotPermitted):
+			utils.NotPermittedResponse(w)
+		default:
+			utils.WriteServerError(h.logger, "failed to create share link", err)
+			utils.ServerErrorResponse(w, "failed to create share link")
+		}
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusCreated, utils.Envelope{
+		"message":    "keep the share link safe, the token will not be shown again",
+		"token":      token,
+		"url":        "/s/" + token,
+		"share_link": link,
+	}, nil)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+	}
+}
+
+// ListShareLinks lists the share links created for a file
+func (h *FileHandler) ListShareLinks(w http.ResponseWriter, r *http.Request) {
+	fileID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		utils.BadRequestResponse(w, errors.New("invalid file ID parameter"))
+		return
+	}
+
+	user, ok := security.GetUserFromContext(r)
+	if !ok || user.IsAnonymous() {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	links, err := h.service.ListShareLinks(r.Context(), fileID, user.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrRecordNotFound):
+			utils.NotFoundResponse(w)
+		case errors.Is(err, utils.ErrNotPermitted):
+			utils.NotPermittedResponse(w)
+		default:
+			utils.WriteServerError(h.logger, "failed to list share links", err)
+			utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+		}
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"share_links": links}, nil)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+	}
+}
+
+// RevokeShareLink disables a share link of a file
+func (h *FileHandler) RevokeShareLink(w http.ResponseWriter, r *http.Request) {
+	fileID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		utils.BadRequestResponse(w, errors.New("invalid file ID parameter"))
+		return
+	}
+
+	shareLinkID, err := uuid.Parse(r.PathValue("shareID"))
+	if err != nil {
+		utils.BadRequestResponse(w, errors.New("invalid share link ID parameter"))
+		return
+	}
+
+	user, ok := security.GetUserFromContext(r)
+	if !ok || user.IsAnonymous() {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	if err := h.service.RevokeShareLink(r.Context(), fileID, shareLinkID, user.UserID); err != nil {
+		if errors.Is(err, utils.ErrRecordNotFound) {
+			utils.NotFoundResponse(w)
+			return
+		}
+		utils.WriteServerError(h.logger, "failed to revoke share link", err)
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "share link revoked successfully"}, nil)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+	}
+}
+
+// SharedDownload streams a file through a share link without requiring an account.
+// The password of a protected link is supplied using HTTP basic authentication, with any username.
+// Counted downloads return a Share-Resume-Token header, passed as the resume query parameter by range requests
+// continuing the download so they are not counted again.
+func (h *FileHandler) SharedDownload(w http.ResponseWriter, r *http.Request) {
+	token := r.PathValue("token")
+
+	v := validator.New()
+	if validator.ValidateTokenPlainText(v, token); !v.Valid() {
+		utils.NotFoundResponse(w)
+		return
+	}
+
+	_, password, _ := r.BasicAuth()
+
+	stream, fileInfo, newResumeToken, err := h.service.OpenSharedFile(r.Context(), token, password, sharedRequest{
+		download:    r.Method == http.MethodGet,
+		byteRange:   r.Header.Get("Range"),
+		ifRange:     r.Header.Get("If-Range"),
+		resumeToken: r.URL.Query().Get("resume"),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrRecordNotFound):
+			utils.NotFoundResponse(w)
+		case errors.Is(err, utils.ErrLinkUnavailable):
+			utils.WriteErrorJSON(w, http.StatusGone, err.Error())
+		case errors.Is(err, utils.ErrInvalidPassword):
+			w.Header().Set("WWW-Authenticate", `Basic realm="shared file", charset="UTF-8"`)
+			utils.UnauthorisedResponse(w, err.Error())
+		case errors.Is(err, utils.ErrScanPending), errors.Is(err, utils.ErrFileInfected), errors.Is(err, utils.ErrStripPending):
+			withheldResponse(w, err)
+		default:
+			utils.WriteServerError(h.logger, "failed to open shared file", err)
+			utils.ServerErrorResponse(w, "file unavailable")
+		}
+		return
+	}
+
+	if newResumeToken != "" {
+		w.Header().Set("Share-Resume-Token", newResumeToken)
+	}
+
+	serveFile(w, r, stream, fileInfo)
+}
@@ -0,0 +1,244 @@
+package files
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/i-christian/fileShare/internal/database"
+	"github.com/i-christian/fileShare/internal/filestore"
+	"github.com/i-christian/fileShare/internal/utils"
+	"github.com/i-christian/fileShare/internal/utils/security"
+)
+
+// ownedFile retrieves a file which must belong to the user.
+func (s *FileService) ownedFile(ctx context.Context, fileID, userID uuid.UUID) (database.GetFileInfoRow, error) {
+	file, err := s.db.GetFileInfo(ctx, fileID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return database.GetFileInfoRow{}, utils.ErrRecordNotFound
+		}
+		return database.GetFileInfoRow{}, err
+	}
+
+	if file.OwnerID != userID {
+		return database.GetFileInfoRow{}, utils.ErrNotPermitted
+	}
+
+	return file, nil
+}
+
+// CreateShareLink creates a link granting access to the file without an account.
+// Only a hash of the token is stored, the plain text token is returned once to the caller.
+// A zero expiresAt, maxDownloads or empty password leaves that restriction off.
+func (s *FileService) CreateShareLink(ctx context.Context, fileID, userID uuid.UUID, expiresAt time.Time, maxDownloads int32, password string) (token string, link database.CreateShareLinkRow, err error) {
+	if _, err := s.ownedFile(ctx, fileID, userID); err != nil {
+		return "", database.CreateShareLinkRow{}, err
+	}
+
+	params := database.CreateShareLinkParams{
+		FileID:       fileID,
+		UserID:       userID,
+		ExpiresAt:    sql.NullTime{Time: expiresAt, Valid: !expiresAt.IsZero()},
+		MaxDownloads: sql.NullInt32{Int32: maxDownloads, Valid: maxDownloads > 0},
+	}
+
+	if password != "" {
+		passwordHash, err := security.HashPassword(password)
+		if err != nil {
+			return "", database.CreateShareLinkRow{}, err
+		}
+		params.PasswordHash = sql.NullString{String: passwordHash, Valid: true}
+	}
+
+	token, params.TokenHash = security.GenerateStringAndHash()
+
+	link, err = s.db.CreateShareLink(ctx, params)
+	if err != nil {
+		return "", database.CreateShareLinkRow{}, err
+	}
+
+	return token, link, nil
+}
+
+// ListShareLinks returns all share links the user has created for the file
+func (s *FileService) ListShareLinks(ctx context.Context, fileID, userID uuid.UUID) ([]database.ListFileShareLinksRow, error) {
+	if _, err := s.ownedFile(ctx, fileID, userID); err != nil {
+		return nil, err
+	}
+
+	return s.db.ListFileShareLinks(ctx, database.ListFileShareLinksParams{
+		FileID: fileID,
+		UserID: userID,
+	})
+}
+
+// RevokeShareLink disables a share link, any further request using it is rejected
+func (s *FileService) RevokeShareLink(ctx context.Context, fileID, shareLinkID, userID uuid.UUID) error {
+	_, err := s.db.RevokeShareLink(ctx, database.RevokeShareLinkParams{
+		ShareLinkID: shareLinkID,
+		FileID:      fileID,
+		UserID:      userID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.ErrRecordNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// shareResumeWindow is how long a counted share link download can be continued with range requests.
+const shareResumeWindow = time.Hour
+
+// signResume returns the signature of a token continuing a download of the content with the checksum through the link.
+func (s *FileService) signResume(shareLinkID uuid.UUID, checksum string, expires int64) string {
+	mac := hmac.New(sha256.New, s.resumeKey)
+	mac.Write([]byte(shareLinkID.String() + "/" + checksum + "/" + strconv.FormatInt(expires, 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// newResumeToken returns a token continuing a counted download of the content with the checksum through the link.
+func (s *FileService) newResumeToken(shareLinkID uuid.UUID, checksum string) string {
+	expires := time.Now().Add(shareResumeWindow).Unix()
+	return strconv.FormatInt(expires, 10) + "." + s.signResume(shareLinkID, checksum, expires)
+}
+
+// validResumeToken reports whether the token continues a download of the content with the checksum through the link
+// which was counted less than shareResumeWindow ago.
+func (s *FileService) validResumeToken(token string, shareLinkID uuid.UUID, checksum string) bool {
+	expiresText, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+
+	expires, err := strconv.ParseInt(expiresText, 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return false
+	}
+
+	return hmac.Equal([]byte(s.signResume(shareLinkID, checksum, expires)), []byte(signature))
+}
+
+// sharedRequest describes a request for a shared file, which decides whether it counts as a download.
+type sharedRequest struct {
+	// download is set for GET requests, HEAD requests only read the headers of the file
+	download bool
+	// byteRange and ifRange are the Range and If-Range headers of the request
+	byteRange   string
+	ifRange     string
+	resumeToken string
+}
+
+// startsDownload reports whether the request is served from the first byte of the file, which is how a download starts.
+// Range requests past the first byte continue a download, unless their If-Range validator no longer matches the file,
+// in which case the whole file is served.
+func (r sharedRequest) startsDownload(fileInfo database.GetFileInfoRow) bool {
+	if !r.download {
+		return false
+	}
+
+	spec, ok := strings.CutPrefix(r.byteRange, "bytes=")
+	if !ok {
+		return true
+	}
+
+	first, _, _ := strings.Cut(spec, ",")
+	start, _, _ := strings.Cut(strings.TrimSpace(first), "-")
+	if offset, err := strconv.ParseInt(strings.TrimSpace(start), 10, 64); err == nil && offset == 0 {
+		return true
+	}
+
+	return r.ifRange != "" &&
+		r.ifRange != strconv.Quote(fileInfo.Checksum) &&
+		r.ifRange != fileInfo.UpdatedAt.UTC().Format(http.TimeFormat)
+}
+
+// OpenSharedFile resolves a share link token and returns a seekable stream of the shared file.
+//
+// Only GET requests served from the first byte of the file count towards the download limit of the link, so range
+// requests of browsers, download managers and video players continuing a download are not counted again.
+// Once the link is used up, range requests continuing the download which used it up are still served for
+// shareResumeWindow. A counted download also returns a resume token, requests carrying it are not counted and
+// are served for as long as the token is valid, so any earlier download can be continued or restarted.
+func (s *FileService) OpenSharedFile(ctx context.Context, token, password string, req sharedRequest) (reader io.ReadSeekCloser, fileInfo database.GetFileInfoRow, newResumeToken string, err error) {
+	tokenHash := sha256.Sum256([]byte(token))
+
+	link, err := s.db.GetShareLinkByToken(ctx, tokenHash[:])
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, database.GetFileInfoRow{}, "", utils.ErrRecordNotFound
+		}
+		return nil, database.GetFileInfoRow{}, "", err
+	}
+
+	expired := link.ExpiresAt.Valid && time.Now().After(link.ExpiresAt.Time)
+	if link.IsRevoked || expired {
+		return nil, database.GetFileInfoRow{}, "", utils.ErrLinkUnavailable
+	}
+
+	if link.PasswordHash.Valid {
+		if password == "" || security.VerifyPassword(link.PasswordHash.String, password) != nil {
+			return nil, database.GetFileInfoRow{}, "", utils.ErrInvalidPassword
+		}
+	}
+
+	fileInfo, err = s.db.GetFileInfo(ctx, link.FileID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, database.GetFileInfoRow{}, "", utils.ErrRecordNotFound
+		}
+		return nil, database.GetFileInfoRow{}, "", err
+	}
+
+	resumed := req.resumeToken != "" && s.validResumeToken(req.resumeToken, link.ShareLinkID, fileInfo.Checksum)
+	counted := req.startsDownload(fileInfo) && !resumed
+
+	exhausted := link.MaxDownloads.Valid && link.DownloadCount >= link.MaxDownloads.Int32
+	if exhausted && !resumed {
+		lastDownloadRecent := link.LastAccessedAt.Valid && time.Since(link.LastAccessedAt.Time) < shareResumeWindow
+		if counted || !lastDownloadRecent {
+			return nil, database.GetFileInfoRow{}, "", utils.ErrLinkUnavailable
+		}
+	}
+
+	if err := checkScanStatus(fileInfo.ScanStatus); err != nil {
+		return nil, database.GetFileInfoRow{}, "", err
+	}
+
+	// Shared links are opened by anyone holding them, who must not see the location
+	if fileInfo.StripLocationPending {
+		return nil, database.GetFileInfoRow{}, "", utils.ErrStripPending
+	}
+
+	// The content is opened first, so a download which can not be served is not counted
+	stream, err := filestore.NewRangeReader(ctx, s.store, fileInfo.StorageKey, fileInfo.SizeBytes, s.logger)
+	if err != nil {
+		return nil, database.GetFileInfoRow{}, "", err
+	}
+
+	if counted {
+		_, err = s.db.RecordShareLinkAccess(ctx, link.ShareLinkID)
+		if err != nil {
+			stream.Close()
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, database.GetFileInfoRow{}, "", utils.ErrLinkUnavailable
+			}
+			return nil, database.GetFileInfoRow{}, "", err
+		}
+		newResumeToken = s.newResumeToken(link.ShareLinkID, fileInfo.Checksum)
+	}
+
+	return stream, fileInfo, newResumeToken, nil
+}
@@ -0,0 +1,115 @@
+package files
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/i-christian/fileShare/internal/database"
+	"github.com/i-christian/fileShare/internal/utils"
+	"github.com/i-christian/fileShare/internal/utils/security"
+	"github.com/i-christian/fileShare/internal/validator"
+)
+
+// tagUpdate is a service method changing the tags of a file
+type tagUpdate func(ctx context.Context, fileID, userID uuid.UUID, tags []string, version int32) (database.SetFileTagsRow, error)
+
+// updateTags reads a list of tags and the file version from the request body and applies update to the file.
+// Replacing tags accepts an empty list, adding and removing tags requires at least one tag.
+func (h *FileHandler) updateTags(w http.ResponseWriter, r *http.Request, update tagUpdate, allowEmpty bool) {
+	fileID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		utils.BadRequestResponse(w, errors.New("invalid file ID parameter"))
+		return
+	}
+
+	user, ok := security.GetUserFromContext(r)
+	if !ok || user.IsAnonymous() {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	var input struct {
+		Version int32    `json:"version"`
+		Tags    []string `json:"tags"`
+	}
+
+	err = utils.ReadJSON(w, r, &input)
+	if err != nil {
+		utils.BadRequestResponse(w, err)
+		return
+	}
+
+	tags := validator.NormalizeTags(input.Tags)
+
+	v := validator.New()
+	v.Check(allowEmpty || len(tags) > 0, "tags", "must contain at least one tag")
+	validator.ValidateTags(v, "tags", tags)
+	if validator.ValidateDeleteFile(v, validator.FileInfo{Version: input.Version}); !v.Valid() {
+		utils.FailedValidationResponse(w, v.Errors)
+		return
+	}
+
+	updated, err := update(r.Context(), fileID, user.UserID, tags, input.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrRecordNotFound):
+			utils.NotFoundResponse(w)
+		case errors.Is(err, utils.ErrNotPermitted):
+			utils.NotPermittedResponse(w)
+		case errors.Is(err, utils.ErrEditConflict):
+			utils.EditConflictResponse(w)
+		case errors.Is(err, utils.ErrTooManyTags):
+			utils.FailedValidationResponse(w, map[string]string{"tags": err.Error()})
+		default:
+			utils.WriteServerError(h.logger, "failed to update file tags", err)
+			utils.ServerErrorResponse(w, "failed to update file tags")
+		}
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"tags":    updated.Tags,
+		"version": updated.Version,
+	}, nil)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+	}
+}
+
+// AddTags adds tags to a file
+func (h *FileHandler) AddTags(w http.ResponseWriter, r *http.Request) {
+	h.updateTags(w, r, h.service.AddFileTags, false)
+}
+
+// RemoveTags removes tags from a file
+func (h *FileHandler) RemoveTags(w http.ResponseWriter, r *http.Request) {
+	h.updateTags(w, r, h.service.RemoveFileTags, false)
+}
+
+// ReplaceTags replaces all tags of a file
+func (h *FileHandler) ReplaceTags(w http.ResponseWriter, r *http.Request) {
+	h.updateTags(w, r, h.service.ReplaceFileTags, true)
+}
+
+// ListTags retrieves the tags used on the user's files with the number of files carrying each tag
+func (h *FileHandler) ListTags(w http.ResponseWriter, r *http.Request) {
+	user, ok := security.GetUserFromContext(r)
+	if !ok || user.IsAnonymous() {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	tags, err := h.service.ListUserTags(r.Context(), user.UserID)
+	if err != nil {
+		utils.WriteServerError(h.logger, "failed to fetch user tags", err)
+		utils.ServerErrorResponse(w, "failed to fetch tags")
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"tags": tags}, nil)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+	}
+}
@@ -0,0 +1,118 @@
+package files
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/i-christian/fileShare/internal/database"
+	"github.com/i-christian/fileShare/internal/utils"
+	"github.com/i-christian/fileShare/internal/validator"
+)
+
+// tagFilters converts the tag filter into the all_tags and any_tags arguments of the listing queries,
+// both are nil when no tags are requested.
+func tagFilters(filters utils.Filters) (allTags, anyTags []string) {
+	if len(filters.Tags) == 0 {
+		return nil, nil
+	}
+
+	if filters.MatchAllTags {
+		return filters.Tags, nil
+	}
+
+	return nil, filters.Tags
+}
+
+// updateFileTags replaces the tags of a file owned by the user with the result of applying change to its current tags.
+// The update only succeeds if version is still the current version of the file.
+func (s *FileService) updateFileTags(ctx context.Context, fileID, userID uuid.UUID, version int32, change func(current []string) []string) (database.SetFileTagsRow, error) {
+	file, err := s.ownedFile(ctx, fileID, userID)
+	if err != nil {
+		return database.SetFileTagsRow{}, err
+	}
+
+	if file.Version != version {
+		return database.SetFileTagsRow{}, utils.ErrEditConflict
+	}
+
+	tags, err := changedTags(file.Tags, change)
+	if err != nil {
+		return database.SetFileTagsRow{}, err
+	}
+
+	updated, err := s.db.SetFileTags(ctx, database.SetFileTagsParams{
+		Tags:    tags,
+		FileID:  fileID,
+		UserID:  userID,
+		Version: version,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return database.SetFileTagsRow{}, utils.ErrEditConflict
+		}
+		return database.SetFileTagsRow{}, err
+	}
+
+	return updated, nil
+}
+
+// changedTags applies change to the current tags of a file, failing with utils.ErrTooManyTags when the file
+// would end up with more tags than permitted. Each request is limited on its own, so added tags are checked again
+// together with the tags the file already has.
+func changedTags(current []string, change func(current []string) []string) ([]string, error) {
+	tags := change(current)
+
+	v := validator.New()
+	if validator.ValidateTags(v, "tags", tags); !v.Valid() {
+		return nil, utils.ErrTooManyTags
+	}
+
+	return tags, nil
+}
+
+// AddFileTags adds tags to a file owned by the user, tags the file already has are kept once
+func (s *FileService) AddFileTags(ctx context.Context, fileID, userID uuid.UUID, tags []string, version int32) (database.SetFileTagsRow, error) {
+	return s.updateFileTags(ctx, fileID, userID, version, func(current []string) []string {
+		return withTags(current, tags)
+	})
+}
+
+// RemoveFileTags removes tags from a file owned by the user
+func (s *FileService) RemoveFileTags(ctx context.Context, fileID, userID uuid.UUID, tags []string, version int32) (database.SetFileTagsRow, error) {
+	return s.updateFileTags(ctx, fileID, userID, version, func(current []string) []string {
+		return withoutTags(current, tags)
+	})
+}
+
+// ReplaceFileTags sets the tags of a file owned by the user, an empty list removes all tags
+func (s *FileService) ReplaceFileTags(ctx context.Context, fileID, userID uuid.UUID, tags []string, version int32) (database.SetFileTagsRow, error) {
+	return s.updateFileTags(ctx, fileID, userID, version, func([]string) []string {
+		return tags
+	})
+}
+
+// withTags returns the current tags followed by the tags not among them yet.
+func withTags(current, tags []string) []string {
+	updated := append([]string{}, current...)
+	for _, tag := range tags {
+		if !slices.Contains(updated, tag) {
+			updated = append(updated, tag)
+		}
+	}
+	return updated
+}
+
+// withoutTags returns the current tags except the given tags.
+func withoutTags(current, tags []string) []string {
+	return slices.DeleteFunc(append([]string{}, current...), func(tag string) bool {
+		return slices.Contains(tags, tag)
+	})
+}
+
+// ListUserTags returns the tags used on the user's files with the number of files carrying each tag
+func (s *FileService) ListUserTags(ctx context.Context, userID uuid.UUID) ([]database.ListUserTagsRow, error) {
+	return s.db.ListUserTags(ctx, userID)
+}
@@ -0,0 +1,84 @@
+package files
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/i-christian/fileShare/internal/database"
+	"github.com/i-christian/fileShare/internal/utils"
+	"github.com/i-christian/fileShare/internal/utils/security"
+	"github.com/i-christian/fileShare/internal/validator"
+)
+
+const (
+	// thumbnailMaxAge is how long clients may cache thumbnails, in seconds. Thumbnails keep their URL when a new
+	// version of a file is uploaded, their ETag lets clients revalidate them cheaply once they expire.
+	thumbnailMaxAge = 24 * 60 * 60
+	// iconMaxAge is how long clients may cache the icon served while a file has no thumbnail, in seconds,
+	// kept short as the thumbnail of a new image is generated shortly after it is uploaded.
+	iconMaxAge = 5 * 60
+)
+
+// Thumbnail serves a preview image of a file, or the generic icon of its type when it has no thumbnail
+func (h *FileHandler) Thumbnail(w http.ResponseWriter, r *http.Request) {
+	fileID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		utils.BadRequestResponse(w, errors.New("invalid file ID parameter"))
+		return
+	}
+
+	user, ok := security.GetUserFromContext(r)
+	if !ok {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	qs := r.URL.Query()
+	size := utils.ReadString(qs, "size", "medium")
+	format := utils.ReadString(qs, "format", "jpeg")
+
+	v := validator.New()
+	v.Check(validator.PermittedValue(size, "small", "medium", "large"), "size", "must be small, medium or large")
+	v.Check(validator.PermittedValue(format, "jpeg", "webp"), "format", "must be jpeg or webp")
+	if !v.Valid() {
+		utils.FailedValidationResponse(w, v.Errors)
+		return
+	}
+
+	thumbnail, err := h.service.GetThumbnail(r.Context(), fileID, user.UserID, size, format)
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrRecordNotFound):
+			utils.NotFoundResponse(w)
+		case errors.Is(err, utils.ErrNotPermitted):
+			utils.NotPermittedResponse(w)
+		case errors.Is(err, utils.ErrScanPending), errors.Is(err, utils.ErrFileInfected):
+			withheldResponse(w, err)
+		default:
+			utils.WriteServerError(h.logger, "failed to get thumbnail", err)
+			utils.ServerErrorResponse(w, "thumbnail unavailable")
+		}
+		return
+	}
+
+	scope := "public"
+	if thumbnail.Visibility == database.FileVisibilityPrivate {
+		scope = "private"
+	}
+	maxAge := thumbnailMaxAge
+	if thumbnail.IsIcon {
+		maxAge = iconMaxAge
+	}
+
+	w.Header().Set("Cache-Control", fmt.Sprintf("%s, max-age=%d", scope, maxAge))
+	w.Header().Set("Content-Type", thumbnail.ContentType)
+	w.Header().Set("ETag", strconv.Quote(thumbnail.ETag))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	// ServeContent answers If-None-Match revalidation with 304 Not Modified
+	http.ServeContent(w, r, "", thumbnail.UpdatedAt, bytes.NewReader(thumbnail.Content))
+}
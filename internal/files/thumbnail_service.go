@@ -0,0 +1,322 @@
+package files
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"image"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/disintegration/imaging"
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/i-christian/fileShare/internal/database"
+	"github.com/i-christian/fileShare/internal/utils"
+	"github.com/i-christian/fileShare/internal/webp"
+	"github.com/i-christian/fileShare/internal/worker"
+)
+
+//go:embed "icons"
+var iconFS embed.FS
+
+// thumbnailSize is a size thumbnails are generated in, fitting in a square of `bound` pixels.
+type thumbnailSize struct {
+	name  string
+	bound int
+}
+
+// thumbnailSizes are the sizes thumbnails are generated in, medium being the default.
+var thumbnailSizes = []thumbnailSize{
+	{name: "small", bound: 150},
+	{name: "medium", bound: 300},
+	{name: "large", bound: 600},
+}
+
+// thumbnailFormat is an image format thumbnails are generated in.
+type thumbnailFormat struct {
+	name        string
+	ext         string
+	contentType string
+	encode      func(w io.Writer, img image.Image) error
+}
+
+// thumbnailFormats are the formats thumbnails are generated in, WebP thumbnails are lossless and keep transparency.
+var thumbnailFormats = []thumbnailFormat{
+	{
+		name:        "jpeg",
+		ext:         "jpg",
+		contentType: "image/jpeg",
+		encode: func(w io.Writer, img image.Image) error {
+			return imaging.Encode(w, img, imaging.JPEG)
+		},
+	},
+	{name: "webp", ext: "webp", contentType: "image/webp", encode: webp.Encode},
+}
+
+// Thumbnail is the content served as the thumbnail of a file,
+// the generic icon of its MIME type when no thumbnail has been generated for it.
+type Thumbnail struct {
+	Content     []byte
+	ContentType string
+	// ETag changes whenever the thumbnail is generated again
+	ETag       string
+	IsIcon     bool
+	Visibility database.FileVisibility
+	UpdatedAt  time.Time
+}
+
+// thumbnailObject returns the storage key of a thumbnail in one of the generated sizes and formats.
+// thumbnailKey is the prefix the thumbnails of a file share, thumbnails generated before several sizes
+// were introduced are a single JPEG, which is served for every size.
+func thumbnailObject(thumbnailKey, size string, format thumbnailFormat) (string, string) {
+	if path.Ext(thumbnailKey) != "" {
+		return thumbnailKey, "image/jpeg"
+	}
+
+	return thumbnailKey + "-" + size + "." + format.ext, format.contentType
+}
+
+// thumbnailObjects returns the storage keys of every thumbnail generated under the thumbnail keys.
+func thumbnailObjects(thumbnailKeys ...string) []string {
+	var objects []string
+	for _, key := range thumbnailKeys {
+		if path.Ext(key) != "" {
+			objects = append(objects, key)
+			continue
+		}
+
+		for _, size := range thumbnailSizes {
+			for _, format := range thumbnailFormats {
+				object, _ := thumbnailObject(key, size.name, format)
+				objects = append(objects, object)
+			}
+		}
+	}
+
+	return objects
+}
+
+// enqueueThumbnail schedules thumbnail generation for the content at storageKey if a preview can be rendered for it.
+func (s *FileService) enqueueThumbnail(fileID uuid.UUID, storageKey, contentType, fileName string) {
+	if !s.previewers.Supports(contentType, fileName) {
+		return
+	}
+
+	taskPayload := &worker.ThumbnailPayload{
+		FileID:     fileID,
+		StorageKey: storageKey,
+	}
+
+	opts := []asynq.Option{
+		asynq.MaxRetry(3),
+		asynq.Queue("default"),
+		// Leaves room for rendering a PDF preview, which is bounded to a minute
+		asynq.Timeout(2 * time.Minute),
+	}
+
+	err := s.taskDistributor.DistributeGenerateThumbnail(context.Background(), taskPayload, opts...)
+	if err != nil {
+		utils.WriteServerError(s.logger, "failed to enqueue thumbnail task", err)
+	}
+}
+
+// GenerateThumbnail renders a preview of a file with the previewer registered for its MIME type and
+// creates thumbnails of it in every size and format. Nothing is done when the file has been deleted
+// or its content replaced since the task was scheduled.
+func (s *FileService) GenerateThumbnail(fileID uuid.UUID, storageKey string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	file, err := s.db.GetFileInfo(ctx, fileID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	if file.StorageKey != storageKey {
+		return nil
+	}
+
+	previewer := s.previewers.Lookup(file.MimeType, file.Filename)
+	if previewer == nil {
+		return nil
+	}
+
+	originalFile, err := s.store.Get(ctx, storageKey)
+	if err != nil {
+		return err
+	}
+	defer originalFile.Close()
+
+	img, err := previewer.Preview(ctx, originalFile)
+	if err != nil {
+		return err
+	}
+
+	thumbKey := "thumbnails/" + uuid.New().String()
+	var saved []string
+
+	for _, size := range thumbnailSizes {
+		thumbnail := imaging.Fit(img, size.bound, size.bound, imaging.Lanczos)
+
+		for _, format := range thumbnailFormats {
+			buf := new(bytes.Buffer)
+			if err := format.encode(buf, thumbnail); err != nil {
+				s.deleteThumbnails(ctx, saved)
+				return err
+			}
+
+			object, _ := thumbnailObject(thumbKey, size.name, format)
+			if _, err := s.store.Save(ctx, buf, object); err != nil {
+				s.deleteThumbnails(ctx, saved)
+				return err
+			}
+			saved = append(saved, object)
+		}
+	}
+
+	err = s.db.UpdateFileThumbnail(ctx, database.UpdateFileThumbnailParams{
+		ThumbnailKey: sql.NullString{String: thumbKey, Valid: true},
+		FileID:       fileID,
+	})
+	if err != nil {
+		s.deleteThumbnails(ctx, saved)
+		return err
+	}
+
+	// A retried task replaces the thumbnails an earlier attempt generated
+	if file.ThumbnailKey.Valid {
+		s.deleteThumbnails(ctx, thumbnailObjects(file.ThumbnailKey.String))
+	}
+
+	return nil
+}
+
+// deleteThumbnails removes thumbnail objects from storage.
+func (s *FileService) deleteThumbnails(ctx context.Context, objects []string) {
+	if len(objects) == 0 {
+		return
+	}
+
+	_, _, err := s.store.Delete(ctx, objects)
+	if err != nil {
+		utils.WriteServerError(s.logger, "failed to delete thumbnails", err)
+	}
+}
+
+// GetThumbnail returns the thumbnail of a file in the requested size and format, applying the same checks as DownloadFile.
+// The generic icon of the file's MIME type is returned when it has no thumbnail.
+func (s *FileService) GetThumbnail(ctx context.Context, fileID, userID uuid.UUID, size, format string) (Thumbnail, error) {
+	fileInfo, err := s.db.GetFileInfo(ctx, fileID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Thumbnail{}, utils.ErrRecordNotFound
+		}
+		return Thumbnail{}, err
+	}
+
+	isOwner := userID != uuid.Nil && fileInfo.OwnerID == userID
+	if !isOwner && fileInfo.Visibility == database.FileVisibilityPrivate {
+		return Thumbnail{}, utils.ErrNotPermitted
+	}
+
+	if err := checkScanStatus(fileInfo.ScanStatus); err != nil {
+		return Thumbnail{}, err
+	}
+
+	if fileInfo.ThumbnailKey.Valid {
+		thumbnail, err := s.readThumbnail(ctx, fileInfo.ThumbnailKey.String, size, format)
+		if err == nil {
+			thumbnail.Visibility = fileInfo.Visibility
+			thumbnail.UpdatedAt = fileInfo.UpdatedAt
+			return thumbnail, nil
+		}
+
+		// The thumbnail may have been removed along with outdated content, the icon is served meanwhile
+		utils.WriteServerError(s.logger, "failed to read thumbnail", err)
+	}
+
+	name := iconName(fileInfo.MimeType)
+	icon, err := iconFS.ReadFile("icons/" + name + ".svg")
+	if err != nil {
+		return Thumbnail{}, err
+	}
+
+	return Thumbnail{
+		Content:     icon,
+		ContentType: "image/svg+xml",
+		ETag:        "icon-" + name,
+		IsIcon:      true,
+		Visibility:  fileInfo.Visibility,
+		UpdatedAt:   fileInfo.UpdatedAt,
+	}, nil
+}
+
+// readThumbnail reads a generated thumbnail from storage.
+func (s *FileService) readThumbnail(ctx context.Context, thumbnailKey, size, formatName string) (Thumbnail, error) {
+	format := thumbnailFormats[0]
+	for _, f := range thumbnailFormats {
+		if f.name == formatName {
+			format = f
+		}
+	}
+
+	object, contentType := thumbnailObject(thumbnailKey, size, format)
+
+	content, err := s.store.Get(ctx, object)
+	if err != nil {
+		return Thumbnail{}, err
+	}
+	defer content.Close()
+
+	data, err := io.ReadAll(content)
+	if err != nil {
+		return Thumbnail{}, err
+	}
+
+	sum := sha256.Sum256([]byte(object))
+
+	return Thumbnail{
+		Content:     data,
+		ContentType: contentType,
+		ETag:        hex.EncodeToString(sum[:8]),
+	}, nil
+}
+
+// iconName returns the name of the generic icon shown for files of the MIME type.
+func iconName(mimeType string) string {
+	mimeType, _, _ = strings.Cut(mimeType, ";")
+
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return "image"
+	case strings.HasPrefix(mimeType, "video/"):
+		return "video"
+	case strings.HasPrefix(mimeType, "audio/"):
+		return "audio"
+	case mimeType == "application/pdf":
+		return "pdf"
+	case strings.Contains(mimeType, "spreadsheet") || strings.Contains(mimeType, "excel") || mimeType == "text/csv":
+		return "spreadsheet"
+	case strings.Contains(mimeType, "presentation") || strings.Contains(mimeType, "powerpoint"):
+		return "presentation"
+	case strings.Contains(mimeType, "wordprocessing") || mimeType == "application/msword" || mimeType == "application/rtf":
+		return "document"
+	case strings.HasPrefix(mimeType, "text/") || mimeType == "application/json" || mimeType == "application/xml":
+		return "text"
+	case strings.Contains(mimeType, "zip") || strings.Contains(mimeType, "tar") ||
+		strings.Contains(mimeType, "compressed") || mimeType == "application/vnd.rar":
+		return "archive"
+	default:
+		return "file"
+	}
+}
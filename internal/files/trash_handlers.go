@@ -0,0 +1,163 @@
+package files
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/i-christian/fileShare/internal/utils"
+	"github.com/i-christian/fileShare/internal/utils/security"
+	"github.com/i-christian/fileShare/internal/validator"
+)
+
+// ListTrash retrieves the user's deleted files which can still be restored
+func (h *FileHandler) ListTrash(w http.ResponseWriter, r *http.Request) {
+	user, ok := security.GetUserFromContext(r)
+	if !ok || user.IsAnonymous() {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	qs := r.URL.Query()
+	input := validator.Filters{
+		Page:     utils.ReadInt(qs, "page", 1),
+		PageSize: utils.ReadInt(qs, "page_size", 20),
+	}
+
+	v := validator.New()
+	useCursor, cursor := readCursor(qs, v)
+	if validator.ValidateFilters(v, input); !v.Valid() {
+		utils.FailedValidationResponse(w, v.Errors)
+		return
+	}
+
+	filters := utils.Filters{
+		Page:         input.Page,
+		PageSize:     input.PageSize,
+		UseCursor:    useCursor,
+		Cursor:       cursor,
+		IncludeTotal: utils.ReadBool(qs, "include_total", false),
+	}
+
+	files, metadata, err := h.service.ListTrashedFiles(r.Context(), user.UserID, filters)
+	if err != nil {
+		utils.WriteServerError(h.logger, "failed to fetch trashed files", err)
+		utils.ServerErrorResponse(w, "failed to fetch files")
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"metadata": metadata,
+		"files":    files,
+	}, nil)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+	}
+}
+
+// Restore takes a file out of the trash
+func (h *FileHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	fileID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		utils.BadRequestResponse(w, errors.New("invalid file ID parameter"))
+		return
+	}
+
+	user, ok := security.GetUserFromContext(r)
+	if !ok || user.IsAnonymous() {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	var input struct {
+		Version int32 `json:"version"`
+	}
+
+	err = utils.ReadJSON(w, r, &input)
+	if err != nil {
+		utils.BadRequestResponse(w, err)
+		return
+	}
+
+	v := validator.New()
+	f := validator.FileInfo{
+		Version: input.Version,
+	}
+	if validator.ValidateDeleteFile(v, f); !v.Valid() {
+		utils.FailedValidationResponse(w, v.Errors)
+		return
+	}
+
+	version, err := h.service.RestoreFile(r.Context(), fileID, user.UserID, input.Version)
+	if err != nil {
+		if errors.Is(err, utils.ErrRecordNotFound) {
+			utils.NotFoundResponse(w)
+			return
+		}
+		utils.WriteServerError(h.logger, "failed to restore file", err)
+		utils.ServerErrorResponse(w, "failed to restore file")
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"message": "file restored successfully",
+		"version": version,
+	}, nil)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+	}
+}
+
+// Purge permanently deletes a file in the trash
+func (h *FileHandler) Purge(w http.ResponseWriter, r *http.Request) {
+	fileID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		utils.BadRequestResponse(w, errors.New("invalid file ID parameter"))
+		return
+	}
+
+	user, ok := security.GetUserFromContext(r)
+	if !ok || user.IsAnonymous() {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	if err := h.service.PurgeFile(r.Context(), fileID, user.UserID); err != nil {
+		if errors.Is(err, utils.ErrRecordNotFound) {
+			utils.NotFoundResponse(w)
+			return
+		}
+		utils.WriteServerError(h.logger, "failed to purge file", err)
+		utils.ServerErrorResponse(w, "failed to delete file")
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "file permanently deleted"}, nil)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+	}
+}
+
+// EmptyTrash permanently deletes every file in the user's trash
+func (h *FileHandler) EmptyTrash(w http.ResponseWriter, r *http.Request) {
+	user, ok := security.GetUserFromContext(r)
+	if !ok || user.IsAnonymous() {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	purged, err := h.service.EmptyTrash(r.Context(), user.UserID)
+	if err != nil {
+		utils.WriteServerError(h.logger, "failed to empty trash", err)
+		utils.ServerErrorResponse(w, "failed to empty trash")
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"message":      "trash emptied successfully",
+		"purged_files": purged,
+	}, nil)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+	}
+}
@@ -0,0 +1,116 @@
+package files
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/i-christian/fileShare/internal/database"
+	"github.com/i-christian/fileShare/internal/utils"
+)
+
+// ListTrashedFiles returns the files the user has deleted which have not been purged yet, most recently deleted first.
+// With cursor pagination the files are only counted when filters.IncludeTotal is set.
+func (s *FileService) ListTrashedFiles(ctx context.Context, userID uuid.UUID, filters utils.Filters) ([]database.ListTrashedFilesRow, utils.Metadata, error) {
+	limit, offset := pageBounds(filters)
+
+	var count int64
+	if !filters.UseCursor || filters.IncludeTotal {
+		var err error
+		count, err = s.db.CountTrashedFiles(ctx, userID)
+		if err != nil {
+			return []database.ListTrashedFilesRow{}, utils.Metadata{}, err
+		}
+	}
+
+	cursorTime, cursorID := cursorArgs(filters)
+	files, err := s.db.ListTrashedFiles(ctx, database.ListTrashedFilesParams{
+		UserID:     userID,
+		CursorTime: cursorTime,
+		CursorID:   cursorID,
+		Ascending:  filters.Cursor != nil && filters.Cursor.Backward,
+		PageLimit:  limit,
+		PageOffset: offset,
+	})
+	if err != nil {
+		return []database.ListTrashedFilesRow{}, utils.Metadata{}, err
+	}
+
+	if filters.UseCursor {
+		files, meta := cursorPage(files, filters, int(count), func(f database.ListTrashedFilesRow) utils.Cursor {
+			return utils.Cursor{Time: f.DeletedAt.Time, ID: f.FileID}
+		})
+		return files, meta, nil
+	}
+
+	meta := utils.CalculateMetadata(int(count), filters.Page, filters.PageSize)
+
+	return files, meta, nil
+}
+
+// RestoreFile takes a file owned by the user out of the trash, returning its new version
+func (s *FileService) RestoreFile(ctx context.Context, fileID, userID uuid.UUID, version int32) (int32, error) {
+	newVersion, err := s.db.RestoreFile(ctx, database.RestoreFileParams{
+		FileID:  fileID,
+		UserID:  userID,
+		Version: version,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, utils.ErrRecordNotFound
+		}
+		return 0, err
+	}
+
+	return newVersion, nil
+}
+
+// PurgeFile permanently deletes a file in the user's trash without waiting for its retention period to pass
+func (s *FileService) PurgeFile(ctx context.Context, fileID, userID uuid.UUID) error {
+	purged, err := s.purgeTrash(ctx, userID, uuid.NullUUID{UUID: fileID, Valid: true})
+	if err != nil {
+		return err
+	}
+
+	if purged == 0 {
+		return utils.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// EmptyTrash permanently deletes every file in the user's trash
+func (s *FileService) EmptyTrash(ctx context.Context, userID uuid.UUID) (purgedFiles int, err error) {
+	return s.purgeTrash(ctx, userID, uuid.NullUUID{})
+}
+
+// purgeTrash permanently deletes the user's trashed files, or only the given file when fileID is set.
+func (s *FileService) purgeTrash(ctx context.Context, userID uuid.UUID, fileID uuid.NullUUID) (int, error) {
+	files, err := s.db.GetTrashedFilesForPurge(ctx, database.GetTrashedFilesForPurgeParams{
+		UserID: userID,
+		FileID: fileID,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if len(files) == 0 {
+		return 0, nil
+	}
+
+	var fileIDs []uuid.UUID
+	var thumbnailKeys []string
+	for _, f := range files {
+		fileIDs = append(fileIDs, f.FileID)
+		if f.ThumbnailKey.Valid {
+			thumbnailKeys = append(thumbnailKeys, f.ThumbnailKey.String)
+		}
+	}
+
+	if err := s.purgeFiles(ctx, fileIDs, thumbnailKeys); err != nil {
+		return 0, err
+	}
+
+	return len(files), nil
+}
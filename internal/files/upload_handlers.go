@@ -0,0 +1,313 @@
+package files
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/i-christian/fileShare/internal/utils"
+	"github.com/i-christian/fileShare/internal/utils/security"
+	"github.com/i-christian/fileShare/internal/validator"
+)
+
+// tus protocol constants, see https://tus.io/protocols/resumable-upload
+const (
+	tusVersion     = "1.0.0"
+	tusExtensions  = "creation,termination,expiration"
+	tusContentType = "application/offset+octet-stream"
+)
+
+// requireTusResumable checks that the client uses a supported version of the tus protocol
+func requireTusResumable(w http.ResponseWriter, r *http.Request) bool {
+	w.Header().Set("Tus-Resumable", tusVersion)
+
+	if r.Header.Get("Tus-Resumable") != tusVersion {
+		w.Header().Set("Tus-Version", tusVersion)
+		utils.WriteErrorJSON(w, http.StatusPreconditionFailed, "unsupported tus protocol version")
+		return false
+	}
+
+	return true
+}
+
+// parseUploadMetadata decodes the Upload-Metadata header,
+// a comma separated list of keys each followed by a space and a base64 encoded value.
+func parseUploadMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	if strings.TrimSpace(header) == "" {
+		return metadata, nil
+	}
+
+	for pair := range strings.SplitSeq(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, errors.New("malformed Upload-Metadata header")
+		}
+
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, errors.New("Upload-Metadata values must be base64 encoded")
+		}
+
+		metadata[key] = string(value)
+	}
+
+	return metadata, nil
+}
+
+// UploadOptions advertises the tus protocol version and extensions supported by the server
+func (h *FileHandler) UploadOptions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Tus-Version", tusVersion)
+	w.Header().Set("Tus-Extension", tusExtensions)
+	w.Header().Set("Tus-Max-Size", strconv.FormatUint(h.maxUploadSize, 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateUpload starts a new resumable upload
+func (h *FileHandler) CreateUpload(w http.ResponseWriter, r *http.Request) {
+	user, ok := security.GetUserFromContext(r)
+	if !ok || user.IsAnonymous() {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	if !requireTusResumable(w, r) {
+		return
+	}
+
+	if r.Header.Get("Upload-Defer-Length") != "" {
+		utils.BadRequestResponse(w, errors.New("deferred upload length is not supported"))
+		return
+	}
+
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		utils.BadRequestResponse(w, errors.New("invalid Upload-Length header"))
+		return
+	}
+
+	if uint64(length) > h.maxUploadSize {
+		utils.WriteErrorJSON(w, http.StatusRequestEntityTooLarge, "upload length exceeds the maximum upload size")
+		return
+	}
+
+	rawMetadata := r.Header.Get("Upload-Metadata")
+	metadata, err := parseUploadMetadata(rawMetadata)
+	if err != nil {
+		utils.BadRequestResponse(w, err)
+		return
+	}
+
+	f := &validator.FileUpload{
+		Filename:      metadata["filename"],
+		UploadSize:    length,
+		MaxUploadSize: int64(h.maxUploadSize),
+	}
+	v := validator.New()
+	validator.ValidateTags(v, "tags", validator.NormalizeTags(strings.Split(metadata["tags"], ",")))
+	if _, err := parseStripLocation(metadata["strip_location"]); err != nil {
+		v.AddError("strip_location", err.Error())
+	}
+	if validator.ValidateResumableUpload(v, f); !v.Valid() {
+		utils.FailedValidationResponse(w, v.Errors)
+		return
+	}
+
+	upload, err := h.service.CreateUpload(r.Context(), user.UserID, f.Filename, rawMetadata, length)
+	if err != nil {
+		if errors.Is(err, utils.ErrQuotaExceeded) {
+			utils.WriteErrorJSON(w, http.StatusRequestEntityTooLarge, err.Error())
+			return
+		}
+		utils.WriteServerError(h.logger, "failed to create upload", err)
+		utils.ServerErrorResponse(w, "failed to create upload")
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", path.Join(r.URL.Path, upload.UploadID.String()))
+	headers.Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+
+	err = utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"upload": upload}, headers)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+	}
+}
+
+// UploadStatus reports how many bytes of a resumable upload have been received
+func (h *FileHandler) UploadStatus(w http.ResponseWriter, r *http.Request) {
+	uploadID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		utils.BadRequestResponse(w, errors.New("invalid upload ID parameter"))
+		return
+	}
+
+	user, ok := security.GetUserFromContext(r)
+	if !ok || user.IsAnonymous() {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	if !requireTusResumable(w, r) {
+		return
+	}
+
+	upload, err := h.service.GetUpload(r.Context(), uploadID, user.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrRecordNotFound):
+			utils.NotFoundResponse(w)
+		case errors.Is(err, utils.ErrUploadExpired):
+			utils.WriteErrorJSON(w, http.StatusGone, err.Error())
+		default:
+			utils.WriteServerError(h.logger, "failed to get upload", err)
+			utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+		}
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.UploadOffset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.UploadLength, 10))
+	if upload.Metadata != "" {
+		w.Header().Set("Upload-Metadata", upload.Metadata)
+	}
+	if upload.FileID.Valid {
+		w.Header().Set("File-Id", upload.FileID.UUID.String())
+	} else {
+		w.Header().Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// UploadChunk writes a chunk of a resumable upload, creating the file once the last chunk is received
+func (h *FileHandler) UploadChunk(w http.ResponseWriter, r *http.Request) {
+	uploadID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		utils.BadRequestResponse(w, errors.New("invalid upload ID parameter"))
+		return
+	}
+
+	user, ok := security.GetUserFromContext(r)
+	if !ok || user.IsAnonymous() {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	if !requireTusResumable(w, r) {
+		return
+	}
+
+	if r.Header.Get("Content-Type") != tusContentType {
+		utils.WriteErrorJSON(w, http.StatusUnsupportedMediaType, "content type must be "+tusContentType)
+		return
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		utils.BadRequestResponse(w, errors.New("invalid Upload-Offset header"))
+		return
+	}
+
+	upload, err := h.service.GetUpload(r.Context(), uploadID, user.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrRecordNotFound):
+			utils.NotFoundResponse(w)
+		case errors.Is(err, utils.ErrUploadExpired):
+			utils.WriteErrorJSON(w, http.StatusGone, err.Error())
+		default:
+			utils.WriteServerError(h.logger, "failed to get upload", err)
+			utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+		}
+		return
+	}
+
+	if offset != upload.UploadOffset {
+		w.Header().Set("Upload-Offset", strconv.FormatInt(upload.UploadOffset, 10))
+		utils.WriteErrorJSON(w, http.StatusConflict, utils.ErrOffsetMismatch.Error())
+		return
+	}
+
+	var chunk io.Reader = http.MaxBytesReader(w, r.Body, upload.UploadLength-offset)
+	var contentType string
+	if offset == 0 {
+		chunk, contentType, err = validator.ValidateAndPrepareStream(upload.Filename, chunk)
+		if err != nil {
+			utils.FailedValidationResponse(w, map[string]string{"error": err.Error()})
+			return
+		}
+	}
+
+	newOffset, fileID, err := h.service.WriteUploadChunk(r.Context(), upload, offset, chunk, contentType)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.Is(err, utils.ErrOffsetMismatch):
+			w.Header().Set("Upload-Offset", strconv.FormatInt(newOffset, 10))
+			utils.WriteErrorJSON(w, http.StatusConflict, err.Error())
+		case errors.Is(err, utils.ErrUploadLocked):
+			utils.WriteErrorJSON(w, http.StatusLocked, err.Error())
+		case errors.Is(err, utils.ErrRecordNotFound):
+			utils.NotFoundResponse(w)
+		case errors.As(err, &maxBytesErr):
+			utils.WriteErrorJSON(w, http.StatusRequestEntityTooLarge, "chunk exceeds the remaining upload length")
+		case errors.Is(err, utils.ErrQuotaExceeded):
+			utils.WriteErrorJSON(w, http.StatusRequestEntityTooLarge, err.Error())
+		case errors.Is(err, utils.ErrDuplicateUpload):
+			utils.WriteErrorJSON(w, http.StatusConflict, err.Error())
+		default:
+			utils.WriteServerError(h.logger, "failed to write upload chunk", err)
+			utils.ServerErrorResponse(w, "failed to process upload")
+		}
+		return
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(newOffset, 10))
+	if fileID != uuid.Nil {
+		w.Header().Set("File-Id", fileID.String())
+	} else {
+		w.Header().Set("Upload-Expires", time.Now().Add(resumableUploadTTL).UTC().Format(http.TimeFormat))
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TerminateUpload cancels a resumable upload and discards the received chunks
+func (h *FileHandler) TerminateUpload(w http.ResponseWriter, r *http.Request) {
+	uploadID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		utils.BadRequestResponse(w, errors.New("invalid upload ID parameter"))
+		return
+	}
+
+	user, ok := security.GetUserFromContext(r)
+	if !ok || user.IsAnonymous() {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	if !requireTusResumable(w, r) {
+		return
+	}
+
+	if err := h.service.TerminateUpload(r.Context(), uploadID, user.UserID); err != nil {
+		if errors.Is(err, utils.ErrRecordNotFound) {
+			utils.NotFoundResponse(w)
+			return
+		}
+		utils.WriteServerError(h.logger, "failed to terminate upload", err)
+		utils.ServerErrorResponse(w, "failed to terminate upload")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,374 @@
+package files
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/i-christian/fileShare/internal/database"
+	"github.com/i-christian/fileShare/internal/filestore"
+	"github.com/i-christian/fileShare/internal/utils"
+	"github.com/i-christian/fileShare/internal/validator"
+)
+
+// resumableUploadTTL is how long a resumable upload is kept after its last received chunk.
+const resumableUploadTTL = 24 * time.Hour
+
+// uploadClaimTTL is how long a request writing a chunk holds its claim on the upload without renewing it.
+// The claim is renewed while the chunk is streamed, so it only lapses when the server handling the request stops.
+const uploadClaimTTL = time.Minute
+
+// checkUploadQuota fails with utils.ErrQuotaExceeded when a new file of `length` bytes would exceed the user's storage quota.
+func (s *FileService) checkUploadQuota(ctx context.Context, userID uuid.UUID, length int64) error {
+	quota, err := s.remainingQuota(ctx, userID, 1)
+	if err != nil {
+		return err
+	}
+	if length > quota {
+		return utils.ErrQuotaExceeded
+	}
+
+	return nil
+}
+
+// CreateUpload registers a new resumable upload of `length` bytes for the user.
+// Uploads which would exceed the user's storage quota are refused with utils.ErrQuotaExceeded.
+func (s *FileService) CreateUpload(ctx context.Context, userID uuid.UUID, fileName, metadata string, length int64) (database.CreateUploadRow, error) {
+	if err := s.checkUploadQuota(ctx, userID, length); err != nil {
+		return database.CreateUploadRow{}, err
+	}
+
+	return s.db.CreateUpload(ctx, database.CreateUploadParams{
+		UserID:       userID,
+		Filename:     fileName,
+		Metadata:     metadata,
+		UploadLength: length,
+		ExpiresAt:    time.Now().Add(resumableUploadTTL),
+	})
+}
+
+// GetUpload retrieves a resumable upload owned by the user.
+// Incomplete uploads which have passed their expiry time are reported as expired.
+func (s *FileService) GetUpload(ctx context.Context, uploadID, userID uuid.UUID) (database.GetUploadRow, error) {
+	upload, err := s.db.GetUpload(ctx, database.GetUploadParams{
+		UploadID: uploadID,
+		UserID:   userID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return database.GetUploadRow{}, utils.ErrRecordNotFound
+		}
+		return database.GetUploadRow{}, err
+	}
+
+	if !upload.FileID.Valid && time.Now().After(upload.ExpiresAt) {
+		return database.GetUploadRow{}, utils.ErrUploadExpired
+	}
+
+	return upload, nil
+}
+
+// restoreHasher returns a SHA-256 hasher resumed from the state saved after the previous chunk.
+func restoreHasher(state []byte) (hash.Hash, error) {
+	hasher := sha256.New()
+	if len(state) == 0 {
+		return hasher, nil
+	}
+
+	if err := hasher.(encoding.BinaryUnmarshaler).UnmarshalBinary(state); err != nil {
+		return nil, fmt.Errorf("failed to restore checksum state: %w", err)
+	}
+
+	return hasher, nil
+}
+
+// WriteUploadChunk writes a chunk to a resumable upload at `offset` while updating the running checksum.
+// Once all bytes have been received the chunks are assembled and the file record is created.
+//
+// contentType is only set for the first chunk of an upload, an empty value keeps the stored MIME type.
+// A chunk which fails to be stored is discarded and the client resumes from the last recorded offset.
+// The quota is checked again before every chunk, since other uploads may have used up the space in the meantime.
+//
+// Requests writing to the same upload run one at a time: the request claims the upload at `offset` before anything
+// is stored and holds the claim until the offset is recorded. A request sent at an offset another request has already
+// written past is refused with utils.ErrOffsetMismatch, one sent while another request writes is refused with utils.ErrUploadLocked.
+// No transaction is held open while the chunk is streamed, so slow clients do not hold on to database connections.
+func (s *FileService) WriteUploadChunk(ctx context.Context, upload database.GetUploadRow, offset int64, chunk io.Reader, contentType string) (newOffset int64, fileID uuid.UUID, err error) {
+	token := uuid.NullUUID{UUID: uuid.New(), Valid: true}
+
+	claimed, err := s.db.ClaimUpload(ctx, database.ClaimUploadParams{
+		WriteToken:    token,
+		ClaimedUntil:  sql.NullTime{Time: time.Now().Add(uploadClaimTTL), Valid: true},
+		UploadID:      upload.UploadID,
+		UserID:        upload.UserID,
+		CurrentOffset: offset,
+	})
+	if err != nil {
+		return upload.UploadOffset, uuid.Nil, err
+	}
+
+	// Another request may have written to the upload since it was read
+	upload, err = s.db.GetUpload(ctx, database.GetUploadParams{
+		UploadID: upload.UploadID,
+		UserID:   upload.UserID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, uuid.Nil, utils.ErrRecordNotFound
+		}
+		return 0, uuid.Nil, err
+	}
+
+	if claimed == 0 {
+		switch {
+		case offset != upload.UploadOffset:
+			return upload.UploadOffset, uuid.Nil, utils.ErrOffsetMismatch
+		case upload.FileID.Valid:
+			return upload.UploadOffset, upload.FileID.UUID, nil
+		default:
+			return upload.UploadOffset, uuid.Nil, utils.ErrUploadLocked
+		}
+	}
+
+	claimCtx, release := s.holdUploadClaim(ctx, upload.UploadID, token)
+	defer release()
+
+	if err := s.checkUploadQuota(ctx, upload.UserID, upload.UploadLength); err != nil {
+		return upload.UploadOffset, uuid.Nil, err
+	}
+
+	if contentType == "" {
+		contentType = upload.MimeType
+	}
+
+	hasher, err := restoreHasher(upload.HashState)
+	if err != nil {
+		return upload.UploadOffset, uuid.Nil, err
+	}
+
+	chunk = &claimReader{r: io.TeeReader(chunk, hasher), ctx: claimCtx}
+	written, err := s.store.WriteChunk(filestore.WithOwner(claimCtx, upload.UserID), upload.UploadID.String(), offset, chunk)
+	if err != nil {
+		if errors.Is(context.Cause(claimCtx), utils.ErrUploadLocked) {
+			return upload.UploadOffset, uuid.Nil, utils.ErrUploadLocked
+		}
+		return upload.UploadOffset, uuid.Nil, fmt.Errorf("storage error: %w", err)
+	}
+
+	hashState, err := hasher.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return upload.UploadOffset, uuid.Nil, fmt.Errorf("failed to save checksum state: %w", err)
+	}
+
+	newOffset, err = s.db.UpdateUploadOffset(ctx, database.UpdateUploadOffsetParams{
+		NewOffset:     offset + written,
+		HashState:     hashState,
+		MimeType:      contentType,
+		ExpiresAt:     time.Now().Add(resumableUploadTTL),
+		UploadID:      upload.UploadID,
+		CurrentOffset: offset,
+		WriteToken:    token,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return upload.UploadOffset, uuid.Nil, utils.ErrUploadLocked
+		}
+		return upload.UploadOffset, uuid.Nil, err
+	}
+
+	if newOffset < upload.UploadLength {
+		return newOffset, uuid.Nil, nil
+	}
+
+	// The claim is held until the upload is complete, so a request sent at the final offset does not complete it twice
+	fileRec, err := s.completeUpload(claimCtx, upload, contentType, hex.EncodeToString(hasher.Sum(nil)))
+	if err != nil {
+		return newOffset, uuid.Nil, err
+	}
+
+	return newOffset, fileRec.FileID, nil
+}
+
+// claimReader stops reading a chunk once the claim on its upload is lost,
+// since storage backends writing to disk do not watch the context.
+type claimReader struct {
+	r   io.Reader
+	ctx context.Context
+}
+
+func (c *claimReader) Read(p []byte) (int, error) {
+	if err := context.Cause(c.ctx); err != nil {
+		return 0, err
+	}
+
+	return c.r.Read(p)
+}
+
+// holdUploadClaim renews the claim `token` holds on the upload until release is called, which also gives the claim up.
+// The returned context is cancelled with utils.ErrUploadLocked once the claim can not be renewed in time,
+// so a request whose claim lapsed stops writing before another request takes the upload over.
+func (s *FileService) holdUploadClaim(ctx context.Context, uploadID uuid.UUID, token uuid.NullUUID) (context.Context, func()) {
+	claimCtx, cancel := context.WithCancelCause(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(uploadClaimTTL / 4)
+		defer ticker.Stop()
+
+		claimedUntil := time.Now().Add(uploadClaimTTL)
+		for {
+			select {
+			case <-done:
+				return
+			case <-claimCtx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			until := time.Now().Add(uploadClaimTTL)
+			renewed, err := s.db.RenewUploadClaim(claimCtx, database.RenewUploadClaimParams{
+				ClaimedUntil: sql.NullTime{Time: until, Valid: true},
+				UploadID:     uploadID,
+				WriteToken:   token,
+			})
+			switch {
+			case err == nil && renewed > 0:
+				claimedUntil = until
+			case err == nil:
+				cancel(utils.ErrUploadLocked)
+				return
+			case time.Until(claimedUntil) < uploadClaimTTL/2:
+				utils.WriteServerError(s.logger, "failed to renew upload claim", err)
+				cancel(utils.ErrUploadLocked)
+				return
+			}
+		}
+	}()
+
+	release := func() {
+		close(done)
+		cancel(nil)
+
+		err := s.db.ReleaseUpload(context.WithoutCancel(ctx), database.ReleaseUploadParams{
+			UploadID:   uploadID,
+			WriteToken: token,
+		})
+		if err != nil {
+			utils.WriteServerError(s.logger, "failed to release upload claim", err)
+		}
+	}
+
+	return claimCtx, release
+}
+
+// completeUpload assembles the chunks of a fully received upload and creates its file record.
+func (s *FileService) completeUpload(ctx context.Context, upload database.GetUploadRow, contentType, checksum string) (database.CreateFileRow, error) {
+	storageKey := newStorageKey()
+
+	fileSize, err := s.store.CompleteChunks(filestore.WithOwner(ctx, upload.UserID), upload.UploadID.String(), storageKey)
+	if err != nil {
+		s.logger.Error("failed to assemble upload chunks", "upload_id", upload.UploadID, "error", err)
+		return database.CreateFileRow{}, fmt.Errorf("storage error")
+	}
+	if fileSize != upload.UploadLength {
+		s.logger.Error("assembled upload does not match its length", "upload_id", upload.UploadID, "size", fileSize, "length", upload.UploadLength)
+		_, _, _ = s.store.Delete(ctx, []string{storageKey})
+		// The chunks were removed once assembled, so the upload can not be resumed.
+		_ = s.db.DeleteUpload(ctx, database.DeleteUploadParams{
+			UploadID: upload.UploadID,
+			UserID:   upload.UserID,
+		})
+		return database.CreateFileRow{}, fmt.Errorf("storage error")
+	}
+
+	// Tags and options were validated when the upload was created
+	tags := []string{}
+	var stripLocation sql.NullBool
+	if metadata, err := parseUploadMetadata(upload.Metadata); err == nil {
+		tags = validator.NormalizeTags(strings.Split(metadata["tags"], ","))
+		stripLocation, _ = parseStripLocation(metadata["strip_location"])
+	}
+
+	fileRec, err := s.createFileRecord(upload.UserID, storageKey, contentType, upload.Filename, tags, stripLocation, fileSize, checksum)
+	if err != nil {
+		// The assembled content has already been removed, so the upload can not be resumed.
+		_ = s.db.DeleteUpload(ctx, database.DeleteUploadParams{
+			UploadID: upload.UploadID,
+			UserID:   upload.UserID,
+		})
+		return database.CreateFileRow{}, err
+	}
+
+	err = s.db.CompleteUpload(ctx, database.CompleteUploadParams{
+		FileID:   uuid.NullUUID{UUID: fileRec.FileID, Valid: true},
+		UploadID: upload.UploadID,
+	})
+	if err != nil {
+		utils.WriteServerError(s.logger, "failed to mark upload as complete", err)
+	}
+
+	return fileRec, nil
+}
+
+// TerminateUpload discards a resumable upload and any chunks received so far.
+func (s *FileService) TerminateUpload(ctx context.Context, uploadID, userID uuid.UUID) error {
+	upload, err := s.db.GetUpload(ctx, database.GetUploadParams{
+		UploadID: uploadID,
+		UserID:   userID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.ErrRecordNotFound
+		}
+		return err
+	}
+
+	if !upload.FileID.Valid {
+		if err := s.store.AbortChunks(ctx, uploadID.String()); err != nil {
+			return fmt.Errorf("failed to remove upload chunks: %w", err)
+		}
+	}
+
+	return s.db.DeleteUpload(ctx, database.DeleteUploadParams{
+		UploadID: uploadID,
+		UserID:   userID,
+	})
+}
+
+// CleanupExpiredUploads removes expired resumable uploads together with their partial data
+func (s *FileService) CleanupExpiredUploads(ctx context.Context, limit int32) (deletedUploads int, err error) {
+	uploads, err := s.db.GetExpiredUploads(ctx, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch expired uploads: %w", err)
+	}
+
+	if len(uploads) == 0 {
+		return 0, nil
+	}
+
+	var uploadIDs []uuid.UUID
+	for _, u := range uploads {
+		if !u.FileID.Valid {
+			if err := s.store.AbortChunks(ctx, u.UploadID.String()); err != nil {
+				utils.WriteServerError(s.logger, "failed to remove expired upload chunks", err)
+				continue
+			}
+		}
+		uploadIDs = append(uploadIDs, u.UploadID)
+	}
+
+	if err := s.db.HardDeleteUploads(ctx, uploadIDs); err != nil {
+		return 0, fmt.Errorf("failed to delete upload records: %w", err)
+	}
+
+	return len(uploadIDs), nil
+}
@@ -0,0 +1,164 @@
+package files
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/i-christian/fileShare/internal/utils"
+	"github.com/i-christian/fileShare/internal/utils/security"
+	"github.com/i-christian/fileShare/internal/validator"
+)
+
+// readVersionNumber parses the file version number path parameter
+func readVersionNumber(r *http.Request) (int32, error) {
+	versionNumber, err := strconv.ParseInt(r.PathValue("versionNumber"), 10, 32)
+	if err != nil || versionNumber < 1 {
+		return 0, errors.New("invalid version number parameter")
+	}
+
+	return int32(versionNumber), nil
+}
+
+// ListFileVersions lists the version history of a file
+func (h *FileHandler) ListFileVersions(w http.ResponseWriter, r *http.Request) {
+	fileID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		utils.BadRequestResponse(w, errors.New("invalid file ID parameter"))
+		return
+	}
+
+	user, ok := security.GetUserFromContext(r)
+	if !ok || user.IsAnonymous() {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	versions, err := h.service.ListFileVersions(r.Context(), fileID, user.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrRecordNotFound):
+			utils.NotFoundResponse(w)
+		case errors.Is(err, utils.ErrNotPermitted):
+			utils.NotPermittedResponse(w)
+		default:
+			utils.WriteServerError(h.logger, "failed to list file versions", err)
+			utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+		}
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"versions": versions}, nil)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+	}
+}
+
+// DownloadFileVersion streams a specific version of a file, or the requested byte ranges of it, to the client
+func (h *FileHandler) DownloadFileVersion(w http.ResponseWriter, r *http.Request) {
+	fileID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		utils.BadRequestResponse(w, errors.New("invalid file ID parameter"))
+		return
+	}
+
+	versionNumber, err := readVersionNumber(r)
+	if err != nil {
+		utils.BadRequestResponse(w, err)
+		return
+	}
+
+	user, ok := security.GetUserFromContext(r)
+	if !ok || user.IsAnonymous() {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	stream, fileInfo, err := h.service.DownloadFileVersion(r.Context(), fileID, user.UserID, versionNumber)
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrRecordNotFound):
+			utils.NotFoundResponse(w)
+		case errors.Is(err, utils.ErrNotPermitted):
+			utils.NotPermittedResponse(w)
+		case errors.Is(err, utils.ErrScanPending), errors.Is(err, utils.ErrFileInfected):
+			withheldResponse(w, err)
+		default:
+			utils.WriteServerError(h.logger, "failed to prepare file version download", err)
+			utils.ServerErrorResponse(w, "file unavailable")
+		}
+		return
+	}
+
+	serveFile(w, r, stream, fileInfo)
+}
+
+// PromoteFileVersion restores an earlier version as the current content of a file
+func (h *FileHandler) PromoteFileVersion(w http.ResponseWriter, r *http.Request) {
+	fileID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		utils.BadRequestResponse(w, errors.New("invalid file ID parameter"))
+		return
+	}
+
+	versionNumber, err := readVersionNumber(r)
+	if err != nil {
+		utils.BadRequestResponse(w, err)
+		return
+	}
+
+	user, ok := security.GetUserFromContext(r)
+	if !ok || user.IsAnonymous() {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	var input struct {
+		Version int32 `json:"version"`
+	}
+
+	err = utils.ReadJSON(w, r, &input)
+	if err != nil {
+		utils.BadRequestResponse(w, err)
+		return
+	}
+
+	f := validator.FileInfo{
+		Version:       input.Version,
+		VersionNumber: versionNumber,
+	}
+	v := validator.New()
+	if validator.ValidatePromoteVersion(v, f); !v.Valid() {
+		utils.FailedValidationResponse(w, v.Errors)
+		return
+	}
+
+	promoted, err := h.service.PromoteFileVersion(r.Context(), fileID, user.UserID, versionNumber, input.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrRecordNotFound):
+			utils.NotFoundResponse(w)
+		case errors.Is(err, utils.ErrNotPermitted):
+			utils.NotPermittedResponse(w)
+		case errors.Is(err, utils.ErrEditConflict):
+			utils.EditConflictResponse(w)
+		case errors.Is(err, utils.ErrCurrentVersion):
+			utils.BadRequestResponse(w, err)
+		default:
+			utils.WriteServerError(h.logger, "failed to promote file version", err)
+			utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+		}
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"message":         fmt.Sprintf("version %d is now the current version of the file", promoted.CurrentVersion),
+		"current_version": promoted.CurrentVersion,
+		"version":         promoted.Version,
+	}, nil)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+	}
+}
@@ -0,0 +1,256 @@
+package files
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/google/uuid"
+	"github.com/i-christian/fileShare/internal/database"
+	"github.com/i-christian/fileShare/internal/filestore"
+	"github.com/i-christian/fileShare/internal/utils"
+)
+
+// UploadFileVersion streams new content for an existing file owned by the user and makes it the current version.
+// Earlier content is kept in the version history until it is pruned by the cleanup job.
+func (s *FileService) UploadFileVersion(ctx context.Context, fileID, userID uuid.UUID, fileStream io.Reader, contentType string, fileName string, stripLocation sql.NullBool, maxUploadSize int64) (database.AddFileVersionRow, error) {
+	file, err := s.ownedFile(ctx, fileID, userID)
+	if err != nil {
+		return database.AddFileVersionRow{}, err
+	}
+
+	stripPending, err := s.stripsLocation(ctx, userID, contentType, stripLocation)
+	if err != nil {
+		return database.AddFileVersionRow{}, err
+	}
+
+	// Earlier versions stay in storage, so the new content counts towards the quota in full
+	quota, err := s.remainingQuota(ctx, userID, 0)
+	if err != nil {
+		return database.AddFileVersionRow{}, err
+	}
+
+	storageKey := newStorageKey()
+
+	fileSize, checksum, err := s.saveStream(filestore.WithOwner(ctx, userID), fileStream, storageKey, maxUploadSize, quota)
+	if err != nil {
+		return database.AddFileVersionRow{}, err
+	}
+
+	if checksum == file.Checksum {
+		_, _, _ = s.store.Delete(ctx, []string{storageKey})
+		return database.AddFileVersionRow{}, utils.ErrDuplicateUpload
+	}
+
+	blobKey, err := s.referenceBlob(ctx, userID, storageKey, checksum, fileSize)
+	if err != nil {
+		_, _, _ = s.store.Delete(ctx, []string{storageKey})
+		return database.AddFileVersionRow{}, fmt.Errorf("database error: %w", err)
+	}
+	storageKey = blobKey
+
+	fileVersion, err := s.insertFileVersion(ctx, database.AddFileVersionParams{
+		StorageKey:           storageKey,
+		MimeType:             contentType,
+		SizeBytes:            fileSize,
+		Checksum:             checksum,
+		ScanStatus:           s.initialScanStatus(),
+		StripLocationPending: stripPending,
+		FileID:               fileID,
+		UserID:               userID,
+		Version:              file.Version,
+	})
+	if err != nil {
+		if err := s.releaseBlobs(ctx, []string{storageKey}); err != nil {
+			utils.WriteServerError(s.logger, "failed to release blob of a version which could not be added", err)
+		}
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return database.AddFileVersionRow{}, utils.ErrEditConflict
+		case errors.Is(err, utils.ErrQuotaExceeded):
+			return database.AddFileVersionRow{}, err
+		default:
+			return database.AddFileVersionRow{}, fmt.Errorf("database error: %w", err)
+		}
+	}
+
+	s.enqueueScan(fileID, storageKey)
+	s.replaceThumbnail(ctx, file, storageKey, contentType)
+	s.enqueueImageMetadata(fileID, storageKey, contentType)
+	s.enqueueTextExtraction(fileID, storageKey, contentType, file.Filename)
+
+	return fileVersion, nil
+}
+
+// insertFileVersion adds the version once the user's quota, checked again in the same transaction, leaves room for it.
+func (s *FileService) insertFileVersion(ctx context.Context, params database.AddFileVersionParams) (database.AddFileVersionRow, error) {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return database.AddFileVersionRow{}, err
+	}
+	defer tx.Rollback()
+	q := s.db.WithTx(tx)
+
+	if err := s.reserveQuota(ctx, q, params.UserID, 0, params.SizeBytes); err != nil {
+		return database.AddFileVersionRow{}, err
+	}
+
+	fileVersion, err := q.AddFileVersion(ctx, params)
+	if err != nil {
+		return database.AddFileVersionRow{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return database.AddFileVersionRow{}, err
+	}
+
+	return fileVersion, nil
+}
+
+// replaceThumbnail removes the thumbnail of the previous content of a file and schedules one for the new content.
+func (s *FileService) replaceThumbnail(ctx context.Context, file database.GetFileInfoRow, storageKey, contentType string) {
+	if file.ThumbnailKey.Valid {
+		_, _, err := s.store.Delete(ctx, thumbnailObjects(file.ThumbnailKey.String))
+		if err != nil {
+			utils.WriteServerError(s.logger, "failed to delete outdated thumbnail", err)
+		}
+	}
+
+	s.enqueueThumbnail(file.FileID, storageKey, contentType, file.Filename)
+}
+
+// ListFileVersions returns the version history of a file owned by the user, newest first
+func (s *FileService) ListFileVersions(ctx context.Context, fileID, userID uuid.UUID) ([]database.ListFileVersionsRow, error) {
+	if _, err := s.ownedFile(ctx, fileID, userID); err != nil {
+		return nil, err
+	}
+
+	return s.db.ListFileVersions(ctx, fileID)
+}
+
+// DownloadFileVersion returns a seekable stream of a specific version of a file owned by the user.
+// The returned file info describes the requested version rather than the current one.
+func (s *FileService) DownloadFileVersion(ctx context.Context, fileID, userID uuid.UUID, versionNumber int32) (reader io.ReadSeekCloser, fileInfo database.GetFileInfoRow, err error) {
+	fileInfo, err = s.ownedFile(ctx, fileID, userID)
+	if err != nil {
+		return nil, database.GetFileInfoRow{}, err
+	}
+
+	fileVersion, err := s.db.GetFileVersion(ctx, database.GetFileVersionParams{
+		FileID:        fileID,
+		VersionNumber: versionNumber,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, database.GetFileInfoRow{}, utils.ErrRecordNotFound
+		}
+		return nil, database.GetFileInfoRow{}, err
+	}
+
+	// Only the current content is scanned, earlier versions are served unless they were quarantined
+	if fileVersion.VersionNumber == fileInfo.CurrentVersion {
+		if err := checkScanStatus(fileInfo.ScanStatus); err != nil {
+			return nil, database.GetFileInfoRow{}, err
+		}
+	} else if isQuarantined(fileVersion.StorageKey) {
+		return nil, database.GetFileInfoRow{}, utils.ErrFileInfected
+	}
+
+	fileInfo.StorageKey = fileVersion.StorageKey
+	fileInfo.MimeType = fileVersion.MimeType
+	fileInfo.SizeBytes = fileVersion.SizeBytes
+	fileInfo.Checksum = fileVersion.Checksum
+	fileInfo.UpdatedAt = fileVersion.CreatedAt
+
+	stream, err := filestore.NewRangeReader(ctx, s.store, fileInfo.StorageKey, fileInfo.SizeBytes, s.logger)
+	if err != nil {
+		return nil, database.GetFileInfoRow{}, err
+	}
+
+	return stream, fileInfo, nil
+}
+
+// PromoteFileVersion makes an earlier version the current content of a file owned by the user
+func (s *FileService) PromoteFileVersion(ctx context.Context, fileID, userID uuid.UUID, versionNumber, version int32) (database.PromoteFileVersionRow, error) {
+	file, err := s.ownedFile(ctx, fileID, userID)
+	if err != nil {
+		return database.PromoteFileVersionRow{}, err
+	}
+
+	if file.CurrentVersion == versionNumber {
+		return database.PromoteFileVersionRow{}, utils.ErrCurrentVersion
+	}
+
+	earlier, err := s.db.GetFileVersion(ctx, database.GetFileVersionParams{
+		FileID:        fileID,
+		VersionNumber: versionNumber,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return database.PromoteFileVersionRow{}, utils.ErrRecordNotFound
+		}
+		return database.PromoteFileVersionRow{}, err
+	}
+
+	// Earlier content may predate the user's location stripping setting
+	stripPending, err := s.stripsLocation(ctx, userID, earlier.MimeType, sql.NullBool{})
+	if err != nil {
+		return database.PromoteFileVersionRow{}, err
+	}
+
+	promoted, err := s.db.PromoteFileVersion(ctx, database.PromoteFileVersionParams{
+		ScanStatus:           s.initialScanStatus(),
+		StripLocationPending: stripPending,
+		VersionNumber:        versionNumber,
+		FileID:               fileID,
+		UserID:               userID,
+		Version:              version,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return database.PromoteFileVersionRow{}, utils.ErrEditConflict
+		}
+		return database.PromoteFileVersionRow{}, err
+	}
+
+	s.enqueueScan(fileID, promoted.StorageKey)
+	s.replaceThumbnail(ctx, file, promoted.StorageKey, promoted.MimeType)
+	s.enqueueImageMetadata(fileID, promoted.StorageKey, promoted.MimeType)
+	s.enqueueTextExtraction(fileID, promoted.StorageKey, promoted.MimeType, file.Filename)
+
+	return promoted, nil
+}
+
+// CleanupExcessFileVersions removes the oldest versions of files which have more than the configured number of versions
+func (s *FileService) CleanupExcessFileVersions(ctx context.Context, limit int32) (deletedVersions int, err error) {
+	versions, err := s.db.GetExcessFileVersions(ctx, database.GetExcessFileVersionsParams{
+		KeepVersions: s.maxFileVersions,
+		MaxResults:   limit,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch excess file versions: %w", err)
+	}
+
+	if len(versions) == 0 {
+		return 0, nil
+	}
+
+	var versionIDs []uuid.UUID
+	var storagePaths []string
+	for _, v := range versions {
+		versionIDs = append(versionIDs, v.FileVersionID)
+		storagePaths = append(storagePaths, v.StorageKey)
+	}
+
+	if err := s.db.HardDeleteFileVersions(ctx, versionIDs); err != nil {
+		return 0, fmt.Errorf("failed to delete file version records: %w", err)
+	}
+
+	if err := s.releaseBlobs(ctx, storagePaths); err != nil {
+		return 0, err
+	}
+
+	return len(versionIDs), nil
+}
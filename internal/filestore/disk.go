@@ -2,10 +2,12 @@ package filestore
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 
 	"github.com/i-christian/fileShare/internal/utils"
 )
@@ -63,11 +65,130 @@ func (s *DiskStorage) Get(ctx context.Context, path string) (io.ReadCloser, erro
 	return s.root.Open(path)
 }
 
+// GetRange retrieves part of the file at the specified `path` by seeking to offset within it.
+func (s *DiskStorage) GetRange(ctx context.Context, path string, offset, length int64) (io.ReadCloser, error) {
+	file, err := s.root.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(file, length), file}, nil
+}
+
+// Move renames the file at `source` to `destination` within the DiskStorage's root directory.
+func (s *DiskStorage) Move(ctx context.Context, source, destination string) error {
+	if err := s.ensureParent(destination); err != nil {
+		return err
+	}
+
+	return s.root.Rename(source, destination)
+}
+
 // getRootPath retrieves the root path to files directory
 func (s *DiskStorage) getRootPath() string {
 	return s.root.Name()
 }
 
+// chunkDir returns the directory which holds the chunks of an upload.
+func (s *DiskStorage) chunkDir(uploadID string) string {
+	return filepath.Join(uploadsDir, uploadID)
+}
+
+// listChunkPaths returns the paths of all chunks stored for an upload, ordered by offset.
+func (s *DiskStorage) listChunkPaths(uploadID string) ([]string, error) {
+	dir, err := s.root.Open(s.chunkDir(uploadID))
+	if err != nil {
+		return nil, err
+	}
+	defer dir.Close()
+
+	names, err := dir.Readdirnames(-1)
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(names)
+
+	paths := make([]string, len(names))
+	for i, name := range names {
+		paths[i] = filepath.Join(s.chunkDir(uploadID), name)
+	}
+
+	return paths, nil
+}
+
+// WriteChunk stores a chunk as a separate file named by its zero padded offset, so that the chunks sort in order.
+// A client retrying a chunk that previously failed midway writes it at the same offset, which replaces
+// whatever the failed attempt left so it does not end up in the assembled file.
+func (s *DiskStorage) WriteChunk(ctx context.Context, uploadID string, offset int64, chunk io.Reader) (written int64, err error) {
+	return s.Save(ctx, chunk, filepath.Join(s.chunkDir(uploadID), fmt.Sprintf("%020d", offset)))
+}
+
+// CompleteChunks copies the chunks of an upload, in order, into a single file at `path` and removes them.
+func (s *DiskStorage) CompleteChunks(ctx context.Context, uploadID string, path string) (size int64, err error) {
+	paths, err := s.listChunkPaths(uploadID)
+	if err != nil {
+		return 0, err
+	}
+	if len(paths) == 0 {
+		return 0, fmt.Errorf("no chunks found for upload %s", uploadID)
+	}
+
+	if err := s.ensureParent(path); err != nil {
+		return 0, err
+	}
+
+	out, err := s.root.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		out.Close()
+		if err != nil {
+			_ = s.root.Remove(path)
+		}
+	}()
+
+	for _, chunkPath := range paths {
+		chunk, err := s.root.Open(chunkPath)
+		if err != nil {
+			return 0, err
+		}
+
+		n, err := io.Copy(out, chunk)
+		chunk.Close()
+		if err != nil {
+			return 0, err
+		}
+		size += n
+	}
+
+	if err := out.Close(); err != nil {
+		return 0, err
+	}
+
+	if err := s.root.RemoveAll(s.chunkDir(uploadID)); err != nil {
+		utils.WriteServerError(s.logger, "failed to remove assembled upload chunks", err)
+	}
+
+	return size, nil
+}
+
+// AbortChunks removes all chunks stored for an upload, including the single partial file uploads used to be written to.
+func (s *DiskStorage) AbortChunks(ctx context.Context, uploadID string) error {
+	if err := s.root.RemoveAll(filepath.Join(uploadsDir, uploadID+".part")); err != nil {
+		return err
+	}
+	return s.root.RemoveAll(s.chunkDir(uploadID))
+}
+
 // Delete removes the file at the specified `path` from the DiskStorage's root directory.
 func (s *DiskStorage) Delete(ctx context.Context, paths []string) (successCount, failureCount int, err error) {
 	if len(paths) == 0 {
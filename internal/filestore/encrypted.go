@@ -0,0 +1,277 @@
+package filestore
+
+import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"path/filepath"
+
+	"github.com/google/uuid"
+)
+
+// KeyResolver supplies the data keys EncryptedStorage encrypts content with.
+type KeyResolver interface {
+	// EncryptionKey returns the data key new content is encrypted with,
+	// which belongs to the owner attached to ctx by WithOwner.
+	EncryptionKey(ctx context.Context) (keyID uuid.UUID, key []byte, err error)
+
+	// DecryptionKey returns the data key identified by keyID.
+	DecryptionKey(ctx context.Context, keyID uuid.UUID) ([]byte, error)
+}
+
+type ownerContextKey struct{}
+
+// WithOwner attaches the user content is stored for to ctx, so it is encrypted with that user's data key.
+func WithOwner(ctx context.Context, userID uuid.UUID) context.Context {
+	return context.WithValue(ctx, ownerContextKey{}, userID)
+}
+
+// OwnerFromContext returns the user attached to ctx by WithOwner.
+func OwnerFromContext(ctx context.Context) (uuid.UUID, bool) {
+	userID, ok := ctx.Value(ownerContextKey{}).(uuid.UUID)
+	return userID, ok
+}
+
+// encryptedSuffix is appended to the path encrypted content is stored under in the wrapped storage.
+// Content is only decrypted when it is found under such a path, content stored at the plain path
+// before encryption was enabled is served as it is whatever bytes it starts with.
+const encryptedSuffix = ".fse"
+
+// encryptedPath returns the path in the wrapped storage of the encrypted content at path.
+func encryptedPath(path string) string {
+	return path + encryptedSuffix
+}
+
+// EncryptedStorage is an implementation of the FileStorage interface that encrypts content
+// before handing it to the wrapped storage. Content is encrypted in chunks, so it is streamed
+// in both directions and byte ranges are served by decrypting only the chunks they cover.
+// Sizes returned by Save and CompleteChunks are those of the plaintext content.
+type EncryptedStorage struct {
+	store FileStorage
+	keys  KeyResolver
+}
+
+// NewEncryptedStorage is a constructor for EncryptedStorage.
+func NewEncryptedStorage(store FileStorage, keys KeyResolver) *EncryptedStorage {
+	return &EncryptedStorage{store: store, keys: keys}
+}
+
+// Save encrypts the content of the provided io.Reader with the owner's data key and writes it to the specified path.
+func (s *EncryptedStorage) Save(ctx context.Context, file io.Reader, path string) (size int64, err error) {
+	keyID, key, err := s.keys.EncryptionKey(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get data key: %w", err)
+	}
+
+	var salt [encryptionSaltSize]byte
+	if _, err := rand.Read(salt[:]); err != nil {
+		return 0, err
+	}
+
+	header := newStreamHeader(keyID, salt)
+	aead, err := header.aead(key)
+	if err != nil {
+		return 0, err
+	}
+
+	encrypted := newEncryptReader(file, header, aead)
+	if _, err := s.store.Save(ctx, encrypted, encryptedPath(path)); err != nil {
+		return 0, err
+	}
+
+	return encrypted.size, nil
+}
+
+// readHeader reads the header of the encrypted content at path, reporting false if the content is not encrypted.
+func (s *EncryptedStorage) readHeader(ctx context.Context, path string) (streamHeader, bool, error) {
+	body, err := s.store.GetRange(ctx, encryptedPath(path), 0, int64(encryptionHeader))
+	if errors.Is(err, fs.ErrNotExist) {
+		return streamHeader{}, false, nil
+	}
+	if err != nil {
+		return streamHeader{}, false, err
+	}
+	defer body.Close()
+
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		return streamHeader{}, false, err
+	}
+
+	header, ok := parseStreamHeader(raw)
+	if !ok {
+		return streamHeader{}, false, ErrContentCorrupted
+	}
+
+	return header, true, nil
+}
+
+// aead returns the cipher which decrypts content with the given header.
+func (s *EncryptedStorage) aead(ctx context.Context, header streamHeader) (cipher.AEAD, error) {
+	key, err := s.keys.DecryptionKey(ctx, header.keyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data key: %w", err)
+	}
+
+	return header.aead(key)
+}
+
+// Get retrieves and decrypts the content of the file at the specified path.
+// Content stored before encryption was enabled is returned as it is.
+func (s *EncryptedStorage) Get(ctx context.Context, path string) (io.ReadCloser, error) {
+	body, err := s.store.Get(ctx, encryptedPath(path))
+	if errors.Is(err, fs.ErrNotExist) {
+		return s.store.Get(ctx, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	raw := make([]byte, encryptionHeader)
+	if _, err := io.ReadFull(body, raw); err != nil {
+		body.Close()
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, ErrContentCorrupted
+		}
+		return nil, err
+	}
+
+	header, ok := parseStreamHeader(raw)
+	if !ok {
+		body.Close()
+		return nil, ErrContentCorrupted
+	}
+
+	aead, err := s.aead(ctx, header)
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+
+	return newDecryptReader(body, body, header, aead, 0, 0), nil
+}
+
+// GetRange retrieves `length` bytes of the file at the specified path, starting at offset,
+// by reading and decrypting only the chunks which hold them.
+func (s *EncryptedStorage) GetRange(ctx context.Context, path string, offset, length int64) (io.ReadCloser, error) {
+	header, ok, err := s.readHeader(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return s.store.GetRange(ctx, path, offset, length)
+	}
+	if length <= 0 {
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	}
+
+	aead, err := s.aead(ctx, header)
+	if err != nil {
+		return nil, err
+	}
+
+	first := offset / header.chunkSize
+	last := (offset + length - 1) / header.chunkSize
+	if last > math.MaxUint32 {
+		return nil, errors.New("range is beyond the end of the content")
+	}
+
+	// One byte past the last chunk is requested, its presence tells that the chunk is not the last of the content
+	start := int64(encryptionHeader) + first*header.sealedChunkSize()
+	size := (last-first+1)*header.sealedChunkSize() + 1
+
+	body, err := s.store.GetRange(ctx, encryptedPath(path), start, size)
+	if err != nil {
+		return nil, err
+	}
+
+	decrypted := newDecryptReader(body, body, header, aead, uint32(first), offset-first*header.chunkSize)
+
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(decrypted, length), decrypted}, nil
+}
+
+// Delete removes files from the wrapped storage, both the encrypted content and any content stored before
+// encryption was enabled. The counts returned cover both paths of every file.
+func (s *EncryptedStorage) Delete(ctx context.Context, paths []string) (successCount, failureCount int, err error) {
+	all := make([]string, 0, 2*len(paths))
+	for _, path := range paths {
+		all = append(all, encryptedPath(path), path)
+	}
+
+	return s.store.Delete(ctx, all)
+}
+
+// Move moves the file within the wrapped storage, encrypted content stays readable since its header names its data key.
+func (s *EncryptedStorage) Move(ctx context.Context, source, destination string) error {
+	err := s.store.Move(ctx, encryptedPath(source), encryptedPath(destination))
+	if errors.Is(err, fs.ErrNotExist) {
+		return s.store.Move(ctx, source, destination)
+	}
+	return err
+}
+
+// WriteChunk seals the chunk into a separate part of the partial upload, using a key no other part is sealed with.
+// The returned size is that of the plaintext chunk.
+func (s *EncryptedStorage) WriteChunk(ctx context.Context, uploadID string, offset int64, chunk io.Reader) (written int64, err error) {
+	keyID, key, err := s.keys.EncryptionKey(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get data key: %w", err)
+	}
+
+	sealed, err := newSealPartReader(chunk, keyID, key, uploadID, offset)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := s.store.WriteChunk(ctx, uploadID, offset, sealed); err != nil {
+		return 0, err
+	}
+
+	return sealed.size, nil
+}
+
+// CompleteChunks assembles the parts of the partial upload in the wrapped storage, then decrypts them and
+// stores the content encrypted in chunks at the specified path like any other content.
+func (s *EncryptedStorage) CompleteChunks(ctx context.Context, uploadID string, path string) (size int64, err error) {
+	assembledPath := filepath.Join(uploadsDir, uploadID+".assembled")
+	if _, err := s.store.CompleteChunks(ctx, uploadID, assembledPath); err != nil {
+		return 0, err
+	}
+	defer s.store.Delete(context.WithoutCancel(ctx), []string{assembledPath})
+
+	assembled, err := s.store.Get(ctx, assembledPath)
+	if err != nil {
+		return 0, err
+	}
+	defer assembled.Close()
+
+	parts := newOpenPartsReader(assembled, uploadID, func(keyID uuid.UUID) ([]byte, error) {
+		key, err := s.keys.DecryptionKey(ctx, keyID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get data key: %w", err)
+		}
+		return key, nil
+	})
+
+	size, err = s.Save(ctx, parts, path)
+	if err != nil {
+		_, _, _ = s.store.Delete(context.WithoutCancel(ctx), []string{encryptedPath(path)})
+		return 0, err
+	}
+
+	return size, nil
+}
+
+// AbortChunks removes any partial upload data stored for uploadID.
+func (s *EncryptedStorage) AbortChunks(ctx context.Context, uploadID string) error {
+	return s.store.AbortChunks(ctx, uploadID)
+}
@@ -2,8 +2,12 @@ package filestore
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"path"
+	"slices"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -67,6 +71,16 @@ func (s *S3Storage) Save(ctx context.Context, file io.Reader, path string) (int6
 	return *object.ContentLength, nil
 }
 
+// notFound marks the error of a request for an object which does not exist with fs.ErrNotExist,
+// so missing objects are reported the same way as missing files of DiskStorage.
+func notFound(err error) error {
+	var noSuchKey *types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return fmt.Errorf("%w: %w", fs.ErrNotExist, err)
+	}
+	return err
+}
+
 // Get retrieves a file stream from S3
 func (s *S3Storage) Get(ctx context.Context, path string) (io.ReadCloser, error) {
 	output, err := s.client.GetObject(ctx, &s3.GetObjectInput{
@@ -74,7 +88,21 @@ func (s *S3Storage) Get(ctx context.Context, path string) (io.ReadCloser, error)
 		Key:    aws.String(path),
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get file from s3: %w", err)
+		return nil, fmt.Errorf("failed to get file from s3: %w", notFound(err))
+	}
+
+	return output.Body, nil
+}
+
+// GetRange retrieves part of a file from S3 using a Range request
+func (s *S3Storage) GetRange(ctx context.Context, path string, offset, length int64) (io.ReadCloser, error) {
+	output, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(path),
+		Range:  aws.String(fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file range from s3: %w", notFound(err))
 	}
 
 	return output.Body, nil
@@ -82,21 +110,135 @@ func (s *S3Storage) Get(ctx context.Context, path string) (io.ReadCloser, error)
 
 // Delete removes files from storage
 func (s *S3Storage) Delete(ctx context.Context, paths []string) (successCount, failureCount int, err error) {
-	var objectIds []types.ObjectIdentifier
-	for _, path := range paths {
-		objectIds = append(objectIds, types.ObjectIdentifier{Key: aws.String(path)})
+	// DeleteObjects accepts at most 1000 keys per request
+	for batch := range slices.Chunk(paths, 1000) {
+		var objectIds []types.ObjectIdentifier
+		for _, path := range batch {
+			objectIds = append(objectIds, types.ObjectIdentifier{Key: aws.String(path)})
+		}
+
+		output, err := s.client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(s.bucketName),
+			Delete: &types.Delete{Objects: objectIds, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return successCount, failureCount, err
+		}
+
+		successCount += len(output.Deleted)
+		failureCount += len(output.Errors)
+	}
+
+	return successCount, failureCount, nil
+}
+
+// Move copies the object at `source` to `destination` and deletes the original.
+// The content is streamed through the server, which unlike CopyObject is not limited to objects of 5 GB.
+func (s *S3Storage) Move(ctx context.Context, source, destination string) error {
+	content, err := s.Get(ctx, source)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	if _, err := s.Save(ctx, content, destination); err != nil {
+		return err
 	}
 
-	output, err := s.client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+	_, failed, err := s.Delete(ctx, []string{source})
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to delete %s after copying it", source)
+	}
+
+	return nil
+}
+
+// chunkPrefix returns the key prefix under which the chunks of an upload are stored.
+func (s *S3Storage) chunkPrefix(uploadID string) string {
+	return path.Join(uploadsDir, uploadID) + "/"
+}
+
+// listChunkKeys returns the keys of all chunks stored for an upload, ordered by offset.
+func (s *S3Storage) listChunkKeys(ctx context.Context, uploadID string) ([]string, error) {
+	var keys []string
+
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
 		Bucket: aws.String(s.bucketName),
-		Delete: &types.Delete{Objects: objectIds, Quiet: aws.Bool(true)},
+		Prefix: aws.String(s.chunkPrefix(uploadID)),
 	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list upload chunks: %w", err)
+		}
+		for _, object := range page.Contents {
+			keys = append(keys, aws.ToString(object.Key))
+		}
+	}
+
+	return keys, nil
+}
+
+// WriteChunk stores a chunk as a separate object keyed by its zero padded offset,
+// so that listing the upload's chunks returns them in order.
+func (s *S3Storage) WriteChunk(ctx context.Context, uploadID string, offset int64, chunk io.Reader) (written int64, err error) {
+	key := fmt.Sprintf("%s%020d", s.chunkPrefix(uploadID), offset)
+	return s.Save(ctx, chunk, key)
+}
+
+// CompleteChunks streams the chunks of an upload, in order, into a single object at `path`.
+func (s *S3Storage) CompleteChunks(ctx context.Context, uploadID string, path string) (size int64, err error) {
+	keys, err := s.listChunkKeys(ctx, uploadID)
 	if err != nil {
-		return 0, 0, err
+		return 0, err
+	}
+	if len(keys) == 0 {
+		return 0, fmt.Errorf("no chunks found for upload %s", uploadID)
 	}
 
-	successCount = len(output.Deleted)
-	failureCount = len(output.Errors)
+	pr, pw := io.Pipe()
+	go func() {
+		for _, key := range keys {
+			chunk, err := s.Get(ctx, key)
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+
+			_, err = io.Copy(pw, chunk)
+			chunk.Close()
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+		pw.Close()
+	}()
 
-	return successCount, failureCount, nil
+	size, err = s.Save(ctx, pr, path)
+	pr.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	_, _, _ = s.Delete(ctx, keys)
+
+	return size, nil
+}
+
+// AbortChunks removes all chunks stored for an upload.
+func (s *S3Storage) AbortChunks(ctx context.Context, uploadID string) error {
+	keys, err := s.listChunkKeys(ctx, uploadID)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	_, _, err = s.Delete(ctx, keys)
+	return err
 }
@@ -0,0 +1,125 @@
+package filestore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+
+	"github.com/i-christian/fileShare/internal/utils"
+)
+
+// ErrContentMissing is returned when a file's content can not be read from storage.
+var ErrContentMissing = errors.New("file content missing")
+
+// rangeReader implements io.ReadSeekCloser on top of FileStorage.GetRange,
+// which allows partial content to be served from storage backends that are not seekable.
+type rangeReader struct {
+	ctx    context.Context
+	store  FileStorage
+	logger *slog.Logger
+	body   io.ReadCloser
+	path   string
+	size   int64
+	offset int64
+	// bodyOffset is the position in the file body reads from next.
+	bodyOffset int64
+}
+
+// NewRangeReader returns a seekable reader for the `size` bytes long file stored at path.
+// The content is opened straight away, so a missing file is reported with ErrContentMissing before any
+// response is written. It is only requested again when the reader is positioned elsewhere, so seeking to
+// the end to determine the size and back to the start, as http.ServeContent does, is free.
+func NewRangeReader(ctx context.Context, store FileStorage, path string, size int64, logger *slog.Logger) (io.ReadSeekCloser, error) {
+	r := &rangeReader{
+		ctx:    ctx,
+		store:  store,
+		logger: logger,
+		path:   path,
+		size:   size,
+	}
+
+	if size > 0 {
+		if err := r.open(); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// open requests the content from the current offset to the end of the file.
+func (r *rangeReader) open() error {
+	if r.body != nil {
+		r.body.Close()
+		r.body = nil
+	}
+
+	body, err := r.store.GetRange(r.ctx, r.path, r.offset, r.size-r.offset)
+	if err != nil {
+		utils.WriteServerError(r.logger, "file found in database but missing in storage key="+r.path, err)
+		return ErrContentMissing
+	}
+
+	r.body = body
+	r.bodyOffset = r.offset
+	return nil
+}
+
+func (r *rangeReader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+
+	if r.body == nil || r.bodyOffset != r.offset {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+	r.bodyOffset += int64(n)
+
+	return n, err
+}
+
+// Seek moves the read position, opening the content at the new position unless it is the end of the file.
+// Opening eagerly lets a missing file be reported before any response headers are written.
+func (r *rangeReader) Seek(offset int64, whence int) (int64, error) {
+	var position int64
+	switch whence {
+	case io.SeekStart:
+		position = offset
+	case io.SeekCurrent:
+		position = r.offset + offset
+	case io.SeekEnd:
+		position = r.size + offset
+	default:
+		return 0, errors.New("invalid whence")
+	}
+
+	if position < 0 {
+		return 0, errors.New("negative position")
+	}
+	r.offset = position
+
+	if whence != io.SeekEnd && position < r.size && (r.body == nil || r.bodyOffset != position) {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	return position, nil
+}
+
+func (r *rangeReader) Close() error {
+	if r.body == nil {
+		return nil
+	}
+
+	err := r.body.Close()
+	r.body = nil
+
+	return err
+}
@@ -30,10 +30,30 @@ type FileStorage interface {
 	// It returns an io.Reader from which the file's content can be read.
 	Get(ctx context.Context, path string) (io.ReadCloser, error)
 
+	// GetRange retrieves `length` bytes of the file at the specified path, starting at offset.
+	GetRange(ctx context.Context, path string, offset, length int64) (io.ReadCloser, error)
+
 	// Delete removes files from storage.
 	Delete(ctx context.Context, paths []string) (successCount, failureCount int, err error)
+
+	// Move moves the file at the source path to the destination path.
+	Move(ctx context.Context, source, destination string) error
+
+	// WriteChunk writes the content of the provided io.Reader to the partial upload
+	// identified by uploadID, starting at offset. It returns the number of bytes written.
+	WriteChunk(ctx context.Context, uploadID string, offset int64, chunk io.Reader) (written int64, err error)
+
+	// CompleteChunks assembles all chunks written for uploadID into a single file at the specified path
+	// and removes the partial upload data.
+	CompleteChunks(ctx context.Context, uploadID string, path string) (size int64, err error)
+
+	// AbortChunks removes any partial upload data stored for uploadID.
+	AbortChunks(ctx context.Context, uploadID string) error
 }
 
+// uploadsDir is the storage prefix under which partial upload data is kept until it is assembled.
+const uploadsDir = "uploads"
+
 // SetUpFileStorage initializes the storage provider based on env config
 func SetUpFileStorage(logger *slog.Logger) FileStorage {
 	storageType := StorageType(utils.GetEnvOrFile("STORAGE_TYPE"))
@@ -71,6 +91,7 @@ func SetUpFileStorage(logger *slog.Logger) FileStorage {
 
 		subDirsToCreate := []string{
 			"users",
+			uploadsDir,
 		}
 
 		for _, subDir := range subDirsToCreate {
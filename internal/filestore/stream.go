@@ -0,0 +1,502 @@
+package filestore
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+
+	"github.com/google/uuid"
+)
+
+// Encrypted content starts with a header followed by the content split into chunks, each sealed with AES-GCM.
+// Every chunk except the last holds chunkSize bytes of plaintext, so the chunk holding any offset is found
+// without reading the chunks before it. The header and a flag marking the last chunk are authenticated
+// with every chunk, which stops chunks from being reordered, mixed between files or the content being truncated.
+//
+//	header: magic (8 bytes) | data key ID (16 bytes) | chunk size (4 bytes) | salt (16 bytes)
+//	chunk:  ciphertext (up to chunk size bytes) | GCM tag (16 bytes)
+const (
+	// encryptionMagic starts the header of encrypted content. Whether content is encrypted is recorded by its
+	// path in storage, content stored before encryption was enabled may start with these bytes as well.
+	encryptionMagic = "\x89FSE\r\n\x1a\n"
+
+	encryptedChunkSize = 64 * 1024
+	maxChunkSize       = 16 * 1024 * 1024
+	encryptionSaltSize = 16
+	encryptionHeader   = len(encryptionMagic) + 16 + 4 + encryptionSaltSize
+	gcmTagSize         = 16
+)
+
+var (
+	// ErrContentCorrupted is returned when encrypted content fails authentication.
+	ErrContentCorrupted = errors.New("encrypted content is corrupted")
+
+	errContentTooLarge = errors.New("content is too large to be encrypted")
+)
+
+// streamHeader describes how a file's content was encrypted.
+type streamHeader struct {
+	keyID     uuid.UUID
+	chunkSize int64
+	salt      [encryptionSaltSize]byte
+	raw       []byte
+}
+
+// newStreamHeader creates the header for content encrypted with the data key keyID.
+func newStreamHeader(keyID uuid.UUID, salt [encryptionSaltSize]byte) streamHeader {
+	raw := make([]byte, 0, encryptionHeader)
+	raw = append(raw, encryptionMagic...)
+	raw = append(raw, keyID[:]...)
+	raw = binary.BigEndian.AppendUint32(raw, encryptedChunkSize)
+	raw = append(raw, salt[:]...)
+
+	return streamHeader{keyID: keyID, chunkSize: encryptedChunkSize, salt: salt, raw: raw}
+}
+
+// parseStreamHeader reads the header at the start of encrypted content, reporting false if it is malformed.
+func parseStreamHeader(raw []byte) (streamHeader, bool) {
+	if len(raw) < encryptionHeader || !bytes.HasPrefix(raw, []byte(encryptionMagic)) {
+		return streamHeader{}, false
+	}
+
+	raw = raw[:encryptionHeader:encryptionHeader]
+	h := streamHeader{raw: bytes.Clone(raw)}
+	rest := raw[len(encryptionMagic):]
+	copy(h.keyID[:], rest[:16])
+	h.chunkSize = int64(binary.BigEndian.Uint32(rest[16:20]))
+	copy(h.salt[:], rest[20:])
+
+	return h, h.chunkSize > 0 && h.chunkSize <= maxChunkSize
+}
+
+// sealedChunkSize is the stored size of a full chunk.
+func (h streamHeader) sealedChunkSize() int64 {
+	return h.chunkSize + gcmTagSize
+}
+
+// aead derives the key of this file's content from the data key and returns the cipher sealing its chunks.
+// Deriving a key per file keeps the GCM nonces, which are chunk indices, unique for each key.
+func (h streamHeader) aead(dataKey []byte) (cipher.AEAD, error) {
+	mac := hmac.New(sha256.New, dataKey)
+	mac.Write([]byte("content:"))
+	mac.Write(h.salt[:])
+
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// nonce returns the GCM nonce of chunk index.
+func (h streamHeader) nonce(index uint32) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint32(nonce[8:], index)
+	return nonce
+}
+
+// additionalData returns the data authenticated along with a chunk.
+func (h streamHeader) additionalData(last bool) []byte {
+	ad := append(bytes.Clone(h.raw), 0)
+	if last {
+		ad[len(ad)-1] = 1
+	}
+	return ad
+}
+
+// encryptReader encrypts the content read from src, producing the header followed by the sealed chunks.
+type encryptReader struct {
+	src    *bufio.Reader
+	header streamHeader
+	aead   cipher.AEAD
+	plain  []byte
+	sealed []byte
+	out    []byte
+	index  uint32
+	done   bool
+	// size is the number of plaintext bytes encrypted so far.
+	size int64
+}
+
+func newEncryptReader(src io.Reader, header streamHeader, aead cipher.AEAD) *encryptReader {
+	return &encryptReader{
+		src:    bufio.NewReaderSize(src, int(header.chunkSize)),
+		header: header,
+		aead:   aead,
+		plain:  make([]byte, header.chunkSize),
+		sealed: make([]byte, 0, header.sealedChunkSize()),
+		out:    header.raw,
+	}
+}
+
+func (r *encryptReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.sealNext(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+
+	return n, nil
+}
+
+// sealNext encrypts the next chunk. A chunk is the last one when no content follows it,
+// so empty content is stored as a single empty chunk.
+func (r *encryptReader) sealNext() error {
+	n, err := io.ReadFull(r.src, r.plain)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return err
+	}
+
+	last := n < len(r.plain)
+	if !last {
+		if _, err := r.src.Peek(1); errors.Is(err, io.EOF) {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	}
+
+	if !last && r.index == math.MaxUint32 {
+		return errContentTooLarge
+	}
+
+	r.out = r.aead.Seal(r.sealed[:0], r.header.nonce(r.index), r.plain[:n], r.header.additionalData(last))
+	r.size += int64(n)
+	r.index++
+	r.done = last
+
+	return nil
+}
+
+// decryptReader decrypts sealed chunks read from src, starting with chunk index.
+// The first `skip` bytes of plaintext are discarded, so reading can start within a chunk.
+type decryptReader struct {
+	src    *bufio.Reader
+	body   io.Closer
+	header streamHeader
+	aead   cipher.AEAD
+	sealed []byte
+	out    []byte
+	index  uint32
+	skip   int64
+	done   bool
+}
+
+func newDecryptReader(src io.Reader, body io.Closer, header streamHeader, aead cipher.AEAD, index uint32, skip int64) *decryptReader {
+	return &decryptReader{
+		src:    bufio.NewReaderSize(src, int(header.sealedChunkSize())),
+		body:   body,
+		header: header,
+		aead:   aead,
+		sealed: make([]byte, header.sealedChunkSize()),
+		index:  index,
+		skip:   skip,
+	}
+}
+
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.openNext(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+
+	return n, nil
+}
+
+// openNext decrypts the next chunk, which is the last one when no content follows it.
+// Running out of content before the last chunk means the stored content was truncated.
+func (r *decryptReader) openNext() error {
+	n, err := io.ReadFull(r.src, r.sealed)
+	switch {
+	case errors.Is(err, io.EOF):
+		return ErrContentCorrupted
+	case err != nil && !errors.Is(err, io.ErrUnexpectedEOF):
+		return err
+	}
+
+	last := n < len(r.sealed)
+	if !last {
+		if _, err := r.src.Peek(1); errors.Is(err, io.EOF) {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	}
+
+	plain, err := r.aead.Open(r.sealed[:0], r.header.nonce(r.index), r.sealed[:n], r.header.additionalData(last))
+	if err != nil {
+		return ErrContentCorrupted
+	}
+
+	skip := min(r.skip, int64(len(plain)))
+	r.out = plain[skip:]
+	r.skip -= skip
+	r.index++
+	r.done = last
+
+	return nil
+}
+
+func (r *decryptReader) Close() error {
+	return r.body.Close()
+}
+
+// The partial content of an upload is written in parts, one for every chunk the client sends, which are only stored
+// until the upload completes and are then encrypted like any other content. Each part is sealed with a key derived
+// from a fresh random salt, so a chunk which is sent again after failing midway is never encrypted like before.
+// Parts are split into frames which carry their length, so the parts assembled in order by the wrapped storage can be
+// told apart again. Every frame is authenticated along with the offset of its part and a flag marking the last frame
+// of the part, which stops parts from being reordered, truncated or taken from another upload.
+//
+//	part:  data key ID (16 bytes) | salt (16 bytes) | frames
+//	frame: length and last frame flag (4 bytes) | ciphertext (up to encryptedChunkSize bytes) | GCM tag (16 bytes)
+const (
+	uploadPartHeader = 16 + encryptionSaltSize
+	lastFrameFlag    = 1 << 31
+)
+
+// uploadPartAEAD derives the key of an upload part from the data key and returns the cipher sealing its frames.
+func uploadPartAEAD(dataKey []byte, uploadID string, salt []byte) (cipher.AEAD, error) {
+	mac := hmac.New(sha256.New, dataKey)
+	mac.Write([]byte("upload:" + uploadID + ":"))
+	mac.Write(salt)
+
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// frameNonce returns the GCM nonce of frame index of a part.
+func frameNonce(index uint32) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint32(nonce[8:], index)
+	return nonce
+}
+
+// frameAdditionalData returns the data authenticated along with a frame of the part written at offset.
+func frameAdditionalData(offset int64, length []byte) []byte {
+	ad := binary.BigEndian.AppendUint64(nil, uint64(offset))
+	return append(ad, length...)
+}
+
+// sealPartReader encrypts the content of a chunk read from src into an upload part written at offset.
+type sealPartReader struct {
+	src    *bufio.Reader
+	aead   cipher.AEAD
+	offset int64
+	plain  []byte
+	sealed []byte
+	out    []byte
+	index  uint32
+	done   bool
+	// size is the number of plaintext bytes encrypted so far.
+	size int64
+}
+
+// newSealPartReader seals the chunk read from src with a key derived from the data key keyID and a random salt.
+func newSealPartReader(src io.Reader, keyID uuid.UUID, dataKey []byte, uploadID string, offset int64) (*sealPartReader, error) {
+	header := make([]byte, uploadPartHeader)
+	copy(header, keyID[:])
+	if _, err := rand.Read(header[16:]); err != nil {
+		return nil, err
+	}
+
+	aead, err := uploadPartAEAD(dataKey, uploadID, header[16:])
+	if err != nil {
+		return nil, err
+	}
+
+	return &sealPartReader{
+		src:    bufio.NewReaderSize(src, encryptedChunkSize),
+		aead:   aead,
+		offset: offset,
+		plain:  make([]byte, encryptedChunkSize),
+		sealed: make([]byte, 0, 4+encryptedChunkSize+gcmTagSize),
+		out:    header,
+	}, nil
+}
+
+func (r *sealPartReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.sealNext(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+
+	return n, nil
+}
+
+// sealNext encrypts the next frame, which is the last one of the part when no content follows it.
+func (r *sealPartReader) sealNext() error {
+	n, err := io.ReadFull(r.src, r.plain)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return err
+	}
+
+	last := n < len(r.plain)
+	if !last {
+		if _, err := r.src.Peek(1); errors.Is(err, io.EOF) {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	}
+
+	if !last && r.index == math.MaxUint32 {
+		return errContentTooLarge
+	}
+
+	length := uint32(n)
+	if last {
+		length |= lastFrameFlag
+	}
+	frame := binary.BigEndian.AppendUint32(r.sealed[:0], length)
+	r.out = r.aead.Seal(frame, frameNonce(r.index), r.plain[:n], frameAdditionalData(r.offset, frame))
+	r.size += int64(n)
+	r.index++
+	r.done = last
+
+	return nil
+}
+
+// openPartsReader decrypts the parts of an upload assembled in order, read from src.
+type openPartsReader struct {
+	src      *bufio.Reader
+	uploadID string
+	// dataKey returns the data key identified by the ID in the header of a part.
+	dataKey func(keyID uuid.UUID) ([]byte, error)
+	// aead opens the frames of the current part, it is nil between parts.
+	aead   cipher.AEAD
+	offset int64
+	index  uint32
+	sealed []byte
+	out    []byte
+	// size is the number of plaintext bytes decrypted so far, which is the offset of the next part.
+	size int64
+}
+
+func newOpenPartsReader(src io.Reader, uploadID string, dataKey func(keyID uuid.UUID) ([]byte, error)) *openPartsReader {
+	return &openPartsReader{
+		src:      bufio.NewReaderSize(src, 4+encryptedChunkSize+gcmTagSize),
+		uploadID: uploadID,
+		dataKey:  dataKey,
+		sealed:   make([]byte, 4+encryptedChunkSize+gcmTagSize),
+	}
+}
+
+func (r *openPartsReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.aead == nil {
+			if err := r.openPart(); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		if err := r.openFrame(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+
+	return n, nil
+}
+
+// openPart reads the header of the next part, the content ends when no part follows.
+func (r *openPartsReader) openPart() error {
+	var header [uploadPartHeader]byte
+	_, err := io.ReadFull(r.src, header[:])
+	switch {
+	case errors.Is(err, io.EOF):
+		return io.EOF
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return ErrContentCorrupted
+	case err != nil:
+		return err
+	}
+
+	key, err := r.dataKey(uuid.UUID(header[:16]))
+	if err != nil {
+		return err
+	}
+
+	r.aead, err = uploadPartAEAD(key, r.uploadID, header[16:])
+	if err != nil {
+		return err
+	}
+	r.offset = r.size
+	r.index = 0
+
+	return nil
+}
+
+// openFrame decrypts the next frame of the current part.
+// Running out of content before the last frame of a part means the part was truncated.
+func (r *openPartsReader) openFrame() error {
+	if _, err := io.ReadFull(r.src, r.sealed[:4]); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return ErrContentCorrupted
+		}
+		return err
+	}
+
+	length := binary.BigEndian.Uint32(r.sealed[:4])
+	n := int(length &^ lastFrameFlag)
+	if n > encryptedChunkSize {
+		return ErrContentCorrupted
+	}
+
+	frame := r.sealed[:4+n+gcmTagSize]
+	if _, err := io.ReadFull(r.src, frame[4:]); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return ErrContentCorrupted
+		}
+		return err
+	}
+
+	plain, err := r.aead.Open(frame[4:4], frameNonce(r.index), frame[4:], frameAdditionalData(r.offset, frame[:4]))
+	if err != nil {
+		return ErrContentCorrupted
+	}
+
+	r.out = plain
+	r.size += int64(len(plain))
+	r.index++
+	if length&lastFrameFlag != 0 {
+		r.aead = nil
+	}
+
+	return nil
+}
@@ -56,7 +56,8 @@ func AuthMiddleware(authService *auth.AuthService, apiKeyService *auth.APIKeySer
 				next.ServeHTTP(w, req)
 
 			case "ApiKey":
-				user, err := apiKeyService.ValidateAPIKey(r.Context(), credential)
+				ip, _ := security.GetIPAddress(r)
+				user, err := apiKeyService.ValidateAPIKey(r.Context(), credential, ip)
 				if err != nil {
 					if errors.Is(err, utils.ErrUnexpectedError) {
 						utils.UnauthorisedResponse(w, utils.ErrUnexpectedError.Error())
@@ -104,6 +105,37 @@ func RequireActivatedUser(next http.Handler) http.Handler {
 	return fn
 }
 
+// RequireAdmin only lets activated users with the admin role through
+func RequireAdmin(next http.Handler) http.Handler {
+	fn := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _ := security.GetUserFromContext(r)
+		if user.Role != "admin" {
+			utils.NotPermittedResponse(w)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+
+	return RequireActivatedUser(fn)
+}
+
+// RequireScope only lets authenticated requests through whose credentials were granted the scope,
+// anonymous requests are left to the rest of the middlewares and handlers of the route
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := security.GetUserFromContext(r)
+			if ok && !user.IsAnonymous() && !user.HasScope(scope) {
+				utils.MissingScopeResponse(w, scope)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // Limiter is a config struct for rate limit middleware
 type Limiter struct {
 	Rps     float64
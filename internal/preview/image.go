@@ -0,0 +1,16 @@
+package preview
+
+import (
+	"context"
+	"image"
+	"io"
+
+	"github.com/disintegration/imaging"
+	_ "golang.org/x/image/webp"
+)
+
+// imagePreview decodes an image in any of the formats supported by the imaging package, or WebP.
+// Images are rotated and flipped as their EXIF orientation says, so previews are shown upright.
+func imagePreview(ctx context.Context, content io.Reader) (image.Image, error) {
+	return imaging.Decode(content, imaging.AutoOrientation(true))
+}
@@ -0,0 +1,97 @@
+package preview
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maxPDFSize is the largest document a preview is rendered for, documents are written to a temporary file.
+const maxPDFSize = 100 << 20
+
+// renderSize bounds the longest side of the images rendered by external tools, in pixels.
+const renderSize = 1024
+
+var errTooLarge = errors.New("content is too large to preview")
+
+// PDFPreviewer is an implementation of the Previewer interface which rasterizes the first page of PDF documents
+// with pdftoppm, see https://poppler.freedesktop.org
+type PDFPreviewer struct {
+	path    string
+	timeout time.Duration
+}
+
+// NewPDFPreviewer is a constructor for PDFPreviewer.
+// path is the pdftoppm executable, timeout bounds rendering a single document.
+func NewPDFPreviewer(path string, timeout time.Duration) *PDFPreviewer {
+	return &PDFPreviewer{path: path, timeout: timeout}
+}
+
+// Preview renders the first page of the document scaled to fit in a square of renderSize pixels.
+func (p *PDFPreviewer) Preview(ctx context.Context, content io.Reader) (image.Image, error) {
+	dir, err := os.MkdirTemp("", "pdf-preview-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	document := filepath.Join(dir, "document.pdf")
+	if err := writeLimited(document, content, maxPDFSize); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	// -singlefile writes the page to <root>.png instead of numbering it
+	root := filepath.Join(dir, "page")
+	cmd := exec.CommandContext(ctx, p.path,
+		"-f", "1", "-l", "1", "-singlefile", "-png",
+		"-scale-to", fmt.Sprint(renderSize),
+		document, root,
+	)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("pdftoppm failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	page, err := os.Open(root + ".png")
+	if err != nil {
+		return nil, err
+	}
+	defer page.Close()
+
+	return png.Decode(page)
+}
+
+// writeLimited writes the content to a new file, failing with errTooLarge when it is larger than limit bytes.
+func writeLimited(path string, content io.Reader, limit int64) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	n, err := io.Copy(f, io.LimitReader(content, limit+1))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if n > limit {
+		return errTooLarge
+	}
+
+	return nil
+}
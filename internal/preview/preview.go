@@ -0,0 +1,120 @@
+// Package preview renders preview images of file content, which thumbnails are generated from.
+// Previewers are registered by MIME type, so support for new formats is added by registering a previewer.
+package preview
+
+import (
+	"context"
+	"image"
+	"io"
+	"log/slog"
+	"mime"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Previewer defines the interface for renderers of preview images.
+type Previewer interface {
+	// Preview reads the content from the provided io.Reader and renders an image of it.
+	Preview(ctx context.Context, content io.Reader) (image.Image, error)
+}
+
+// PreviewerFunc adapts a function to the Previewer interface.
+type PreviewerFunc func(ctx context.Context, content io.Reader) (image.Image, error)
+
+func (f PreviewerFunc) Preview(ctx context.Context, content io.Reader) (image.Image, error) {
+	return f(ctx, content)
+}
+
+// genericTypes are MIME types content sniffing settles on for many text based formats, e.g. SVG images are
+// detected as text/xml. The type of the file extension is preferred for such content.
+var genericTypes = map[string]bool{
+	"text/plain":               true,
+	"text/xml":                 true,
+	"application/octet-stream": true,
+}
+
+// Registry maps MIME types to the previewers which render them.
+// A type is either exact, such as application/pdf, or a wildcard matching a whole family, such as image/*.
+type Registry struct {
+	previewers map[string]Previewer
+}
+
+// NewRegistry is a constructor for an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{previewers: make(map[string]Previewer)}
+}
+
+// Register sets the previewer for a MIME type, replacing any previewer registered for it before.
+func (r *Registry) Register(mimeType string, previewer Previewer) {
+	r.previewers[strings.ToLower(mimeType)] = previewer
+}
+
+// Lookup returns the previewer for a file with the detected MIME type and filename, or nil when there is none.
+// Exact types take precedence over wildcards.
+func (r *Registry) Lookup(mimeType, filename string) Previewer {
+	mimeType = baseType(mimeType)
+
+	candidates := []string{mimeType}
+	if genericTypes[mimeType] {
+		if extType := baseType(mime.TypeByExtension(filepath.Ext(filename))); extType != "" {
+			candidates = []string{extType, mimeType}
+		}
+	}
+
+	for _, candidate := range candidates {
+		if previewer, ok := r.previewers[candidate]; ok {
+			return previewer
+		}
+	}
+
+	for _, candidate := range candidates {
+		family, _, _ := strings.Cut(candidate, "/")
+		if previewer, ok := r.previewers[family+"/*"]; ok {
+			return previewer
+		}
+	}
+
+	return nil
+}
+
+// Supports reports whether a preview can be rendered for a file with the detected MIME type and filename.
+func (r *Registry) Supports(mimeType, filename string) bool {
+	return r.Lookup(mimeType, filename) != nil
+}
+
+// baseType strips the parameters from a MIME type, such as the charset of text.
+func baseType(mimeType string) string {
+	mimeType, _, _ = strings.Cut(mimeType, ";")
+	return strings.ToLower(strings.TrimSpace(mimeType))
+}
+
+// SetUpRegistry registers the built in previewers.
+// PDF documents and SVG images are rendered by external tools, their previewers are only registered
+// when pdftoppm from poppler-utils and rsvg-convert from librsvg are installed.
+func SetUpRegistry(logger *slog.Logger) *Registry {
+	registry := NewRegistry()
+
+	registry.Register("image/*", PreviewerFunc(imagePreview))
+
+	for _, mimeType := range []string{"text/*", "application/json", "application/xml", "application/yaml", "application/toml"} {
+		registry.Register(mimeType, PreviewerFunc(textPreview))
+	}
+
+	if path, err := exec.LookPath("pdftoppm"); err == nil {
+		registry.Register("application/pdf", NewPDFPreviewer(path, time.Minute))
+	} else {
+		logger.Warn("pdftoppm is not installed, PDF documents get no previews")
+	}
+
+	if path, err := exec.LookPath("rsvg-convert"); err == nil {
+		registry.Register("image/svg+xml", NewSVGPreviewer(path, 30*time.Second))
+	} else {
+		// image/* would otherwise match, which only decodes raster images
+		registry.Register("image/svg+xml", PreviewerFunc(textPreview))
+		logger.Warn("rsvg-convert is not installed, SVG images are previewed as text")
+	}
+
+	return registry
+}
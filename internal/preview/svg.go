@@ -0,0 +1,227 @@
+package preview
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// maxSVGSize is the largest SVG image a preview is rendered for.
+const maxSVGSize = 5 << 20
+
+// droppedElements are SVG elements removed along with their content, as they run scripts or embed other documents.
+var droppedElements = map[string]bool{
+	"script":        true,
+	"foreignobject": true,
+	"iframe":        true,
+	"object":        true,
+	"embed":         true,
+	"audio":         true,
+	"video":         true,
+}
+
+var (
+	// cssURL matches url() references in style sheets and style attributes, capturing their target.
+	cssURL = regexp.MustCompile(`(?i)url\(\s*['"]?\s*([^'")\s]*)`)
+	// safeDataURL matches images embedded as data URLs, which can not reference anything else.
+	safeDataURL = regexp.MustCompile(`(?i)^data:image/(png|jpeg|gif|webp);`)
+)
+
+// SVGPreviewer is an implementation of the Previewer interface which rasterizes sanitized SVG images
+// with rsvg-convert, see https://gitlab.gnome.org/GNOME/librsvg
+type SVGPreviewer struct {
+	path    string
+	timeout time.Duration
+}
+
+// NewSVGPreviewer is a constructor for SVGPreviewer.
+// path is the rsvg-convert executable, timeout bounds rendering a single image.
+func NewSVGPreviewer(path string, timeout time.Duration) *SVGPreviewer {
+	return &SVGPreviewer{path: path, timeout: timeout}
+}
+
+// Preview sanitizes the image and renders it renderSize pixels wide.
+func (p *SVGPreviewer) Preview(ctx context.Context, content io.Reader) (image.Image, error) {
+	data, err := io.ReadAll(io.LimitReader(content, maxSVGSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxSVGSize {
+		return nil, errTooLarge
+	}
+
+	sanitized, err := sanitizeSVG(data)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, p.path, "--format", "png", "--width", fmt.Sprint(renderSize), "--keep-aspect-ratio")
+	cmd.Stdin = bytes.NewReader(sanitized)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("rsvg-convert failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return png.Decode(&stdout)
+}
+
+// sanitizeSVG rewrites an SVG image without anything that could run code or make the renderer read other
+// files or URLs: scripts and embedded documents, event handler attributes, processing instructions, DTDs
+// and references other than fragments of the image itself and embedded raster images.
+func sanitizeSVG(data []byte) ([]byte, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = true
+
+	var out bytes.Buffer
+	// skipDepth is the nesting depth within a dropped element, 0 when outside of one
+	skipDepth := 0
+	hasRoot := false
+
+	for {
+		tok, err := decoder.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid SVG image: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if skipDepth > 0 || droppedElements[strings.ToLower(t.Name.Local)] {
+				skipDepth++
+				continue
+			}
+			// A style sheet importing or referencing external resources is dropped as a whole
+			if strings.EqualFold(t.Name.Local, "style") {
+				if css, ok := styleText(decoder); ok && safeStyle(css) {
+					writeStartElement(&out, t)
+					xml.EscapeText(&out, []byte(css))
+					out.WriteString("</" + qualifiedName(t.Name) + ">")
+				}
+				continue
+			}
+			if t.Name.Local == "svg" {
+				hasRoot = true
+			}
+			writeStartElement(&out, t)
+
+		case xml.EndElement:
+			if skipDepth > 0 {
+				skipDepth--
+				continue
+			}
+			out.WriteString("</" + qualifiedName(t.Name) + ">")
+
+		case xml.CharData:
+			if skipDepth == 0 {
+				xml.EscapeText(&out, t)
+			}
+		}
+	}
+
+	if !hasRoot {
+		return nil, errors.New("invalid SVG image: missing svg element")
+	}
+
+	return out.Bytes(), nil
+}
+
+// styleText reads the content of a style element up to and including its own end element.
+// A style element holding other elements is not a plain style sheet, false is returned for it.
+func styleText(decoder *xml.Decoder) (string, bool) {
+	var text strings.Builder
+	// depth is the nesting depth of elements within the style element
+	depth := 0
+	plain := true
+	for {
+		tok, err := decoder.RawToken()
+		if err != nil {
+			return "", false
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			if depth == 0 {
+				text.Write(t)
+			}
+		case xml.StartElement:
+			depth++
+			plain = false
+		case xml.EndElement:
+			if depth == 0 {
+				return text.String(), plain
+			}
+			depth--
+		}
+	}
+}
+
+// safeStyle reports whether CSS only references fragments of the image itself and embedded raster images.
+func safeStyle(css string) bool {
+	if strings.Contains(strings.ToLower(css), "@import") {
+		return false
+	}
+
+	for _, match := range cssURL.FindAllStringSubmatch(css, -1) {
+		if !safeReference(match[1]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// safeReference reports whether a link target is a fragment of the image itself or an embedded raster image.
+func safeReference(target string) bool {
+	target = strings.TrimSpace(target)
+	return strings.HasPrefix(target, "#") || safeDataURL.MatchString(target)
+}
+
+// writeStartElement writes a start element without event handlers, unsafe references and unsafe styles.
+func writeStartElement(out *bytes.Buffer, t xml.StartElement) {
+	out.WriteString("<" + qualifiedName(t.Name))
+
+	for _, attr := range t.Attr {
+		name := strings.ToLower(attr.Name.Local)
+		switch {
+		case strings.HasPrefix(name, "on"):
+			continue
+		case name == "href" && !safeReference(attr.Value):
+			continue
+		case name == "style" && !safeStyle(attr.Value):
+			continue
+		case strings.Contains(strings.ToLower(attr.Value), "url(") && !safeStyle(attr.Value):
+			// Presentation attributes such as fill and filter take url() references too
+			continue
+		}
+
+		out.WriteString(" " + qualifiedName(attr.Name) + `="`)
+		xml.EscapeText(out, []byte(attr.Value))
+		out.WriteString(`"`)
+	}
+
+	out.WriteString(">")
+}
+
+// qualifiedName returns the name as it was written, with its namespace prefix.
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
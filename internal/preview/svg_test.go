@@ -0,0 +1,143 @@
+package preview
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// wellFormed reports whether data parses as XML with matching start and end elements.
+func wellFormed(data []byte) error {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		_, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func TestSanitizeSVG(t *testing.T) {
+	tests := []struct {
+		name string
+		svg  string
+		// kept must appear in the sanitized image, removed must not
+		kept    []string
+		removed []string
+	}{
+		{
+			name:    "script elements",
+			svg:     `<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script><rect width="1" height="1"/></svg>`,
+			kept:    []string{"<rect"},
+			removed: []string{"script", "alert"},
+		},
+		{
+			name:    "nested embedded documents",
+			svg:     `<svg xmlns="http://www.w3.org/2000/svg"><foreignObject><div><iframe src="https://example.com"/></div></foreignObject><circle r="1"/></svg>`,
+			kept:    []string{"<circle"},
+			removed: []string{"foreignObject", "div", "iframe", "example.com"},
+		},
+		{
+			name:    "event handlers",
+			svg:     `<svg xmlns="http://www.w3.org/2000/svg" onload="alert(1)"><rect onClick="alert(2)" OnMouseOver="alert(3)" width="1"/></svg>`,
+			kept:    []string{`width="1"`},
+			removed: []string{"onload", "onClick", "OnMouseOver", "alert"},
+		},
+		{
+			name:    "external href",
+			svg:     `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"><image xlink:href="file:///etc/passwd"/><image href="https://example.com/a.png"/><use href="#shape"/></svg>`,
+			kept:    []string{`href="#shape"`},
+			removed: []string{"/etc/passwd", "example.com"},
+		},
+		{
+			name:    "embedded raster images",
+			svg:     `<svg xmlns="http://www.w3.org/2000/svg"><image href="data:image/png;base64,iVBORw0KGgo="/><image href="data:text/html;base64,PHNjcmlwdD4="/></svg>`,
+			kept:    []string{"data:image/png"},
+			removed: []string{"data:text/html"},
+		},
+		{
+			name:    "external url() in attributes",
+			svg:     `<svg xmlns="http://www.w3.org/2000/svg"><rect style="fill: url('https://example.com/p.svg#p')" filter="url(https://example.com/f.svg#f)" fill="url(#local)"/></svg>`,
+			kept:    []string{`fill="url(#local)"`},
+			removed: []string{"example.com"},
+		},
+		{
+			name:    "style sheet with external url()",
+			svg:     `<svg xmlns="http://www.w3.org/2000/svg"><style>rect { fill: url(http://example.com/p.svg#p) }</style><rect/></svg>`,
+			kept:    []string{"<rect"},
+			removed: []string{"<style", "example.com"},
+		},
+		{
+			name:    "style sheet with @import",
+			svg:     `<svg xmlns="http://www.w3.org/2000/svg"><style>@IMPORT "https://example.com/a.css";</style><rect/></svg>`,
+			kept:    []string{"<rect"},
+			removed: []string{"<style", "example.com"},
+		},
+		{
+			name:    "safe style sheet",
+			svg:     `<svg xmlns="http://www.w3.org/2000/svg"><style>rect { fill: url(#gradient) &amp; }</style><rect/></svg>`,
+			kept:    []string{"<style>rect { fill: url(#gradient) &amp; }</style>", "<rect"},
+			removed: nil,
+		},
+		{
+			name:    "style element holding elements",
+			svg:     `<svg xmlns="http://www.w3.org/2000/svg"><style>rect {}<b>x</b> circle {}</style><rect/></svg>`,
+			kept:    []string{"<rect"},
+			removed: []string{"<style", "<b>", "circle"},
+		},
+		{
+			name:    "DTD and processing instructions",
+			svg:     `<?xml version="1.0"?><?xml-stylesheet href="https://example.com/a.css"?><!DOCTYPE svg [<!ENTITY ext SYSTEM "file:///etc/passwd">]><svg xmlns="http://www.w3.org/2000/svg"><rect/></svg>`,
+			kept:    []string{"<rect"},
+			removed: []string{"DOCTYPE", "ENTITY", "/etc/passwd", "xml-stylesheet", "example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sanitized, err := sanitizeSVG([]byte(tt.svg))
+			if err != nil {
+				t.Fatalf("sanitizeSVG: %v", err)
+			}
+			if err := wellFormed(sanitized); err != nil {
+				t.Fatalf("sanitized image is malformed: %v\n%s", err, sanitized)
+			}
+
+			for _, s := range tt.kept {
+				if !strings.Contains(string(sanitized), s) {
+					t.Errorf("sanitized image is missing %q\n%s", s, sanitized)
+				}
+			}
+			for _, s := range tt.removed {
+				if strings.Contains(string(sanitized), s) {
+					t.Errorf("sanitized image still contains %q\n%s", s, sanitized)
+				}
+			}
+		})
+	}
+}
+
+func TestSanitizeSVGRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		svg  string
+	}{
+		{"entity references", `<!DOCTYPE svg [<!ENTITY ext SYSTEM "file:///etc/passwd">]><svg xmlns="http://www.w3.org/2000/svg"><text>&ext;</text></svg>`},
+		{"missing svg element", `<html><body/></html>`},
+		{"malformed XML", `<svg xmlns="http://www.w3.org/2000/svg"><rect></svg`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if sanitized, err := sanitizeSVG([]byte(tt.svg)); err == nil {
+				t.Errorf("sanitizeSVG succeeded, want an error\n%s", sanitized)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package preview
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"strings"
+	"unicode"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	// textPreviewBytes is how much of a text file is read for its preview.
+	textPreviewBytes = 16 << 10
+	textPreviewLines = 40
+	// textPreviewColumns is the width of the preview in characters, longer lines are cut off.
+	textPreviewColumns = 80
+	textPreviewPadding = 12
+	textTabWidth       = 4
+)
+
+var textColor = color.NRGBA{R: 0x33, G: 0x33, B: 0x33, A: 0xff}
+
+// textPreview renders the first lines of a text file, such as Markdown or source code, as a page of monospaced text.
+func textPreview(ctx context.Context, content io.Reader) (image.Image, error) {
+	data, err := io.ReadAll(io.LimitReader(content, textPreviewBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	lines := snippetLines(string(data))
+
+	face := basicfont.Face7x13
+	width := 2*textPreviewPadding + textPreviewColumns*face.Advance
+	height := 2*textPreviewPadding + textPreviewLines*face.Height
+
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	drawer := font.Drawer{Dst: img, Src: image.NewUniform(textColor), Face: face}
+	for i, line := range lines {
+		drawer.Dot = fixed.P(textPreviewPadding, textPreviewPadding+face.Ascent+i*face.Height)
+		drawer.DrawString(line)
+	}
+
+	return img, nil
+}
+
+// snippetLines splits the text into the lines shown in its preview. Tabs are expanded and
+// characters the font has no glyph for, including invalid UTF-8, are replaced with U+FFFD.
+func snippetLines(text string) []string {
+	text = strings.ToValidUTF8(text, "�")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	lines := strings.SplitN(text, "\n", textPreviewLines+1)
+	lines = lines[:min(len(lines), textPreviewLines)]
+
+	for i, line := range lines {
+		var b strings.Builder
+		columns := 0
+		for _, r := range line {
+			if columns >= textPreviewColumns {
+				break
+			}
+
+			switch {
+			case r == '\t':
+				spaces := textTabWidth - columns%textTabWidth
+				b.WriteString(strings.Repeat(" ", spaces))
+				columns += spaces
+				continue
+			case r > unicode.MaxASCII || !unicode.IsPrint(r):
+				r = '�'
+			}
+
+			b.WriteRune(r)
+			columns++
+		}
+		lines[i] = b.String()
+	}
+
+	return lines
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/i-christian/fileShare/internal/middlewares"
 	"github.com/i-christian/fileShare/internal/public"
 	"github.com/i-christian/fileShare/internal/user"
+	"github.com/i-christian/fileShare/internal/utils/security"
 )
 
 type RoutesConfig struct {
@@ -36,51 +37,128 @@ func RegisterRoutes(config *RoutesConfig, aH *auth.AuthHandler, authService *aut
 	// CORS setup
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{config.Domain},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type"},
+		AllowedMethods:   []string{"GET", "HEAD", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "Range", "If-Range", "If-None-Match", "If-Modified-Since", "Tus-Resumable", "Upload-Length", "Upload-Offset", "Upload-Metadata", "Upload-Defer-Length"},
+		ExposedHeaders:   []string{"Location", "ETag", "Last-Modified", "Accept-Ranges", "Content-Range", "Content-Disposition", "Tus-Resumable", "Tus-Version", "Tus-Extension", "Tus-Max-Size", "Upload-Offset", "Upload-Length", "Upload-Metadata", "Upload-Expires", "File-Id", "Share-Resume-Token"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
 
 	r.Handle("/debug/vars", expvar.Handler())
 
+	r.Get("/.well-known/jwks.json", aH.JWKS)
+
+	r.Get("/s/{token}", fH.SharedDownload)
+	r.Head("/s/{token}", fH.SharedDownload)
+
+	// Scopes an API key needs for a route, routes authenticated with a token are not limited
+	read := middlewares.RequireScope(security.ScopeRead)
+	write := middlewares.RequireScope(security.ScopeWrite)
+	super := middlewares.RequireScope(security.ScopeSuper)
+
 	r.Route("/api/v1", func(r chi.Router) {
 		r.Get("/healthcheck", pH.HealthStatus)
 		r.Route("/auth", func(r chi.Router) {
 			r.Post("/signup", aH.Signup)
 			r.Post("/login", aH.LoginWithRefresh)
 			r.Post("/refresh", aH.Refresh)
+			r.Post("/logout", aH.Logout)
+			r.With(middlewares.AuthMiddleware(authService, apiKeyService), super).Post("/logout/all", aH.LogoutEverywhere)
 			r.Post("/password/recover", aH.SendPasswordResetLink)
 			r.Put("/password/reset", aH.ResetPassword)
 		})
 
 		r.Route("/user", func(r chi.Router) {
 			r.Use(middlewares.AuthMiddleware(authService, apiKeyService))
-			r.Put("/activated", uH.ActivateUserHandler)
+			r.With(write).Put("/activated", uH.ActivateUserHandler)
 
 			r.Group(func(r chi.Router) {
 				r.Use(middlewares.RequireActivatedUser)
-				r.Get("/me", uH.MyProfile)
-				r.Post("/api-keys", aH.CreateAPIKey)
+				r.With(read).Get("/me", uH.MyProfile)
+				r.With(write).Put("/me/trash-retention", uH.SetTrashRetention)
+				r.With(write).Put("/me/location-stripping", uH.SetLocationStripping)
+				r.With(read).Get("/me/usage", uH.MyUsage)
+				r.With(super).Post("/api-keys", aH.CreateAPIKey)
+				r.With(super).Get("/api-keys", aH.ListAPIKeys)
+				r.With(super).Put("/api-keys/{id}/revoke", aH.RevokeAPIKey)
+				r.With(super).Put("/api-keys/{id}/rename", aH.RenameAPIKey)
+				r.With(super).Post("/api-keys/{id}/rotate", aH.RotateAPIKey)
+				r.With(super).Delete("/api-keys/{id}", aH.DeleteAPIKey)
+				r.With(super).Get("/sessions", aH.ListSessions)
+				r.With(super).Delete("/sessions/{id}", aH.RevokeSession)
 			})
 		})
 
+		// tus clients discover the supported protocol version and extensions without credentials
+		r.Options("/files/uploads", fH.UploadOptions)
+
 		r.Route("/files", func(r chi.Router) {
 			r.Use(middlewares.AuthMiddleware(authService, apiKeyService))
-			r.Get("/", fH.ListPublicFiles)
-			r.Get("/{id}/download", fH.Download)
+			r.With(read).Get("/", fH.ListPublicFiles)
+			r.With(read).Get("/search", fH.Search)
+			r.With(read).Get("/{id}/download", fH.Download)
+			r.With(read).Head("/{id}/download", fH.Download)
+			r.With(read).Get("/{id}/thumbnail", fH.Thumbnail)
+			r.With(read).Head("/{id}/thumbnail", fH.Thumbnail)
+			r.With(read).Get("/{id}/image", fH.Image)
+			r.With(read).Head("/{id}/image", fH.Image)
 
 			r.Group(func(r chi.Router) {
 				r.Use(middlewares.RequireActivatedUser)
 
-				r.Post("/upload", fH.Upload)
-				r.Get("/me", fH.ListMyFiles)
-				r.Get("/{id}", fH.GetMetadata)
-				r.Put("/{id}", fH.Delete)
-				r.Put("/{id}/visible", fH.SetFileVisibility)
-				r.Put("/{id}/edit", fH.UpdateFileName)
+				r.With(write).Post("/upload", fH.Upload)
+				r.With(write).Post("/uploads", fH.CreateUpload)
+				r.With(write).Head("/uploads/{id}", fH.UploadStatus)
+				r.With(write).Patch("/uploads/{id}", fH.UploadChunk)
+				r.With(write).Delete("/uploads/{id}", fH.TerminateUpload)
+				r.With(read).Get("/me", fH.ListMyFiles)
+				r.With(read).Post("/archive", fH.Archive)
+				r.With(write).Post("/batch", fH.Batch)
+				r.With(read).Get("/tags", fH.ListTags)
+				r.With(read).Get("/trash", fH.ListTrash)
+				r.With(write).Delete("/trash", fH.EmptyTrash)
+				r.With(write).Post("/{id}/restore", fH.Restore)
+				r.With(write).Delete("/{id}/purge", fH.Purge)
+				r.With(read).Get("/{id}", fH.GetMetadata)
+				r.With(read).Get("/{id}/image/url", fH.ImageURL)
+				r.With(write).Put("/{id}", fH.Delete)
+				r.With(write).Put("/{id}/visible", fH.SetFileVisibility)
+				r.With(write).Put("/{id}/edit", fH.UpdateFileName)
+				r.With(write).Put("/{id}/move", fH.MoveFile)
+				r.With(write).Post("/{id}/tags", fH.AddTags)
+				r.With(write).Put("/{id}/tags", fH.ReplaceTags)
+				r.With(write).Delete("/{id}/tags", fH.RemoveTags)
+				r.With(write).Post("/{id}/shares", fH.CreateShareLink)
+				r.With(read).Get("/{id}/shares", fH.ListShareLinks)
+				r.With(write).Delete("/{id}/shares/{shareID}", fH.RevokeShareLink)
+				r.With(read).Get("/{id}/versions", fH.ListFileVersions)
+				r.With(read).Get("/{id}/versions/{versionNumber}/download", fH.DownloadFileVersion)
+				r.With(read).Head("/{id}/versions/{versionNumber}/download", fH.DownloadFileVersion)
+				r.With(write).Put("/{id}/versions/{versionNumber}/promote", fH.PromoteFileVersion)
 			})
 		})
+
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(middlewares.AuthMiddleware(authService, apiKeyService))
+			r.Use(middlewares.RequireAdmin)
+
+			r.With(super).Get("/quotas", uH.ListRoleQuotas)
+			r.With(super).Put("/quotas/{role}", uH.SetRoleQuota)
+			r.With(super).Get("/users/{id}/usage", uH.UserUsage)
+			r.With(super).Put("/users/{id}/quota", uH.SetUserQuota)
+		})
+
+		r.Route("/folders", func(r chi.Router) {
+			r.Use(middlewares.AuthMiddleware(authService, apiKeyService))
+			r.Use(middlewares.RequireActivatedUser)
+
+			r.With(write).Post("/", fH.CreateFolder)
+			r.With(read).Get("/{id}", fH.GetFolder)
+			r.With(read).Get("/{id}/children", fH.ListFolderChildren)
+			r.With(write).Put("/{id}/rename", fH.RenameFolder)
+			r.With(write).Put("/{id}/move", fH.MoveFolder)
+			r.With(write).Delete("/{id}", fH.DeleteFolder)
+		})
 	})
 
 	return r
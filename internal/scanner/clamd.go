@@ -0,0 +1,137 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// clamdChunkSize is the size of the chunks content is streamed to clamd in.
+const clamdChunkSize = 64 * 1024
+
+// ClamdScanner is an implementation of the Scanner interface which streams content to a clamd daemon
+// using its INSTREAM command, see https://docs.clamav.net/manual/Usage/Scanning.html#clamd
+type ClamdScanner struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// NewClamdScanner is a constructor for ClamdScanner.
+// address is either tcp://host:port or unix:///path/to/clamd.sock, a plain host:port is treated as TCP.
+// timeout bounds a whole scan, including streaming the content.
+func NewClamdScanner(address string, timeout time.Duration) (*ClamdScanner, error) {
+	if !strings.Contains(address, "://") {
+		return &ClamdScanner{network: "tcp", address: address, timeout: timeout}, nil
+	}
+
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid clamd address: %w", err)
+	}
+
+	switch u.Scheme {
+	case "tcp":
+		return &ClamdScanner{network: "tcp", address: u.Host, timeout: timeout}, nil
+	case "unix":
+		return &ClamdScanner{network: "unix", address: u.Path, timeout: timeout}, nil
+	default:
+		return nil, fmt.Errorf("unsupported clamd address scheme %q", u.Scheme)
+	}
+}
+
+// Scan streams the content to clamd in length prefixed chunks, terminated by an empty chunk, and parses its verdict.
+func (c *ClamdScanner) Scan(ctx context.Context, content io.Reader) (Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, c.network, c.address)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	// Commands prefixed with z are terminated by a null byte, as are their replies
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return Result{}, fmt.Errorf("failed to send clamd command: %w", err)
+	}
+
+	if err := c.stream(conn, content); err != nil {
+		return Result{}, err
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return Result{}, fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+
+	return parseClamdReply(strings.TrimRight(reply, "\x00"))
+}
+
+// stream writes the content to clamd as INSTREAM chunks.
+// clamd closes the connection when the content exceeds its StreamMaxLength, which surfaces as a write error
+// and is reported through the reply when it can still be read.
+func (c *ClamdScanner) stream(conn net.Conn, content io.Reader) error {
+	buf := make([]byte, 4+clamdChunkSize)
+	for {
+		n, err := io.ReadFull(content, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, werr := conn.Write(buf[:4+n]); werr != nil {
+				return c.streamError(conn, werr)
+			}
+		}
+
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read content to scan: %w", err)
+		}
+	}
+
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return c.streamError(conn, err)
+	}
+
+	return nil
+}
+
+// streamError returns the reason clamd gave for aborting a stream, or the write error when it gave none.
+func (c *ClamdScanner) streamError(conn net.Conn, writeErr error) error {
+	reply, _ := bufio.NewReader(conn).ReadString(0)
+	if reply = strings.TrimRight(reply, "\x00"); reply != "" {
+		return fmt.Errorf("clamd aborted the scan: %s", reply)
+	}
+
+	return fmt.Errorf("failed to stream content to clamd: %w", writeErr)
+}
+
+// parseClamdReply parses a reply such as "stream: OK" or "stream: Eicar-Test-Signature FOUND".
+func parseClamdReply(reply string) (Result, error) {
+	verdict, ok := strings.CutPrefix(reply, "stream: ")
+	if !ok {
+		return Result{}, fmt.Errorf("unexpected clamd reply: %q", reply)
+	}
+
+	switch {
+	case verdict == "OK":
+		return Result{}, nil
+	case strings.HasSuffix(verdict, " FOUND"):
+		return Result{Infected: true, Signature: strings.TrimSuffix(verdict, " FOUND")}, nil
+	default:
+		return Result{}, fmt.Errorf("clamd failed to scan the content: %s", strings.TrimSpace(verdict))
+	}
+}
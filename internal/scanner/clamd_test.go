@@ -0,0 +1,211 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClamd is a clamd server accepting a single INSTREAM command, recording the chunks it receives
+// and answering with reply once the zero length chunk terminating the stream arrives.
+type fakeClamd struct {
+	listener net.Listener
+	reply    string
+
+	done       chan struct{}
+	command    string
+	chunkSizes []int
+	content    []byte
+	terminated bool
+	err        error
+}
+
+func newFakeClamd(t *testing.T, reply string) *fakeClamd {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	f := &fakeClamd{listener: listener, reply: reply, done: make(chan struct{})}
+	go f.serve()
+
+	return f
+}
+
+func (f *fakeClamd) serve() {
+	defer close(f.done)
+
+	conn, err := f.listener.Accept()
+	if err != nil {
+		f.err = err
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	f.command, f.err = r.ReadString(0)
+	if f.err != nil {
+		return
+	}
+
+	for {
+		var size uint32
+		if f.err = binary.Read(r, binary.BigEndian, &size); f.err != nil {
+			return
+		}
+		if size == 0 {
+			f.terminated = true
+			break
+		}
+
+		chunk := make([]byte, size)
+		if _, f.err = io.ReadFull(r, chunk); f.err != nil {
+			return
+		}
+		f.chunkSizes = append(f.chunkSizes, int(size))
+		f.content = append(f.content, chunk...)
+	}
+
+	_, f.err = conn.Write([]byte(f.reply + "\x00"))
+}
+
+// wait returns once the fake has answered, failing the test if it did not receive a well formed stream.
+func (f *fakeClamd) wait(t *testing.T) {
+	t.Helper()
+
+	select {
+	case <-f.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("fake clamd did not finish")
+	}
+
+	if f.err != nil {
+		t.Fatalf("fake clamd: %v", f.err)
+	}
+	if f.command != "zINSTREAM\x00" {
+		t.Errorf("command = %q, want %q", f.command, "zINSTREAM\x00")
+	}
+	if !f.terminated {
+		t.Error("stream was not terminated by a zero length chunk")
+	}
+}
+
+func newTestScanner(t *testing.T, f *fakeClamd) *ClamdScanner {
+	t.Helper()
+
+	c, err := NewClamdScanner("tcp://"+f.listener.Addr().String(), 5*time.Second)
+	if err != nil {
+		t.Fatalf("NewClamdScanner: %v", err)
+	}
+
+	return c
+}
+
+func TestClamdScanReplies(t *testing.T) {
+	tests := []struct {
+		name      string
+		reply     string
+		want      Result
+		wantError string
+	}{
+		{name: "clean", reply: "stream: OK", want: Result{}},
+		{name: "infected", reply: "stream: Eicar-Test-Signature FOUND", want: Result{Infected: true, Signature: "Eicar-Test-Signature"}},
+		{name: "error", reply: "stream: INSTREAM size limit exceeded. ERROR", wantError: "INSTREAM size limit exceeded. ERROR"},
+		{name: "unexpected", reply: "UNKNOWN COMMAND", wantError: "unexpected clamd reply"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeClamd(t, tt.reply)
+
+			got, err := newTestScanner(t, f).Scan(context.Background(), strings.NewReader("content to scan"))
+			f.wait(t)
+
+			if tt.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+					t.Fatalf("Scan error = %v, want it to contain %q", err, tt.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Scan: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Scan = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClamdScanChunks(t *testing.T) {
+	tests := []struct {
+		name       string
+		size       int
+		wantChunks []int
+	}{
+		{name: "empty", size: 0, wantChunks: nil},
+		{name: "single chunk", size: 10, wantChunks: []int{10}},
+		{name: "exact chunk", size: clamdChunkSize, wantChunks: []int{clamdChunkSize}},
+		{name: "several chunks", size: 2*clamdChunkSize + 10, wantChunks: []int{clamdChunkSize, clamdChunkSize, 10}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := bytes.Repeat([]byte("0123456789abcdef"), tt.size/16+1)[:tt.size]
+			f := newFakeClamd(t, "stream: OK")
+
+			if _, err := newTestScanner(t, f).Scan(context.Background(), bytes.NewReader(content)); err != nil {
+				t.Fatalf("Scan: %v", err)
+			}
+			f.wait(t)
+
+			if !slices.Equal(f.chunkSizes, tt.wantChunks) {
+				t.Errorf("chunk sizes = %v, want %v", f.chunkSizes, tt.wantChunks)
+			}
+			if !bytes.Equal(f.content, content) {
+				t.Errorf("clamd received %d bytes which differ from the %d bytes of content", len(f.content), len(content))
+			}
+		})
+	}
+}
+
+func TestNewClamdScannerAddress(t *testing.T) {
+	tests := []struct {
+		address     string
+		wantNetwork string
+		wantAddress string
+		wantErr     bool
+	}{
+		{address: "localhost:3310", wantNetwork: "tcp", wantAddress: "localhost:3310"},
+		{address: "tcp://clamav:3310", wantNetwork: "tcp", wantAddress: "clamav:3310"},
+		{address: "unix:///run/clamav/clamd.ctl", wantNetwork: "unix", wantAddress: "/run/clamav/clamd.ctl"},
+		{address: "http://clamav:3310", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		c, err := NewClamdScanner(tt.address, time.Second)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("NewClamdScanner(%q) succeeded, want an error", tt.address)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("NewClamdScanner(%q): %v", tt.address, err)
+			continue
+		}
+		if c.network != tt.wantNetwork || c.address != tt.wantAddress {
+			t.Errorf("NewClamdScanner(%q) = %s %s, want %s %s", tt.address, c.network, c.address, tt.wantNetwork, tt.wantAddress)
+		}
+	}
+}
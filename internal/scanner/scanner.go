@@ -0,0 +1,44 @@
+// Package scanner checks file content for malware.
+package scanner
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/i-christian/fileShare/internal/utils"
+)
+
+// Result is the outcome of scanning content.
+type Result struct {
+	Infected bool
+	// Signature names the malware found in infected content.
+	Signature string
+}
+
+// Scanner defines the interface for malware scanners.
+type Scanner interface {
+	// Scan reads the content from the provided io.Reader and reports whether it is infected.
+	Scan(ctx context.Context, content io.Reader) (Result, error)
+}
+
+// SetUpScanner initializes the scanner based on env config.
+// It returns nil when no scanner is configured, in which case uploaded files are not scanned.
+func SetUpScanner(logger *slog.Logger) Scanner {
+	address := utils.GetEnvOrFile("CLAMD_ADDRESS")
+	if address == "" {
+		logger.Warn("CLAMD_ADDRESS is not set, uploaded files are not scanned for malware")
+		return nil
+	}
+
+	clamd, err := NewClamdScanner(address, 2*time.Minute)
+	if err != nil {
+		utils.WriteServerError(logger, "failed to initialise clamd scanner", err)
+		os.Exit(1)
+	}
+
+	logger.Info("Initialised clamd scanner", "address", address)
+	return clamd
+}
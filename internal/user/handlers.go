@@ -40,6 +40,81 @@ func (h *UserHandler) MyProfile(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// SetTrashRetention changes how long the user's deleted files are kept before they are permanently deleted
+func (h *UserHandler) SetTrashRetention(w http.ResponseWriter, r *http.Request) {
+	ctxUser, ok := security.GetUserFromContext(r)
+	if !ok || ctxUser.IsAnonymous() {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	var input struct {
+		Days int32 `json:"days"`
+	}
+
+	err := utils.ReadJSON(w, r, &input)
+	if err != nil {
+		utils.BadRequestResponse(w, err)
+		return
+	}
+
+	v := validator.New()
+	if validator.ValidateTrashRetention(v, input.Days); !v.Valid() {
+		utils.FailedValidationResponse(w, v.Errors)
+		return
+	}
+
+	err = h.userService.SetTrashRetention(r.Context(), ctxUser.UserID, input.Days)
+	if err != nil {
+		utils.WriteServerError(h.userService.logger, "failed to set trash retention", err)
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "trash retention period updated, it applies to files deleted from now on"}, nil)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+	}
+}
+
+// SetLocationStripping changes whether location metadata is removed from the images the user uploads,
+// unless an upload asks otherwise
+func (h *UserHandler) SetLocationStripping(w http.ResponseWriter, r *http.Request) {
+	ctxUser, ok := security.GetUserFromContext(r)
+	if !ok || ctxUser.IsAnonymous() {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	var input struct {
+		Enabled *bool `json:"enabled"`
+	}
+
+	err := utils.ReadJSON(w, r, &input)
+	if err != nil {
+		utils.BadRequestResponse(w, err)
+		return
+	}
+
+	v := validator.New()
+	if v.Check(input.Enabled != nil, "enabled", "must be provided"); !v.Valid() {
+		utils.FailedValidationResponse(w, v.Errors)
+		return
+	}
+
+	err = h.userService.SetStripLocation(r.Context(), ctxUser.UserID, *input.Enabled)
+	if err != nil {
+		utils.WriteServerError(h.userService.logger, "failed to set location stripping", err)
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "location stripping updated, it applies to images uploaded from now on"}, nil)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+	}
+}
+
 func (h *UserHandler) ActivateUserHandler(w http.ResponseWriter, r *http.Request) {
 	userFromContext, ok := security.GetUserFromContext(r)
 	if !ok {
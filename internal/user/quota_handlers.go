@@ -0,0 +1,132 @@
+package user
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/i-christian/fileShare/internal/utils"
+	"github.com/i-christian/fileShare/internal/utils/security"
+	"github.com/i-christian/fileShare/internal/validator"
+)
+
+// MyUsage reports the storage used by the user against their quota
+func (h *UserHandler) MyUsage(w http.ResponseWriter, r *http.Request) {
+	ctxUser, ok := security.GetUserFromContext(r)
+	if !ok || ctxUser.IsAnonymous() {
+		utils.UnauthorisedResponse(w, utils.ErrAuthRequired.Error())
+		return
+	}
+
+	h.writeUsage(w, r, ctxUser.UserID)
+}
+
+// UserUsage reports the storage used by any user, for admins
+func (h *UserHandler) UserUsage(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		utils.BadRequestResponse(w, errors.New("invalid user ID parameter"))
+		return
+	}
+
+	h.writeUsage(w, r, userID)
+}
+
+func (h *UserHandler) writeUsage(w http.ResponseWriter, r *http.Request, userID uuid.UUID) {
+	usage, err := h.userService.GetStorageUsage(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, utils.ErrRecordNotFound) {
+			utils.NotFoundResponse(w)
+			return
+		}
+		utils.WriteServerError(h.userService.logger, "failed to get storage usage", err)
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"usage": usage}, nil)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+	}
+}
+
+// SetUserQuota overrides the storage limits of a user's role, for admins
+func (h *UserHandler) SetUserQuota(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		utils.BadRequestResponse(w, errors.New("invalid user ID parameter"))
+		return
+	}
+
+	var input validator.Quota
+	err = utils.ReadJSON(w, r, &input)
+	if err != nil {
+		utils.BadRequestResponse(w, err)
+		return
+	}
+
+	v := validator.New()
+	if validator.ValidateQuota(v, input); !v.Valid() {
+		utils.FailedValidationResponse(w, v.Errors)
+		return
+	}
+
+	err = h.userService.SetUserQuota(r.Context(), userID, input)
+	if err != nil {
+		if errors.Is(err, utils.ErrRecordNotFound) {
+			utils.NotFoundResponse(w)
+			return
+		}
+		utils.WriteServerError(h.userService.logger, "failed to set user quota", err)
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+		return
+	}
+
+	h.writeUsage(w, r, userID)
+}
+
+// ListRoleQuotas returns the storage limits of every role, for admins
+func (h *UserHandler) ListRoleQuotas(w http.ResponseWriter, r *http.Request) {
+	quotas, err := h.userService.ListRoleQuotas(r.Context())
+	if err != nil {
+		utils.WriteServerError(h.userService.logger, "failed to list role quotas", err)
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"quotas": quotas}, nil)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+	}
+}
+
+// SetRoleQuota sets the storage limits of the users with a role, for admins
+func (h *UserHandler) SetRoleQuota(w http.ResponseWriter, r *http.Request) {
+	role := r.PathValue("role")
+
+	var input validator.Quota
+	err := utils.ReadJSON(w, r, &input)
+	if err != nil {
+		utils.BadRequestResponse(w, err)
+		return
+	}
+
+	v := validator.New()
+	validator.ValidateRole(v, role)
+	if validator.ValidateQuota(v, input); !v.Valid() {
+		utils.FailedValidationResponse(w, v.Errors)
+		return
+	}
+
+	quota, err := h.userService.SetRoleQuota(r.Context(), role, input)
+	if err != nil {
+		utils.WriteServerError(h.userService.logger, "failed to set role quota", err)
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"quota": quota}, nil)
+	if err != nil {
+		utils.ServerErrorResponse(w, utils.ErrUnexpectedError.Error())
+	}
+}
@@ -0,0 +1,118 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/i-christian/fileShare/internal/database"
+	"github.com/i-christian/fileShare/internal/utils"
+	"github.com/i-christian/fileShare/internal/validator"
+)
+
+// StorageUsage is the storage used by a user and the limits that apply to them, a null limit is unlimited
+type StorageUsage struct {
+	UsedBytes int64  `json:"used_bytes"`
+	UsedFiles int64  `json:"used_files"`
+	MaxBytes  *int64 `json:"max_bytes"`
+	MaxFiles  *int64 `json:"max_files"`
+}
+
+// RoleQuota holds the storage limits of the users with a role, a null limit is unlimited
+type RoleQuota struct {
+	Role      string    `json:"role"`
+	MaxBytes  *int64    `json:"max_bytes"`
+	MaxFiles  *int64    `json:"max_files"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// limitPtr converts a nullable limit to its JSON representation, where null is unlimited
+func limitPtr(n sql.NullInt64) *int64 {
+	if !n.Valid {
+		return nil
+	}
+	return &n.Int64
+}
+
+// nullLimit converts a limit read from JSON to a query argument
+func nullLimit(n *int64) sql.NullInt64 {
+	if n == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: *n, Valid: true}
+}
+
+func toRoleQuota(q database.RoleQuota) RoleQuota {
+	return RoleQuota{
+		Role:      string(q.Role),
+		MaxBytes:  limitPtr(q.MaxBytes),
+		MaxFiles:  limitPtr(q.MaxFiles),
+		UpdatedAt: q.UpdatedAt,
+	}
+}
+
+// GetStorageUsage reports the storage used by the user, files in the trash count until they are purged
+func (s *UserService) GetStorageUsage(ctx context.Context, userID uuid.UUID) (StorageUsage, error) {
+	usage, err := s.queries.GetStorageUsage(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return StorageUsage{}, utils.ErrRecordNotFound
+		}
+		return StorageUsage{}, err
+	}
+
+	return StorageUsage{
+		UsedBytes: usage.UsedBytes,
+		UsedFiles: usage.UsedFiles,
+		MaxBytes:  limitPtr(usage.MaxBytes),
+		MaxFiles:  limitPtr(usage.MaxFiles),
+	}, nil
+}
+
+// SetUserQuota overrides the storage limits of the user's role, nil limits use the role limit
+func (s *UserService) SetUserQuota(ctx context.Context, userID uuid.UUID, quota validator.Quota) error {
+	updated, err := s.queries.SetUserQuota(ctx, database.SetUserQuotaParams{
+		QuotaMaxBytes: nullLimit(quota.MaxBytes),
+		QuotaMaxFiles: nullLimit(quota.MaxFiles),
+		UserID:        userID,
+	})
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return utils.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// ListRoleQuotas returns the storage limits of every role
+func (s *UserService) ListRoleQuotas(ctx context.Context) ([]RoleQuota, error) {
+	quotas, err := s.queries.ListRoleQuotas(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	roleQuotas := make([]RoleQuota, 0, len(quotas))
+	for _, q := range quotas {
+		roleQuotas = append(roleQuotas, toRoleQuota(q))
+	}
+
+	return roleQuotas, nil
+}
+
+// SetRoleQuota sets the storage limits of the users with a role, nil limits are unlimited
+func (s *UserService) SetRoleQuota(ctx context.Context, role string, quota validator.Quota) (RoleQuota, error) {
+	q, err := s.queries.SetRoleQuota(ctx, database.SetRoleQuotaParams{
+		Role:     database.UserRole(role),
+		MaxBytes: nullLimit(quota.MaxBytes),
+		MaxFiles: nullLimit(quota.MaxFiles),
+	})
+	if err != nil {
+		return RoleQuota{}, err
+	}
+
+	return toRoleQuota(q), nil
+}
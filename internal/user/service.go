@@ -30,6 +30,22 @@ func (s *UserService) GetUserInfo(ctx context.Context, userID uuid.UUID) (databa
 	return s.queries.GetUserByID(ctx, userID)
 }
 
+// SetTrashRetention sets how many days the user's deleted files stay in the trash, zero restores the deployment default
+func (s *UserService) SetTrashRetention(ctx context.Context, userID uuid.UUID, days int32) error {
+	return s.queries.SetTrashRetention(ctx, database.SetTrashRetentionParams{
+		TrashRetentionDays: sql.NullInt32{Int32: days, Valid: days > 0},
+		UserID:             userID,
+	})
+}
+
+// SetStripLocation sets whether location metadata is removed from the images the user uploads
+func (s *UserService) SetStripLocation(ctx context.Context, userID uuid.UUID, enabled bool) error {
+	return s.queries.SetStripLocation(ctx, database.SetStripLocationParams{
+		StripLocation: enabled,
+		UserID:        userID,
+	})
+}
+
 func (s *UserService) ActivateUser(ctx context.Context, userID uuid.UUID, tokenPlain string, v *validator.Validator) (email string, activated bool, err error) {
 	tokenHash := sha256.Sum256([]byte(tokenPlain))
 
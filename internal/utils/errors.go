@@ -2,6 +2,7 @@ package utils
 
 import (
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 )
@@ -15,7 +16,25 @@ var (
 	ErrDuplicateUpload = errors.New("file already exists")
 	ErrFilesNotFound   = errors.New("files do not exist")
 	ErrInvalidFile     = errors.New("invalid file")
-	ErrNotPermitted = errors.New("you do not have the permission to access this resource")
+	ErrNotPermitted    = errors.New("you do not have the permission to access this resource")
+	ErrUploadExpired   = errors.New("upload has expired")
+	ErrOffsetMismatch  = errors.New("upload offset does not match the current offset")
+	ErrUploadLocked    = errors.New("another request is writing to the upload, please try again shortly")
+	ErrLinkUnavailable = errors.New("this link has expired, been revoked or reached its download limit")
+	ErrInvalidPassword = errors.New("a valid password is required to access this resource")
+	ErrCurrentVersion  = errors.New("the version is already the current version of the file")
+	ErrFolderCycle     = errors.New("a folder can not be moved into itself or one of its subfolders")
+	ErrInvalidCursor   = errors.New("invalid or malformed cursor")
+	ErrQuotaExceeded   = errors.New("the upload would exceed your storage quota")
+	ErrScanPending     = errors.New("the file is being scanned for malware, please try again shortly")
+	ErrFileInfected    = errors.New("the file has been quarantined because malware was detected in it")
+	ErrStripPending    = errors.New("location metadata is being removed from the file, please try again shortly")
+	ErrNotAnImage      = errors.New("the file is not an image renditions can be generated from")
+	ErrImageTooLarge   = errors.New("the image is too large to generate renditions from")
+	ErrBadSignature    = errors.New("the URL signature is missing or invalid")
+	ErrArchiveTooLarge = errors.New("too many files are selected to download as a single archive")
+	ErrKeyInactive     = errors.New("the API key has been revoked or has expired")
+	ErrTooManyTags     = errors.New("the file would have more than 20 tags")
 )
 
 // WriteErrorJSON returns an error in json format to the client
@@ -50,6 +69,12 @@ func NotPermittedResponse(w http.ResponseWriter) {
 	WriteErrorJSON(w, http.StatusForbidden, message)
 }
 
+// MissingScopeResponse sends a 403 error if the API key a request was made with was not granted the scope a route requires.
+func MissingScopeResponse(w http.ResponseWriter, scope string) {
+	message := fmt.Sprintf("this API key does not have the %q scope required to access this resource", scope)
+	WriteErrorJSON(w, http.StatusForbidden, message)
+}
+
 // NotFoundResponse send a 404 error to client if no record is found
 func NotFoundResponse(w http.ResponseWriter) {
 	WriteErrorJSON(w, http.StatusNotFound, ErrRecordNotFound.Error())
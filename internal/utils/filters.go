@@ -1,17 +1,78 @@
 package utils
 
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
 type Filters struct {
 	Page     int
 	PageSize int
+	// Sort is a column name, prefixed with - for descending order
+	Sort string
+	// Tags restricts results to files carrying any of the tags, or all of them when MatchAllTags is set
+	Tags         []string
+	MatchAllTags bool
+	// The remaining filters are not applied when left at their zero value, MinSize and MaxSize use -1 instead
+	MimePrefix    string
+	MinSize       int64
+	MaxSize       int64
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	Visibility    string
+	// UseCursor selects cursor pagination, starting after Cursor or at the first page when it is nil.
+	// The total number of records is only counted for cursor pagination when IncludeTotal is set.
+	UseCursor    bool
+	Cursor       *Cursor
+	IncludeTotal bool
+}
+
+// Cursor is a position in a listing ordered by a timestamp and then by ID. Folder listings are ordered by IsFile
+// and Name instead of the timestamp and search results by Rank. Backward cursors select the records before
+// the position instead of after it
+type Cursor struct {
+	Time     time.Time `json:"t,omitzero"`
+	IsFile   bool      `json:"f,omitzero"`
+	Name     string    `json:"n,omitzero"`
+	Rank     float32   `json:"r,omitzero"`
+	ID       uuid.UUID `json:"id"`
+	Backward bool      `json:"b,omitzero"`
+}
+
+// Encode returns the cursor as an opaque URL safe string
+func (c Cursor) Encode() string {
+	js, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+// DecodeCursor parses a cursor returned by Cursor.Encode
+func DecodeCursor(s string) (Cursor, error) {
+	js, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	var c Cursor
+	// The sort values are not checked, a rank or a folder listing position may be at their zero value
+	if err := json.Unmarshal(js, &c); err != nil || c.ID == uuid.Nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	return c, nil
 }
 
 // Metadata holds pagination data
 type Metadata struct {
-	CurrentPage  int `json:"current_page,omitzero"`
-	PageSize     int `json:"page_size,omitzero"`
-	FirstPage    int `json:"first_page,omitzero"`
-	LastPage     int `json:"last_page,omitzero"`
-	TotalRecords int `json:"total_records,omitzero"`
+	CurrentPage  int    `json:"current_page,omitzero"`
+	PageSize     int    `json:"page_size,omitzero"`
+	FirstPage    int    `json:"first_page,omitzero"`
+	LastPage     int    `json:"last_page,omitzero"`
+	TotalRecords int    `json:"total_records,omitzero"`
+	NextCursor   string `json:"next_cursor,omitzero"`
+	PrevCursor   string `json:"prev_cursor,omitzero"`
 }
 
 func CalculateMetadata(totalRecords int, page, pageSize int) Metadata {
@@ -26,3 +87,28 @@ func CalculateMetadata(totalRecords int, page, pageSize int) Metadata {
 		TotalRecords: totalRecords,
 	}
 }
+
+// CalculateCursorMetadata returns the pagination data of a page fetched with cursor.
+// first and last are the positions of the first and last records on the page and hasMore reports
+// whether further records exist in the direction the page was fetched, totalRecords is left out unless positive.
+func CalculateCursorMetadata(cursor *Cursor, first, last Cursor, pageLen int, hasMore bool, pageSize, totalRecords int) Metadata {
+	meta := Metadata{PageSize: pageSize}
+	if totalRecords > 0 {
+		meta.TotalRecords = totalRecords
+	}
+	if pageLen == 0 {
+		return meta
+	}
+
+	backward := cursor != nil && cursor.Backward
+	if backward || hasMore {
+		last.Backward = false
+		meta.NextCursor = last.Encode()
+	}
+	if (cursor != nil && !backward) || (backward && hasMore) {
+		first.Backward = true
+		meta.PrevCursor = first.Encode()
+	}
+
+	return meta
+}
@@ -8,17 +8,35 @@ import (
 	"encoding/hex"
 	"net"
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Methods a request can be authenticated with.
+const (
+	AuthAnonymous = "anonymous"
+	AuthToken     = "token"
+	AuthAPIKey    = "api_key"
+)
+
+// Scopes an API key can be granted.
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+	ScopeSuper = "super"
+)
+
 type ContextUser struct {
 	FirstName   string
 	LastName    string
 	Email       string
 	Role        string
+	AuthMethod  string
+	Scopes      []string
+	SessionID   uuid.UUID
 	UserID      uuid.UUID
 	IsActivated bool
 }
@@ -28,13 +46,26 @@ type contextKey string
 const UserContextKey = contextKey("user")
 
 // AnonymousUser holds a pointer to a User struct representing an inactivated user with no ID, name, email or password.
-var AnonymousUser = &ContextUser{}
+var AnonymousUser = &ContextUser{AuthMethod: AuthAnonymous}
 
 // IsAnonymous checks if a user instance is the AnonymousUser
 func (u *ContextUser) IsAnonymous() bool {
 	return u == AnonymousUser
 }
 
+// HasScope reports whether the user was granted the scope. A session token grants every scope, an API key
+// only its own scopes, where the super scope grants every other scope. Any other method is granted none.
+func (u *ContextUser) HasScope(scope string) bool {
+	switch u.AuthMethod {
+	case AuthToken:
+		return true
+	case AuthAPIKey:
+		return slices.Contains(u.Scopes, ScopeSuper) || slices.Contains(u.Scopes, scope)
+	default:
+		return false
+	}
+}
+
 // SetContextUser function returns a new copy of the request with the provided User struct added to the context
 func SetContextUser(r *http.Request, user *ContextUser) *http.Request {
 	ctx := context.WithValue(r.Context(), UserContextKey, user)
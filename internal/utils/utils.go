@@ -64,3 +64,37 @@ func ReadInt(qs map[string][]string, key string, defaultValue int) int {
 	}
 	return i
 }
+
+// ReadString is a helper for string query params
+func ReadString(qs map[string][]string, key string, defaultValue string) string {
+	s := qs[key]
+	if len(s) == 0 || s[0] == "" {
+		return defaultValue
+	}
+
+	return s[0]
+}
+
+// ReadCSV is a helper for comma separated query params
+func ReadCSV(qs map[string][]string, key string, defaultValue []string) []string {
+	s := qs[key]
+	if len(s) == 0 || s[0] == "" {
+		return defaultValue
+	}
+
+	return strings.Split(s[0], ",")
+}
+
+// ReadBool is a helper for boolean query params
+func ReadBool(qs map[string][]string, key string, defaultValue bool) bool {
+	s := qs[key]
+	if len(s) == 0 {
+		return defaultValue
+	}
+
+	b, err := strconv.ParseBool(s[0])
+	if err != nil {
+		return defaultValue
+	}
+	return b
+}
@@ -2,6 +2,9 @@ package validator
 
 import "time"
 
+// MaxKeyRotationOverlap is the longest time the secret a rotated API key replaces can still be accepted for.
+const MaxKeyRotationOverlap = 7 * 24 * time.Hour
+
 type ApiKey struct {
 	KeyName string    `json:"key_name"`
 	Expires time.Time `json:"expires_at,omitzero"`
@@ -10,9 +13,19 @@ type ApiKey struct {
 
 func ValidateAPIKeyLogin(v *Validator, key *ApiKey) {
 	v.Check(len(key.Scope) > 0, "scope", "must be provided")
-	v.Check(len(key.KeyName) > 2 && len(key.KeyName) <= 50, "key_name", "must be between 3 and 50 characters long")
+	ValidateAPIKeyName(v, key.KeyName)
 	if !key.Expires.IsZero() {
 		v.Check(IsValidTimeFormat(time.RFC3339, key.Expires.Format(time.RFC3339)) && key.Expires.After(time.Now()),
 			"expires_at", "must be a valid value and not in the past")
 	}
 }
+
+func ValidateAPIKeyName(v *Validator, name string) {
+	v.Check(len(name) > 2 && len(name) <= 50, "key_name", "must be between 3 and 50 characters long")
+}
+
+// ValidateKeyRotation checks the overlap window of an API key rotation, in minutes
+func ValidateKeyRotation(v *Validator, overlapMinutes int) {
+	v.Check(overlapMinutes >= 0, "overlap_minutes", "must not be negative")
+	v.Check(overlapMinutes <= int(MaxKeyRotationOverlap/time.Minute), "overlap_minutes", "must not be more than 7 days")
+}
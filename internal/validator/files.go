@@ -6,6 +6,8 @@ import (
 	"io"
 	"net/http"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 )
 
@@ -22,12 +24,23 @@ type Filters struct {
 	PageSize     int
 	Sort         string
 	SortSafelist []string
+	Tags         []string
+	TagMatch     string
+	MimePrefix   string
+	MinSize      int64
+	MaxSize      int64
+	Visibility   string
+	UseCursor    bool
 }
 
+// mimePrefixRX matches a MIME type or the start of one, such as "image/" or "application/pdf"
+var mimePrefixRX = regexp.MustCompile(`^[a-z0-9][a-z0-9.+-]*(/[a-z0-9.+-]*)?$`)
+
 type FileInfo struct {
-	Version    int32
-	Filename   string
-	Visibility string
+	Version       int32
+	VersionNumber int32
+	Filename      string
+	Visibility    string
 }
 
 func ValidateFileUpload(v *Validator, file *FileUpload) {
@@ -36,11 +49,71 @@ func ValidateFileUpload(v *Validator, file *FileUpload) {
 	v.Check(len(file.Filename) <= 255, "file", "filename must be less than 255 characters")
 }
 
+// ValidateResumableUpload checks the declared length and filename of a new resumable upload
+func ValidateResumableUpload(v *Validator, file *FileUpload) {
+	ValidateFileUpload(v, file)
+	v.Check(file.UploadSize > 0, "Upload-Length", "must be greater than zero")
+	if err := ValidateFileExtension(file.Filename); err != nil {
+		v.AddError("filename", err.Error())
+	}
+}
+
 func ValidateFilters(v *Validator, f Filters) {
 	v.Check(f.Page > 0, "page", "must be greater than zero")
 	v.Check(f.Page <= 10_000, "page", "must be a maximum of 10,000")
 	v.Check(f.PageSize > 0, "page_size", "must be greater than zero")
 	v.Check(f.PageSize <= 100, "page_size", "must be a maximum of 100")
+	if f.Sort != "" {
+		v.Check(PermittedValue(f.Sort, f.SortSafelist...), "sort", "invalid sort value")
+		if f.UseCursor {
+			v.Check(PermittedValue(f.Sort, "created_at", "-created_at"), "sort", "must be created_at or -created_at when paginating with a cursor")
+		}
+	}
+	if f.MimePrefix != "" {
+		v.Check(mimePrefixRX.MatchString(f.MimePrefix), "mime_type", "must be a MIME type or a prefix of one such as image/")
+	}
+	v.Check(f.MinSize >= -1, "min_size", "must not be negative")
+	v.Check(f.MaxSize >= -1, "max_size", "must not be negative")
+	if f.MinSize >= 0 && f.MaxSize >= 0 {
+		v.Check(f.MaxSize >= f.MinSize, "max_size", "must not be less than min_size")
+	}
+	if f.Visibility != "" {
+		v.Check(f.Visibility == "public" || f.Visibility == "private", "visibility", "must be either private or public")
+	}
+	if len(f.Tags) > 0 {
+		ValidateTags(v, "tags", f.Tags)
+		v.Check(PermittedValue(f.TagMatch, "any", "all"), "tag_match", "must be either any or all")
+	}
+}
+
+// NormalizeTags trims and lowercases tags, dropping empty and repeated tags
+func NormalizeTags(tags []string) []string {
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+
+	return normalized
+}
+
+// MaxFileTags is the largest number of tags a file can have.
+const MaxFileTags = 20
+
+// ValidateTags checks normalized tags, key is the field reported in validation errors
+func ValidateTags(v *Validator, key string, tags []string) {
+	v.Check(len(tags) <= MaxFileTags, key, fmt.Sprintf("must not contain more than %d tags", MaxFileTags))
+	for _, tag := range tags {
+		v.Check(len(tag) <= 50, key, "each tag must be atmost 50 bytes long")
+		v.Check(!strings.Contains(tag, ","), key, "tags must not contain commas")
+	}
+}
+
+func ValidateSearchQuery(v *Validator, query string) {
+	v.Check(strings.TrimSpace(query) != "", "q", "must be provided")
+	v.Check(len(query) <= 200, "q", "must be atmost 200 bytes long")
 }
 
 func ValidateDeleteFile(v *Validator, f FileInfo) {
@@ -52,14 +125,19 @@ func ValidateVisibility(v *Validator, f FileInfo) {
 	v.Check(f.Visibility == "public" || f.Visibility == "private", "visibility", "must be either private or public")
 }
 
+func ValidatePromoteVersion(v *Validator, f FileInfo) {
+	v.Check(f.Version > 0, "version", "must be greater than zero")
+	v.Check(f.VersionNumber > 0, "version_number", "must be greater than zero")
+}
+
 func ValidateFileNameChange(v *Validator, f FileInfo) {
 	v.Check(f.Version > 0, "version", "must be greater than zero")
 	v.Check(len(f.Filename) >= 3, "filename", "must be atleast 3 bytes long")
 	v.Check(len(f.Filename) <= 50, "filename", "must be atmost 50 bytes long")
 }
 
-// ValidateAndPrepareStream checks the file extension and MIME type.
-func ValidateAndPrepareStream(filename string, stream io.Reader) (fileStream io.Reader, contentType string, err error) {
+// ValidateFileExtension rejects filenames with an executable or script extension.
+func ValidateFileExtension(filename string) error {
 	ext := strings.ToLower(filepath.Ext(filename))
 	blockedExtensions := map[string]bool{
 		".exe": true, ".dll": true, ".so": true, ".bat": true, ".cmd": true,
@@ -67,7 +145,16 @@ func ValidateAndPrepareStream(filename string, stream io.Reader) (fileStream io.
 		".vbs": true, ".powershell": true, ".js": true,
 	}
 	if blockedExtensions[ext] {
-		return nil, "", fmt.Errorf("file extension '%s' is not allowed", ext)
+		return fmt.Errorf("file extension '%s' is not allowed", ext)
+	}
+
+	return nil
+}
+
+// ValidateAndPrepareStream checks the file extension and MIME type.
+func ValidateAndPrepareStream(filename string, stream io.Reader) (fileStream io.Reader, contentType string, err error) {
+	if err := ValidateFileExtension(filename); err != nil {
+		return nil, "", err
 	}
 
 	header := make([]byte, 512)
@@ -0,0 +1,19 @@
+package validator
+
+import "strings"
+
+type FolderInfo struct {
+	Name    string
+	Version int32
+}
+
+func ValidateFolderName(v *Validator, f FolderInfo) {
+	v.Check(strings.TrimSpace(f.Name) != "", "name", "must be provided")
+	v.Check(len(f.Name) <= 255, "name", "must be atmost 255 bytes long")
+	v.Check(!strings.ContainsAny(f.Name, "/\\"), "name", "must not contain slashes")
+}
+
+func ValidateRenameFolder(v *Validator, f FolderInfo) {
+	v.Check(f.Version > 0, "version", "must be greater than zero")
+	ValidateFolderName(v, f)
+}
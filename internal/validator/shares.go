@@ -0,0 +1,20 @@
+package validator
+
+import "time"
+
+type ShareLink struct {
+	Expires      time.Time `json:"expires_at,omitzero"`
+	MaxDownloads int32     `json:"max_downloads,omitzero"`
+	Password     string    `json:"password,omitzero"`
+}
+
+func ValidateShareLink(v *Validator, link *ShareLink) {
+	if !link.Expires.IsZero() {
+		v.Check(link.Expires.After(time.Now()), "expires_at", "must not be in the past")
+	}
+	v.Check(link.MaxDownloads >= 0, "max_downloads", "must not be negative")
+	if link.Password != "" {
+		v.Check(len(link.Password) >= 8, "password", "must be atleast 8 bytes long")
+		v.Check(len(link.Password) <= 72, "password", "must not be more than 72 bytes long")
+	}
+}
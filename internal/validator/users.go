@@ -43,3 +43,27 @@ func ValidateResetPassword(v *Validator, token, password string) {
 	v.Check(password != "", "password", "must be provided")
 	v.Check(len(password) > 8, "password", "must be atleast 8 bytes long")
 }
+
+func ValidateTrashRetention(v *Validator, days int32) {
+	v.Check(days >= 0, "days", "must not be negative")
+	v.Check(days <= 365, "days", "must be a maximum of 365")
+}
+
+// Quota holds storage limits, a nil limit is unlimited or inherited from the user's role
+type Quota struct {
+	MaxBytes *int64 `json:"max_bytes"`
+	MaxFiles *int64 `json:"max_files"`
+}
+
+func ValidateQuota(v *Validator, q Quota) {
+	if q.MaxBytes != nil {
+		v.Check(*q.MaxBytes >= 0, "max_bytes", "must not be negative")
+	}
+	if q.MaxFiles != nil {
+		v.Check(*q.MaxFiles >= 0, "max_files", "must not be negative")
+	}
+}
+
+func ValidateRole(v *Validator, role string) {
+	v.Check(PermittedValue(role, "admin", "user"), "role", "must be either admin or user")
+}
@@ -0,0 +1,158 @@
+// Package webp encodes images in the lossless WebP format, see https://www.rfc-editor.org/rfc/rfc9649.
+// Only encoding is implemented, golang.org/x/image/webp decodes WebP images.
+package webp
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+)
+
+const (
+	// maxDimension is the largest width or height a WebP image can have.
+	maxDimension = 1 << 14
+	// predictorBits is the log2 of the width and height of the blocks sharing a predictor mode.
+	predictorBits = 4
+)
+
+// Transform types written before the image data, in the order they are applied by the encoder.
+const (
+	transformPredictor     = 0
+	transformSubtractGreen = 2
+)
+
+// Encode writes img to w as a lossless WebP image.
+// The subtract green and predictor transforms are applied and pixels are coded with LZ77 backward references.
+func Encode(w io.Writer, img image.Image) error {
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if width < 1 || height < 1 || width > maxDimension || height > maxDimension {
+		return errors.New("webp: invalid image dimensions")
+	}
+
+	argb, hasAlpha := toARGB(img)
+
+	bw := &bitWriter{}
+	bw.write(0x2f, 8)
+	bw.write(uint32(width-1), 14)
+	bw.write(uint32(height-1), 14)
+	bw.write(boolBit(hasAlpha), 1)
+	bw.write(0, 3)
+
+	bw.write(1, 1)
+	bw.write(transformSubtractGreen, 2)
+	subtractGreen(argb)
+
+	bw.write(1, 1)
+	bw.write(transformPredictor, 2)
+	bw.write(predictorBits-2, 3)
+	modes := predict(argb, width, height)
+	// The predictor modes are an entropy coded image without a color cache
+	bw.write(0, 1)
+	writeImageData(bw, modes)
+
+	bw.write(0, 1)
+
+	// The main image has neither a color cache nor meta prefix codes
+	bw.write(0, 1)
+	bw.write(0, 1)
+	writeImageData(bw, argb)
+
+	return writeRIFF(w, bw.bytes())
+}
+
+// toARGB converts img to non-premultiplied ARGB pixels, reporting whether any of them is not fully opaque.
+func toARGB(img image.Image) ([]uint32, bool) {
+	b := img.Bounds()
+	argb := make([]uint32, 0, b.Dx()*b.Dy())
+	hasAlpha := false
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			var c color.NRGBA
+			if nrgba, ok := img.(*image.NRGBA); ok {
+				c = nrgba.NRGBAAt(x, y)
+			} else {
+				c = color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			}
+
+			if c.A != 0xff {
+				hasAlpha = true
+			}
+			argb = append(argb, uint32(c.A)<<24|uint32(c.R)<<16|uint32(c.G)<<8|uint32(c.B))
+		}
+	}
+
+	return argb, hasAlpha
+}
+
+// subtractGreen subtracts the green value of every pixel from its red and blue values,
+// which removes much of the correlation between the channels.
+func subtractGreen(argb []uint32) {
+	for i, p := range argb {
+		green := (p >> 8) & 0xff
+		red := ((p >> 16) - green) & 0xff
+		blue := (p - green) & 0xff
+		argb[i] = p&0xff00ff00 | red<<16 | blue
+	}
+}
+
+// writeRIFF wraps the VP8L bitstream in the RIFF container of a WebP file.
+func writeRIFF(w io.Writer, data []byte) error {
+	pad := len(data) & 1
+
+	header := make([]byte, 20)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(12+len(data)+pad))
+	copy(header[8:], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(header[16:], uint32(len(data)))
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if pad == 1 {
+		_, err := w.Write([]byte{0})
+		return err
+	}
+
+	return nil
+}
+
+// bitWriter writes values to a byte slice least significant bit first.
+type bitWriter struct {
+	buf   []byte
+	acc   uint64
+	nbits uint
+}
+
+// write appends the n low bits of v, n is at most 32.
+func (b *bitWriter) write(v uint32, n uint) {
+	b.acc |= uint64(v) << b.nbits
+	b.nbits += n
+	for b.nbits >= 8 {
+		b.buf = append(b.buf, byte(b.acc))
+		b.acc >>= 8
+		b.nbits -= 8
+	}
+}
+
+// bytes returns the written bits, the last byte is padded with zeros.
+func (b *bitWriter) bytes() []byte {
+	if b.nbits > 0 {
+		b.buf = append(b.buf, byte(b.acc))
+		b.acc, b.nbits = 0, 0
+	}
+	return b.buf
+}
+
+func boolBit(v bool) uint32 {
+	if v {
+		return 1
+	}
+	return 0
+}
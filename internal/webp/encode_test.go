@@ -0,0 +1,155 @@
+package webp
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math/rand/v2"
+	"testing"
+
+	xwebp "golang.org/x/image/webp"
+)
+
+// roundTrip encodes img, decodes the result with golang.org/x/image/webp and
+// checks every decoded pixel matches the non-premultiplied color of the source pixel.
+func roundTrip(t *testing.T, img image.Image) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, img); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	decoded, err := xwebp.Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	b := img.Bounds()
+	if got := decoded.Bounds(); got.Dx() != b.Dx() || got.Dy() != b.Dy() {
+		t.Fatalf("decoded size = %dx%d, want %dx%d", got.Dx(), got.Dy(), b.Dx(), b.Dy())
+	}
+
+	db := decoded.Bounds()
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			want := color.NRGBAModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.NRGBA)
+			got := color.NRGBAModel.Convert(decoded.At(db.Min.X+x, db.Min.Y+y)).(color.NRGBA)
+			if got != want {
+				t.Fatalf("pixel (%d, %d) = %v, want %v", x, y, got, want)
+			}
+		}
+	}
+}
+
+// noise returns an image of random colors, with random alpha values when alpha is set.
+func noise(width, height int, alpha bool, seed uint64) *image.NRGBA {
+	rng := rand.New(rand.NewPCG(seed, seed))
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i] = uint8(rng.Uint32())
+		img.Pix[i+1] = uint8(rng.Uint32())
+		img.Pix[i+2] = uint8(rng.Uint32())
+		img.Pix[i+3] = 0xff
+		if alpha {
+			img.Pix[i+3] = uint8(rng.Uint32())
+		}
+	}
+	return img
+}
+
+// gradient returns a smooth image, which the predictor transform and backward references compress well.
+func gradient(width, height int, alpha bool) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.NRGBA{R: uint8(x * 7), G: uint8(y * 3), B: uint8((x + y) / 2), A: 0xff}
+			if alpha {
+				c.A = uint8(x * 255 / max(width-1, 1))
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// solid returns an image of a single color, whose prefix codes each have a single symbol.
+func solid(width, height int, c color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		img  image.Image
+	}{
+		{name: "1x1 opaque", img: noise(1, 1, false, 1)},
+		{name: "1x1 transparent", img: noise(1, 1, true, 2)},
+		{name: "opaque gradient", img: gradient(64, 48, false)},
+		{name: "alpha gradient", img: gradient(64, 48, true)},
+		{name: "opaque noise", img: noise(40, 30, false, 3)},
+		{name: "alpha noise", img: noise(40, 30, true, 4)},
+		{name: "odd size opaque", img: gradient(37, 23, false)},
+		{name: "odd size alpha", img: noise(19, 33, true, 5)},
+		{name: "single row", img: gradient(101, 1, false)},
+		{name: "single column", img: noise(1, 67, true, 6)},
+		{name: "larger than a predictor block", img: noise(129, 65, false, 7)},
+		{name: "solid color", img: solid(31, 17, color.NRGBA{R: 10, G: 200, B: 30, A: 0xff})},
+		{name: "fully transparent", img: image.NewNRGBA(image.Rect(0, 0, 9, 7))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roundTrip(t, tt.img)
+		})
+	}
+}
+
+func TestEncodeRoundTripOtherModels(t *testing.T) {
+	t.Run("premultiplied RGBA", func(t *testing.T) {
+		img := image.NewRGBA(image.Rect(0, 0, 13, 11))
+		for y := 0; y < 11; y++ {
+			for x := 0; x < 13; x++ {
+				// Fully opaque and fully transparent pixels convert to NRGBA without rounding
+				a := uint8(0xff)
+				if (x+y)%3 == 0 {
+					a = 0
+				}
+				c := color.NRGBA{R: uint8(x * 19), G: uint8(y * 23), B: 90, A: a}
+				img.Set(x, y, c)
+			}
+		}
+		roundTrip(t, img)
+	})
+
+	t.Run("gray", func(t *testing.T) {
+		img := image.NewGray(image.Rect(0, 0, 21, 9))
+		for i := range img.Pix {
+			img.Pix[i] = uint8(i * 5)
+		}
+		roundTrip(t, img)
+	})
+
+	t.Run("sub image with an offset origin", func(t *testing.T) {
+		img := noise(50, 40, true, 8).SubImage(image.Rect(7, 5, 30, 26))
+		roundTrip(t, img)
+	})
+}
+
+func TestEncodeInvalidDimensions(t *testing.T) {
+	for _, r := range []image.Rectangle{
+		image.Rect(0, 0, 0, 10),
+		image.Rect(0, 0, 10, 0),
+		image.Rect(0, 0, maxDimension+1, 1),
+	} {
+		if err := Encode(&bytes.Buffer{}, image.NewNRGBA(r)); err == nil {
+			t.Errorf("Encode of a %dx%d image succeeded, want an error", r.Dx(), r.Dy())
+		}
+	}
+}
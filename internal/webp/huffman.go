@@ -0,0 +1,408 @@
+package webp
+
+import (
+	"cmp"
+	"math/bits"
+	"slices"
+)
+
+const (
+	// numLengthCodes is the number of prefix codes for backward reference lengths, which follow the 256 green values.
+	numLengthCodes   = 24
+	numDistanceCodes = 40
+	// distanceOffset is added to linear distances, smaller distance codes refer to pixels in a 2D neighbourhood.
+	distanceOffset = 120
+
+	maxCodeLength = 15
+	// maxCodeLengthCodeLength is the longest code of the prefix code used to code code lengths.
+	maxCodeLengthCodeLength = 7
+
+	minMatchLength   = 3
+	maxMatchLength   = 4096
+	maxMatchDistance = 1 << 18
+	hashBits         = 16
+	// maxChain bounds the number of earlier positions compared when looking for a match.
+	maxChain = 32
+)
+
+// codeLengthCodeOrder is the order the code lengths of the code length code are written in.
+var codeLengthCodeOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// token is either a literal pixel or a backward reference of `length` pixels `distance` pixels back.
+type token struct {
+	length   int
+	distance int
+	pixel    uint32
+}
+
+// writeImageData writes the prefix codes of the pixels followed by the coded pixels.
+// The five codes are the green values and reference lengths, red, blue, alpha and reference distances.
+func writeImageData(bw *bitWriter, argb []uint32) {
+	tokens := backwardReferences(argb)
+
+	histograms := [5][]uint32{
+		make([]uint32, 256+numLengthCodes),
+		make([]uint32, 256),
+		make([]uint32, 256),
+		make([]uint32, 256),
+		make([]uint32, numDistanceCodes),
+	}
+	for _, t := range tokens {
+		if t.length == 0 {
+			histograms[0][(t.pixel>>8)&0xff]++
+			histograms[1][(t.pixel>>16)&0xff]++
+			histograms[2][t.pixel&0xff]++
+			histograms[3][t.pixel>>24]++
+			continue
+		}
+
+		code, _, _ := prefixEncode(t.length)
+		histograms[0][256+code]++
+		code, _, _ = prefixEncode(t.distance + distanceOffset)
+		histograms[4][code]++
+	}
+
+	var codes [5]prefixCode
+	for i, h := range histograms {
+		codes[i] = writePrefixCode(bw, h)
+	}
+
+	for _, t := range tokens {
+		if t.length == 0 {
+			codes[0].write(bw, int(t.pixel>>8)&0xff)
+			codes[1].write(bw, int(t.pixel>>16)&0xff)
+			codes[2].write(bw, int(t.pixel&0xff))
+			codes[3].write(bw, int(t.pixel>>24))
+			continue
+		}
+
+		code, extraBits, extra := prefixEncode(t.length)
+		codes[0].write(bw, 256+code)
+		bw.write(extra, extraBits)
+
+		code, extraBits, extra = prefixEncode(t.distance + distanceOffset)
+		codes[4].write(bw, code)
+		bw.write(extra, extraBits)
+	}
+}
+
+// backwardReferences finds repeated runs of pixels with hash chains and replaces them with references to earlier ones.
+func backwardReferences(argb []uint32) []token {
+	n := len(argb)
+	tokens := make([]token, 0, n)
+
+	head := make([]int32, 1<<hashBits)
+	for i := range head {
+		head[i] = -1
+	}
+	prev := make([]int32, n)
+
+	insert := func(i int) {
+		if i+1 >= n {
+			return
+		}
+		h := hashPixels(argb[i], argb[i+1])
+		prev[i] = head[h]
+		head[h] = int32(i)
+	}
+
+	for i := 0; i < n; {
+		bestLength, bestDistance := 0, 0
+		if i+1 < n {
+			maxLength := min(maxMatchLength, n-i)
+			candidate := head[hashPixels(argb[i], argb[i+1])]
+			for chain := 0; candidate >= 0 && chain < maxChain && i-int(candidate) <= maxMatchDistance; chain++ {
+				c := int(candidate)
+				length := 0
+				for length < maxLength && argb[c+length] == argb[i+length] {
+					length++
+				}
+				if length > bestLength {
+					bestLength, bestDistance = length, i-c
+					if length == maxLength {
+						break
+					}
+				}
+				candidate = prev[c]
+			}
+		}
+
+		if bestLength < minMatchLength {
+			tokens = append(tokens, token{pixel: argb[i]})
+			insert(i)
+			i++
+			continue
+		}
+
+		tokens = append(tokens, token{length: bestLength, distance: bestDistance})
+		for j := i; j < i+bestLength; j++ {
+			insert(j)
+		}
+		i += bestLength
+	}
+
+	return tokens
+}
+
+func hashPixels(a, b uint32) uint32 {
+	return ((a * 0x9e3779b1) ^ (b * 0x85ebca6b)) >> (32 - hashBits)
+}
+
+// prefixEncode splits a length or distance of at least 1 into its prefix code and the extra bits following it.
+func prefixEncode(v int) (code int, extraBits uint, extra uint32) {
+	d := v - 1
+	if d < 4 {
+		return d, 0, 0
+	}
+
+	highBit := bits.Len(uint(d)) - 1
+	secondBit := (d >> (highBit - 1)) & 1
+	extraBits = uint(highBit - 1)
+
+	return 2*highBit + secondBit, extraBits, uint32(d & (1<<extraBits - 1))
+}
+
+// prefixCode holds the canonical codes of an alphabet, stored bit reversed as they are read most significant bit first.
+// The symbol of a simple code with a single symbol has no length, it is decoded without reading any bits.
+type prefixCode struct {
+	lengths []uint8
+	codes   []uint32
+}
+
+func (c prefixCode) write(bw *bitWriter, symbol int) {
+	if n := c.lengths[symbol]; n > 0 {
+		bw.write(c.codes[symbol], uint(n))
+	}
+}
+
+// writePrefixCode builds a prefix code for the symbol counts of histogram and writes it.
+// Codes of one or two symbols below 256 are written as simple codes, the rest as code lengths.
+func writePrefixCode(bw *bitWriter, histogram []uint32) prefixCode {
+	var used []int
+	for symbol, count := range histogram {
+		if count > 0 {
+			used = append(used, symbol)
+		}
+	}
+
+	lengths := make([]uint8, len(histogram))
+
+	// An unused code, such as the distances of an image without backward references, needs a symbol all the same
+	if len(used) == 0 {
+		used = []int{0}
+	}
+
+	if len(used) <= 2 && used[len(used)-1] < 256 {
+		bw.write(1, 1)
+		bw.write(uint32(len(used)-1), 1)
+		if used[0] < 2 {
+			bw.write(0, 1)
+			bw.write(uint32(used[0]), 1)
+		} else {
+			bw.write(1, 1)
+			bw.write(uint32(used[0]), 8)
+		}
+		if len(used) == 2 {
+			bw.write(uint32(used[1]), 8)
+			lengths[used[0]], lengths[used[1]] = 1, 1
+		}
+		return canonicalCode(lengths)
+	}
+
+	if len(used) == 1 {
+		// A single symbol of 256 or more is paired with an unused one, so it gets a one bit code
+		histogram = slices.Clone(histogram)
+		histogram[0] = 1
+	}
+
+	lengths = codeLengths(histogram, maxCodeLength)
+	writeCodeLengths(bw, lengths)
+
+	return canonicalCode(lengths)
+}
+
+// writeCodeLengths writes the code lengths of a prefix code, run length coded and coded with a code of their own.
+func writeCodeLengths(bw *bitWriter, lengths []uint8) {
+	type rleToken struct {
+		symbol int
+		extra  uint32
+	}
+
+	var tokens []rleToken
+	for i := 0; i < len(lengths); {
+		v := lengths[i]
+		run := 1
+		for i+run < len(lengths) && lengths[i+run] == v {
+			run++
+		}
+		i += run
+
+		if v == 0 {
+			for run >= 11 {
+				r := min(run, 138)
+				tokens = append(tokens, rleToken{symbol: 18, extra: uint32(r - 11)})
+				run -= r
+			}
+			if run >= 3 {
+				tokens = append(tokens, rleToken{symbol: 17, extra: uint32(run - 3)})
+				run = 0
+			}
+		} else {
+			// Code 16 repeats the previous non-zero length, so the length is written once first
+			tokens = append(tokens, rleToken{symbol: int(v)})
+			run--
+			for run >= 3 {
+				r := min(run, 6)
+				tokens = append(tokens, rleToken{symbol: 16, extra: uint32(r - 3)})
+				run -= r
+			}
+		}
+		for ; run > 0; run-- {
+			tokens = append(tokens, rleToken{symbol: int(v)})
+		}
+	}
+
+	histogram := make([]uint32, len(codeLengthCodeOrder))
+	for _, t := range tokens {
+		histogram[t.symbol]++
+	}
+	if nonZero(histogram) == 1 {
+		// A code needs at least two symbols for its symbols to take a bit
+		if histogram[0] == 0 {
+			histogram[0] = 1
+		} else {
+			histogram[1] = 1
+		}
+	}
+
+	codeLengthLengths := codeLengths(histogram, maxCodeLengthCodeLength)
+	codeLengthCode := canonicalCode(codeLengthLengths)
+
+	numCodes := 4
+	for i, symbol := range codeLengthCodeOrder {
+		if codeLengthLengths[symbol] > 0 {
+			numCodes = max(numCodes, i+1)
+		}
+	}
+
+	bw.write(0, 1)
+	bw.write(uint32(numCodes-4), 4)
+	for _, symbol := range codeLengthCodeOrder[:numCodes] {
+		bw.write(uint32(codeLengthLengths[symbol]), 3)
+	}
+
+	// Every symbol of the alphabet is written, rather than stopping after the last used one
+	bw.write(0, 1)
+
+	for _, t := range tokens {
+		codeLengthCode.write(bw, t.symbol)
+		switch t.symbol {
+		case 16:
+			bw.write(t.extra, 2)
+		case 17:
+			bw.write(t.extra, 3)
+		case 18:
+			bw.write(t.extra, 7)
+		}
+	}
+}
+
+func nonZero(histogram []uint32) int {
+	n := 0
+	for _, count := range histogram {
+		if count > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// codeLengths returns the Huffman code lengths of the symbols counted in histogram, no longer than maxLength.
+// Codes which would be too long are avoided by raising the counts of rare symbols until the tree is shallow enough.
+func codeLengths(histogram []uint32, maxLength int) []uint8 {
+	for minCount := uint32(1); ; minCount *= 2 {
+		lengths := huffmanLengths(histogram, minCount)
+		if int(slices.Max(lengths)) <= maxLength {
+			return lengths
+		}
+	}
+}
+
+// huffmanLengths builds a Huffman tree of the used symbols, counting each at least minCount times,
+// and returns the depth of every symbol in it.
+func huffmanLengths(histogram []uint32, minCount uint32) []uint8 {
+	type node struct {
+		weight      uint64
+		symbol      int
+		left, right int
+	}
+
+	var nodes []node
+	for symbol, count := range histogram {
+		if count > 0 {
+			nodes = append(nodes, node{weight: uint64(max(count, minCount)), symbol: symbol, left: -1, right: -1})
+		}
+	}
+	slices.SortStableFunc(nodes, func(a, b node) int {
+		return cmp.Compare(a.weight, b.weight)
+	})
+
+	// Two queue construction, leaves are sorted by weight and merged nodes are created in order of weight
+	leaves := len(nodes)
+	nextLeaf, nextMerged := 0, leaves
+	takeSmallest := func() int {
+		if nextLeaf < leaves && (nextMerged >= len(nodes) || nodes[nextLeaf].weight <= nodes[nextMerged].weight) {
+			nextLeaf++
+			return nextLeaf - 1
+		}
+		nextMerged++
+		return nextMerged - 1
+	}
+
+	for range leaves - 1 {
+		a := takeSmallest()
+		b := takeSmallest()
+		nodes = append(nodes, node{weight: nodes[a].weight + nodes[b].weight, symbol: -1, left: a, right: b})
+	}
+
+	lengths := make([]uint8, len(histogram))
+	var walk func(i int, depth uint8)
+	walk = func(i int, depth uint8) {
+		if nodes[i].symbol >= 0 {
+			lengths[nodes[i].symbol] = max(depth, 1)
+			return
+		}
+		walk(nodes[i].left, depth+1)
+		walk(nodes[i].right, depth+1)
+	}
+	walk(len(nodes)-1, 0)
+
+	return lengths
+}
+
+// canonicalCode assigns canonical codes to the code lengths, shorter codes first and in symbol order for equal lengths.
+func canonicalCode(lengths []uint8) prefixCode {
+	var count [maxCodeLength + 1]uint32
+	for _, n := range lengths {
+		count[n]++
+	}
+	count[0] = 0
+
+	var next [maxCodeLength + 1]uint32
+	code := uint32(0)
+	for n := 1; n <= maxCodeLength; n++ {
+		code = (code + count[n-1]) << 1
+		next[n] = code
+	}
+
+	codes := make([]uint32, len(lengths))
+	for symbol, n := range lengths {
+		if n == 0 {
+			continue
+		}
+		codes[symbol] = bits.Reverse32(next[n]) >> (32 - n)
+		next[n]++
+	}
+
+	return prefixCode{lengths: lengths, codes: codes}
+}
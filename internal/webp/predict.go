@@ -0,0 +1,169 @@
+package webp
+
+// numPredictors is the number of predictor modes defined by the format.
+const numPredictors = 14
+
+// predict replaces every pixel with its residual from the pixel predicted by its neighbours.
+// Each block of 1<<predictorBits pixels square uses the mode which leaves the smallest residuals,
+// the modes are returned as an image of one pixel per block, holding the mode in its green channel.
+func predict(argb []uint32, width, height int) []uint32 {
+	blockSize := 1 << predictorBits
+	modesWidth := (width + blockSize - 1) >> predictorBits
+	modesHeight := (height + blockSize - 1) >> predictorBits
+	modes := make([]uint32, modesWidth*modesHeight)
+
+	// Residuals are computed from the original pixels, which the decoder restores before predicting the next ones
+	residuals := make([]uint32, len(argb))
+
+	for by := range modesHeight {
+		for bx := range modesWidth {
+			x0, y0 := bx*blockSize, by*blockSize
+			x1, y1 := min(x0+blockSize, width), min(y0+blockSize, height)
+
+			best, bestCost := 0, -1
+			for mode := range numPredictors {
+				cost := 0
+				for y := y0; y < y1; y++ {
+					for x := x0; x < x1; x++ {
+						cost += residualCost(residual(argb[y*width+x], predictPixel(argb, width, x, y, mode)))
+					}
+				}
+				if bestCost < 0 || cost < bestCost {
+					best, bestCost = mode, cost
+				}
+			}
+
+			modes[by*modesWidth+bx] = 0xff000000 | uint32(best)<<8
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					residuals[y*width+x] = residual(argb[y*width+x], predictPixel(argb, width, x, y, best))
+				}
+			}
+		}
+	}
+
+	copy(argb, residuals)
+	return modes
+}
+
+// predictPixel predicts the pixel at x, y from its left (L), top (T), top-left (TL) and top-right (TR) neighbours.
+// The top-left pixel is predicted as opaque black, the rest of the top row from L and the left column from T.
+// The TR neighbour of the rightmost column is the leftmost pixel of the current row, as it follows TL in memory.
+func predictPixel(argb []uint32, width, x, y, mode int) uint32 {
+	i := y*width + x
+	switch {
+	case x == 0 && y == 0:
+		return 0xff000000
+	case y == 0:
+		return argb[i-1]
+	case x == 0:
+		return argb[i-width]
+	}
+
+	l, t, tl, tr := argb[i-1], argb[i-width], argb[i-width-1], argb[i-width+1]
+	switch mode {
+	case 0:
+		return 0xff000000
+	case 1:
+		return l
+	case 2:
+		return t
+	case 3:
+		return tr
+	case 4:
+		return tl
+	case 5:
+		return average2(average2(l, tr), t)
+	case 6:
+		return average2(l, tl)
+	case 7:
+		return average2(l, t)
+	case 8:
+		return average2(tl, t)
+	case 9:
+		return average2(t, tr)
+	case 10:
+		return average2(average2(l, tl), average2(t, tr))
+	case 11:
+		return selectPredictor(l, t, tl)
+	case 12:
+		return clampAddSubtractFull(l, t, tl)
+	default:
+		return clampAddSubtractHalf(average2(l, t), tl)
+	}
+}
+
+// channel returns the 8 bit channel of p starting at bit shift.
+func channel(p uint32, shift uint) int {
+	return int((p >> shift) & 0xff)
+}
+
+// perChannel builds a pixel by applying f to the ARGB channels.
+func perChannel(f func(shift uint) int) uint32 {
+	var p uint32
+	for _, shift := range []uint{0, 8, 16, 24} {
+		p |= uint32(f(shift)&0xff) << shift
+	}
+	return p
+}
+
+func clamp(v int) int {
+	return min(max(v, 0), 255)
+}
+
+func average2(a, b uint32) uint32 {
+	return perChannel(func(shift uint) int {
+		return (channel(a, shift) + channel(b, shift)) / 2
+	})
+}
+
+func clampAddSubtractFull(a, b, c uint32) uint32 {
+	return perChannel(func(shift uint) int {
+		return clamp(channel(a, shift) + channel(b, shift) - channel(c, shift))
+	})
+}
+
+func clampAddSubtractHalf(a, b uint32) uint32 {
+	return perChannel(func(shift uint) int {
+		return clamp(channel(a, shift) + (channel(a, shift)-channel(b, shift))/2)
+	})
+}
+
+// selectPredictor returns whichever of L and T is closer to the gradient estimate L + T - TL.
+func selectPredictor(l, t, tl uint32) uint32 {
+	pl, pt := 0, 0
+	for _, shift := range []uint{0, 8, 16, 24} {
+		estimate := channel(l, shift) + channel(t, shift) - channel(tl, shift)
+		pl += abs(estimate - channel(l, shift))
+		pt += abs(estimate - channel(t, shift))
+	}
+
+	if pl < pt {
+		return l
+	}
+	return t
+}
+
+// residual subtracts the predicted pixel from the actual one, channel by channel modulo 256.
+func residual(actual, predicted uint32) uint32 {
+	return perChannel(func(shift uint) int {
+		return channel(actual, shift) - channel(predicted, shift)
+	})
+}
+
+// residualCost estimates how expensive a residual is to code by the size of its channels as signed values.
+func residualCost(r uint32) int {
+	cost := 0
+	for _, shift := range []uint{0, 8, 16, 24} {
+		v := channel(r, shift)
+		cost += min(v, 256-v)
+	}
+	return cost
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -12,9 +12,13 @@ import (
 )
 
 const (
-	TaskGenerateThumbnail = "task:image:generate_thumbnail"
-	TaskSendEmail         = "task:email:send"
-	TaskCleanupSystem     = "task:system:cleaup_expired"
+	TaskGenerateThumbnail    = "task:image:generate_thumbnail"
+	TaskExtractImageMetadata = "task:image:extract_metadata"
+	TaskExtractText          = "task:file:extract_text"
+	TaskScanFile             = "task:file:scan"
+	TaskSendEmail            = "task:email:send"
+	TaskCleanupSystem        = "task:system:cleaup_expired"
+	TaskRewrapDataKeys       = "task:encryption:rewrap_data_keys"
 )
 
 type ThumbnailPayload struct {
@@ -22,6 +26,21 @@ type ThumbnailPayload struct {
 	StorageKey string    `json:"storage_key"`
 }
 
+type ImageMetadataPayload struct {
+	FileID     uuid.UUID `json:"file_id"`
+	StorageKey string    `json:"storage_key"`
+}
+
+type ExtractTextPayload struct {
+	FileID     uuid.UUID `json:"file_id"`
+	StorageKey string    `json:"storage_key"`
+}
+
+type ScanFilePayload struct {
+	FileID     uuid.UUID `json:"file_id"`
+	StorageKey string    `json:"storage_key"`
+}
+
 type EmailPayload struct {
 	TemplateFile string `json:"template_file"`
 	UserID       uuid.UUID
@@ -34,6 +53,9 @@ type CleanupPayload struct{}
 // Distributor defines how to send tasks to the queue
 type Distributor interface {
 	DistributeGenerateThumbnail(ctx context.Context, payload *ThumbnailPayload, opts ...asynq.Option) error
+	DistributeExtractImageMetadata(ctx context.Context, payload *ImageMetadataPayload, opts ...asynq.Option) error
+	DistributeExtractText(ctx context.Context, payload *ExtractTextPayload, opts ...asynq.Option) error
+	DistributeScanFile(ctx context.Context, payload *ScanFilePayload, opts ...asynq.Option) error
 	DistributeSendEmail(ctx context.Context, payload *EmailPayload, opts ...asynq.Option) error
 }
 
@@ -71,6 +93,69 @@ func (d *RedisTaskDistributor) DistributeGenerateThumbnail(ctx context.Context,
 	return nil
 }
 
+func (d *RedisTaskDistributor) DistributeExtractImageMetadata(ctx context.Context, payload *ImageMetadataPayload, opts ...asynq.Option) error {
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task payload: %w", err)
+	}
+
+	task := asynq.NewTask(TaskExtractImageMetadata, jsonPayload, opts...)
+
+	info, err := d.client.EnqueueContext(ctx, task)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue task: %w", err)
+	}
+
+	slog.Info("enqueued task",
+		"type", task.Type(),
+		"queue", info.Queue,
+		"max_retry", info.MaxRetry,
+	)
+	return nil
+}
+
+func (d *RedisTaskDistributor) DistributeExtractText(ctx context.Context, payload *ExtractTextPayload, opts ...asynq.Option) error {
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task payload: %w", err)
+	}
+
+	task := asynq.NewTask(TaskExtractText, jsonPayload, opts...)
+
+	info, err := d.client.EnqueueContext(ctx, task)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue task: %w", err)
+	}
+
+	slog.Info("enqueued task",
+		"type", task.Type(),
+		"queue", info.Queue,
+		"max_retry", info.MaxRetry,
+	)
+	return nil
+}
+
+func (d *RedisTaskDistributor) DistributeScanFile(ctx context.Context, payload *ScanFilePayload, opts ...asynq.Option) error {
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal task payload: %w", err)
+	}
+
+	task := asynq.NewTask(TaskScanFile, jsonPayload, opts...)
+
+	info, err := d.client.EnqueueContext(ctx, task)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue task: %w", err)
+	}
+
+	slog.Info("enqueued task",
+		"type", task.Type(),
+		"queue", info.Queue,
+		"max_retry", info.MaxRetry,
+	)
+	return nil
+}
+
 func (d *RedisTaskDistributor) DistributeSendEmail(ctx context.Context, payload *EmailPayload, opts ...asynq.Option) error {
 	jsonPayload, err := json.Marshal(payload)
 	if err != nil {